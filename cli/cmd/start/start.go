@@ -12,6 +12,7 @@ import (
 func StartCmd(ver version.Version) *cobra.Command {
 	var olapDriver string
 	var olapDSN string
+	var attach []string
 	var projectPath string
 	var httpPort int
 	var grpcPort int
@@ -23,6 +24,18 @@ func StartCmd(ver version.Version) *cobra.Command {
 		Use:   "start",
 		Short: "Build project and start web app",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			// Attach DuckDB files produced by other tools read-only, so their tables can be explored without modifying them
+			if len(attach) > 0 {
+				if olapDriver != "duckdb" {
+					return fmt.Errorf("--attach requires the duckdb driver")
+				}
+				dsn, err := local.AttachDSN(olapDSN, attach)
+				if err != nil {
+					return err
+				}
+				olapDSN = dsn
+			}
+
 			app, err := local.NewApp(cmd.Context(), ver, verbose, olapDriver, olapDSN, projectPath)
 			if err != nil {
 				return err
@@ -41,6 +54,13 @@ func StartCmd(ver version.Version) *cobra.Command {
 				return fmt.Errorf("reconcile project: %w", err)
 			}
 
+			if len(attach) > 0 {
+				err = app.SyncExistingTables()
+				if err != nil {
+					return fmt.Errorf("sync tables: %w", err)
+				}
+			}
+
 			err = app.Serve(httpPort, grpcPort, !noUI, !noOpen)
 			if err != nil {
 				return fmt.Errorf("serve: %w", err)
@@ -55,6 +75,7 @@ func StartCmd(ver version.Version) *cobra.Command {
 	startCmd.Flags().BoolVar(&noOpen, "no-open", false, "Do not open browser")
 	startCmd.Flags().StringVar(&olapDSN, "db", local.DefaultOLAPDSN, "Database DSN")
 	startCmd.Flags().StringVar(&olapDriver, "db-driver", local.DefaultOLAPDriver, "Database driver")
+	startCmd.Flags().StringSliceVar(&attach, "attach", nil, "DuckDB files to attach read-only and show the tables of")
 	startCmd.Flags().IntVar(&httpPort, "port", 9009, "Port for HTTP")
 	startCmd.Flags().IntVar(&grpcPort, "port-grpc", 9010, "Port for gRPC")
 	startCmd.Flags().BoolVar(&noUI, "no-ui", false, "Serve only the backend")
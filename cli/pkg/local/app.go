@@ -6,9 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/mattn/go-colorable"
@@ -29,6 +31,7 @@ import (
 	_ "github.com/rilldata/rill/runtime/connectors/gcs"
 	_ "github.com/rilldata/rill/runtime/connectors/https"
 	_ "github.com/rilldata/rill/runtime/connectors/s3"
+	_ "github.com/rilldata/rill/runtime/drivers/clickhouse"
 	_ "github.com/rilldata/rill/runtime/drivers/druid"
 	_ "github.com/rilldata/rill/runtime/drivers/duckdb"
 	_ "github.com/rilldata/rill/runtime/drivers/file"
@@ -41,6 +44,8 @@ const (
 	DefaultInstanceID = "default"
 	DefaultOLAPDriver = "duckdb"
 	DefaultOLAPDSN    = "stage.db"
+	// DefaultQueryCacheDir is the directory, relative to the project, for persisting cached query results
+	DefaultQueryCacheDir = "tmp/query_cache"
 )
 
 // App encapsulates the logic associated with configuring and running the UI and the runtime in a local environment.
@@ -74,33 +79,42 @@ func NewApp(ctx context.Context, ver version.Version, verbose bool, olapDriver,
 	}
 	logger = logger.WithOptions(zap.IncreaseLevel(lvl))
 
+	// Get full path to project
+	projectPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(projectPath, os.ModePerm) // Create project dir if it doesn't exist
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a local runtime with an in-memory metastore
 	rtOpts := &runtime.Options{
 		ConnectionCacheSize: 100,
 		MetastoreDriver:     "sqlite",
 		MetastoreDSN:        "file:rill?mode=memory&cache=shared",
-		QueryCacheSize:      10000,
-	}
-	rt, err := runtime.New(rtOpts, logger)
-	if err != nil {
-		return nil, err
+		QueryCacheSizeBytes: 100 * 1024 * 1024,
 	}
 
-	// Get full path to project
-	projectPath, err = filepath.Abs(projectPath)
-	if err != nil {
-		return nil, err
+	// If the OLAP is the default OLAP (DuckDB in stage.db), we make it relative to the project directory (not the working directory).
+	// Since the catalog is persisted in stage.db, we also persist cached query results in the project directory.
+	// The DSN may have options, such as attached files (see AttachDSN).
+	dbPath, dbOptions, hasOptions := strings.Cut(olapDSN, "?")
+	if olapDriver == DefaultOLAPDriver && dbPath == DefaultOLAPDSN {
+		olapDSN = path.Join(projectPath, dbPath)
+		if hasOptions {
+			olapDSN += "?" + dbOptions
+		}
+		rtOpts.QueryCacheDir = path.Join(projectPath, DefaultQueryCacheDir)
+		rtOpts.QueryCacheDirSizeBytes = 1024 * 1024 * 1024
 	}
-	err = os.MkdirAll(projectPath, os.ModePerm) // Create project dir if it doesn't exist
+
+	rt, err := runtime.New(rtOpts, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	// If the OLAP is the default OLAP (DuckDB in stage.db), we make it relative to the project directory (not the working directory)
-	if olapDriver == DefaultOLAPDriver && olapDSN == DefaultOLAPDSN {
-		olapDSN = path.Join(projectPath, olapDSN)
-	}
-
 	// Create instance with its repo set to the project directory
 	inst := &drivers.Instance{
 		ID:           DefaultInstanceID,
@@ -225,6 +239,44 @@ func (a *App) Reconcile() error {
 	return nil
 }
 
+// SyncExistingTables adds the tables in the OLAP store that are not created by the project to the catalog.
+// It's used to show the tables of attached DuckDB files.
+func (a *App) SyncExistingTables() error {
+	err := a.Runtime.SyncExistingTables(a.Context, a.Instance.ID)
+	if err != nil {
+		return err
+	}
+
+	cat, err := a.Runtime.Catalog(a.Context, a.Instance.ID)
+	if err != nil {
+		return err
+	}
+	for _, obj := range cat.FindEntries(a.Context, drivers.ObjectTypeTable) {
+		if !obj.GetTable().Managed {
+			a.Logger.Infof("Found table: %s", obj.Name)
+		}
+	}
+	return nil
+}
+
+// AttachDSN adds DuckDB files to a DuckDB DSN, which are attached read-only when the database is opened.
+// Relative paths are resolved against the working directory.
+func AttachDSN(dsn string, paths []string) (string, error) {
+	qry := url.Values{}
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		qry.Add("attach", p)
+	}
+
+	if strings.Contains(dsn, "?") {
+		return dsn + "&" + qry.Encode(), nil
+	}
+	return dsn + "?" + qry.Encode(), nil
+}
+
 func (a *App) ReconcileSource(sourcePath string) error {
 	a.Logger.Infof("Reconciling source and impacted models in project '%s'", a.ProjectPath)
 	paths := []string{sourcePath}
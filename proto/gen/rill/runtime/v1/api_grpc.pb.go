@@ -28,6 +28,8 @@ type RuntimeServiceClient interface {
 	ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error)
 	// GetInstance returns information about a specific instance
 	GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GetInstanceResponse, error)
+	// GetInstanceCapabilities returns the queries and features supported by an instance's OLAP datastore
+	GetInstanceCapabilities(ctx context.Context, in *GetInstanceCapabilitiesRequest, opts ...grpc.CallOption) (*GetInstanceCapabilitiesResponse, error)
 	// CreateInstance creates a new instance
 	CreateInstance(ctx context.Context, in *CreateInstanceRequest, opts ...grpc.CallOption) (*CreateInstanceResponse, error)
 	// DeleteInstance deletes an instance
@@ -69,6 +71,35 @@ type RuntimeServiceClient interface {
 	RefreshAndReconcile(ctx context.Context, in *RefreshAndReconcileRequest, opts ...grpc.CallOption) (*RefreshAndReconcileResponse, error)
 	// Query runs a SQL query against the instance's OLAP datastore.
 	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// QueryStream runs a SQL query against the instance's OLAP datastore and streams the results in batches of rows.
+	// Rows are sent as they're read from the datastore (including for queries against metrics views, whose results
+	// are not cached when streamed). Over HTTP, the batches are returned as newline-delimited JSON.
+	QueryStream(ctx context.Context, in *QueryStreamRequest, opts ...grpc.CallOption) (RuntimeService_QueryStreamClient, error)
+	// ExplainQuery returns the OLAP engine's plan for a SQL or metrics view query.
+	// If analyze is true, it also executes the query and returns a profile with operator timings and row counts.
+	// Explaining queries against metrics views with a security policy requires admin access.
+	ExplainQuery(ctx context.Context, in *ExplainQueryRequest, opts ...grpc.CallOption) (*ExplainQueryResponse, error)
+	// CancelQuery cancels a query started with RuntimeService.Query or RuntimeService.QueryStream.
+	// Callers can only cancel their own queries unless they're admins.
+	CancelQuery(ctx context.Context, in *CancelQueryRequest, opts ...grpc.CallOption) (*CancelQueryResponse, error)
+	// ListRunningQueries lists the queries that are running or waiting in the OLAP datastore's queue.
+	// Callers only see their own queries unless they're admins (who also see internal statements).
+	ListRunningQueries(ctx context.Context, in *ListRunningQueriesRequest, opts ...grpc.CallOption) (*ListRunningQueriesResponse, error)
+	// ListQueryHistory lists the most recently executed queries for an instance, newest first.
+	// Callers only see their own queries unless they're admins. Row filters are shown unrendered, without the callers' attributes.
+	ListQueryHistory(ctx context.Context, in *ListQueryHistoryRequest, opts ...grpc.CallOption) (*ListQueryHistoryResponse, error)
+	// FlushQueryCache removes cached query results for an instance.
+	// If dependency_name is set, it only removes results of queries that target the named source, model or metrics view.
+	FlushQueryCache(ctx context.Context, in *FlushQueryCacheRequest, opts ...grpc.CallOption) (*FlushQueryCacheResponse, error)
+	// CreateExport starts an async job that exports the result of a query to a file.
+	// Use GetExport to poll the job's status and DownloadExport to fetch the file when it has completed.
+	CreateExport(ctx context.Context, in *CreateExportRequest, opts ...grpc.CallOption) (*CreateExportResponse, error)
+	// GetExport returns the status of an export job
+	GetExport(ctx context.Context, in *GetExportRequest, opts ...grpc.CallOption) (*GetExportResponse, error)
+	// DownloadExport streams the file of a completed export job in chunks.
+	// It's only available over gRPC. Over HTTP, use GET /v1/instances/{instance_id}/exports/{export_id}/download,
+	// which returns the file as a regular download.
+	DownloadExport(ctx context.Context, in *DownloadExportRequest, opts ...grpc.CallOption) (RuntimeService_DownloadExportClient, error)
 	// MetricsViewToplist returns the top dimension values of a metrics view sorted by one or more measures.
 	// It's a convenience API for querying a metrics view.
 	MetricsViewToplist(ctx context.Context, in *MetricsViewToplistRequest, opts ...grpc.CallOption) (*MetricsViewToplistResponse, error)
@@ -78,6 +109,9 @@ type RuntimeServiceClient interface {
 	// MetricsViewTotals returns totals over a time period for the measures in a metrics view.
 	// It's a convenience API for querying a metrics view.
 	MetricsViewTotals(ctx context.Context, in *MetricsViewTotalsRequest, opts ...grpc.CallOption) (*MetricsViewTotalsResponse, error)
+	// MetricsViewDimensionValues returns the distinct values of a dimension in a metrics view with their row counts.
+	// It supports searching the values, e.g. for populating filters.
+	MetricsViewDimensionValues(ctx context.Context, in *MetricsViewDimensionValuesRequest, opts ...grpc.CallOption) (*MetricsViewDimensionValuesResponse, error)
 	// EstimateRollupInterval (TODO: add description)
 	EstimateRollupInterval(ctx context.Context, in *EstimateRollupIntervalRequest, opts ...grpc.CallOption) (*EstimateRollupIntervalResponse, error)
 	// Get TopK elements from a table for a column given an agg function
@@ -145,6 +179,15 @@ func (c *runtimeServiceClient) GetInstance(ctx context.Context, in *GetInstanceR
 	return out, nil
 }
 
+func (c *runtimeServiceClient) GetInstanceCapabilities(ctx context.Context, in *GetInstanceCapabilitiesRequest, opts ...grpc.CallOption) (*GetInstanceCapabilitiesResponse, error) {
+	out := new(GetInstanceCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/GetInstanceCapabilities", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *runtimeServiceClient) CreateInstance(ctx context.Context, in *CreateInstanceRequest, opts ...grpc.CallOption) (*CreateInstanceResponse, error) {
 	out := new(CreateInstanceResponse)
 	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/CreateInstance", in, out, opts...)
@@ -298,6 +341,133 @@ func (c *runtimeServiceClient) Query(ctx context.Context, in *QueryRequest, opts
 	return out, nil
 }
 
+func (c *runtimeServiceClient) QueryStream(ctx context.Context, in *QueryStreamRequest, opts ...grpc.CallOption) (RuntimeService_QueryStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RuntimeService_ServiceDesc.Streams[0], "/rill.runtime.v1.RuntimeService/QueryStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &runtimeServiceQueryStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RuntimeService_QueryStreamClient interface {
+	Recv() (*QueryStreamResponse, error)
+	grpc.ClientStream
+}
+
+type runtimeServiceQueryStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *runtimeServiceQueryStreamClient) Recv() (*QueryStreamResponse, error) {
+	m := new(QueryStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *runtimeServiceClient) ExplainQuery(ctx context.Context, in *ExplainQueryRequest, opts ...grpc.CallOption) (*ExplainQueryResponse, error) {
+	out := new(ExplainQueryResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/ExplainQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) CancelQuery(ctx context.Context, in *CancelQueryRequest, opts ...grpc.CallOption) (*CancelQueryResponse, error) {
+	out := new(CancelQueryResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/CancelQuery", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) ListRunningQueries(ctx context.Context, in *ListRunningQueriesRequest, opts ...grpc.CallOption) (*ListRunningQueriesResponse, error) {
+	out := new(ListRunningQueriesResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/ListRunningQueries", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) ListQueryHistory(ctx context.Context, in *ListQueryHistoryRequest, opts ...grpc.CallOption) (*ListQueryHistoryResponse, error) {
+	out := new(ListQueryHistoryResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/ListQueryHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) FlushQueryCache(ctx context.Context, in *FlushQueryCacheRequest, opts ...grpc.CallOption) (*FlushQueryCacheResponse, error) {
+	out := new(FlushQueryCacheResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/FlushQueryCache", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) CreateExport(ctx context.Context, in *CreateExportRequest, opts ...grpc.CallOption) (*CreateExportResponse, error) {
+	out := new(CreateExportResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/CreateExport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) GetExport(ctx context.Context, in *GetExportRequest, opts ...grpc.CallOption) (*GetExportResponse, error) {
+	out := new(GetExportResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/GetExport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *runtimeServiceClient) DownloadExport(ctx context.Context, in *DownloadExportRequest, opts ...grpc.CallOption) (RuntimeService_DownloadExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RuntimeService_ServiceDesc.Streams[1], "/rill.runtime.v1.RuntimeService/DownloadExport", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &runtimeServiceDownloadExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RuntimeService_DownloadExportClient interface {
+	Recv() (*DownloadExportResponse, error)
+	grpc.ClientStream
+}
+
+type runtimeServiceDownloadExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *runtimeServiceDownloadExportClient) Recv() (*DownloadExportResponse, error) {
+	m := new(DownloadExportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *runtimeServiceClient) MetricsViewToplist(ctx context.Context, in *MetricsViewToplistRequest, opts ...grpc.CallOption) (*MetricsViewToplistResponse, error) {
 	out := new(MetricsViewToplistResponse)
 	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/MetricsViewToplist", in, out, opts...)
@@ -325,6 +495,15 @@ func (c *runtimeServiceClient) MetricsViewTotals(ctx context.Context, in *Metric
 	return out, nil
 }
 
+func (c *runtimeServiceClient) MetricsViewDimensionValues(ctx context.Context, in *MetricsViewDimensionValuesRequest, opts ...grpc.CallOption) (*MetricsViewDimensionValuesResponse, error) {
+	out := new(MetricsViewDimensionValuesResponse)
+	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/MetricsViewDimensionValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *runtimeServiceClient) EstimateRollupInterval(ctx context.Context, in *EstimateRollupIntervalRequest, opts ...grpc.CallOption) (*EstimateRollupIntervalResponse, error) {
 	out := new(EstimateRollupIntervalResponse)
 	err := c.cc.Invoke(ctx, "/rill.runtime.v1.RuntimeService/EstimateRollupInterval", in, out, opts...)
@@ -461,6 +640,8 @@ type RuntimeServiceServer interface {
 	ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error)
 	// GetInstance returns information about a specific instance
 	GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error)
+	// GetInstanceCapabilities returns the queries and features supported by an instance's OLAP datastore
+	GetInstanceCapabilities(context.Context, *GetInstanceCapabilitiesRequest) (*GetInstanceCapabilitiesResponse, error)
 	// CreateInstance creates a new instance
 	CreateInstance(context.Context, *CreateInstanceRequest) (*CreateInstanceResponse, error)
 	// DeleteInstance deletes an instance
@@ -502,6 +683,35 @@ type RuntimeServiceServer interface {
 	RefreshAndReconcile(context.Context, *RefreshAndReconcileRequest) (*RefreshAndReconcileResponse, error)
 	// Query runs a SQL query against the instance's OLAP datastore.
 	Query(context.Context, *QueryRequest) (*QueryResponse, error)
+	// QueryStream runs a SQL query against the instance's OLAP datastore and streams the results in batches of rows.
+	// Rows are sent as they're read from the datastore (including for queries against metrics views, whose results
+	// are not cached when streamed). Over HTTP, the batches are returned as newline-delimited JSON.
+	QueryStream(*QueryStreamRequest, RuntimeService_QueryStreamServer) error
+	// ExplainQuery returns the OLAP engine's plan for a SQL or metrics view query.
+	// If analyze is true, it also executes the query and returns a profile with operator timings and row counts.
+	// Explaining queries against metrics views with a security policy requires admin access.
+	ExplainQuery(context.Context, *ExplainQueryRequest) (*ExplainQueryResponse, error)
+	// CancelQuery cancels a query started with RuntimeService.Query or RuntimeService.QueryStream.
+	// Callers can only cancel their own queries unless they're admins.
+	CancelQuery(context.Context, *CancelQueryRequest) (*CancelQueryResponse, error)
+	// ListRunningQueries lists the queries that are running or waiting in the OLAP datastore's queue.
+	// Callers only see their own queries unless they're admins (who also see internal statements).
+	ListRunningQueries(context.Context, *ListRunningQueriesRequest) (*ListRunningQueriesResponse, error)
+	// ListQueryHistory lists the most recently executed queries for an instance, newest first.
+	// Callers only see their own queries unless they're admins. Row filters are shown unrendered, without the callers' attributes.
+	ListQueryHistory(context.Context, *ListQueryHistoryRequest) (*ListQueryHistoryResponse, error)
+	// FlushQueryCache removes cached query results for an instance.
+	// If dependency_name is set, it only removes results of queries that target the named source, model or metrics view.
+	FlushQueryCache(context.Context, *FlushQueryCacheRequest) (*FlushQueryCacheResponse, error)
+	// CreateExport starts an async job that exports the result of a query to a file.
+	// Use GetExport to poll the job's status and DownloadExport to fetch the file when it has completed.
+	CreateExport(context.Context, *CreateExportRequest) (*CreateExportResponse, error)
+	// GetExport returns the status of an export job
+	GetExport(context.Context, *GetExportRequest) (*GetExportResponse, error)
+	// DownloadExport streams the file of a completed export job in chunks.
+	// It's only available over gRPC. Over HTTP, use GET /v1/instances/{instance_id}/exports/{export_id}/download,
+	// which returns the file as a regular download.
+	DownloadExport(*DownloadExportRequest, RuntimeService_DownloadExportServer) error
 	// MetricsViewToplist returns the top dimension values of a metrics view sorted by one or more measures.
 	// It's a convenience API for querying a metrics view.
 	MetricsViewToplist(context.Context, *MetricsViewToplistRequest) (*MetricsViewToplistResponse, error)
@@ -511,6 +721,9 @@ type RuntimeServiceServer interface {
 	// MetricsViewTotals returns totals over a time period for the measures in a metrics view.
 	// It's a convenience API for querying a metrics view.
 	MetricsViewTotals(context.Context, *MetricsViewTotalsRequest) (*MetricsViewTotalsResponse, error)
+	// MetricsViewDimensionValues returns the distinct values of a dimension in a metrics view with their row counts.
+	// It supports searching the values, e.g. for populating filters.
+	MetricsViewDimensionValues(context.Context, *MetricsViewDimensionValuesRequest) (*MetricsViewDimensionValuesResponse, error)
 	// EstimateRollupInterval (TODO: add description)
 	EstimateRollupInterval(context.Context, *EstimateRollupIntervalRequest) (*EstimateRollupIntervalResponse, error)
 	// Get TopK elements from a table for a column given an agg function
@@ -557,6 +770,9 @@ func (UnimplementedRuntimeServiceServer) ListInstances(context.Context, *ListIns
 func (UnimplementedRuntimeServiceServer) GetInstance(context.Context, *GetInstanceRequest) (*GetInstanceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetInstance not implemented")
 }
+func (UnimplementedRuntimeServiceServer) GetInstanceCapabilities(context.Context, *GetInstanceCapabilitiesRequest) (*GetInstanceCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInstanceCapabilities not implemented")
+}
 func (UnimplementedRuntimeServiceServer) CreateInstance(context.Context, *CreateInstanceRequest) (*CreateInstanceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateInstance not implemented")
 }
@@ -608,6 +824,33 @@ func (UnimplementedRuntimeServiceServer) RefreshAndReconcile(context.Context, *R
 func (UnimplementedRuntimeServiceServer) Query(context.Context, *QueryRequest) (*QueryResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
 }
+func (UnimplementedRuntimeServiceServer) QueryStream(*QueryStreamRequest, RuntimeService_QueryStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method QueryStream not implemented")
+}
+func (UnimplementedRuntimeServiceServer) ExplainQuery(context.Context, *ExplainQueryRequest) (*ExplainQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExplainQuery not implemented")
+}
+func (UnimplementedRuntimeServiceServer) CancelQuery(context.Context, *CancelQueryRequest) (*CancelQueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelQuery not implemented")
+}
+func (UnimplementedRuntimeServiceServer) ListRunningQueries(context.Context, *ListRunningQueriesRequest) (*ListRunningQueriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRunningQueries not implemented")
+}
+func (UnimplementedRuntimeServiceServer) ListQueryHistory(context.Context, *ListQueryHistoryRequest) (*ListQueryHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQueryHistory not implemented")
+}
+func (UnimplementedRuntimeServiceServer) FlushQueryCache(context.Context, *FlushQueryCacheRequest) (*FlushQueryCacheResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FlushQueryCache not implemented")
+}
+func (UnimplementedRuntimeServiceServer) CreateExport(context.Context, *CreateExportRequest) (*CreateExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateExport not implemented")
+}
+func (UnimplementedRuntimeServiceServer) GetExport(context.Context, *GetExportRequest) (*GetExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExport not implemented")
+}
+func (UnimplementedRuntimeServiceServer) DownloadExport(*DownloadExportRequest, RuntimeService_DownloadExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadExport not implemented")
+}
 func (UnimplementedRuntimeServiceServer) MetricsViewToplist(context.Context, *MetricsViewToplistRequest) (*MetricsViewToplistResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MetricsViewToplist not implemented")
 }
@@ -617,6 +860,9 @@ func (UnimplementedRuntimeServiceServer) MetricsViewTimeSeries(context.Context,
 func (UnimplementedRuntimeServiceServer) MetricsViewTotals(context.Context, *MetricsViewTotalsRequest) (*MetricsViewTotalsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MetricsViewTotals not implemented")
 }
+func (UnimplementedRuntimeServiceServer) MetricsViewDimensionValues(context.Context, *MetricsViewDimensionValuesRequest) (*MetricsViewDimensionValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MetricsViewDimensionValues not implemented")
+}
 func (UnimplementedRuntimeServiceServer) EstimateRollupInterval(context.Context, *EstimateRollupIntervalRequest) (*EstimateRollupIntervalResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method EstimateRollupInterval not implemented")
 }
@@ -726,6 +972,24 @@ func _RuntimeService_GetInstance_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RuntimeService_GetInstanceCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInstanceCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).GetInstanceCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/GetInstanceCapabilities",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).GetInstanceCapabilities(ctx, req.(*GetInstanceCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RuntimeService_CreateInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CreateInstanceRequest)
 	if err := dec(in); err != nil {
@@ -1032,6 +1296,174 @@ func _RuntimeService_Query_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RuntimeService_QueryStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RuntimeServiceServer).QueryStream(m, &runtimeServiceQueryStreamServer{stream})
+}
+
+type RuntimeService_QueryStreamServer interface {
+	Send(*QueryStreamResponse) error
+	grpc.ServerStream
+}
+
+type runtimeServiceQueryStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *runtimeServiceQueryStreamServer) Send(m *QueryStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RuntimeService_ExplainQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).ExplainQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/ExplainQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).ExplainQuery(ctx, req.(*ExplainQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_CancelQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelQueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).CancelQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/CancelQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).CancelQuery(ctx, req.(*CancelQueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_ListRunningQueries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunningQueriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).ListRunningQueries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/ListRunningQueries",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).ListRunningQueries(ctx, req.(*ListRunningQueriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_ListQueryHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQueryHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).ListQueryHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/ListQueryHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).ListQueryHistory(ctx, req.(*ListQueryHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_FlushQueryCache_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushQueryCacheRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).FlushQueryCache(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/FlushQueryCache",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).FlushQueryCache(ctx, req.(*FlushQueryCacheRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_CreateExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).CreateExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/CreateExport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).CreateExport(ctx, req.(*CreateExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_GetExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).GetExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/GetExport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).GetExport(ctx, req.(*GetExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuntimeService_DownloadExport_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RuntimeServiceServer).DownloadExport(m, &runtimeServiceDownloadExportServer{stream})
+}
+
+type RuntimeService_DownloadExportServer interface {
+	Send(*DownloadExportResponse) error
+	grpc.ServerStream
+}
+
+type runtimeServiceDownloadExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *runtimeServiceDownloadExportServer) Send(m *DownloadExportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _RuntimeService_MetricsViewToplist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(MetricsViewToplistRequest)
 	if err := dec(in); err != nil {
@@ -1086,6 +1518,24 @@ func _RuntimeService_MetricsViewTotals_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _RuntimeService_MetricsViewDimensionValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsViewDimensionValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuntimeServiceServer).MetricsViewDimensionValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rill.runtime.v1.RuntimeService/MetricsViewDimensionValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuntimeServiceServer).MetricsViewDimensionValues(ctx, req.(*MetricsViewDimensionValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _RuntimeService_EstimateRollupInterval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(EstimateRollupIntervalRequest)
 	if err := dec(in); err != nil {
@@ -1357,6 +1807,10 @@ var RuntimeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetInstance",
 			Handler:    _RuntimeService_GetInstance_Handler,
 		},
+		{
+			MethodName: "GetInstanceCapabilities",
+			Handler:    _RuntimeService_GetInstanceCapabilities_Handler,
+		},
 		{
 			MethodName: "CreateInstance",
 			Handler:    _RuntimeService_CreateInstance_Handler,
@@ -1425,6 +1879,34 @@ var RuntimeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Query",
 			Handler:    _RuntimeService_Query_Handler,
 		},
+		{
+			MethodName: "ExplainQuery",
+			Handler:    _RuntimeService_ExplainQuery_Handler,
+		},
+		{
+			MethodName: "CancelQuery",
+			Handler:    _RuntimeService_CancelQuery_Handler,
+		},
+		{
+			MethodName: "ListRunningQueries",
+			Handler:    _RuntimeService_ListRunningQueries_Handler,
+		},
+		{
+			MethodName: "ListQueryHistory",
+			Handler:    _RuntimeService_ListQueryHistory_Handler,
+		},
+		{
+			MethodName: "FlushQueryCache",
+			Handler:    _RuntimeService_FlushQueryCache_Handler,
+		},
+		{
+			MethodName: "CreateExport",
+			Handler:    _RuntimeService_CreateExport_Handler,
+		},
+		{
+			MethodName: "GetExport",
+			Handler:    _RuntimeService_GetExport_Handler,
+		},
 		{
 			MethodName: "MetricsViewToplist",
 			Handler:    _RuntimeService_MetricsViewToplist_Handler,
@@ -1437,6 +1919,10 @@ var RuntimeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MetricsViewTotals",
 			Handler:    _RuntimeService_MetricsViewTotals_Handler,
 		},
+		{
+			MethodName: "MetricsViewDimensionValues",
+			Handler:    _RuntimeService_MetricsViewDimensionValues_Handler,
+		},
 		{
 			MethodName: "EstimateRollupInterval",
 			Handler:    _RuntimeService_EstimateRollupInterval_Handler,
@@ -1494,6 +1980,17 @@ var RuntimeService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _RuntimeService_ListConnectors_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "QueryStream",
+			Handler:       _RuntimeService_QueryStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadExport",
+			Handler:       _RuntimeService_DownloadExport_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "rill/runtime/v1/api.proto",
 }
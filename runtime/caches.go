@@ -1,13 +1,23 @@
 package runtime
 
 import (
+	"container/list"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
-
-	lru "github.com/hashicorp/golang-lru"
+	"time"
 
 	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/services/catalog"
 )
@@ -84,7 +94,7 @@ func (c *catalogCache) get(ctx context.Context, rt *Runtime, instID string) (*ca
 		return nil, err
 	}
 
-	olapConn, err := rt.connCache.get(ctx, instID, inst.OLAPDriver, inst.OLAPDSN)
+	olapConn, err := rt.connCache.get(ctx, instID, inst.OLAPDriver, olapDSN(inst))
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +102,7 @@ func (c *catalogCache) get(ctx context.Context, rt *Runtime, instID string) (*ca
 
 	var catalogStore drivers.CatalogStore
 	if inst.EmbedCatalog {
-		conn, err := rt.connCache.get(ctx, inst.ID, inst.OLAPDriver, inst.OLAPDSN)
+		conn, err := rt.connCache.get(ctx, inst.ID, inst.OLAPDriver, olapDSN(inst))
 		if err != nil {
 			return nil, err
 		}
@@ -122,22 +132,479 @@ func (c *catalogCache) get(ctx context.Context, rt *Runtime, instID string) (*ca
 	return service, nil
 }
 
+// queryCache is an LRU cache for query results bounded by the estimated size of the results in bytes.
+// Entries can optionally expire after a TTL.
 type queryCache struct {
-	cache *lru.Cache
+	maxBytes   int64
+	defaultTTL time.Duration
+	bytes      int64
+	list       *list.List
+	items      map[queryCacheKey]*list.Element
+	lock       sync.Mutex
 }
 
-func newQueryCache(size int) *queryCache {
-	cache, err := lru.New(size)
-	if err != nil {
-		panic(err)
+type queryCacheEntry struct {
+	key       queryCacheKey
+	deps      []string
+	value     any
+	bytes     int64
+	expiresOn time.Time
+}
+
+func newQueryCache(maxBytes int64, defaultTTL time.Duration) *queryCache {
+	return &queryCache{
+		maxBytes:   maxBytes,
+		defaultTTL: defaultTTL,
+		list:       list.New(),
+		items:      make(map[queryCacheKey]*list.Element),
 	}
-	return &queryCache{cache: cache}
 }
 
 func (c *queryCache) get(key queryCacheKey) (any, bool) {
-	return c.cache.Get(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		queryCacheMisses.Inc()
+		return nil, false
+	}
+
+	entry := el.Value.(*queryCacheEntry)
+	if !entry.expiresOn.IsZero() && time.Now().After(entry.expiresOn) {
+		c.remove(el)
+		queryCacheMisses.Inc()
+		return nil, false
+	}
+
+	c.list.MoveToFront(el)
+	queryCacheHits.Inc()
+	return entry.value, true
+}
+
+// add adds a value to the cache, evicting the least recently used entries until it fits.
+// It returns false if the value is larger than the cache. If ttl is 0, the cache's default TTL is used.
+func (c *queryCache) add(key queryCacheKey, deps []string, value any, bytes int64, ttl time.Duration) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+
+	if bytes > c.maxBytes {
+		return false
+	}
+
+	for c.bytes+bytes > c.maxBytes {
+		c.remove(c.list.Back())
+		queryCacheEvictions.Inc()
+	}
+
+	if ttl == 0 {
+		ttl = c.defaultTTL
+	}
+	entry := &queryCacheEntry{
+		key:   key,
+		deps:  deps,
+		value: value,
+		bytes: bytes,
+	}
+	if ttl > 0 {
+		entry.expiresOn = time.Now().Add(ttl)
+	}
+
+	c.items[key] = c.list.PushFront(entry)
+	c.bytes += bytes
+	queryCacheBytes.Add(float64(bytes))
+	return true
 }
 
-func (c *queryCache) add(key queryCacheKey, value any) bool {
-	return c.cache.Add(key, value)
+// flush removes the cached results for an instance. If dependency is not empty,
+// it only removes results of queries that depend on the named object. It returns the keys of the removed entries.
+func (c *queryCache) flush(instanceID, dependency string) []queryCacheKey {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var keys []queryCacheKey
+	for el := c.list.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*queryCacheEntry)
+		if entry.key.instanceID == instanceID && (dependency == "" || containsDependency(entry.deps, dependency)) {
+			c.remove(el)
+			keys = append(keys, entry.key)
+		}
+		el = next
+	}
+	return keys
+}
+
+func (c *queryCache) remove(el *list.Element) {
+	entry := c.list.Remove(el).(*queryCacheEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.bytes
+	queryCacheBytes.Sub(float64(entry.bytes))
+}
+
+// persistentQueryCache stores serialized query results in a local directory, so they survive restarts.
+// Results are stored in a file per query in a sub-directory per instance.
+// Since the file names are derived from the full cache key (including the dependencies' versions),
+// results are implicitly invalidated when the catalog objects they depend on change. The files of invalidated
+// results are removed by sweep. If maxBytes is not 0, the least recently used results are removed when the total size
+// of the files exceeds it. The sizes and access order of the files are tracked in memory (and loaded from the directory
+// on startup, using the file modification times as access times), so eviction doesn't need to scan the directory.
+type persistentQueryCache struct {
+	dir      string
+	maxBytes int64
+	bytes    int64
+	// list contains a *persistentQueryCacheFile for each stored result, ordered from most to least recently used
+	list  *list.List
+	items map[string]*list.Element
+	lock  sync.Mutex
+}
+
+// persistentQueryCacheEntry is the serialized format of entries in the persistent query cache
+type persistentQueryCacheEntry struct {
+	Deps          []string  `json:"deps"`
+	DependencyKey string    `json:"dependency_key"`
+	ExpiresOn     time.Time `json:"expires_on,omitempty"`
+	Data          []byte    `json:"data"`
 }
+
+func newPersistentQueryCache(dir string, maxBytes int64) (*persistentQueryCache, error) {
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("could not create query cache directory: %w", err)
+	}
+
+	c := &persistentQueryCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		list:     list.New(),
+		items:    make(map[string]*list.Element),
+	}
+
+	// Index the results persisted by previous runs and remove leftover temporary files
+	files, err := c.files()
+	if err != nil {
+		return nil, fmt.Errorf("could not read query cache directory: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if strings.HasPrefix(filepath.Base(f.path), "tmp-") {
+			_ = os.Remove(f.path)
+			continue
+		}
+		c.touchLocked(f.path, f.size)
+	}
+	c.evict()
+
+	return c, nil
+}
+
+func (c *persistentQueryCache) get(key queryCacheKey) ([]byte, bool) {
+	path := c.path(key)
+	entry, err := c.read(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if !entry.ExpiresOn.IsZero() && time.Now().After(entry.ExpiresOn) {
+		_ = c.remove(path)
+		return nil, false
+	}
+
+	// Mark the result as recently used (the modification time keeps the order across restarts)
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	c.lock.Lock()
+	if el, ok := c.items[path]; ok {
+		c.list.MoveToFront(el)
+	}
+	c.lock.Unlock()
+
+	return entry.Data, true
+}
+
+// add stores a serialized result. If ttl is 0, the result doesn't expire.
+func (c *persistentQueryCache) add(key queryCacheKey, deps []string, data []byte, ttl time.Duration) error {
+	entry := persistentQueryCacheEntry{
+		Deps:          deps,
+		DependencyKey: key.dependencyKey,
+		Data:          data,
+	}
+	if ttl > 0 {
+		entry.ExpiresOn = time.Now().Add(ttl)
+	}
+
+	blob, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	dir := c.instanceDir(key.instanceID)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it to avoid partially written entries
+	f, err := os.CreateTemp(dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(blob)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.path(key)
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	c.touchLocked(path, int64(len(blob)))
+
+	c.evictLocked()
+	return nil
+}
+
+// sweep removes the stored results for an instance whose dependencies have changed since they were stored.
+// The dependencyKey function should return the current dependency key for a result's dependencies.
+// It returns the number of removed results.
+func (c *persistentQueryCache) sweep(instanceID string, dependencyKey func(deps []string) (string, error)) (int, error) {
+	files, err := os.ReadDir(c.instanceDir(instanceID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	n := 0
+	for _, f := range files {
+		path := filepath.Join(c.instanceDir(instanceID), f.Name())
+		entry, err := c.read(path)
+		if err != nil {
+			// Skip files that are being written or were removed concurrently
+			continue
+		}
+		key, err := dependencyKey(entry.Deps)
+		if err == nil && key == entry.DependencyKey {
+			continue
+		}
+		if err := c.remove(path); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// flush removes the stored results for an instance. If dependency is not empty,
+// it only removes results of queries that depend on the named object. It returns the paths of the removed entries.
+func (c *persistentQueryCache) flush(instanceID, dependency string) ([]string, error) {
+	dir := c.instanceDir(instanceID)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var paths []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name())
+		if dependency != "" {
+			entry, err := c.read(path)
+			if err != nil || !containsDependency(entry.Deps, dependency) {
+				continue
+			}
+		}
+		err = c.remove(path)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// remove deletes a stored result
+func (c *persistentQueryCache) remove(path string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	c.forgetLocked(path)
+	return nil
+}
+
+// touchLocked adds or updates a stored result in the index and marks it as the most recently used.
+// It must be called while holding the lock.
+func (c *persistentQueryCache) touchLocked(path string, size int64) {
+	if el, ok := c.items[path]; ok {
+		f := el.Value.(*persistentQueryCacheFile)
+		c.bytes += size - f.size
+		f.size = size
+		c.list.MoveToFront(el)
+		return
+	}
+	c.items[path] = c.list.PushFront(&persistentQueryCacheFile{path: path, size: size})
+	c.bytes += size
+}
+
+// forgetLocked removes a stored result from the index. It must be called while holding the lock.
+func (c *persistentQueryCache) forgetLocked(path string) {
+	el, ok := c.items[path]
+	if !ok {
+		return
+	}
+	c.list.Remove(el)
+	delete(c.items, path)
+	c.bytes -= el.Value.(*persistentQueryCacheFile).size
+}
+
+func (c *persistentQueryCache) evict() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.evictLocked()
+}
+
+// evictLocked removes the least recently used results until the stored results fit in maxBytes.
+// It must be called while holding the lock.
+func (c *persistentQueryCache) evictLocked() {
+	if c.maxBytes == 0 {
+		return
+	}
+
+	for c.bytes > c.maxBytes {
+		el := c.list.Back()
+		if el == nil {
+			return
+		}
+		f := el.Value.(*persistentQueryCacheFile)
+		err := os.Remove(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			// Keep the file in the index, it may be removable later
+			c.list.MoveToFront(el)
+			return
+		}
+		c.forgetLocked(f.path)
+		persistentQueryCacheEvictions.Inc()
+	}
+}
+
+type persistentQueryCacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// files lists the files in the cache directory (across all instances)
+func (c *persistentQueryCache) files() ([]persistentQueryCacheFile, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []persistentQueryCacheFile
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(c.dir, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			info, err := f.Info()
+			if err != nil {
+				// Removed concurrently
+				continue
+			}
+			res = append(res, persistentQueryCacheFile{
+				path:    filepath.Join(c.dir, d.Name(), f.Name()),
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			})
+		}
+	}
+	return res, nil
+}
+
+func (c *persistentQueryCache) read(path string) (*persistentQueryCacheEntry, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &persistentQueryCacheEntry{}
+	err = json.Unmarshal(blob, entry)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (c *persistentQueryCache) instanceDir(instanceID string) string {
+	return filepath.Join(c.dir, url.PathEscape(instanceID))
+}
+
+func (c *persistentQueryCache) path(key queryCacheKey) string {
+	h := sha256.New()
+	h.Write([]byte(key.queryKey))
+	h.Write([]byte{0})
+	h.Write([]byte(key.dependencyKey))
+	return filepath.Join(c.instanceDir(key.instanceID), hex.EncodeToString(h.Sum(nil)))
+}
+
+// containsDependency checks if deps contains name. Catalog object names are case insensitive.
+func containsDependency(deps []string, name string) bool {
+	for _, dep := range deps {
+		if strings.EqualFold(dep, name) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	queryCacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rill_runtime_query_cache_hits_total",
+		Help: "Number of query results served from the cache",
+	})
+	queryCacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rill_runtime_query_cache_misses_total",
+		Help: "Number of queries not found in the cache (including expired results)",
+	})
+	queryCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rill_runtime_query_cache_evictions_total",
+		Help: "Number of query results evicted from the cache to stay within its size",
+	})
+	persistentQueryCacheEvictions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "rill_runtime_persistent_query_cache_evictions_total",
+		Help: "Number of query results removed from the query cache directory to stay within its size",
+	})
+	queryCacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "rill_runtime_query_cache_bytes",
+		Help: "Estimated size of the query results in the cache",
+	})
+)
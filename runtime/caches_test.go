@@ -2,7 +2,9 @@ package runtime
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/rilldata/rill/runtime/drivers/sqlite"
@@ -31,14 +33,14 @@ func TestConnectionCache(t *testing.T) {
 }
 
 func TestNilValues(t *testing.T) {
-	qc := newQueryCache(10)
+	qc := newQueryCache(100, 0)
 
-	qc.add(queryCacheKey{"1", "1", "1"}, "value")
+	qc.add(queryCacheKey{"1", "1", "1"}, nil, "value", 5, 0)
 	v, ok := qc.get(queryCacheKey{"1", "1", "1"})
 	require.Equal(t, "value", v)
 	require.True(t, ok)
 
-	qc.add(queryCacheKey{"1", "1", "1"}, nil)
+	qc.add(queryCacheKey{"1", "1", "1"}, nil, nil, 0, 0)
 	v, ok = qc.get(queryCacheKey{"1", "1", "1"})
 	require.Nil(t, v)
 	require.True(t, ok)
@@ -47,3 +49,235 @@ func TestNilValues(t *testing.T) {
 	require.Nil(t, v)
 	require.False(t, ok)
 }
+
+func TestQueryCacheEvictsBySize(t *testing.T) {
+	qc := newQueryCache(100, 0)
+
+	require.True(t, qc.add(queryCacheKey{"1", "a", ""}, nil, "a", 40, 0))
+	require.True(t, qc.add(queryCacheKey{"1", "b", ""}, nil, "b", 40, 0))
+
+	// Touch "a" so "b" is the least recently used
+	_, ok := qc.get(queryCacheKey{"1", "a", ""})
+	require.True(t, ok)
+
+	require.True(t, qc.add(queryCacheKey{"1", "c", ""}, nil, "c", 40, 0))
+	_, ok = qc.get(queryCacheKey{"1", "a", ""})
+	require.True(t, ok)
+	_, ok = qc.get(queryCacheKey{"1", "b", ""})
+	require.False(t, ok)
+	_, ok = qc.get(queryCacheKey{"1", "c", ""})
+	require.True(t, ok)
+	require.Equal(t, int64(80), qc.bytes)
+
+	// Values larger than the cache are not added
+	require.False(t, qc.add(queryCacheKey{"1", "d", ""}, nil, "d", 101, 0))
+	_, ok = qc.get(queryCacheKey{"1", "d", ""})
+	require.False(t, ok)
+	require.Equal(t, int64(80), qc.bytes)
+}
+
+func TestQueryCacheTTL(t *testing.T) {
+	qc := newQueryCache(100, time.Hour)
+
+	qc.add(queryCacheKey{"1", "a", ""}, nil, "a", 1, time.Millisecond)
+	qc.add(queryCacheKey{"1", "b", ""}, nil, "b", 1, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := qc.get(queryCacheKey{"1", "a", ""})
+	require.False(t, ok)
+	_, ok = qc.get(queryCacheKey{"1", "b", ""})
+	require.True(t, ok)
+	require.Equal(t, int64(1), qc.bytes)
+}
+
+func TestQueryCacheFlush(t *testing.T) {
+	qc := newQueryCache(100, 0)
+
+	qc.add(queryCacheKey{"1", "a", ""}, []string{"ad_bids"}, "a", 1, 0)
+	qc.add(queryCacheKey{"1", "b", ""}, []string{"ad_bids_metrics"}, "b", 1, 0)
+	qc.add(queryCacheKey{"1", "c", ""}, []string{"ad_bids"}, "c", 1, 0)
+	qc.add(queryCacheKey{"2", "a", ""}, []string{"ad_bids"}, "a", 1, 0)
+
+	require.Len(t, qc.flush("1", "AD_BIDS"), 2)
+	_, ok := qc.get(queryCacheKey{"1", "a", ""})
+	require.False(t, ok)
+	_, ok = qc.get(queryCacheKey{"1", "b", ""})
+	require.True(t, ok)
+
+	require.Len(t, qc.flush("1", ""), 1)
+	_, ok = qc.get(queryCacheKey{"2", "a", ""})
+	require.True(t, ok)
+	require.Equal(t, int64(1), qc.bytes)
+}
+
+func TestPersistentQueryCache(t *testing.T) {
+	pc, err := newPersistentQueryCache(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	require.NoError(t, pc.add(queryCacheKey{"1", "a", ""}, []string{"ad_bids"}, []byte("a"), 0))
+	require.NoError(t, pc.add(queryCacheKey{"1", "b", ""}, []string{"ad_bids_metrics"}, []byte("b"), 0))
+	require.NoError(t, pc.add(queryCacheKey{"1", "c", ""}, nil, []byte("c"), time.Millisecond))
+	require.NoError(t, pc.add(queryCacheKey{"2", "a", ""}, []string{"ad_bids"}, []byte("a"), 0))
+
+	v, ok := pc.get(queryCacheKey{"1", "a", ""})
+	require.True(t, ok)
+	require.Equal(t, []byte("a"), v)
+
+	// Same query with other dependency versions
+	_, ok = pc.get(queryCacheKey{"1", "a", "ad_bids:v2"})
+	require.False(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+	_, ok = pc.get(queryCacheKey{"1", "c", ""})
+	require.False(t, ok)
+
+	paths, err := pc.flush("1", "ad_bids")
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	_, ok = pc.get(queryCacheKey{"1", "a", ""})
+	require.False(t, ok)
+
+	paths, err = pc.flush("1", "")
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	_, ok = pc.get(queryCacheKey{"1", "b", ""})
+	require.False(t, ok)
+
+	_, ok = pc.get(queryCacheKey{"2", "a", ""})
+	require.True(t, ok)
+}
+
+func TestPersistentQueryCacheEvictsBySize(t *testing.T) {
+	dir := t.TempDir()
+	pc, err := newPersistentQueryCache(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, pc.add(queryCacheKey{"1", "a", ""}, nil, make([]byte, 100), 0))
+	size := pc.bytes
+
+	pc, err = newPersistentQueryCache(dir, 2*size+size/2)
+	require.NoError(t, err)
+	require.Equal(t, size, pc.bytes)
+	require.NoError(t, pc.add(queryCacheKey{"1", "b", ""}, nil, make([]byte, 100), 0))
+
+	// Use "a" so "b" is the least recently used
+	_, ok := pc.get(queryCacheKey{"1", "a", ""})
+	require.True(t, ok)
+
+	require.NoError(t, pc.add(queryCacheKey{"2", "c", ""}, nil, make([]byte, 100), 0))
+	require.Equal(t, 2*size, pc.bytes)
+	_, ok = pc.get(queryCacheKey{"1", "b", ""})
+	require.False(t, ok)
+	_, ok = pc.get(queryCacheKey{"1", "a", ""})
+	require.True(t, ok)
+	_, ok = pc.get(queryCacheKey{"2", "c", ""})
+	require.True(t, ok)
+
+	// The access order is restored from the modification times on startup
+	pc, err = newPersistentQueryCache(dir, size+size/2)
+	require.NoError(t, err)
+	require.Equal(t, size, pc.bytes)
+	require.Equal(t, 1, pc.list.Len())
+	_, ok = pc.get(queryCacheKey{"1", "a", ""})
+	require.False(t, ok)
+	_, ok = pc.get(queryCacheKey{"2", "c", ""})
+	require.True(t, ok)
+}
+
+func TestPersistentQueryCacheSweep(t *testing.T) {
+	pc, err := newPersistentQueryCache(t.TempDir(), 0)
+	require.NoError(t, err)
+	require.NoError(t, pc.add(queryCacheKey{"1", "a", "ad_bids:v1"}, []string{"ad_bids"}, []byte("a"), 0))
+	require.NoError(t, pc.add(queryCacheKey{"1", "b", "ad_bids:v2"}, []string{"ad_bids"}, []byte("b"), 0))
+	require.NoError(t, pc.add(queryCacheKey{"1", "c", "other:v1"}, []string{"other"}, []byte("c"), 0))
+
+	n, err := pc.sweep("1", func(deps []string) (string, error) {
+		if deps[0] == "other" {
+			return "", fmt.Errorf("not found")
+		}
+		return "ad_bids:v2", nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	_, ok := pc.get(queryCacheKey{"1", "a", "ad_bids:v1"})
+	require.False(t, ok)
+	_, ok = pc.get(queryCacheKey{"1", "b", "ad_bids:v2"})
+	require.True(t, ok)
+	_, ok = pc.get(queryCacheKey{"1", "c", "other:v1"})
+	require.False(t, ok)
+}
+
+func TestPersistentQueryCacheAcrossRuntimes(t *testing.T) {
+	ctx := context.Background()
+	opts := &Options{
+		ConnectionCacheSize: 10,
+		MetastoreDriver:     "sqlite",
+		MetastoreDSN:        fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()),
+		QueryCacheSizeBytes: 1024,
+		QueryCacheDir:       t.TempDir(),
+	}
+
+	rt, err := New(opts, nil)
+	require.NoError(t, err)
+	q := &testPersistentQuery{key: "a", value: "value"}
+	require.NoError(t, rt.Query(ctx, "1", q, 0))
+	require.Equal(t, 1, q.resolved)
+
+	// A new runtime resolves the query from the persisted result
+	rt, err = New(opts, nil)
+	require.NoError(t, err)
+	q = &testPersistentQuery{key: "a"}
+	require.NoError(t, rt.Query(ctx, "1", q, 0))
+	require.Equal(t, 0, q.resolved)
+	require.Equal(t, "value", q.result)
+
+	n, err := rt.FlushQueryCache("1", "")
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	rt, err = New(opts, nil)
+	require.NoError(t, err)
+	q = &testPersistentQuery{key: "a", value: "value"}
+	require.NoError(t, rt.Query(ctx, "1", q, 0))
+	require.Equal(t, 1, q.resolved)
+}
+
+type testPersistentQuery struct {
+	key      string
+	value    string
+	result   string
+	resolved int
+}
+
+var _ PersistentQuery = &testPersistentQuery{}
+
+func (q *testPersistentQuery) Key() string {
+	return q.key
+}
+
+func (q *testPersistentQuery) Deps() []string {
+	return nil
+}
+
+func (q *testPersistentQuery) MarshalResult() *QueryResult {
+	return &QueryResult{Value: q.result, Bytes: int64(len(q.result))}
+}
+
+func (q *testPersistentQuery) UnmarshalResult(v any) error {
+	q.result = v.(string)
+	return nil
+}
+
+func (q *testPersistentQuery) MarshalResultBytes() ([]byte, error) {
+	return []byte(q.result), nil
+}
+
+func (q *testPersistentQuery) UnmarshalResultBytes(data []byte) error {
+	q.result = string(data)
+	return nil
+}
+
+func (q *testPersistentQuery) Resolve(ctx context.Context, rt *Runtime, instanceID string, priority int) error {
+	q.resolved++
+	q.result = q.value
+	return nil
+}
@@ -50,6 +50,10 @@ func (r *Runtime) Reconcile(ctx context.Context, instanceID string, changedPaths
 		return nil, err
 	}
 
+	if !dry && len(resp.AffectedPaths) > 0 {
+		r.sweepQueryCache(ctx, instanceID)
+	}
+
 	return resp, nil
 }
 
@@ -76,6 +80,8 @@ func (r *Runtime) RefreshSource(ctx context.Context, instanceID, name string) er
 		return errors.New(resp.Errors[0].Message)
 	}
 
+	r.sweepQueryCache(ctx, instanceID)
+
 	return nil
 }
 
@@ -124,10 +130,11 @@ func (r *Runtime) SyncExistingTables(ctx context.Context, instanceID string) err
 
 		// Create or update in catalog if relevant
 		if ok && obj.Type == drivers.ObjectTypeTable && !obj.GetTable().Managed {
-			// If the table has already been synced, update the schema if it has changed
+			// If the table has already been synced, update the schema and metadata if they have changed
 			tbl := obj.GetTable()
-			if !proto.Equal(t.Schema, tbl.Schema) {
+			if !proto.Equal(t.Schema, tbl.Schema) || !proto.Equal(t.Metadata, tbl.Metadata) {
 				tbl.Schema = t.Schema
+				tbl.Metadata = t.Metadata
 				err := cat.Catalog.UpdateEntry(ctx, instanceID, obj)
 				if err != nil {
 					return err
@@ -140,9 +147,10 @@ func (r *Runtime) SyncExistingTables(ctx context.Context, instanceID string) err
 				Name: t.Name,
 				Type: drivers.ObjectTypeTable,
 				Object: &runtimev1.Table{
-					Name:    t.Name,
-					Schema:  t.Schema,
-					Managed: false,
+					Name:     t.Name,
+					Schema:   t.Schema,
+					Managed:  false,
+					Metadata: t.Metadata,
 				},
 			})
 			if err != nil {
@@ -2,8 +2,10 @@ package runtime_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
+	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/services/catalog"
 	_ "github.com/rilldata/rill/runtime/services/catalog/artifacts/sql"
 	_ "github.com/rilldata/rill/runtime/services/catalog/artifacts/yaml"
@@ -37,3 +39,49 @@ func TestCatalog(t *testing.T) {
 	require.NoError(t, err)
 	testutils.AssertMigration(t, res, 0, 0, 3, 0, []string{sourcePath, modelPath, metricsPath})
 }
+
+func TestSyncExistingTables_Attach(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a DuckDB file like another tool would
+	path := filepath.Join(t.TempDir(), "external.db")
+	conn, err := drivers.Open("duckdb", path)
+	require.NoError(t, err)
+	olap, _ := conn.OLAPStore()
+	res, err := olap.Execute(ctx, &drivers.Statement{Query: "CREATE TABLE events AS SELECT range AS id FROM range(10)"})
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.NoError(t, conn.Close())
+
+	rt := testruntime.New(t)
+	inst := &drivers.Instance{
+		OLAPDriver:   "duckdb",
+		OLAPDSN:      filepath.Join(t.TempDir(), "stage.db") + "?attach=" + path,
+		RepoDriver:   "file",
+		RepoDSN:      t.TempDir(),
+		EmbedCatalog: true,
+	}
+	require.NoError(t, rt.CreateInstance(ctx, inst))
+
+	// The attached table is added to the catalog as an unmanaged table
+	require.NoError(t, rt.SyncExistingTables(ctx, inst.ID))
+	cat, err := rt.Catalog(ctx, inst.ID)
+	require.NoError(t, err)
+	obj, ok := cat.FindEntry(ctx, "events")
+	require.True(t, ok)
+	require.Equal(t, drivers.ObjectTypeTable, obj.Type)
+	require.False(t, obj.GetTable().Managed)
+	require.Equal(t, "id", obj.GetTable().Schema.Fields[0].Name)
+
+	// The catalog is embedded in the stage database, not in the attached file
+	olap, err = rt.OLAP(ctx, inst.ID)
+	require.NoError(t, err)
+	res, err = olap.Execute(ctx, &drivers.Statement{Query: "SELECT DISTINCT table_catalog FROM information_schema.tables WHERE table_schema = 'rill'"})
+	require.NoError(t, err)
+	var database string
+	require.True(t, res.Next())
+	require.NoError(t, res.Scan(&database))
+	require.False(t, res.Next())
+	require.NoError(t, res.Close())
+	require.Equal(t, "stage", database)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -11,6 +12,7 @@ import (
 	_ "github.com/rilldata/rill/runtime/connectors/gcs"
 	_ "github.com/rilldata/rill/runtime/connectors/https"
 	_ "github.com/rilldata/rill/runtime/connectors/s3"
+	_ "github.com/rilldata/rill/runtime/drivers/clickhouse"
 	_ "github.com/rilldata/rill/runtime/drivers/druid"
 	_ "github.com/rilldata/rill/runtime/drivers/duckdb"
 	_ "github.com/rilldata/rill/runtime/drivers/file"
@@ -24,14 +26,23 @@ import (
 )
 
 type Config struct {
-	Env                 string        `default:"development"`
-	HTTPPort            int           `default:"8080" split_words:"true"`
-	GRPCPort            int           `default:"9090" split_words:"true"`
-	LogLevel            zapcore.Level `default:"info" split_words:"true"`
-	DatabaseDriver      string        `default:"sqlite"`
-	DatabaseURL         string        `default:"file:rill?mode=memory&cache=shared" split_words:"true"`
-	ConnectionCacheSize int           `default:"100" split_words:"true"`
-	QueryCacheSize      int           `default:"10000" split_words:"true"`
+	Env                    string        `default:"development"`
+	HTTPPort               int           `default:"8080" split_words:"true"`
+	GRPCPort               int           `default:"9090" split_words:"true"`
+	LogLevel               zapcore.Level `default:"info" split_words:"true"`
+	DatabaseDriver         string        `default:"sqlite"`
+	DatabaseURL            string        `default:"file:rill?mode=memory&cache=shared" split_words:"true"`
+	ConnectionCacheSize    int           `default:"100" split_words:"true"`
+	QueryCacheSizeBytes    int64         `default:"104857600" split_words:"true"`
+	QueryCacheTTL          time.Duration `split_words:"true"`
+	QueryCacheDir          string        `split_words:"true"`
+	QueryCacheDirSizeBytes int64         `default:"1073741824" split_words:"true"`
+	ExportDir              string        `split_words:"true"`
+	ExportTTL              time.Duration `split_words:"true"`
+	QueryHistorySize       int           `split_words:"true"`
+	QueryHistoryPath       string        `split_words:"true"`
+	QuerySlowThreshold     time.Duration `split_words:"true"`
+	AuthSecret             string        `split_words:"true"`
 }
 
 func main() {
@@ -58,12 +69,25 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The query cache used to be bounded by number of entries, which can't be converted to a byte budget
+	if _, ok := os.LookupEnv("RILL_RUNTIME_QUERY_CACHE_SIZE"); ok {
+		logger.Warn("RILL_RUNTIME_QUERY_CACHE_SIZE is deprecated and ignored, use RILL_RUNTIME_QUERY_CACHE_SIZE_BYTES instead")
+	}
+
 	// Init runtime
 	opts := &runtime.Options{
-		ConnectionCacheSize: conf.ConnectionCacheSize,
-		MetastoreDriver:     conf.DatabaseDriver,
-		MetastoreDSN:        conf.DatabaseURL,
-		QueryCacheSize:      conf.QueryCacheSize,
+		ConnectionCacheSize:    conf.ConnectionCacheSize,
+		MetastoreDriver:        conf.DatabaseDriver,
+		MetastoreDSN:           conf.DatabaseURL,
+		QueryCacheSizeBytes:    conf.QueryCacheSizeBytes,
+		QueryCacheTTL:          conf.QueryCacheTTL,
+		QueryCacheDir:          conf.QueryCacheDir,
+		QueryCacheDirSizeBytes: conf.QueryCacheDirSizeBytes,
+		ExportDir:              conf.ExportDir,
+		ExportTTL:              conf.ExportTTL,
+		QueryHistorySize:       conf.QueryHistorySize,
+		QueryHistoryPath:       conf.QueryHistoryPath,
+		QuerySlowThreshold:     conf.QuerySlowThreshold,
 	}
 	rt, err := runtime.New(opts, logger)
 	if err != nil {
@@ -72,8 +96,9 @@ func main() {
 
 	// Init server
 	srvOpts := &server.Options{
-		HTTPPort: conf.HTTPPort,
-		GRPCPort: conf.GRPCPort,
+		HTTPPort:   conf.HTTPPort,
+		GRPCPort:   conf.GRPCPort,
+		AuthSecret: conf.AuthSecret,
 	}
 	s, err := server.NewServer(srvOpts, rt, logger)
 	if err != nil {
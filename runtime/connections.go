@@ -3,6 +3,8 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strconv"
 
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/services/catalog"
@@ -38,12 +40,22 @@ func (r *Runtime) Repo(ctx context.Context, instanceID string) (drivers.RepoStor
 }
 
 func (r *Runtime) OLAP(ctx context.Context, instanceID string) (drivers.OLAPStore, error) {
+	olap, err := r.olap(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loggedOLAPStore{OLAPStore: olap, instanceID: instanceID, history: r.queryHistory}, nil
+}
+
+// olap returns the instance's OLAP store without recording its statements in the query history
+func (r *Runtime) olap(ctx context.Context, instanceID string) (drivers.OLAPStore, error) {
 	inst, err := r.FindInstance(ctx, instanceID)
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := r.connCache.get(ctx, instanceID, inst.OLAPDriver, inst.OLAPDSN)
+	conn, err := r.connCache.get(ctx, instanceID, inst.OLAPDriver, olapDSN(inst))
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +69,30 @@ func (r *Runtime) OLAP(ctx context.Context, instanceID string) (drivers.OLAPStor
 	return olap, nil
 }
 
+// olapDSN returns the DSN for an instance's OLAP connection with the instance's resource limits applied.
+// DuckDB reads the memory_limit and threads options from the DSN (unless they are already set in it).
+func olapDSN(inst *drivers.Instance) string {
+	if inst.OLAPDriver != "duckdb" || (inst.OLAPMemoryLimitBytes == 0 && inst.OLAPThreads == 0) {
+		return inst.OLAPDSN
+	}
+
+	u, err := url.Parse(inst.OLAPDSN)
+	if err != nil {
+		// The driver will return the error when opening the DSN
+		return inst.OLAPDSN
+	}
+
+	qry := u.Query()
+	if inst.OLAPMemoryLimitBytes != 0 && !qry.Has("memory_limit") {
+		qry.Set("memory_limit", fmt.Sprintf("%dB", inst.OLAPMemoryLimitBytes))
+	}
+	if inst.OLAPThreads != 0 && !qry.Has("threads") {
+		qry.Set("threads", strconv.FormatUint(uint64(inst.OLAPThreads), 10))
+	}
+	u.RawQuery = qry.Encode()
+	return u.String()
+}
+
 func (r *Runtime) Catalog(ctx context.Context, instanceID string) (*catalog.Service, error) {
 	c, err := r.catalogCache.get(ctx, r, instanceID)
 	if err != nil {
@@ -0,0 +1,70 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+func init() {
+	drivers.Register("clickhouse", driver{})
+}
+
+type driver struct{}
+
+// Open connects to ClickHouse using the native protocol ("clickhouse://host:9000/database")
+// or the HTTP interface ("http://host:8123/database").
+func (d driver) Open(dsn string) (drivers.Connection, error) {
+	opts, err := clickhouse.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clickhouse dsn: %w", err)
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector{clickhouse.Connector(opts)}), "clickhouse")
+
+	conn := &connection{db: db}
+	return conn, nil
+}
+
+type connection struct {
+	db *sqlx.DB
+}
+
+// Close implements drivers.Connection.
+func (c *connection) Close() error {
+	return c.db.Close()
+}
+
+// Registry implements drivers.Connection.
+func (c *connection) RegistryStore() (drivers.RegistryStore, bool) {
+	return nil, false
+}
+
+// Catalog implements drivers.Connection.
+func (c *connection) CatalogStore() (drivers.CatalogStore, bool) {
+	return nil, false
+}
+
+// Repo implements drivers.Connection.
+func (c *connection) RepoStore() (drivers.RepoStore, bool) {
+	return nil, false
+}
+
+// OLAP implements drivers.Connection.
+func (c *connection) OLAPStore() (drivers.OLAPStore, bool) {
+	return c, true
+}
+
+// Migrate implements drivers.Connection.
+func (c *connection) Migrate(ctx context.Context) (err error) {
+	return nil
+}
+
+// MigrationStatus implements drivers.Connection.
+func (c *connection) MigrationStatus(ctx context.Context) (current, desired int, err error) {
+	return 0, 0, nil
+}
@@ -0,0 +1,376 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/connectors"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/queries"
+	"github.com/rilldata/rill/runtime/testruntime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const testTable = "test_data"
+
+var testCSV = strings.TrimSpace(`
+id,timestamp,publisher,domain,bid_price
+5000,2022-03-18 12:25:58.074,Facebook,facebook.com,4.19
+9000,2022-03-15 11:17:23.530,Microsoft,msn.com,3.48
+10000,2022-03-02 04:00:56.643,Microsoft,msn.com,3.57
+11000,2022-01-16 00:26:44.770,\N,instagram.com,5.38
+12000,2022-01-17 08:55:09.270,\N,msn.com,1.34
+13000,2022-03-20 03:16:57.618,Yahoo,news.yahoo.com,1.05
+14000,2022-01-29 19:05:33.545,Google,news.google.com,4.54
+15000,2022-03-22 00:56:22.035,Yahoo,news.yahoo.com,1.13
+16000,2022-01-24 13:41:43.527,\N,instagram.com,1.78
+`)
+
+const testMetricsView = "test_metrics"
+
+var testMetricsViewYAML = strings.TrimSpace(`
+model: test_data
+timeseries: timestamp
+timegrains:
+  - 1 day
+  - 1 month
+dimensions:
+  - property: publisher
+  - property: domain
+measures:
+  - name: count
+    expression: count(*)
+`)
+
+func TestDatabaseTypeToPB(t *testing.T) {
+	cases := []struct {
+		dbt      string
+		expected *runtimev1.Type
+	}{
+		{"UInt8", &runtimev1.Type{Code: runtimev1.Type_CODE_UINT8}},
+		{"Nullable(Int64)", &runtimev1.Type{Code: runtimev1.Type_CODE_INT64, Nullable: true}},
+		{"LowCardinality(Nullable(String))", &runtimev1.Type{Code: runtimev1.Type_CODE_STRING, Nullable: true}},
+		{"DateTime64(3, 'UTC')", &runtimev1.Type{Code: runtimev1.Type_CODE_TIMESTAMP}},
+		{"Decimal(18, 2)", &runtimev1.Type{Code: runtimev1.Type_CODE_DECIMAL}},
+		{"Enum8('a' = 1, 'b' = 2)", &runtimev1.Type{Code: runtimev1.Type_CODE_STRING}},
+		{"Array(Nullable(Float64))", &runtimev1.Type{
+			Code:             runtimev1.Type_CODE_ARRAY,
+			ArrayElementType: &runtimev1.Type{Code: runtimev1.Type_CODE_FLOAT64, Nullable: true},
+		}},
+		{"Map(String, Array(UInt32))", &runtimev1.Type{
+			Code: runtimev1.Type_CODE_MAP,
+			MapType: &runtimev1.MapType{
+				KeyType: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING},
+				ValueType: &runtimev1.Type{
+					Code:             runtimev1.Type_CODE_ARRAY,
+					ArrayElementType: &runtimev1.Type{Code: runtimev1.Type_CODE_UINT32},
+				},
+			},
+		}},
+		{"Tuple(a Int32, b Map(String, String))", &runtimev1.Type{
+			Code: runtimev1.Type_CODE_STRUCT,
+			StructType: &runtimev1.StructType{Fields: []*runtimev1.StructType_Field{
+				{Name: "a", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_INT32}},
+				{Name: "b", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_MAP, MapType: &runtimev1.MapType{
+					KeyType:   &runtimev1.Type{Code: runtimev1.Type_CODE_STRING},
+					ValueType: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING},
+				}}},
+			}},
+		}},
+	}
+	for _, c := range cases {
+		typ, err := databaseTypeToPB(c.dbt, false)
+		require.NoError(t, err, c.dbt)
+		require.Equal(t, c.expected.String(), typ.String(), c.dbt)
+	}
+
+	_, err := databaseTypeToPB("AggregateFunction(uniq, String)", false)
+	require.Error(t, err)
+}
+
+func TestNormalizeValue(t *testing.T) {
+	s := "foo"
+	var nilString *string
+	require.Equal(t, "foo", normalizeValue(&s))
+	require.Nil(t, normalizeValue(nilString))
+	require.Equal(t, int64(1), normalizeValue(uint8(1)))
+	require.Equal(t, float64(1.5), normalizeValue(float32(1.5)))
+	require.Equal(t, []any{"foo", nil}, normalizeValue([]*string{&s, nil}))
+	require.Equal(t, map[string]any{"1": []any{int64(2)}}, normalizeValue(map[int32][]int16{1: {2}}))
+	require.Equal(t, big.NewInt(10), normalizeValue(*big.NewInt(10)))
+	require.Equal(t, "127.0.0.1", normalizeValue(net.ParseIP("127.0.0.1")))
+}
+
+func TestIngestSQL(t *testing.T) {
+	qry, err := ingestSQL(&connectors.Source{
+		Name:       "ad_bids",
+		Connector:  "s3",
+		Properties: map[string]any{"path": "s3://bucket/path/*.parquet", "aws.region": "eu-west-1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "CREATE OR REPLACE TABLE `ad_bids` ENGINE = MergeTree ORDER BY tuple() AS SELECT * FROM s3('https://bucket.s3.eu-west-1.amazonaws.com/path/*.parquet', 'Parquet')", qry)
+
+	qry, err = ingestSQL(&connectors.Source{
+		Name:       "ad_bids",
+		Connector:  "https",
+		Properties: map[string]any{"path": "https://example.com/ad_bids.csv", "csv.delimiter": "|"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "CREATE OR REPLACE TABLE `ad_bids` ENGINE = MergeTree ORDER BY tuple() AS SELECT * FROM url('https://example.com/ad_bids.csv', 'CSVWithNames') SETTINGS format_csv_delimiter = '|'", qry)
+
+	qry, err = ingestSQL(&connectors.Source{
+		Name:       "ad_bids",
+		Connector:  "gcs",
+		Properties: map[string]any{"path": "gs://bucket/ad_bids.json"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, qry, "s3('https://storage.googleapis.com/bucket/ad_bids.json', 'JSONEachRow')")
+
+	_, err = ingestSQL(&connectors.Source{
+		Name:       "ad_bids",
+		Connector:  "local_file",
+		Properties: map[string]any{"path": "data/ad_bids.csv"},
+	})
+	require.ErrorIs(t, err, drivers.ErrUnsupportedConnector)
+}
+
+// TestClickHouse starts a local ClickHouse server (if the clickhouse binary is available), loads data into it,
+// then runs all other tests in this file as sub-tests (to prevent spawning many servers).
+func TestClickHouse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("clickhouse: skipping test in short mode")
+	}
+
+	dsn := startServer(t)
+
+	conn, err := driver{}.Open(dsn)
+	require.NoError(t, err)
+
+	c := conn.(*connection)
+	_, err = c.db.Exec(fmt.Sprintf(`CREATE TABLE %s (
+		id Int64,
+		timestamp DateTime64(3, 'UTC'),
+		publisher Nullable(String),
+		domain LowCardinality(String),
+		bid_price Float64
+	) ENGINE = MergeTree ORDER BY timestamp`, testTable))
+	require.NoError(t, err)
+	_, err = c.db.Exec(fmt.Sprintf("INSERT INTO %s FORMAT CSVWithNames %s", testTable, testCSV))
+	require.NoError(t, err)
+
+	olap, ok := conn.OLAPStore()
+	require.True(t, ok)
+
+	t.Run("count", func(t *testing.T) { testCount(t, olap) })
+	t.Run("types", func(t *testing.T) { testTypes(t, olap) })
+	t.Run("dry run", func(t *testing.T) { testDryRun(t, olap) })
+	t.Run("schema all", func(t *testing.T) { testSchemaAll(t, olap) })
+	t.Run("schema lookup", func(t *testing.T) { testSchemaLookup(t, olap) })
+	t.Run("queries", func(t *testing.T) { testQueries(t, dsn) })
+	// Add new tests here
+
+	require.NoError(t, conn.Close())
+}
+
+// startServer starts a ClickHouse server that stores its data in a temporary directory and returns its DSN
+func startServer(t *testing.T) string {
+	bin, err := exec.LookPath("clickhouse")
+	if err != nil {
+		t.Skip("clickhouse: binary not found in PATH")
+	}
+
+	tcpPort := freePort(t)
+	dir := t.TempDir()
+	cmd := exec.Command(bin, "server", "--",
+		"--path="+dir+"/",
+		"--listen_host=127.0.0.1",
+		fmt.Sprintf("--tcp_port=%d", tcpPort),
+		fmt.Sprintf("--http_port=%d", freePort(t)),
+	)
+	cmd.Dir = dir
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	dsn := fmt.Sprintf("clickhouse://127.0.0.1:%d/default", tcpPort)
+	conn, err := driver{}.Open(dsn)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.Eventually(t, func() bool {
+		return conn.(*connection).db.Ping() == nil
+	}, 30*time.Second, 100*time.Millisecond)
+
+	return dsn
+}
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func testCount(t *testing.T, olap drivers.OLAPStore) {
+	rows, err := olap.Execute(context.Background(), &drivers.Statement{
+		Query: fmt.Sprintf("SELECT count(*) FROM %s WHERE bid_price > ?", testTable),
+		Args:  []any{1.2},
+	})
+	require.NoError(t, err)
+
+	var count int
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&count))
+	require.Equal(t, 7, count)
+	require.NoError(t, rows.Close())
+}
+
+func testTypes(t *testing.T, olap drivers.OLAPStore) {
+	rows, err := olap.Execute(context.Background(), &drivers.Statement{
+		Query: "SELECT CAST(NULL AS Nullable(String)) AS a, toDecimal64(1.25, 2) AS b, [1, 2] AS c",
+	})
+	require.NoError(t, err)
+	defer rows.Close()
+
+	require.Equal(t, runtimev1.Type_CODE_STRING, rows.Schema.Fields[0].Type.Code)
+	require.True(t, rows.Schema.Fields[0].Type.Nullable)
+	require.Equal(t, runtimev1.Type_CODE_DECIMAL, rows.Schema.Fields[1].Type.Code)
+	require.Equal(t, runtimev1.Type_CODE_ARRAY, rows.Schema.Fields[2].Type.Code)
+
+	require.True(t, rows.Next())
+	row := make(map[string]any)
+	require.NoError(t, rows.MapScan(row))
+	require.Nil(t, row["a"])
+	require.Equal(t, 1.25, row["b"])
+	require.Equal(t, []any{int64(1), int64(2)}, row["c"])
+}
+
+func testDryRun(t *testing.T, olap drivers.OLAPStore) {
+	_, err := olap.Execute(context.Background(), &drivers.Statement{
+		Query:  fmt.Sprintf("SELECT id FROM %s WHERE domain = ?", testTable),
+		Args:   []any{"msn.com"},
+		DryRun: true,
+	})
+	require.NoError(t, err)
+
+	_, err = olap.Execute(context.Background(), &drivers.Statement{
+		Query:  fmt.Sprintf("SELECT foo FROM %s", testTable),
+		DryRun: true,
+	})
+	require.Error(t, err)
+}
+
+func testSchemaAll(t *testing.T, olap drivers.OLAPStore) {
+	tables, err := olap.InformationSchema().All(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(tables))
+	require.Equal(t, testTable, tables[0].Name)
+
+	fields := tables[0].Schema.Fields
+	require.Equal(t, 5, len(fields))
+	require.Equal(t, "id", fields[0].Name)
+	require.Equal(t, runtimev1.Type_CODE_INT64, fields[0].Type.Code)
+	require.Equal(t, "timestamp", fields[1].Name)
+	require.Equal(t, runtimev1.Type_CODE_TIMESTAMP, fields[1].Type.Code)
+	require.Equal(t, "publisher", fields[2].Name)
+	require.Equal(t, runtimev1.Type_CODE_STRING, fields[2].Type.Code)
+	require.Equal(t, true, fields[2].Type.Nullable)
+	require.Equal(t, "domain", fields[3].Name)
+	require.Equal(t, runtimev1.Type_CODE_STRING, fields[3].Type.Code)
+	require.Equal(t, false, fields[3].Type.Nullable)
+}
+
+func testSchemaLookup(t *testing.T, olap drivers.OLAPStore) {
+	ctx := context.Background()
+	table, err := olap.InformationSchema().Lookup(ctx, testTable)
+	require.NoError(t, err)
+	require.Equal(t, testTable, table.Name)
+
+	_, err = olap.InformationSchema().Lookup(ctx, "foo")
+	require.Equal(t, drivers.ErrNotFound, err)
+}
+
+// testQueries runs metrics view and profiling queries against an instance that uses ClickHouse as its OLAP driver
+func testQueries(t *testing.T, dsn string) {
+	rt, instanceID := newClickHouseInstance(t, dsn)
+	ctx := context.Background()
+
+	toplist := &queries.MetricsViewToplist{
+		MetricsViewName: testMetricsView,
+		DimensionName:   "domain",
+		MeasureNames:    []string{"count"},
+		Sort:            []*runtimev1.MetricsViewSort{{Name: "count"}},
+		Limit:           2,
+		Filter: &runtimev1.MetricsViewFilter{
+			Exclude: []*runtimev1.MetricsViewFilter_Cond{{Name: "domain", Like: []*structpb.Value{structpb.NewStringValue("%GOOGLE%")}}},
+		},
+	}
+	require.NoError(t, rt.Query(ctx, instanceID, toplist, 1))
+	require.Len(t, toplist.Result.Data, 2)
+	require.Equal(t, "msn.com", toplist.Result.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 3.0, toplist.Result.Data[0].Fields["count"].GetNumberValue())
+
+	timeseries := &queries.MetricsViewTimeSeries{
+		MetricsViewName: testMetricsView,
+		MeasureNames:    []string{"count"},
+		TimeGranularity: "MONTH",
+		TimeZone:        "America/Los_Angeles",
+	}
+	require.NoError(t, rt.Query(ctx, instanceID, timeseries, 1))
+	require.Len(t, timeseries.Result.Data, 2)
+	require.Equal(t, "2022-01-01T00:00:00-08:00", timeseries.Result.Data[0].Fields["timestamp"].GetStringValue())
+	require.Equal(t, 4.0, timeseries.Result.Data[0].Fields["count"].GetNumberValue())
+
+	nullCount := &queries.ColumnNullCount{TableName: testTable, ColumnName: "publisher"}
+	require.NoError(t, rt.Query(ctx, instanceID, nullCount, 1))
+	require.Equal(t, 3.0, nullCount.Result)
+
+	topK := &queries.ColumnTopK{TableName: testTable, ColumnName: "publisher", Agg: "count(*)", K: 2}
+	require.NoError(t, rt.Query(ctx, instanceID, topK, 1))
+	require.Len(t, topK.Result.Entries, 2)
+
+	histogram := &queries.ColumnNumericHistogram{TableName: testTable, ColumnName: "bid_price"}
+	require.NoError(t, rt.Query(ctx, instanceID, histogram, 1))
+	var total float64
+	for _, bin := range histogram.Result {
+		total += bin.Count
+	}
+	require.Equal(t, 9.0, total)
+
+	timeRange := &queries.ColumnTimeRange{TableName: testTable, ColumnName: "timestamp"}
+	require.NoError(t, rt.Query(ctx, instanceID, timeRange, 1))
+	require.Equal(t, int32(65), timeRange.Result.Interval.Days)
+}
+
+func newClickHouseInstance(t *testing.T, dsn string) (*runtime.Runtime, string) {
+	ctx := context.Background()
+	rt := testruntime.New(t)
+
+	inst := &drivers.Instance{
+		OLAPDriver: "clickhouse",
+		OLAPDSN:    dsn,
+		RepoDriver: "file",
+		RepoDSN:    t.TempDir(),
+	}
+	require.NoError(t, rt.CreateInstance(ctx, inst))
+
+	err := rt.PutFile(ctx, inst.ID, "dashboards/"+testMetricsView+".yaml", strings.NewReader(testMetricsViewYAML), true, false)
+	require.NoError(t, err)
+	res, err := rt.Reconcile(ctx, inst.ID, nil, nil, false, false)
+	require.NoError(t, err)
+	require.Empty(t, res.Errors)
+
+	return rt, inst.ID
+}
@@ -0,0 +1,139 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rilldata/rill/runtime/connectors"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/fileutil"
+)
+
+// sourceConfig contains the source properties used for ingestion into ClickHouse.
+type sourceConfig struct {
+	Path         string `mapstructure:"path"`
+	Format       string `mapstructure:"format"`
+	CSVDelimiter string `mapstructure:"csv.delimiter"`
+	AWSRegion    string `mapstructure:"aws.region"`
+}
+
+// Ingest implements drivers.OLAPStore by creating a table from a ClickHouse table function that reads the source.
+// ClickHouse reads the data itself, so only sources with URLs reachable from the ClickHouse server are supported.
+// Progress is reported to the function set with drivers.WithIngestionProgress when using the native protocol.
+func (c *connection) Ingest(ctx context.Context, env *connectors.Env, source *connectors.Source) error {
+	err := source.Validate()
+	if err != nil {
+		return err
+	}
+
+	qry, err := ingestSQL(source)
+	if err != nil {
+		return err
+	}
+
+	if fn := drivers.IngestionProgressFromContext(ctx); fn != nil {
+		// Progress packets contain the rows read since the previous packet
+		var p drivers.IngestionProgress
+		ctx = clickhouse.Context(ctx, clickhouse.WithProgress(func(cp *clickhouse.Progress) {
+			if cp.Rows == 0 {
+				return
+			}
+			p.State = "RUNNING"
+			p.RowsProcessed += int64(cp.Rows)
+			fn(p)
+		}))
+	}
+
+	_, err = c.db.ExecContext(ctx, qry)
+	return err
+}
+
+// ingestSQL returns a statement that (re)creates the source's table from a table function that reads the source
+func ingestSQL(source *connectors.Source) (string, error) {
+	conf := &sourceConfig{}
+	err := mapstructure.Decode(source.Properties, conf)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	tableFunction, err := tableFunctionSQL(source.Connector, conf)
+	if err != nil {
+		return "", err
+	}
+
+	qry := fmt.Sprintf("CREATE OR REPLACE TABLE %s ENGINE = MergeTree ORDER BY tuple() AS SELECT * FROM %s", safeName(source.Name), tableFunction)
+	if conf.CSVDelimiter != "" {
+		qry += fmt.Sprintf(" SETTINGS format_csv_delimiter = %s", quoteString(conf.CSVDelimiter))
+	}
+
+	return qry, nil
+}
+
+// tableFunctionSQL returns a call to the table function that reads the source's path
+func tableFunctionSQL(connector string, conf *sourceConfig) (string, error) {
+	format, err := inputFormat(conf)
+	if err != nil {
+		return "", err
+	}
+
+	switch connector {
+	case "s3":
+		u, err := url.Parse(conf.Path)
+		if err != nil || u.Scheme != "s3" {
+			return "", fmt.Errorf("invalid s3 path '%s'", conf.Path)
+		}
+		host := fmt.Sprintf("%s.s3.amazonaws.com", u.Host)
+		if conf.AWSRegion != "" {
+			host = fmt.Sprintf("%s.s3.%s.amazonaws.com", u.Host, conf.AWSRegion)
+		}
+		return fmt.Sprintf("s3(%s, %s)", quoteString("https://"+host+u.Path), quoteString(format)), nil
+	case "gcs":
+		u, err := url.Parse(conf.Path)
+		if err != nil || u.Scheme != "gs" {
+			return "", fmt.Errorf("invalid gcs path '%s'", conf.Path)
+		}
+		// GCS is accessed through its S3-compatible XML API
+		return fmt.Sprintf("s3(%s, %s)", quoteString("https://storage.googleapis.com/"+u.Host+u.Path), quoteString(format)), nil
+	case "https":
+		return fmt.Sprintf("url(%s, %s)", quoteString(conf.Path), quoteString(format)), nil
+	default:
+		return "", drivers.ErrUnsupportedConnector
+	}
+}
+
+// inputFormat returns the ClickHouse input format based on the source's format or file extension
+func inputFormat(conf *sourceConfig) (string, error) {
+	ext := conf.Format
+	if ext == "" {
+		ext = fileutil.FullExt(conf.Path)
+	}
+
+	switch {
+	case strings.Contains(ext, ".csv"):
+		return "CSVWithNames", nil
+	case strings.Contains(ext, ".tsv"), strings.Contains(ext, ".txt"):
+		return "TSVWithNames", nil
+	case strings.Contains(ext, ".parquet"):
+		return "Parquet", nil
+	case strings.Contains(ext, ".json"):
+		// ClickHouse expects newline-delimited JSON
+		return "JSONEachRow", nil
+	case ext == "":
+		return "", fmt.Errorf("invalid file")
+	default:
+		return "", fmt.Errorf("file type not supported : %s", ext)
+	}
+}
+
+func safeName(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "\\`"))
+}
+
+func quoteString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", `\'`))
+}
@@ -0,0 +1,126 @@
+package clickhouse
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+type informationSchema struct {
+	c *connection
+}
+
+func (c *connection) InformationSchema() drivers.InformationSchema {
+	return informationSchema{c: c}
+}
+
+func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
+	q := `
+		SELECT
+			T.database AS DATABASE,
+			T.name AS NAME,
+			C.name AS COLUMN_NAME,
+			C.type AS COLUMN_TYPE
+		FROM system.tables T
+		JOIN system.columns C ON T.database = C.database AND T.name = C.table
+		WHERE T.database = currentDatabase() AND NOT T.is_temporary
+		ORDER BY DATABASE, NAME, C.position
+	`
+
+	rows, err := i.c.db.QueryxContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables, err := i.scanTables(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Table, error) {
+	q := `
+		SELECT
+			T.database AS DATABASE,
+			T.name AS NAME,
+			C.name AS COLUMN_NAME,
+			C.type AS COLUMN_TYPE
+		FROM system.tables T
+		JOIN system.columns C ON T.database = C.database AND T.name = C.table
+		WHERE T.database = currentDatabase() AND NOT T.is_temporary AND T.name = ?
+		ORDER BY DATABASE, NAME, C.position
+	`
+
+	rows, err := i.c.db.QueryxContext(ctx, q, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables, err := i.scanTables(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, drivers.ErrNotFound
+	}
+
+	return tables[0], nil
+}
+
+func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error) {
+	var res []*drivers.Table
+
+	for rows.Next() {
+		var database string
+		var name string
+		var columnName string
+		var columnType string
+
+		err := rows.Scan(&database, &name, &columnName, &columnType)
+		if err != nil {
+			return nil, err
+		}
+
+		// set t to res[len(res)-1] if it's the same table, else set t to a new table and append it
+		var t *drivers.Table
+		if len(res) > 0 {
+			t = res[len(res)-1]
+			if !(t.Database == database && t.Name == name) {
+				t = nil
+			}
+		}
+		if t == nil {
+			t = &drivers.Table{
+				Database: database,
+				Name:     name,
+				Schema:   &runtimev1.StructType{},
+			}
+			res = append(res, t)
+		}
+
+		// parse column type (nullability is part of the type in ClickHouse)
+		colType, err := databaseTypeToPB(columnType, false)
+		if err != nil {
+			return nil, err
+		}
+
+		// append column
+		t.Schema.Fields = append(t.Schema.Fields, &runtimev1.StructType_Field{
+			Name: columnName,
+			Type: colType,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,264 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+func (c *connection) Dialect() drivers.Dialect {
+	return drivers.DialectClickHouse
+}
+
+// Capabilities implements drivers.OLAPStore.
+func (c *connection) Capabilities() drivers.Capabilities {
+	return drivers.Capabilities{
+		QueryKinds:          []drivers.QueryKind{drivers.QueryKindMetricsView, drivers.QueryKindColumnProfile},
+		Connectors:          []string{"gcs", "https", "s3"},
+		ApproxQuantile:      true,
+		ApproxCountDistinct: true,
+	}
+}
+
+func (c *connection) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
+	if stmt.DryRun {
+		// EXPLAIN analyzes the query (including its args) without executing it
+		rows, err := c.db.QueryxContext(ctx, "EXPLAIN "+stmt.Query, stmt.Args...)
+		if err != nil {
+			return nil, err
+		}
+		return nil, rows.Close()
+	}
+
+	// The timeout context must stay open until the result has been read, so it's cancelled when the result is closed
+	ctx, cancel := stmt.WithTimeout(ctx)
+
+	rows, err := c.db.QueryxContext(ctx, stmt.Query, stmt.Args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	schema, err := rowsToSchema(rows)
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, err
+	}
+
+	// ClickHouse streams results in blocks, so we enforce the max rows while reading the result
+	res := &drivers.Result{Rows: rows, Schema: schema}
+	res.SetMaxRows(stmt.MaxRows)
+	res.SetCleanupFunc(cancel)
+	return res, nil
+}
+
+// Explain implements drivers.OLAPStore using ClickHouse's EXPLAIN statement.
+// ClickHouse only reports execution statistics in its query log, so it returns drivers.ErrExplainAnalyzeUnsupported if analyze is true.
+func (c *connection) Explain(ctx context.Context, stmt *drivers.Statement, analyze bool) (*drivers.QueryPlan, error) {
+	if analyze {
+		return nil, drivers.ErrExplainAnalyzeUnsupported
+	}
+
+	ctx, cancel := stmt.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryxContext(ctx, "EXPLAIN "+stmt.Query, stmt.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The plan is returned with one row per line
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &drivers.QueryPlan{Plan: strings.Join(lines, "\n")}, nil
+}
+
+func rowsToSchema(r *sqlx.Rows) (*runtimev1.StructType, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	cts, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]*runtimev1.StructType_Field, len(cts))
+	for i, ct := range cts {
+		nullable, ok := ct.Nullable()
+		if !ok {
+			nullable = true
+		}
+
+		t, err := databaseTypeToPB(ct.DatabaseTypeName(), nullable)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = &runtimev1.StructType_Field{
+			Name: ct.Name(),
+			Type: t,
+		}
+	}
+
+	return &runtimev1.StructType{Fields: fields}, nil
+}
+
+// databaseTypeToPB converts a ClickHouse type name (such as "Nullable(Decimal(18, 2))") to a runtimev1.Type
+func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
+	dbt = strings.TrimSpace(dbt)
+
+	// Unwrap modifiers that don't change how values are represented
+	if inner, ok := typeArgs(dbt, "Nullable"); ok {
+		return databaseTypeToPB(inner, true)
+	}
+	if inner, ok := typeArgs(dbt, "LowCardinality"); ok {
+		return databaseTypeToPB(inner, nullable)
+	}
+
+	t := &runtimev1.Type{Nullable: nullable}
+
+	// Handle complex types
+	if inner, ok := typeArgs(dbt, "Array"); ok {
+		elemType, err := databaseTypeToPB(inner, false)
+		if err != nil {
+			return nil, err
+		}
+		t.Code = runtimev1.Type_CODE_ARRAY
+		t.ArrayElementType = elemType
+		return t, nil
+	}
+	if inner, ok := typeArgs(dbt, "Map"); ok {
+		args := splitTypeArgs(inner)
+		if len(args) != 2 {
+			return nil, fmt.Errorf("encountered invalid clickhouse type '%s'", dbt)
+		}
+		keyType, err := databaseTypeToPB(args[0], false)
+		if err != nil {
+			return nil, err
+		}
+		valueType, err := databaseTypeToPB(args[1], false)
+		if err != nil {
+			return nil, err
+		}
+		t.Code = runtimev1.Type_CODE_MAP
+		t.MapType = &runtimev1.MapType{KeyType: keyType, ValueType: valueType}
+		return t, nil
+	}
+	if inner, ok := typeArgs(dbt, "Tuple"); ok {
+		st := &runtimev1.StructType{}
+		for i, arg := range splitTypeArgs(inner) {
+			// Named tuple elements have the form "name Type"
+			name := fmt.Sprintf("%d", i+1)
+			if n, typ, ok := strings.Cut(arg, " "); ok && !strings.Contains(n, "(") {
+				name, arg = n, typ
+			}
+			ft, err := databaseTypeToPB(arg, false)
+			if err != nil {
+				return nil, err
+			}
+			st.Fields = append(st.Fields, &runtimev1.StructType_Field{Name: name, Type: ft})
+		}
+		t.Code = runtimev1.Type_CODE_STRUCT
+		t.StructType = st
+		return t, nil
+	}
+
+	// Strip parameters (such as the precision of decimals or the time zone of timestamps)
+	base, _, _ := strings.Cut(dbt, "(")
+
+	switch base {
+	case "Bool":
+		t.Code = runtimev1.Type_CODE_BOOL
+	case "Int8":
+		t.Code = runtimev1.Type_CODE_INT8
+	case "Int16":
+		t.Code = runtimev1.Type_CODE_INT16
+	case "Int32":
+		t.Code = runtimev1.Type_CODE_INT32
+	case "Int64":
+		t.Code = runtimev1.Type_CODE_INT64
+	case "Int128", "Int256":
+		t.Code = runtimev1.Type_CODE_INT128
+	case "UInt8":
+		t.Code = runtimev1.Type_CODE_UINT8
+	case "UInt16":
+		t.Code = runtimev1.Type_CODE_UINT16
+	case "UInt32":
+		t.Code = runtimev1.Type_CODE_UINT32
+	case "UInt64":
+		t.Code = runtimev1.Type_CODE_UINT64
+	case "UInt128", "UInt256":
+		t.Code = runtimev1.Type_CODE_UINT128
+	case "Float32":
+		t.Code = runtimev1.Type_CODE_FLOAT32
+	case "Float64":
+		t.Code = runtimev1.Type_CODE_FLOAT64
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		t.Code = runtimev1.Type_CODE_DECIMAL
+	case "String", "FixedString":
+		t.Code = runtimev1.Type_CODE_STRING
+	case "Enum8", "Enum16":
+		t.Code = runtimev1.Type_CODE_STRING
+	case "IPv4", "IPv6":
+		t.Code = runtimev1.Type_CODE_STRING
+	case "UUID":
+		t.Code = runtimev1.Type_CODE_UUID
+	case "Date", "Date32":
+		t.Code = runtimev1.Type_CODE_DATE
+	case "DateTime", "DateTime64":
+		t.Code = runtimev1.Type_CODE_TIMESTAMP
+	case "JSON", "Object":
+		t.Code = runtimev1.Type_CODE_JSON
+	case "Nothing":
+		t.Code = runtimev1.Type_CODE_UNSPECIFIED
+	default:
+		return nil, fmt.Errorf("encountered unsupported clickhouse type '%s'", dbt)
+	}
+
+	return t, nil
+}
+
+// typeArgs returns the arguments of a parameterized type such as "Array(String)" if its name is name
+func typeArgs(dbt, name string) (string, bool) {
+	if !strings.HasPrefix(dbt, name+"(") || !strings.HasSuffix(dbt, ")") {
+		return "", false
+	}
+	return dbt[len(name)+1 : len(dbt)-1], true
+}
+
+// splitTypeArgs splits a comma-separated list of type arguments, ignoring commas in nested types
+func splitTypeArgs(args string) []string {
+	var res []string
+	var depth, start int
+	for i, r := range args {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, strings.TrimSpace(args[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(res, strings.TrimSpace(args[start:]))
+}
@@ -0,0 +1,183 @@
+package clickhouse
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+)
+
+// connector wraps the clickhouse-go connector to normalize the values returned in query results.
+// clickhouse-go returns pointers for nullable columns and typed slices and maps for complex columns,
+// which can't be scanned into the sql.Null* types or converted to protobuf values.
+type connector struct {
+	sqldriver.Connector
+}
+
+func (c connector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc}, nil
+}
+
+type conn struct {
+	sqldriver.Conn
+}
+
+var (
+	_ sqldriver.QueryerContext    = &conn{}
+	_ sqldriver.ExecerContext     = &conn{}
+	_ sqldriver.Pinger            = &conn{}
+	_ sqldriver.NamedValueChecker = &conn{}
+	_ sqldriver.SessionResetter   = &conn{}
+)
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	dr, err := c.Conn.(sqldriver.QueryerContext).QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{Rows: dr}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	return c.Conn.(sqldriver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.Conn.(sqldriver.Pinger).Ping(ctx)
+}
+
+func (c *conn) CheckNamedValue(nv *sqldriver.NamedValue) error {
+	return c.Conn.(sqldriver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	return c.Conn.(sqldriver.SessionResetter).ResetSession(ctx)
+}
+
+type rows struct {
+	sqldriver.Rows
+	// decimals flags the columns of type Decimal, which clickhouse-go returns as strings
+	decimals []bool
+}
+
+var (
+	_ sqldriver.RowsColumnTypeDatabaseTypeName = &rows{}
+	_ sqldriver.RowsColumnTypeNullable         = &rows{}
+	_ sqldriver.RowsColumnTypePrecisionScale   = &rows{}
+)
+
+func (r *rows) ColumnTypeDatabaseTypeName(idx int) string {
+	return r.Rows.(sqldriver.RowsColumnTypeDatabaseTypeName).ColumnTypeDatabaseTypeName(idx)
+}
+
+func (r *rows) ColumnTypeNullable(idx int) (nullable, ok bool) {
+	return r.Rows.(sqldriver.RowsColumnTypeNullable).ColumnTypeNullable(idx)
+}
+
+func (r *rows) ColumnTypePrecisionScale(idx int) (precision, scale int64, ok bool) {
+	return r.Rows.(sqldriver.RowsColumnTypePrecisionScale).ColumnTypePrecisionScale(idx)
+}
+
+func (r *rows) Next(dest []sqldriver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil {
+		return err
+	}
+
+	if r.decimals == nil {
+		r.decimals = make([]bool, len(dest))
+		for i := range dest {
+			_, _, r.decimals[i] = r.ColumnTypePrecisionScale(i)
+		}
+	}
+
+	for i, v := range dest {
+		v = normalizeValue(v)
+		if s, ok := v.(string); ok && r.decimals[i] {
+			// Decimals are returned as floats, like on DuckDB
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("clickhouse: invalid decimal %q: %w", s, err)
+			}
+			v = f
+		}
+		dest[i] = v
+	}
+
+	return nil
+}
+
+// normalizeValue dereferences pointers, widens numbers and converts typed slices and maps to []any and map[string]any.
+func normalizeValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string, []byte, int64, uint64, float64:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case *big.Int:
+		if v == nil {
+			return nil
+		}
+		return v
+	case big.Int:
+		return &v
+	case net.IP:
+		return v.String()
+	case sqldriver.Valuer:
+		// Handles values such as decimals and UUIDs (and pointers to them)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		dv, err := v.Value()
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return normalizeValue(dv)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalizeValue(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		res := make([]any, rv.Len())
+		for i := range res {
+			res[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return res
+	case reflect.Map:
+		res := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			res[fmt.Sprint(normalizeValue(iter.Key().Interface()))] = normalizeValue(iter.Value().Interface())
+		}
+		return res
+	}
+
+	return v
+}
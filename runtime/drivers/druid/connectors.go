@@ -0,0 +1,450 @@
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/rilldata/rill/runtime/connectors"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/fileutil"
+)
+
+// missingTimestampColumn is used as the timestamp column for sources that don't configure one.
+// Since it doesn't exist, Druid uses the timestamp spec's missingValue for all rows.
+const missingTimestampColumn = "__rill_missing_timestamp"
+
+// ingestionInterval is the time interval that sources are ingested into. It covers all realistic timestamps.
+const ingestionInterval = "1000-01-01/3000-01-01"
+
+// sampleRows is the number of rows sampled from a source to infer its column types
+const sampleRows = 1000
+
+// dimensionTypes are the types that sources can declare for dimensions
+var dimensionTypes = map[string]bool{"string": true, "long": true, "float": true, "double": true}
+
+// metricTypes are the aggregators that sources can declare for metrics
+var metricTypes = map[string]bool{
+	"count":     true,
+	"longSum":   true,
+	"longMin":   true,
+	"longMax":   true,
+	"floatSum":  true,
+	"floatMin":  true,
+	"floatMax":  true,
+	"doubleSum": true,
+	"doubleMin": true,
+	"doubleMax": true,
+}
+
+// sourceConfig contains the source properties used for ingestion into Druid.
+// Besides the connector's properties, sources can configure how Druid partitions and rolls up the data.
+type sourceConfig struct {
+	Path               string            `mapstructure:"path"`
+	Format             string            `mapstructure:"format"`
+	CSVDelimiter       string            `mapstructure:"csv.delimiter"`
+	TimestampColumn    string            `mapstructure:"druid.timestamp_column"`
+	TimestampFormat    string            `mapstructure:"druid.timestamp_format"`
+	SegmentGranularity string            `mapstructure:"druid.segment_granularity"`
+	QueryGranularity   string            `mapstructure:"druid.query_granularity"`
+	Rollup             bool              `mapstructure:"druid.rollup"`
+	Dimensions         []dimensionConfig `mapstructure:"druid.dimensions"`
+	Metrics            []metricConfig    `mapstructure:"druid.metrics"`
+}
+
+// dimensionConfig declares a column that's ingested as a dimension. The type defaults to string.
+type dimensionConfig struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+}
+
+// metricConfig declares a metric that aggregates a column (named by Field, which defaults to Name) when rows are rolled up.
+type metricConfig struct {
+	Name  string `mapstructure:"name"`
+	Type  string `mapstructure:"type"`
+	Field string `mapstructure:"field"`
+}
+
+// Ingest implements drivers.OLAPStore by submitting a native batch (index_parallel) ingestion task for the source.
+// Druid reads the data itself, so local files must be accessible from the Druid cluster at the same path.
+func (c *connection) Ingest(ctx context.Context, env *connectors.Env, source *connectors.Source) error {
+	err := source.Validate()
+	if err != nil {
+		return err
+	}
+
+	spec, err := ingestionSpec(env, source)
+	if err != nil {
+		return err
+	}
+
+	// Druid 0.23 doesn't discover column types, so if the source doesn't declare its dimensions, we infer them from a sample of the source.
+	// Rows that don't match the inferred types fail the ingestion task (see maxParseExceptions).
+	if !declaresDimensions(spec) {
+		types, err := sampleColumnTypes(ctx, c.coordinatorURL, spec)
+		if err != nil {
+			return fmt.Errorf("failed to sample source: %w", err)
+		}
+		applyColumnTypes(spec, types)
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+
+	return ingestSpec(ctx, c.coordinatorURL, string(specJSON), source.Name)
+}
+
+// ingestionSpec translates a source to a Druid native batch ingestion spec
+func ingestionSpec(env *connectors.Env, source *connectors.Source) (map[string]any, error) {
+	conf := &sourceConfig{}
+	err := mapstructure.Decode(source.Properties, conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	inputSource, err := inputSourceSpec(env, source.Connector, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	inputFormat, err := inputFormatSpec(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	timestampSpec := map[string]any{
+		"column": missingTimestampColumn,
+		// Rows are assigned to the Unix epoch
+		"missingValue": "1970-01-01T00:00:00Z",
+	}
+	if conf.TimestampColumn != "" {
+		format := conf.TimestampFormat
+		if format == "" {
+			format = "auto"
+		}
+		timestampSpec = map[string]any{
+			"column": conf.TimestampColumn,
+			"format": format,
+		}
+	}
+
+	segmentGranularity := conf.SegmentGranularity
+	if segmentGranularity == "" {
+		segmentGranularity = "DAY"
+	}
+	queryGranularity := conf.QueryGranularity
+	if queryGranularity == "" {
+		queryGranularity = "NONE"
+	}
+
+	dimensions, err := dimensionsSpec(conf.Dimensions)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := metricsSpec(conf.Metrics, conf.Rollup)
+	if err != nil {
+		return nil, err
+	}
+
+	dataSchema := map[string]any{
+		"dataSource":    source.Name,
+		"timestampSpec": timestampSpec,
+		// Without explicit dimensions, Druid ingests all other columns as string dimensions.
+		// If the source doesn't declare its dimensions, Ingest sets them with applyColumnTypes.
+		"dimensionsSpec": map[string]any{
+			"dimensions": dimensions,
+		},
+		"granularitySpec": map[string]any{
+			"type":               "uniform",
+			"segmentGranularity": segmentGranularity,
+			"queryGranularity":   queryGranularity,
+			"rollup":             conf.Rollup,
+			// Locks all time so that publishing the new segments drops every existing segment (see dropExisting).
+			// Rows with timestamps outside the interval are not ingested.
+			"intervals": []string{ingestionInterval},
+		},
+	}
+	if len(metrics) > 0 {
+		dataSchema["metricsSpec"] = metrics
+	}
+
+	return map[string]any{
+		"type": "index_parallel",
+		"spec": map[string]any{
+			"ioConfig": map[string]any{
+				"type":        "index_parallel",
+				"inputSource": inputSource,
+				"inputFormat": inputFormat,
+				// Replaces all existing data, including time intervals the source no longer covers
+				"appendToExisting": false,
+				"dropExisting":     true,
+			},
+			"tuningConfig": map[string]any{
+				"type": "index_parallel",
+				"partitionsSpec": map[string]any{
+					"type": "dynamic",
+				},
+				// Fails the task if a row can't be parsed, e.g. because it doesn't match the dimension types,
+				// instead of ingesting the row with null values or skipping it
+				"maxParseExceptions": 0,
+			},
+			"dataSchema": dataSchema,
+		},
+	}, nil
+}
+
+// dimensionsSpec returns the dimensions declared by a source
+func dimensionsSpec(dims []dimensionConfig) ([]any, error) {
+	res := make([]any, 0, len(dims))
+	for _, d := range dims {
+		if d.Name == "" {
+			return nil, fmt.Errorf("invalid dimension: missing name")
+		}
+		typ := d.Type
+		if typ == "" {
+			typ = "string"
+		}
+		if !dimensionTypes[typ] {
+			return nil, fmt.Errorf("invalid type '%s' for dimension '%s'", d.Type, d.Name)
+		}
+		res = append(res, map[string]any{"type": typ, "name": d.Name})
+	}
+	return res, nil
+}
+
+// metricsSpec returns the metrics declared by a source.
+// For rollups, rows with the same truncated timestamp and dimensions are combined, so a "count" metric counts them
+// (unless the source declares a metric with that name).
+func metricsSpec(metrics []metricConfig, rollup bool) ([]any, error) {
+	var res []any
+	names := make(map[string]bool)
+	for _, m := range metrics {
+		if m.Name == "" {
+			return nil, fmt.Errorf("invalid metric: missing name")
+		}
+		if !metricTypes[m.Type] {
+			return nil, fmt.Errorf("invalid type '%s' for metric '%s'", m.Type, m.Name)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("duplicate metric '%s'", m.Name)
+		}
+		names[m.Name] = true
+
+		if m.Type == "count" {
+			res = append(res, map[string]any{"type": "count", "name": m.Name})
+			continue
+		}
+		field := m.Field
+		if field == "" {
+			field = m.Name
+		}
+		res = append(res, map[string]any{"type": m.Type, "name": m.Name, "fieldName": field})
+	}
+
+	if rollup && !names["count"] {
+		res = append([]any{map[string]any{"type": "count", "name": "count"}}, res...)
+	}
+	return res, nil
+}
+
+// inputSourceSpec returns the Druid input source for the source's connector
+func inputSourceSpec(env *connectors.Env, connector string, conf *sourceConfig) (map[string]any, error) {
+	switch connector {
+	case "s3":
+		return map[string]any{"type": "s3", "uris": []string{conf.Path}}, nil
+	case "gcs":
+		return map[string]any{"type": "google", "uris": []string{conf.Path}}, nil
+	case "https":
+		return map[string]any{"type": "http", "uris": []string{conf.Path}}, nil
+	case "local_file":
+		path := conf.Path
+		if !filepath.IsAbs(path) {
+			// If the path is relative, it's relative to the repo root
+			if env.RepoDriver != "file" || env.RepoDSN == "" {
+				return nil, fmt.Errorf("druid cannot ingest local file '%s': path is relative, but repo is not available", conf.Path)
+			}
+			path = filepath.Join(env.RepoDSN, path)
+		}
+		return map[string]any{"type": "local", "baseDir": filepath.Dir(path), "filter": filepath.Base(path)}, nil
+	default:
+		return nil, drivers.ErrUnsupportedConnector
+	}
+}
+
+// inputFormatSpec returns the Druid input format based on the source's format or file extension
+func inputFormatSpec(conf *sourceConfig) (map[string]any, error) {
+	ext := conf.Format
+	if ext == "" {
+		ext = fileutil.FullExt(conf.Path)
+	}
+
+	switch {
+	case strings.Contains(ext, ".csv"):
+		if conf.CSVDelimiter != "" && conf.CSVDelimiter != "," {
+			return map[string]any{"type": "tsv", "delimiter": conf.CSVDelimiter, "findColumnsFromHeader": true}, nil
+		}
+		return map[string]any{"type": "csv", "findColumnsFromHeader": true}, nil
+	case strings.Contains(ext, ".tsv"), strings.Contains(ext, ".txt"):
+		return map[string]any{"type": "tsv", "findColumnsFromHeader": true}, nil
+	case strings.Contains(ext, ".parquet"):
+		return map[string]any{"type": "parquet"}, nil
+	case strings.Contains(ext, ".json"):
+		// Druid expects newline-delimited JSON
+		return map[string]any{"type": "json"}, nil
+	case ext == "":
+		return nil, fmt.Errorf("invalid file")
+	default:
+		return nil, fmt.Errorf("file type not supported : %s", ext)
+	}
+}
+
+// columnType is the Druid type inferred for a source column
+type columnType int
+
+const (
+	columnTypeLong columnType = iota
+	columnTypeDouble
+	columnTypeString
+)
+
+type samplerResponse struct {
+	Data []struct {
+		Input map[string]any
+	}
+}
+
+// sampleColumnTypes uses Druid's sampler API to read rows of the source described by spec and infers the type of each column.
+// The timestamp column is not included.
+func sampleColumnTypes(ctx context.Context, coordinatorURL string, spec map[string]any) (map[string]columnType, error) {
+	req := map[string]any{
+		"type":          "index_parallel",
+		"spec":          spec["spec"],
+		"samplerConfig": map[string]any{"numRows": sampleRows},
+	}
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res samplerResponse
+	err = sendRequest(ctx, coordinatorURL, http.MethodPost, "/druid/indexer/v1/sampler", string(reqJSON), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	timestampColumn, _ := spec["spec"].(map[string]any)["dataSchema"].(map[string]any)["timestampSpec"].(map[string]any)["column"].(string)
+	rows := make([]map[string]any, 0, len(res.Data))
+	for _, d := range res.Data {
+		row := make(map[string]any, len(d.Input))
+		for k, v := range d.Input {
+			if k != timestampColumn {
+				row[k] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return inferColumnTypes(rows), nil
+}
+
+// inferColumnTypes returns the narrowest type that all non-null values of each column can be parsed as.
+// Text formats like CSV return numbers as strings, so strings are parsed too.
+func inferColumnTypes(rows []map[string]any) map[string]columnType {
+	types := make(map[string]columnType)
+	for _, row := range rows {
+		for name, val := range row {
+			if val == nil {
+				continue
+			}
+			if t, ok := types[name]; !ok || valueType(val) > t {
+				types[name] = valueType(val)
+			}
+		}
+	}
+
+	// Columns that only contain nulls are ingested as strings
+	for _, row := range rows {
+		for name := range row {
+			if _, ok := types[name]; !ok {
+				types[name] = columnTypeString
+			}
+		}
+	}
+	return types
+}
+
+// valueType returns the narrowest type that a sampled value can be parsed as
+func valueType(val any) columnType {
+	var s string
+	switch v := val.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return columnTypeLong
+		}
+		return columnTypeDouble
+	case string:
+		s = v
+	default:
+		return columnTypeString
+	}
+
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return columnTypeLong
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return columnTypeDouble
+	}
+	return columnTypeString
+}
+
+// declaresDimensions returns true if the ingestion spec has the dimensions declared by the source
+func declaresDimensions(spec map[string]any) bool {
+	dataSchema := spec["spec"].(map[string]any)["dataSchema"].(map[string]any)
+	return len(dataSchema["dimensionsSpec"].(map[string]any)["dimensions"].([]any)) > 0
+}
+
+// applyColumnTypes sets the dimensions of an ingestion spec based on the inferred column types.
+// Every column is ingested as a dimension, except for the columns aggregated by the source's metrics.
+// (Numeric columns are not summed implicitly for rollups, since that's wrong for columns like IDs and prices.)
+func applyColumnTypes(spec map[string]any, types map[string]columnType) {
+	dataSchema := spec["spec"].(map[string]any)["dataSchema"].(map[string]any)
+
+	metricFields := make(map[string]bool)
+	if metrics, ok := dataSchema["metricsSpec"].([]any); ok {
+		for _, m := range metrics {
+			if field, ok := m.(map[string]any)["fieldName"].(string); ok {
+				metricFields[field] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for name := range types {
+		if !metricFields[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	dimensions := make([]any, 0, len(names))
+	for _, name := range names {
+		typ := "string"
+		switch types[name] {
+		case columnTypeLong:
+			typ = "long"
+		case columnTypeDouble:
+			typ = "double"
+		}
+		dimensions = append(dimensions, map[string]any{"type": typ, "name": name})
+	}
+
+	dataSchema["dimensionsSpec"] = map[string]any{"dimensions": dimensions}
+}
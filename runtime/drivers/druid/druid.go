@@ -2,6 +2,8 @@ package druid
 
 import (
 	"context"
+	"fmt"
+	"net/url"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rilldata/rill/runtime/drivers"
@@ -18,18 +20,45 @@ type driver struct{}
 
 // Open connects to Druid using Avatica.
 // Note that the Druid connection string must have the form "http://host/druid/v2/sql/avatica-protobuf/".
+// Sources are ingested by submitting tasks to the same host, which works when connecting through a Druid router.
+// To submit tasks to a different host (such as the Overlord), set the "coordinatorURL" query parameter.
 func (d driver) Open(dsn string) (drivers.Connection, error) {
-	db, err := sqlx.Open("avatica", dsn)
+	avaticaURL, coordinatorURL, err := parseDSN(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	conn := &connection{db: db}
+	db, err := sqlx.Open("avatica", avaticaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &connection{db: db, coordinatorURL: coordinatorURL}
 	return conn, nil
 }
 
+// parseDSN removes the "coordinatorURL" parameter from dsn (since Avatica doesn't know it) and returns it.
+// If it's not set, the coordinator URL defaults to the scheme and host of the DSN.
+func parseDSN(dsn string) (string, string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid druid dsn: %w", err)
+	}
+
+	query := u.Query()
+	coordinatorURL := query.Get("coordinatorURL")
+	if coordinatorURL == "" {
+		coordinatorURL = (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String()
+	}
+	query.Del("coordinatorURL")
+	u.RawQuery = query.Encode()
+
+	return u.String(), coordinatorURL, nil
+}
+
 type connection struct {
-	db *sqlx.DB
+	db             *sqlx.DB
+	coordinatorURL string
 }
 
 // Close implements drivers.Connection.
@@ -118,6 +118,8 @@ func TestDruid(t *testing.T) {
 	t.Run("max", func(t *testing.T) { testMax(t, olap) })
 	t.Run("schema all", func(t *testing.T) { testSchemaAll(t, olap) })
 	t.Run("schema lookup", func(t *testing.T) { testSchemaLookup(t, olap) })
+	t.Run("queries", func(t *testing.T) { testQueries(t, avaticaURL) })
+	t.Run("ingest source", func(t *testing.T) { testIngestSource(t, container, avaticaURL, coordinatorURL) })
 	// Add new tests here
 
 	require.NoError(t, conn.Close())
@@ -163,6 +165,12 @@ func testSchemaAll(t *testing.T, olap drivers.OLAPStore) {
 	require.Equal(t, 1, len(tables))
 	require.Equal(t, testTable, tables[0].Name)
 
+	require.NotNil(t, tables[0].Metadata)
+	require.Greater(t, tables[0].Metadata.SegmentCount, int64(0))
+	require.Greater(t, tables[0].Metadata.SizeBytes, int64(0))
+	require.True(t, tables[0].Metadata.MinTime.AsTime().Before(tables[0].Metadata.MaxTime.AsTime()))
+	require.NotEmpty(t, tables[0].Metadata.QueryGranularity)
+
 	require.Equal(t, "__time", tables[0].Schema.Fields[0].Name)
 	require.Equal(t, runtimev1.Type_CODE_TIMESTAMP, tables[0].Schema.Fields[0].Type.Code)
 	require.Equal(t, false, tables[0].Schema.Fields[0].Type.Nullable)
@@ -1,6 +1,7 @@
 package druid
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,18 +9,40 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/rilldata/rill/runtime/drivers"
 )
 
+// ingestPollInterval is the interval at which the status of ingestion tasks is polled
+const ingestPollInterval = 2 * time.Second
+
+// maxFailedTaskPolls is the number of consecutive failed requests for a task's status after which ingestion fails
+const maxFailedTaskPolls = 30
+
 type taskResult struct {
 	Task string
 }
 
+type taskStatus struct {
+	Status struct {
+		StatusCode string
+		ErrorMsg   string
+	}
+}
+
+type rowStats struct {
+	Processed   int64
+	Unparseable int64
+}
+
 type taskReport struct {
 	IngestionStatsAndErrors struct {
 		Payload struct {
 			RowStats struct {
-				BuildSegments struct {
-					Processed float64
+				BuildSegments rowStats
+				// Totals is set in live reports of running tasks
+				Totals struct {
+					BuildSegments rowStats
 				}
 			}
 			IngestionState string
@@ -27,82 +50,121 @@ type taskReport struct {
 	}
 }
 
-type datasourceDetails struct {
-	Segments struct {
-		Count float64
-	}
-}
-
 // Ingest uses the Druid REST API to submit an ingestion spec. It returns once Druid has finished ingesting data.
-// This function is for test and development usage and has not been tested for production use.
+// This function is for test and development usage, use the OLAP store's Ingest to ingest sources.
 func Ingest(coordinatorURL, specJSON, datasourceName string, timeout time.Duration) error {
-	var status taskResult
-	err := sendRequest(coordinatorURL, http.MethodPost, "/druid/indexer/v1/task", specJSON, &status)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return ingestSpec(ctx, coordinatorURL, specJSON, datasourceName)
+}
+
+// ingestSpec submits an ingestion task and waits until it has completed and its segments are available for querying.
+// If ctx is cancelled, the task is shut down. Progress is reported to the function set with drivers.WithIngestionProgress.
+func ingestSpec(ctx context.Context, coordinatorURL, specJSON, datasourceName string) error {
+	var res taskResult
+	err := sendRequest(ctx, coordinatorURL, http.MethodPost, "/druid/indexer/v1/task", specJSON, &res)
 	if err != nil {
+		return fmt.Errorf("failed to submit ingestion task: %w", err)
+	}
+
+	err = waitForTask(ctx, coordinatorURL, res.Task)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Use a new context since ctx is done
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			_ = sendRequest(shutdownCtx, coordinatorURL, http.MethodPost, fmt.Sprintf("/druid/indexer/v1/task/%s/shutdown", res.Task), "", nil)
+			cancel()
+		}
 		return err
 	}
 
-	deadline := time.Now().Add(timeout)
-	pollInterval := 2 * time.Second
+	return waitForSegments(ctx, coordinatorURL, datasourceName)
+}
 
+// waitForTask polls the status of a task until it has succeeded or failed
+func waitForTask(ctx context.Context, coordinatorURL, taskID string) error {
+	progress := drivers.IngestionProgressFromContext(ctx)
+	var last drivers.IngestionProgress
+	var failedPolls int
 	for {
-		time.Sleep(pollInterval)
-
-		if time.Now().After(deadline) {
-			return fmt.Errorf("ingestion timeout")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ingestPollInterval):
 		}
 
-		tr, err := getTaskReport(coordinatorURL, status.Task)
+		var status taskStatus
+		err := sendRequest(ctx, coordinatorURL, http.MethodGet, fmt.Sprintf("/druid/indexer/v1/task/%s/status", taskID), "", &status)
 		if err != nil {
 			// The coordinator may return 404 or 500 on the first few polls
-			if strings.Contains(err.Error(), "failed with status:") {
+			failedPolls++
+			if strings.Contains(err.Error(), "failed with status:") && failedPolls < maxFailedTaskPolls {
 				continue
 			}
 			return err
 		}
+		failedPolls = 0
 
-		success := tr.IngestionStatsAndErrors.Payload.IngestionState != ""
-		if !success {
-			continue
+		switch status.Status.StatusCode {
+		case "SUCCESS":
+			return nil
+		case "FAILED":
+			if status.Status.ErrorMsg != "" {
+				return fmt.Errorf("ingestion task %q failed: %s", taskID, status.Status.ErrorMsg)
+			}
+			return fmt.Errorf("ingestion task %q failed", taskID)
 		}
 
-		ds, err := getDatasourceDetails(coordinatorURL, datasourceName)
-		if err != nil {
-			return err
+		if progress == nil {
+			continue
 		}
 
-		segmentsProcessedCount := tr.IngestionStatsAndErrors.Payload.RowStats.BuildSegments.Processed
-		ingestionState := tr.IngestionStatsAndErrors.Payload.IngestionState
-		segmentsSubmittedCount := ds.Segments.Count
-
-		if ingestionState == "COMPLETED" && segmentsProcessedCount == segmentsSubmittedCount {
-			return nil
+		// Reports are not available for all task types and states, so errors are ignored
+		p := drivers.IngestionProgress{State: status.Status.StatusCode}
+		var report taskReport
+		err = sendRequest(ctx, coordinatorURL, http.MethodGet, fmt.Sprintf("/druid/indexer/v1/task/%s/reports", taskID), "", &report)
+		if err == nil {
+			stats := report.IngestionStatsAndErrors.Payload.RowStats.Totals.BuildSegments
+			if stats == (rowStats{}) {
+				stats = report.IngestionStatsAndErrors.Payload.RowStats.BuildSegments
+			}
+			p.RowsProcessed = stats.Processed
+			p.RowsUnparseable = stats.Unparseable
+			if state := report.IngestionStatsAndErrors.Payload.IngestionState; state != "" {
+				p.State = state
+			}
+		}
+		if p != last {
+			progress(p)
+			last = p
 		}
 	}
 }
 
-func getTaskReport(coordinatorURL, taskID string) (*taskReport, error) {
-	var res taskReport
-	path := fmt.Sprintf("/druid/indexer/v1/task/%s/reports", taskID)
-	err := sendRequest(coordinatorURL, http.MethodGet, path, "", &res)
-	if err != nil {
-		return nil, err
-	}
-	return &res, err
-}
+// waitForSegments polls the coordinator until all the datasource's segments have been loaded by historicals
+func waitForSegments(ctx context.Context, coordinatorURL, datasourceName string) error {
+	path := fmt.Sprintf("/druid/coordinator/v1/datasources/%s/loadstatus?forceMetadataRefresh=true", datasourceName)
+	for {
+		// The coordinator responds without a body until it knows about the datasource
+		loadStatus := make(map[string]float64)
+		err := sendRequest(ctx, coordinatorURL, http.MethodGet, path, "", &loadStatus)
+		if err != nil {
+			return err
+		}
+		if pct, ok := loadStatus[datasourceName]; ok && pct >= 100 {
+			return nil
+		}
 
-func getDatasourceDetails(coordinatorURL, datasourceName string) (*datasourceDetails, error) {
-	var res datasourceDetails
-	path := fmt.Sprintf("/druid/coordinator/v1/datasources/%s", datasourceName)
-	err := sendRequest(coordinatorURL, http.MethodGet, path, "", &res)
-	if err != nil {
-		return nil, err
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ingestPollInterval):
+		}
 	}
-	return &res, err
 }
 
-func sendRequest(coordinatorURL, method, path, jsonBody string, out any) error {
-	reqURL, err := url.JoinPath(coordinatorURL, path)
+func sendRequest(ctx context.Context, coordinatorURL, method, path, jsonBody string, out any) error {
+	reqURL, err := joinURL(coordinatorURL, path)
 	if err != nil {
 		return err
 	}
@@ -112,7 +174,7 @@ func sendRequest(coordinatorURL, method, path, jsonBody string, out any) error {
 		reqBody = strings.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, reqURL, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
 	if err != nil {
 		return err
 	}
@@ -125,16 +187,19 @@ func sendRequest(coordinatorURL, method, path, jsonBody string, out any) error {
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode >= http.StatusBadRequest {
-		return fmt.Errorf("coordinator request failed with status: %d", res.StatusCode)
-	}
-
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		return err
 	}
 
-	if len(body) > 0 {
+	if res.StatusCode >= http.StatusBadRequest {
+		if len(body) > 0 {
+			return fmt.Errorf("coordinator request failed with status: %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return fmt.Errorf("coordinator request failed with status: %d", res.StatusCode)
+	}
+
+	if len(body) > 0 && out != nil {
 		err = json.Unmarshal(body, out)
 		if err != nil {
 			return err
@@ -143,3 +208,15 @@ func sendRequest(coordinatorURL, method, path, jsonBody string, out any) error {
 
 	return nil
 }
+
+// joinURL joins a base URL and a path that may contain a query string
+func joinURL(base, path string) (string, error) {
+	path, rawQuery, _ := strings.Cut(path, "?")
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u = u.JoinPath(path)
+	u.RawQuery = rawQuery
+	return u.String(), nil
+}
@@ -0,0 +1,213 @@
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// segmentMetadata is an element of the result of a segmentMetadata query
+type segmentMetadata struct {
+	Rollup           *bool
+	QueryGranularity json.RawMessage
+}
+
+// maxSegmentMetadataRequests bounds the number of concurrent segmentMetadata queries sent by addMetadata
+const maxSegmentMetadataRequests = 4
+
+// segmentMetadataTimeout is the timeout for each segmentMetadata query
+const segmentMetadataTimeout = 10 * time.Second
+
+// addMetadata sets the metadata of the tables (Druid datasources) in the list.
+// Segment counts, sizes and intervals come from sys.segments. Rollup and query granularity come from a
+// segmentMetadata query sent to the same host as ingestion tasks (which is normally a router that forwards it to a broker).
+//
+// The metadata is best-effort: if it can't be retrieved for a table, the error is set in the metadata's Error field
+// (along with the fields that could be retrieved) instead of failing the listing.
+func (i informationSchema) addMetadata(ctx context.Context, tables []*drivers.Table) {
+	if len(tables) == 0 {
+		return
+	}
+
+	metadata, err := i.segmentsMetadata(ctx, tables)
+	if err != nil {
+		for _, t := range tables {
+			t.Metadata = &runtimev1.TableMetadata{Error: fmt.Sprintf("failed to get segments: %v", err)}
+		}
+		return
+	}
+
+	i.addSegmentMetadataAll(ctx, tables, metadata)
+}
+
+// segmentsMetadata returns the segment counts, sizes and intervals of the tables' published segments from sys.segments.
+// Tables without published segments are not included.
+func (i informationSchema) segmentsMetadata(ctx context.Context, tables []*drivers.Table) (map[string]*runtimev1.TableMetadata, error) {
+	names := make([]any, len(tables))
+	placeholders := make([]string, len(tables))
+	for idx, t := range tables {
+		names[idx] = t.Name
+		placeholders[idx] = "?"
+	}
+
+	q := fmt.Sprintf(`
+		SELECT
+			"datasource",
+			COUNT(*) AS segment_count,
+			SUM("size") AS size_bytes,
+			SUM("num_rows") AS row_count,
+			MIN("start") AS min_time,
+			MAX("end") AS max_time
+		FROM sys.segments
+		WHERE is_published = 1 AND is_overshadowed = 0 AND "datasource" IN (%s)
+		GROUP BY 1
+	`, strings.Join(placeholders, ", "))
+
+	rows, err := i.c.db.QueryxContext(ctx, q, names...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metadata := make(map[string]*runtimev1.TableMetadata)
+	for rows.Next() {
+		var datasource, minTime, maxTime string
+		md := &runtimev1.TableMetadata{}
+		err := rows.Scan(&datasource, &md.SegmentCount, &md.SizeBytes, &md.RowCount, &minTime, &maxTime)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[datasource] = md
+
+		md.MinTime, err = parseSegmentTime(minTime)
+		if err != nil {
+			md.Error = err.Error()
+			continue
+		}
+
+		md.MaxTime, err = parseSegmentTime(maxTime)
+		if err != nil {
+			md.MinTime = nil
+			md.Error = err.Error()
+			continue
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// addSegmentMetadataAll completes the metadata of the tables with segmentMetadata queries and sets it on the tables.
+// The queries are sent concurrently, but at most maxSegmentMetadataRequests at a time.
+func (i informationSchema) addSegmentMetadataAll(ctx context.Context, tables []*drivers.Table, metadata map[string]*runtimev1.TableMetadata) {
+	var g errgroup.Group
+	g.SetLimit(maxSegmentMetadataRequests)
+	for _, t := range tables {
+		t := t
+		md, ok := metadata[t.Name]
+		if !ok {
+			// The datasource doesn't have any published segments
+			continue
+		}
+
+		t.Metadata = md
+
+		// The segmentMetadata query needs the datasource's interval
+		if md.Error != "" {
+			continue
+		}
+
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(ctx, segmentMetadataTimeout)
+			defer cancel()
+
+			err := i.addSegmentMetadata(ctx, t.Name, md)
+			if err != nil {
+				md.Error = err.Error()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// addSegmentMetadata runs a segmentMetadata query over the datasource's interval to get its rollup and query granularity
+func (i informationSchema) addSegmentMetadata(ctx context.Context, datasource string, md *runtimev1.TableMetadata) error {
+	query, err := json.Marshal(map[string]any{
+		"queryType":              "segmentMetadata",
+		"dataSource":             datasource,
+		"intervals":              []string{fmt.Sprintf("%s/%s", md.MinTime.AsTime().Format(time.RFC3339Nano), md.MaxTime.AsTime().Format(time.RFC3339Nano))},
+		"merge":                  true,
+		"analysisTypes":          []string{"rollup", "queryGranularity"},
+		"lenientAggregatorMerge": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	var res []segmentMetadata
+	err = sendRequest(ctx, i.c.coordinatorURL, http.MethodPost, "/druid/v2/", string(query), &res)
+	if err != nil {
+		return fmt.Errorf("failed to query segment metadata: %w", err)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+
+	// Druid returns a null rollup if the segments are mixed
+	md.Rollup = res[0].Rollup != nil && *res[0].Rollup
+	md.QueryGranularity = granularityString(res[0].QueryGranularity)
+	return nil
+}
+
+// parseSegmentTime parses the start or end of a segment's interval as returned in sys.segments
+func parseSegmentTime(s string) (*timestamppb.Timestamp, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("druid: invalid segment time %q: %w", s, err)
+	}
+	return timestamppb.New(t), nil
+}
+
+// granularityString formats a granularity returned by Druid, which is either a string such as "HOUR",
+// an object such as {"type": "period", "period": "PT1H"}, or an object such as {"type": "none"} for simple granularities.
+// It returns an empty string if the granularity is unknown.
+func granularityString(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var g struct {
+		Type     string
+		Period   string
+		Duration int64
+	}
+	if err := json.Unmarshal(raw, &g); err != nil {
+		return ""
+	}
+
+	switch g.Type {
+	case "period":
+		return g.Period
+	case "duration":
+		return (time.Duration(g.Duration) * time.Millisecond).String()
+	default:
+		return g.Type
+	}
+}
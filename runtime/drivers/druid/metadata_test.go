@@ -0,0 +1,97 @@
+package druid
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGranularityString(t *testing.T) {
+	cases := map[string]string{
+		`"HOUR"`: "HOUR",
+		`{"type": "period", "period": "PT1H", "timeZone": "UTC", "origin": null}`: "PT1H",
+		`{"type": "duration", "duration": 60000}`:                                 "1m0s",
+		`{"type": "none"}`: "none",
+		`null`:             "",
+		``:                 "",
+	}
+	for raw, expected := range cases {
+		require.Equal(t, expected, granularityString(json.RawMessage(raw)), raw)
+	}
+}
+
+func TestParseSegmentTime(t *testing.T) {
+	ts, err := parseSegmentTime("2022-03-18T00:00:00.000Z")
+	require.NoError(t, err)
+	require.Equal(t, int64(1647561600), ts.Seconds)
+
+	_, err = parseSegmentTime("foo")
+	require.Error(t, err)
+}
+
+func TestAddSegmentMetadataAll(t *testing.T) {
+	var active, maxActive int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		var query struct{ DataSource string }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		if query.DataSource == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `[{"rollup": true, "queryGranularity": "HOUR"}]`)
+	}))
+	defer srv.Close()
+
+	var tables []*drivers.Table
+	metadata := make(map[string]*runtimev1.TableMetadata)
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("ds%d", i)
+		tables = append(tables, &drivers.Table{Name: name})
+		metadata[name] = &runtimev1.TableMetadata{MinTime: timestamppb.Now(), MaxTime: timestamppb.Now()}
+	}
+	tables = append(tables, &drivers.Table{Name: "broken"}, &drivers.Table{Name: "empty"})
+	metadata["broken"] = &runtimev1.TableMetadata{MinTime: timestamppb.Now(), MaxTime: timestamppb.Now()}
+
+	i := informationSchema{c: &connection{coordinatorURL: srv.URL}}
+	i.addSegmentMetadataAll(context.Background(), tables, metadata)
+
+	// Failures are set in the table's metadata instead of failing the other tables
+	for _, tbl := range tables[:10] {
+		require.NotNil(t, tbl.Metadata, tbl.Name)
+		require.True(t, tbl.Metadata.Rollup)
+		require.Equal(t, "HOUR", tbl.Metadata.QueryGranularity)
+		require.Empty(t, tbl.Metadata.Error)
+	}
+	require.NotNil(t, tables[10].Metadata)
+	require.NotNil(t, tables[10].Metadata.MinTime)
+	require.Contains(t, tables[10].Metadata.Error, "failed to query segment metadata")
+	require.Nil(t, tables[11].Metadata)
+
+	// Tables with an invalid interval are not queried
+	bad := &drivers.Table{Name: "bad"}
+	i.addSegmentMetadataAll(context.Background(), []*drivers.Table{bad}, map[string]*runtimev1.TableMetadata{"bad": {Error: "invalid segment time"}})
+	require.Equal(t, "invalid segment time", bad.Metadata.Error)
+	require.False(t, bad.Metadata.Rollup)
+
+	require.LessOrEqual(t, int(maxActive), maxSegmentMetadataRequests)
+}
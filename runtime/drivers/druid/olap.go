@@ -2,10 +2,11 @@ package druid
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
-	"github.com/rilldata/rill/runtime/connectors"
 	"github.com/rilldata/rill/runtime/drivers"
 )
 
@@ -13,8 +14,15 @@ func (c *connection) Dialect() drivers.Dialect {
 	return drivers.DialectDruid
 }
 
-func (c *connection) Ingest(ctx context.Context, env *connectors.Env, source *connectors.Source) error {
-	return drivers.ErrUnsupportedConnector
+// Capabilities implements drivers.OLAPStore.
+// Quantiles are computed with the druid-datasketches extension.
+func (c *connection) Capabilities() drivers.Capabilities {
+	return drivers.Capabilities{
+		QueryKinds:          []drivers.QueryKind{drivers.QueryKindMetricsView, drivers.QueryKindColumnProfile},
+		Connectors:          []string{"gcs", "https", "local_file", "s3"},
+		ApproxQuantile:      true,
+		ApproxCountDistinct: true,
+	}
 }
 
 func (c *connection) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
@@ -28,17 +36,61 @@ func (c *connection) Execute(ctx context.Context, stmt *drivers.Statement) (*dri
 		return nil, nil
 	}
 
+	// The timeout context must stay open until the result has been read, so it's cancelled when the result is closed
+	ctx, cancel := stmt.WithTimeout(ctx)
+
 	rows, err := c.db.QueryxContext(ctx, stmt.Query, stmt.Args...)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	schema, err := rowsToSchema(rows)
 	if err != nil {
+		rows.Close()
+		cancel()
 		return nil, err
 	}
 
-	return &drivers.Result{Rows: rows, Schema: schema}, nil
+	// Druid returns results in frames, so we enforce the max rows while reading the result
+	res := &drivers.Result{Rows: rows, Schema: schema}
+	res.SetMaxRows(stmt.MaxRows)
+	res.SetCleanupFunc(cancel)
+	return res, nil
+}
+
+// Explain implements drivers.OLAPStore using Druid's EXPLAIN PLAN FOR statement.
+// Druid doesn't support profiling queries, so it returns drivers.ErrExplainAnalyzeUnsupported if analyze is true.
+func (c *connection) Explain(ctx context.Context, stmt *drivers.Statement, analyze bool) (*drivers.QueryPlan, error) {
+	if analyze {
+		return nil, drivers.ErrExplainAnalyzeUnsupported
+	}
+
+	ctx, cancel := stmt.WithTimeout(ctx)
+	defer cancel()
+
+	rows, err := c.db.QueryxContext(ctx, "EXPLAIN PLAN FOR "+stmt.Query, stmt.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The first column contains the plan (other columns list the resources and attributes of the query)
+	var plans []string
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		if len(row) > 0 && row[0] != nil {
+			plans = append(plans, fmt.Sprint(row[0]))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &drivers.QueryPlan{Plan: strings.Join(plans, "\n")}, nil
 }
 
 func rowsToSchema(r *sqlx.Rows) (*runtimev1.StructType, error) {
@@ -107,6 +159,8 @@ func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
 		return nil, err
 	}
 
+	i.addMetadata(ctx, tables)
+
 	return tables, nil
 }
 
@@ -141,6 +195,8 @@ func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Ta
 		return nil, drivers.ErrNotFound
 	}
 
+	i.addMetadata(ctx, tables)
+
 	return tables[0], nil
 }
 
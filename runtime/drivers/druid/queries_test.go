@@ -0,0 +1,175 @@
+package druid
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/queries"
+	"github.com/rilldata/rill/runtime/testruntime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testMetricsView = "test_metrics"
+
+var testMetricsViewYAML = strings.TrimSpace(`
+model: test_data
+timeseries: __time
+timegrains:
+  - 1 day
+  - 1 month
+dimensions:
+  - property: publisher
+  - property: domain
+measures:
+  - name: count
+    expression: count(*)
+  - name: avg_bid_price
+    expression: avg(bid_price)
+`)
+
+// testQueries runs the metrics view and profiling queries against an instance that uses Druid as its OLAP driver
+func testQueries(t *testing.T, avaticaURL string) {
+	rt, instanceID := newDruidInstance(t, avaticaURL)
+
+	t.Run("toplist", func(t *testing.T) { testToplist(t, rt, instanceID) })
+	t.Run("totals", func(t *testing.T) { testTotals(t, rt, instanceID) })
+	t.Run("timeseries", func(t *testing.T) { testTimeseries(t, rt, instanceID) })
+	t.Run("dimension values", func(t *testing.T) { testDimensionValues(t, rt, instanceID) })
+	t.Run("profiling", func(t *testing.T) { testProfiling(t, rt, instanceID) })
+}
+
+func newDruidInstance(t *testing.T, avaticaURL string) (*runtime.Runtime, string) {
+	ctx := context.Background()
+	rt := testruntime.New(t)
+
+	inst := &drivers.Instance{
+		OLAPDriver: "druid",
+		OLAPDSN:    avaticaURL,
+		RepoDriver: "file",
+		RepoDSN:    t.TempDir(),
+	}
+	require.NoError(t, rt.CreateInstance(ctx, inst))
+
+	err := rt.PutFile(ctx, inst.ID, "dashboards/"+testMetricsView+".yaml", strings.NewReader(testMetricsViewYAML), true, false)
+	require.NoError(t, err)
+	res, err := rt.Reconcile(ctx, inst.ID, nil, nil, false, false)
+	require.NoError(t, err)
+	require.Empty(t, res.Errors)
+
+	return rt, inst.ID
+}
+
+func testToplist(t *testing.T, rt *runtime.Runtime, instanceID string) {
+	q := &queries.MetricsViewToplist{
+		MetricsViewName: testMetricsView,
+		DimensionName:   "domain",
+		MeasureNames:    []string{"count"},
+		Sort:            []*runtimev1.MetricsViewSort{{Name: "count"}},
+		Limit:           2,
+		Filter: &runtimev1.MetricsViewFilter{
+			Exclude: []*runtimev1.MetricsViewFilter_Cond{{Name: "domain", Like: []*structpb.Value{structpb.NewStringValue("%GOOGLE%")}}},
+		},
+	}
+	require.NoError(t, rt.Query(context.Background(), instanceID, q, 1))
+	require.Len(t, q.Result.Data, 2)
+	require.Equal(t, "msn.com", q.Result.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 3.0, q.Result.Data[0].Fields["count"].GetNumberValue())
+
+	// Not supported on Druid
+	q = &queries.MetricsViewToplist{
+		MetricsViewName: testMetricsView,
+		DimensionName:   "domain",
+		MeasureNames:    []string{"count"},
+		IncludeOther:    true,
+	}
+	require.Error(t, rt.Query(context.Background(), instanceID, q, 1))
+}
+
+func testTotals(t *testing.T, rt *runtime.Runtime, instanceID string) {
+	q := &queries.MetricsViewTotals{
+		MetricsViewName: testMetricsView,
+		MeasureNames:    []string{"count"},
+		TimeStart:       timestamppb.New(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)),
+		Filter: &runtimev1.MetricsViewFilter{
+			Include: []*runtimev1.MetricsViewFilter_Cond{{Name: "publisher", In: []*structpb.Value{structpb.NewStringValue("Yahoo"), structpb.NewNullValue()}}},
+		},
+	}
+	require.NoError(t, rt.Query(context.Background(), instanceID, q, 1))
+	require.Equal(t, 2.0, q.Result.Data.Fields["count"].GetNumberValue())
+}
+
+func testTimeseries(t *testing.T, rt *runtime.Runtime, instanceID string) {
+	q := &queries.MetricsViewTimeSeries{
+		MetricsViewName: testMetricsView,
+		MeasureNames:    []string{"count"},
+		TimeGranularity: "MONTH",
+	}
+	require.NoError(t, rt.Query(context.Background(), instanceID, q, 1))
+	require.Len(t, q.Result.Data, 2)
+	require.Equal(t, "2022-01-01T00:00:00Z", q.Result.Data[0].Fields["__time"].GetStringValue())
+	require.Equal(t, 4.0, q.Result.Data[0].Fields["count"].GetNumberValue())
+	require.Equal(t, 5.0, q.Result.Data[1].Fields["count"].GetNumberValue())
+
+	// Buckets are aligned to the time zone
+	q = &queries.MetricsViewTimeSeries{
+		MetricsViewName: testMetricsView,
+		MeasureNames:    []string{"count"},
+		TimeGranularity: "DAY",
+		TimeZone:        "America/Los_Angeles",
+		TimeStart:       timestamppb.New(time.Date(2022, 3, 22, 0, 0, 0, 0, time.UTC)),
+	}
+	require.NoError(t, rt.Query(context.Background(), instanceID, q, 1))
+	require.Len(t, q.Result.Data, 1)
+	require.Equal(t, "2022-03-21T00:00:00-07:00", q.Result.Data[0].Fields["__time"].GetStringValue())
+}
+
+func testDimensionValues(t *testing.T, rt *runtime.Runtime, instanceID string) {
+	q := &queries.MetricsViewDimensionValues{
+		MetricsViewName: testMetricsView,
+		DimensionName:   "domain",
+		Search:          "news",
+	}
+	require.NoError(t, rt.Query(context.Background(), instanceID, q, 1))
+	require.Len(t, q.Result.Entries, 2)
+	require.Equal(t, "news.yahoo.com", q.Result.Entries[0].Value.GetStringValue())
+	require.Equal(t, int64(2), q.Result.Entries[0].Count)
+}
+
+func testProfiling(t *testing.T, rt *runtime.Runtime, instanceID string) {
+	ctx := context.Background()
+
+	nullCount := &queries.ColumnNullCount{TableName: testTable, ColumnName: "publisher"}
+	require.NoError(t, rt.Query(ctx, instanceID, nullCount, 1))
+	require.Equal(t, 3.0, nullCount.Result)
+
+	topK := &queries.ColumnTopK{TableName: testTable, ColumnName: "domain", Agg: "count(*)", K: 2}
+	require.NoError(t, rt.Query(ctx, instanceID, topK, 1))
+	require.Len(t, topK.Result.Entries, 2)
+	require.Equal(t, "msn.com", topK.Result.Entries[0].Value.GetStringValue())
+	require.Equal(t, 3.0, topK.Result.Entries[0].Count)
+
+	cardinality := &queries.ColumnCardinality{TableName: testTable, ColumnName: "domain"}
+	require.NoError(t, rt.Query(ctx, instanceID, cardinality, 1))
+	require.Equal(t, 5.0, cardinality.Result)
+
+	histogram := &queries.ColumnNumericHistogram{TableName: testTable, ColumnName: "bid_price"}
+	require.NoError(t, rt.Query(ctx, instanceID, histogram, 1))
+	require.NotEmpty(t, histogram.Result)
+	var total float64
+	for _, bin := range histogram.Result {
+		total += bin.Count
+	}
+	require.Equal(t, 9.0, total)
+
+	timeRange := &queries.ColumnTimeRange{TableName: testTable, ColumnName: "__time"}
+	require.NoError(t, rt.Query(ctx, instanceID, timeRange, 1))
+	require.Equal(t, time.Date(2022, 1, 16, 0, 26, 44, 770000000, time.UTC), timeRange.Result.Min.AsTime())
+	require.Equal(t, int32(65), timeRange.Result.Interval.Days)
+}
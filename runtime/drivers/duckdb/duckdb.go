@@ -1,62 +1,209 @@
 package duckdb
 
 import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/marcboeker/go-duckdb"
 
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/pkg/priorityworker"
-
-	// Load duckdb driver
-	_ "github.com/marcboeker/go-duckdb"
 )
 
 func init() {
 	drivers.Register("duckdb", driver{})
 }
 
+// bootQueries are run on every new connection since DuckDB loads extensions on a per-connection basis
+var bootQueries = []string{
+	"INSTALL 'json'",
+	"LOAD 'json'",
+	"INSTALL 'parquet'",
+	"LOAD 'parquet'",
+	"INSTALL 'httpfs'",
+	"LOAD 'httpfs'",
+	"SET max_expression_depth TO 250",
+}
+
+// queueAgingInterval is the time after which a queued statement's priority is increased by one, so low priority statements don't starve
+const queueAgingInterval = time.Second
+
 type driver struct{}
 
+// Open implements drivers.Driver.
+// Besides DuckDB's own config options, the DSN accepts a "pool_size" option that sets the number of connections
+// queries are dispatched to (defaults to 1). All connections share one database instance, so with a pool_size greater
+// than 1, reads can run while another connection is ingesting data.
+//
+// It also accepts "attach" options with paths to DuckDB files (such as files produced by other tools) that are
+// attached read-only (see parseAttach). Their tables can be queried by name like the tables in the database itself.
 func (d driver) Open(dsn string) (drivers.Connection, error) {
-	db, err := sqlx.Open("duckdb", dsn)
+	dsn, poolSize, err := parsePoolSize(dsn)
 	if err != nil {
 		return nil, err
 	}
-
-	// database/sql has a built-in connection pool, but DuckDB loads extensions on a per-connection basis.
-	// So we allow only one open connection at a time. In the future, we may instead consider using db.Conn()
-	// and building our own connection pool to work around DuckDB's idiosyncracies.
-	db.SetMaxOpenConns(1)
-
-	bootQueries := []string{
-		"INSTALL 'json'",
-		"LOAD 'json'",
-		"INSTALL 'parquet'",
-		"LOAD 'parquet'",
-		"INSTALL 'httpfs'",
-		"LOAD 'httpfs'",
-		"SET max_expression_depth TO 250",
+	dsn, attachments, err := parseAttach(dsn)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, qry := range bootQueries {
-		_, err = db.Exec(qry)
-		if err != nil {
-			return nil, err
+	// Attached databases are shared by all connections, so they're attached when the first connection is opened.
+	// The search path is a connection setting, so it's set on every connection.
+	var attachMu sync.Mutex
+	attached := false
+
+	// The connector opens the database and runs the boot queries on every new connection
+	connector, err := duckdb.NewConnector(dsn, func(execer sqldriver.ExecerContext) error {
+		for _, qry := range bootQueries {
+			_, err := execer.ExecContext(context.Background(), qry, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(attachments) == 0 {
+			return nil
+		}
+
+		attachMu.Lock()
+		defer attachMu.Unlock()
+		if !attached {
+			for _, a := range attachments {
+				_, err := execer.ExecContext(context.Background(), fmt.Sprintf("ATTACH '%s' AS %s (READ_ONLY)", strings.ReplaceAll(a.path, "'", "''"), safeName(a.alias)), nil)
+				if err != nil {
+					return err
+				}
+			}
+			attached = true
 		}
+
+		_, err := execer.ExecContext(context.Background(), searchPath(attachments), nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// database/sql manages the pool connections (and closes the connector when the pool is closed)
+	db := sqlx.NewDb(sql.OpenDB(connector), "duckdb")
+	db.SetMaxOpenConns(poolSize)
+	db.SetMaxIdleConns(poolSize)
+
+	// Open the first connection eagerly to surface config errors
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	conn := &connection{
+		db:       db,
+		poolSize: poolSize,
+		running:  make(map[*job]bool),
 	}
+	// The worker only dispatches one job at a time (acquireConn waits for a free connection), so it doesn't need concurrency
+	conn.worker = priorityworker.NewWithOptions(conn.acquireConn, priorityworker.Options[*job]{
+		AgingInterval: queueAgingInterval,
+		FairKey:       func(j *job) string { return j.queueKey },
+	})
 
-	conn := &connection{db: db}
-	conn.worker = priorityworker.New(conn.executeQuery)
+	openConnsMu.Lock()
+	openConns[conn] = true
+	openConnsMu.Unlock()
 
 	return conn, nil
 }
 
+// parsePoolSize removes the "pool_size" option from a DuckDB DSN and returns it along with the DSN.
+func parsePoolSize(dsn string) (string, int, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", 0, fmt.Errorf("duckdb: could not parse dsn: %w", err)
+	}
+
+	qry := u.Query()
+	if !qry.Has("pool_size") {
+		return dsn, 1, nil
+	}
+
+	poolSize, err := strconv.Atoi(qry.Get("pool_size"))
+	if err != nil || poolSize < 1 {
+		return "", 0, fmt.Errorf("duckdb: invalid pool_size %q", qry.Get("pool_size"))
+	}
+
+	qry.Del("pool_size")
+	u.RawQuery = qry.Encode()
+	return u.String(), poolSize, nil
+}
+
+// attachment is a DuckDB file that's attached read-only to the database
+type attachment struct {
+	path  string
+	alias string
+}
+
+// parseAttach removes the "attach" options from a DuckDB DSN and returns them along with the DSN.
+// Each file is attached with its base name (without extension) as the catalog name, so "data/events.db" is attached as "events".
+func parseAttach(dsn string) (string, []attachment, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", nil, fmt.Errorf("duckdb: could not parse dsn: %w", err)
+	}
+
+	qry := u.Query()
+	if !qry.Has("attach") {
+		return dsn, nil, nil
+	}
+
+	var attachments []attachment
+	for _, path := range qry["attach"] {
+		alias := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if path == "" || alias == "" {
+			return "", nil, fmt.Errorf("duckdb: invalid attach %q", path)
+		}
+		attachments = append(attachments, attachment{path: path, alias: alias})
+	}
+
+	qry.Del("attach")
+	u.RawQuery = qry.Encode()
+	return u.String(), attachments, nil
+}
+
+// searchPath returns a statement that resolves unqualified names in the database's main schema first,
+// and then in the main schemas of the attached databases
+func searchPath(attachments []attachment) string {
+	path := []string{"main"}
+	for _, a := range attachments {
+		path = append(path, safeName(a.alias)+".main")
+	}
+	return fmt.Sprintf("SET search_path='%s'", strings.ReplaceAll(strings.Join(path, ","), "'", "''"))
+}
+
 type connection struct {
-	db     *sqlx.DB
+	db       *sqlx.DB
+	poolSize int
+	// worker dispatches jobs to free connections in priority order
 	worker *priorityworker.PriorityWorker[*job]
+	// running tracks the jobs that hold a connection
+	running   map[*job]bool
+	runningMu sync.Mutex
 }
 
 // Close implements drivers.Connection.
 func (c *connection) Close() error {
+	openConnsMu.Lock()
+	delete(openConns, c)
+	openConnsMu.Unlock()
+
 	c.worker.Stop()
 	return c.db.Close()
 }
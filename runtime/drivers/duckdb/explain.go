@@ -0,0 +1,130 @@
+package duckdb
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+// Explain implements drivers.OLAPStore.
+// It uses EXPLAIN for the plan and EXPLAIN ANALYZE with JSON profiling for the profile.
+func (c *connection) Explain(ctx context.Context, stmt *drivers.Statement, analyze bool) (*drivers.QueryPlan, error) {
+	ctx, cancel := stmt.WithTimeout(ctx)
+	defer cancel()
+
+	j := &job{
+		stmt:    stmt,
+		queryID: drivers.QueryIDFromContext(ctx),
+	}
+
+	// Profiling settings apply to the connection, so we hold on to it until they have been reset
+	conn, release, err := c.dispatch(ctx, j)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	plan := &drivers.QueryPlan{}
+	plan.Plan, err = explainValue(ctx, conn, "EXPLAIN "+stmt.Query, stmt.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if analyze {
+		_, err = conn.ExecContext(ctx, "PRAGMA enable_profiling='json'")
+		if err != nil {
+			return nil, err
+		}
+
+		plan.RawProfile, err = explainValue(ctx, conn, "EXPLAIN ANALYZE "+stmt.Query, stmt.Args)
+
+		// Restore the default profiling settings (even if ctx was cancelled)
+		for _, qry := range []string{"PRAGMA enable_profiling='query_tree'", "PRAGMA disable_profiling"} {
+			_, rerr := conn.ExecContext(context.Background(), qry)
+			if err == nil {
+				err = rerr
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Profile, err = parseProfile(plan.RawProfile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// explainValue runs an EXPLAIN statement and returns its explain_value column
+func explainValue(ctx context.Context, conn *sqlx.Conn, query string, args []any) (string, error) {
+	rows, err := conn.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(values, "\n"), nil
+}
+
+// profileNode is a node in DuckDB's JSON profiling output
+type profileNode struct {
+	Name        string         `json:"name"`
+	Timing      float64        `json:"timing"`
+	Cardinality int64          `json:"cardinality"`
+	ExtraInfo   string         `json:"extra_info"`
+	Children    []*profileNode `json:"children"`
+}
+
+// profileWrappers are operators that DuckDB adds around the profiled query
+var profileWrappers = map[string]bool{
+	"Query":            true,
+	"RESULT_COLLECTOR": true,
+	"EXPLAIN_ANALYZE":  true,
+}
+
+// parseProfile parses DuckDB's JSON profiling output and returns the root operator of the query
+func parseProfile(raw string) (*drivers.PlanNode, error) {
+	root := &profileNode{}
+	err := json.Unmarshal([]byte(raw), root)
+	if err != nil {
+		return nil, err
+	}
+
+	for profileWrappers[root.Name] && len(root.Children) == 1 {
+		root = root.Children[0]
+	}
+
+	return root.toPlanNode(), nil
+}
+
+func (n *profileNode) toPlanNode() *drivers.PlanNode {
+	res := &drivers.PlanNode{
+		Name:     n.Name,
+		Details:  strings.TrimSpace(n.ExtraInfo),
+		Duration: time.Duration(n.Timing * float64(time.Second)),
+		Rows:     n.Cardinality,
+	}
+	for _, c := range n.Children {
+		res.Children = append(res.Children, c.toPlanNode())
+	}
+	return res
+}
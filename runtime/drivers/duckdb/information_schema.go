@@ -3,6 +3,7 @@ package duckdb
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
@@ -18,10 +19,14 @@ func (c *connection) InformationSchema() drivers.InformationSchema {
 	return &informationSchema{c: c}
 }
 
+// All implements drivers.InformationSchema.
+// It lists the tables in the main schema of the database and of the attached databases (see parseAttach).
+// The database's own tables are listed first, and attached tables are skipped if an earlier table has the same name,
+// since unqualified names resolve to the earlier table.
 func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
 	q := `
 		select
-			coalesce(t.table_catalog, '') as "database",
+			coalesce(c.table_catalog, '') as "database",
 			t.table_schema as "schema",
 			t.table_name as "name",
 			t.table_type as "type", 
@@ -29,19 +34,24 @@ func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
 			array_agg(c.data_type order by c.ordinal_position) as "column_types",
 			array_agg(c.is_nullable = 'YES' order by c.ordinal_position) as "column_nullable"
 		from information_schema.tables t
-		join information_schema.columns c on t.table_schema = c.table_schema and t.table_name = c.table_name
-		where t.table_schema = 'main'
+		join information_schema.columns c on coalesce(t.table_catalog, c.table_catalog) = c.table_catalog and t.table_schema = c.table_schema and t.table_name = c.table_name
+		where c.table_catalog not in ('system', 'temp') and t.table_schema = 'main'
 		group by 1, 2, 3, 4
-		order by 1, 2, 3, 4
+		order by "database" != current_database(), 1, 2, 3, 4
 	`
 
+	types, err := i.userTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := i.c.db.QueryxContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	tables, err := i.scanTables(rows)
+	tables, err := i.scanTables(rows, types)
 	if err != nil {
 		return nil, err
 	}
@@ -52,7 +62,7 @@ func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
 func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Table, error) {
 	q := `
 		select
-			coalesce(t.table_catalog, '') as "database",
+			coalesce(c.table_catalog, '') as "database",
 			t.table_schema as "schema",
 			t.table_name as "name",
 			t.table_type as "type", 
@@ -60,19 +70,24 @@ func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Ta
 			array_agg(c.data_type order by c.ordinal_position) as "column_types",
 			array_agg(c.is_nullable = 'YES' order by c.ordinal_position) as "column_nullable"
 		from information_schema.tables t
-		join information_schema.columns c on t.table_schema = c.table_schema and t.table_name = c.table_name
-		where t.table_schema = 'main' and t.table_name = ?
+		join information_schema.columns c on coalesce(t.table_catalog, c.table_catalog) = c.table_catalog and t.table_schema = c.table_schema and t.table_name = c.table_name
+		where c.table_catalog not in ('system', 'temp') and t.table_schema = 'main' and t.table_name = ?
 		group by 1, 2, 3, 4
-		order by 1, 2, 3, 4
+		order by "database" != current_database(), 1, 2, 3, 4
 	`
 
+	types, err := i.userTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := i.c.db.QueryxContext(ctx, q, name)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	tables, err := i.scanTables(rows)
+	tables, err := i.scanTables(rows, types)
 	if err != nil {
 		return nil, err
 	}
@@ -84,8 +99,33 @@ func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Ta
 	return tables[0], nil
 }
 
-func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error) {
+// userTypes returns the user-defined types (created with CREATE TYPE), which information_schema reports by name
+func (i informationSchema) userTypes(ctx context.Context) (userTypes, error) {
+	rows, err := i.c.db.QueryxContext(ctx, "select type_name, logical_type from duckdb_types() where internal = false")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(userTypes)
+	for rows.Next() {
+		var name, logicalType string
+		if err := rows.Scan(&name, &logicalType); err != nil {
+			return nil, err
+		}
+		res[name] = logicalType
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (i informationSchema) scanTables(rows *sqlx.Rows, types userTypes) ([]*drivers.Table, error) {
 	var res []*drivers.Table
+	seen := make(map[string]bool)
 
 	for rows.Next() {
 		var database string
@@ -101,6 +141,12 @@ func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error)
 			return nil, err
 		}
 
+		// Skip tables that are shadowed by an earlier table with the same name
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
 		t := &drivers.Table{
 			Database:       database,
 			DatabaseSchema: schema,
@@ -116,7 +162,7 @@ func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error)
 		for idx, colName := range columnNames {
 			databaseType := columnTypes[idx].(string)
 			nullable := columnNullable[idx].(bool)
-			colType, err := databaseTypeToPB(databaseType, nullable)
+			colType, err := types.databaseTypeToPB(databaseType, nullable)
 			if err != nil {
 				return nil, err
 			}
@@ -138,6 +184,13 @@ func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error)
 }
 
 func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
+	return userTypes(nil).databaseTypeToPB(dbt, nullable)
+}
+
+// userTypes maps the names of user-defined types to their logical type (such as "ENUM" or "INTEGER")
+type userTypes map[string]string
+
+func (u userTypes) databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 	t := &runtimev1.Type{Nullable: nullable}
 	match := true
 	switch dbt {
@@ -172,7 +225,7 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 	case "TIME":
 		t.Code = runtimev1.Type_CODE_TIME
 	case "INTERVAL":
-		t.Code = runtimev1.Type_CODE_UNSPECIFIED // TODO - Consider adding interval type
+		t.Code = runtimev1.Type_CODE_INTERVAL
 	case "HUGEINT":
 		t.Code = runtimev1.Type_CODE_INT128
 	case "VARCHAR":
@@ -186,7 +239,7 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 	case "TIMESTAMP_NS":
 		t.Code = runtimev1.Type_CODE_TIMESTAMP
 	case "ENUM":
-		t.Code = runtimev1.Type_CODE_UNSPECIFIED // TODO - Consider how to handle enums
+		t.Code = runtimev1.Type_CODE_ENUM
 	case "UUID":
 		t.Code = runtimev1.Type_CODE_UUID
 	case "JSON":
@@ -199,6 +252,13 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 		t.Code = runtimev1.Type_CODE_TIME
 	case "NULL":
 		t.Code = runtimev1.Type_CODE_UNSPECIFIED
+	// User-defined nested types are reported without their children
+	case "STRUCT":
+		t.Code = runtimev1.Type_CODE_STRUCT
+	case "MAP":
+		t.Code = runtimev1.Type_CODE_MAP
+	case "LIST":
+		t.Code = runtimev1.Type_CODE_ARRAY
 	default:
 		match = false
 	}
@@ -207,11 +267,16 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 		return t, nil
 	}
 
+	// Handle user-defined types, such as enums, which information_schema reports by name
+	if logicalType, ok := u[dbt]; ok {
+		return u.databaseTypeToPB(logicalType, nullable)
+	}
+
 	// Handle complex types
 
 	// Handle arrays. They have the format "type[]"
 	if strings.HasSuffix(dbt, "[]") {
-		at, err := databaseTypeToPB(dbt[0:len(dbt)-2], true)
+		at, err := u.databaseTypeToPB(dbt[0:len(dbt)-2], true)
 		if err != nil {
 			return nil, err
 		}
@@ -231,6 +296,24 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 	// Example: "DECIMAL(10,20)"
 	case "DECIMAL":
 		t.Code = runtimev1.Type_CODE_DECIMAL
+
+		fieldStrs := splitCommasUnlessNestedInParens(args)
+		if len(fieldStrs) != 2 {
+			return nil, fmt.Errorf("encountered unsupported duckdb type '%s'", dbt)
+		}
+
+		precision, err := strconv.ParseInt(fieldStrs[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("encountered unsupported duckdb type '%s'", dbt)
+		}
+
+		scale, err := strconv.ParseInt(fieldStrs[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("encountered unsupported duckdb type '%s'", dbt)
+		}
+
+		t.DecimalPrecision = int32(precision)
+		t.DecimalScale = int32(scale)
 	// Example: "STRUCT(a INT, b INT)"
 	case "STRUCT":
 		t.Code = runtimev1.Type_CODE_STRUCT
@@ -245,7 +328,7 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 			}
 
 			// Convert to type
-			fieldType, err := databaseTypeToPB(fieldTypeStr, true)
+			fieldType, err := u.databaseTypeToPB(fieldTypeStr, true)
 			if err != nil {
 				return nil, err
 			}
@@ -263,12 +346,12 @@ func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
 			return nil, fmt.Errorf("encountered unsupported duckdb type '%s'", dbt)
 		}
 
-		keyType, err := databaseTypeToPB(fieldStrs[0], true)
+		keyType, err := u.databaseTypeToPB(fieldStrs[0], true)
 		if err != nil {
 			return nil, err
 		}
 
-		valType, err := databaseTypeToPB(fieldStrs[1], true)
+		valType, err := u.databaseTypeToPB(fieldStrs[1], true)
 		if err != nil {
 			return nil, err
 		}
@@ -2,6 +2,7 @@ package duckdb
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
@@ -57,6 +58,89 @@ func TestInformationSchemaLookup(t *testing.T) {
 	require.Equal(t, "model", table.Name)
 }
 
+func TestInformationSchemaUserTypes(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	ctx := context.Background()
+
+	for _, qry := range []string{
+		"CREATE TYPE mood AS ENUM ('happy', 'sad')",
+		"CREATE TYPE id AS BIGINT",
+		"CREATE TABLE moods (m mood, l mood[], i id)",
+	} {
+		rows, err := olap.Execute(ctx, &drivers.Statement{Query: qry})
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+
+	table, err := olap.InformationSchema().Lookup(ctx, "moods")
+	require.NoError(t, err)
+	require.Equal(t, runtimev1.Type_CODE_ENUM, table.Schema.Fields[0].Type.Code)
+	require.Equal(t, runtimev1.Type_CODE_ARRAY, table.Schema.Fields[1].Type.Code)
+	require.Equal(t, runtimev1.Type_CODE_ENUM, table.Schema.Fields[1].Type.ArrayElementType.Code)
+	require.Equal(t, runtimev1.Type_CODE_INT64, table.Schema.Fields[2].Type.Code)
+}
+
+func TestInformationSchemaAttach(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a DuckDB file like another tool would
+	path := filepath.Join(t.TempDir(), "external.db")
+	conn, err := driver{}.Open(path)
+	require.NoError(t, err)
+	olap, _ := conn.OLAPStore()
+	for _, qry := range []string{
+		"CREATE TABLE events AS SELECT range AS id FROM range(10)",
+		"CREATE TABLE foo AS SELECT 1 AS id",
+	} {
+		rows, err := olap.Execute(ctx, &drivers.Statement{Query: qry})
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+	require.NoError(t, conn.Close())
+
+	conn, err = driver{}.Open(filepath.Join(t.TempDir(), "stage.db") + "?pool_size=2&attach=" + path)
+	require.NoError(t, err)
+	defer conn.Close()
+	olap, _ = conn.OLAPStore()
+
+	rows, err := olap.Execute(ctx, &drivers.Statement{Query: "CREATE TABLE foo(bar VARCHAR)"})
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	// The attached table is listed, but the stage table shadows the attached table with the same name
+	tables, err := olap.InformationSchema().All(ctx)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	require.Equal(t, "stage", tables[0].Database)
+	require.Equal(t, "foo", tables[0].Name)
+	require.Equal(t, "bar", tables[0].Schema.Fields[0].Name)
+	require.Equal(t, "external", tables[1].Database)
+	require.Equal(t, "events", tables[1].Name)
+
+	table, err := olap.InformationSchema().Lookup(ctx, "events")
+	require.NoError(t, err)
+	require.Equal(t, "external", table.Database)
+
+	// Attached tables can be queried by name from every connection (the first result holds on to one of them), but not modified
+	var results []*drivers.Result
+	for i := 0; i < 2; i++ {
+		rows, err = olap.Execute(ctx, &drivers.Statement{Query: "SELECT count(*) FROM events"})
+		require.NoError(t, err)
+		var count int
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&count))
+		require.Equal(t, 10, count)
+		results = append(results, rows)
+	}
+	for _, res := range results {
+		require.NoError(t, res.Close())
+	}
+
+	_, err = olap.Execute(ctx, &drivers.Statement{Query: "INSERT INTO events VALUES (10)"})
+	require.Error(t, err)
+}
+
 func TestDatabaseTypeToPB(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -64,7 +148,15 @@ func TestDatabaseTypeToPB(t *testing.T) {
 	}{
 		{
 			input:  "DECIMAL(10,20)",
-			output: &runtimev1.Type{Code: runtimev1.Type_CODE_DECIMAL, Nullable: true},
+			output: &runtimev1.Type{Code: runtimev1.Type_CODE_DECIMAL, Nullable: true, DecimalPrecision: 10, DecimalScale: 20},
+		},
+		{
+			input:  "INTERVAL",
+			output: &runtimev1.Type{Code: runtimev1.Type_CODE_INTERVAL, Nullable: true},
+		},
+		{
+			input:  "ENUM[]",
+			output: &runtimev1.Type{Code: runtimev1.Type_CODE_ARRAY, Nullable: true, ArrayElementType: &runtimev1.Type{Code: runtimev1.Type_CODE_ENUM, Nullable: true}},
 		},
 		{
 			input: "STRUCT(foo HUGEINT, bar STRUCT(a INTEGER, b MAP(INTEGER, BOOLEAN)), baz VARCHAR[])",
@@ -0,0 +1,67 @@
+package duckdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// openConns tracks the open connections, whose queues are reported in the queue metrics
+	openConns   = make(map[*connection]bool)
+	openConnsMu sync.Mutex
+
+	queueWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "rill_runtime_duckdb_queue_wait_seconds",
+		Help:    "Time statements waited in the queue for a DuckDB connection",
+		Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "rill_runtime_duckdb_queued_statements",
+		Help: "Number of statements waiting for a DuckDB connection",
+	}, func() float64 {
+		queued, _, _ := queueStats()
+		return float64(queued)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "rill_runtime_duckdb_executing_statements",
+		Help: "Number of statements holding a DuckDB connection",
+	}, func() float64 {
+		_, executing, _ := queueStats()
+		return float64(executing)
+	})
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "rill_runtime_duckdb_queue_oldest_wait_seconds",
+		Help: "How long the longest waiting statement has waited for a DuckDB connection so far",
+	}, func() float64 {
+		_, _, oldest := queueStats()
+		return oldest.Seconds()
+	})
+)
+
+// queueStats returns the number of queued and executing statements and the longest wait of the queued statements across all open connections
+func queueStats() (queued, executing int, oldestWait time.Duration) {
+	openConnsMu.Lock()
+	conns := make([]*connection, 0, len(openConns))
+	for c := range openConns {
+		conns = append(conns, c)
+	}
+	openConnsMu.Unlock()
+
+	now := time.Now()
+	for _, c := range conns {
+		for _, stmt := range c.QueuedStatements() {
+			if stmt.Position == 0 {
+				executing++
+				continue
+			}
+			queued++
+			if wait := now.Sub(stmt.EnqueuedOn); wait > oldestWait {
+				oldestWait = wait
+			}
+		}
+	}
+	return queued, executing, oldestWait
+}
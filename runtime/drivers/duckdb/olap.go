@@ -3,57 +3,417 @@ package duckdb
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/jmoiron/sqlx"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/connectors"
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/pkg/priorityworker"
 )
 
 type job struct {
-	stmt   *drivers.Statement
-	result *sqlx.Rows
+	stmt    *drivers.Statement
+	queryID string
+	// queueKey is the fair queuing key set with drivers.WithQueueKey
+	queueKey string
+	// enqueuedOn is when the job was submitted to the worker
+	enqueuedOn time.Time
+
+	// conn is the connection acquired for the job by acquireConn.
+	// If the caller stops waiting before the connection is handed off, the job is abandoned and the connection is released.
+	conn      *sqlx.Conn
+	abandoned bool
+	mu        sync.Mutex
 }
 
 func (c *connection) Dialect() drivers.Dialect {
 	return drivers.DialectDuckDB
 }
 
+// Capabilities implements drivers.OLAPStore.
+// DuckDB supports all queries, and it ingests sources from all connectors by downloading them to a local file.
+func (c *connection) Capabilities() drivers.Capabilities {
+	names := make([]string, 0, len(connectors.Connectors))
+	for name := range connectors.Connectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return drivers.Capabilities{
+		QueryKinds: []drivers.QueryKind{
+			drivers.QueryKindMetricsView,
+			drivers.QueryKindMetricsViewToplistOther,
+			drivers.QueryKindMetricsViewSQL,
+			drivers.QueryKindColumnProfile,
+			drivers.QueryKindDescriptiveStatistics,
+			drivers.QueryKindRugHistogram,
+			drivers.QueryKindTimeGrain,
+			drivers.QueryKindColumnTimeseries,
+			drivers.QueryKindTableColumns,
+		},
+		Connectors:          names,
+		ApproxQuantile:      true,
+		ApproxCountDistinct: true,
+		Sampling:            true,
+		MaxConcurrency:      c.poolSize,
+	}
+}
+
+// Execute implements drivers.OLAPStore.
+// DECIMAL values are returned as strings (see castTypesQuery), since the DuckDB driver scans them as float64, which loses precision.
 func (c *connection) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
+	// The timeout context must stay open until the result has been read, so it's cancelled when the result is closed
+	ctx, cancel := stmt.WithTimeout(ctx)
+
 	j := &job{
-		stmt: stmt,
+		stmt:     stmt,
+		queryID:  drivers.QueryIDFromContext(ctx),
+		queueKey: drivers.QueueKeyFromContext(ctx),
 	}
 
-	err := c.worker.Process(ctx, stmt.Priority, j)
+	conn, release, err := c.dispatch(ctx, j)
 	if err != nil {
-		if errors.Is(err, priorityworker.ErrStopped) {
-			return nil, drivers.ErrClosed
-		}
+		cancel()
 		return nil, err
 	}
 
-	schema, err := rowsToSchema(j.result)
+	if stmt.DryRun {
+		defer release()
+		defer cancel()
+		// TODO: Find way to validate with args
+		prepared, err := conn.PrepareContext(ctx, stmt.Query)
+		if err != nil {
+			return nil, err
+		}
+		prepared.Close()
+		return &drivers.Result{}, nil
+	}
+
+	query := stmt.Query
+	if stmt.MaxRows > 0 {
+		query = limitQuery(query, stmt.MaxRows)
+	}
+
+	// The DuckDB driver can't scan ENUM values and loses precision for DECIMAL values, so we cast them to VARCHAR (keeping the original schema)
+	castQuery, castSchema, hasCasts := castTypesQuery(ctx, conn, query, stmt.Args)
+	if hasCasts {
+		query = castQuery
+	}
+
+	rows, err := conn.QueryxContext(ctx, query, stmt.Args...)
+	if err == nil && ctx.Err() != nil {
+		// The DuckDB driver doesn't support cancellation, so the query may complete after ctx is done
+		rows.Close()
+		err = ctx.Err()
+	}
 	if err != nil {
+		release()
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ctx.Err()
+		}
 		return nil, err
 	}
 
-	return &drivers.Result{Rows: j.result, Schema: schema}, nil
+	schema := castSchema
+	if !hasCasts {
+		schema, err = rowsToSchema(rows)
+		if err != nil {
+			rows.Close()
+			release()
+			cancel()
+			return nil, err
+		}
+	}
+
+	// The connection is released when the result is closed
+	res := &drivers.Result{Rows: rows, Schema: schema}
+	res.SetMaxRows(stmt.MaxRows)
+	res.SetCleanupFunc(func() {
+		release()
+		cancel()
+	})
+	return res, nil
 }
 
-func (c *connection) executeQuery(ctx context.Context, j *job) error {
-	if j.stmt.DryRun {
-		// TODO: Find way to validate with args
-		prepared, err := c.db.PrepareContext(ctx, j.stmt.Query)
-		if err != nil {
-			return err
+// dispatch waits for the job's turn in the priority queue and for a free connection.
+// The returned func must be called to release the connection.
+func (c *connection) dispatch(ctx context.Context, j *job) (*sqlx.Conn, func(), error) {
+	j.enqueuedOn = time.Now()
+	err := c.worker.Process(ctx, j.stmt.Priority, j)
+
+	j.mu.Lock()
+	if err != nil {
+		// The worker may still hand off a connection after Process returns early, in which case acquireConn releases it
+		j.abandoned = true
+		if j.conn != nil {
+			c.releaseConn(j)
 		}
-		prepared.Close()
+	}
+	j.mu.Unlock()
+
+	if err != nil {
+		if errors.Is(err, priorityworker.ErrStopped) {
+			return nil, nil, drivers.ErrClosed
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, nil, ctx.Err()
+		}
+		return nil, nil, err
+	}
+	queueWait.Observe(time.Since(j.enqueuedOn).Seconds())
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			j.mu.Lock()
+			c.releaseConn(j)
+			j.mu.Unlock()
+		})
+	}
+
+	return j.conn, release, nil
+}
+
+// acquireConn is the priority worker's handler. It hands off a free connection to the job, waiting for one if
+// they're all in use. Since the worker handles one job at a time, free connections go to jobs in priority order.
+func (c *connection) acquireConn(ctx context.Context, j *job) error {
+	conn, err := c.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.conn = conn
+	if j.abandoned {
+		c.releaseConn(j)
 		return nil
 	}
 
-	rows, err := c.db.QueryxContext(ctx, j.stmt.Query, j.stmt.Args...)
-	j.result = rows
-	return err
+	c.runningMu.Lock()
+	c.running[j] = true
+	c.runningMu.Unlock()
+
+	return nil
+}
+
+// releaseConn returns the job's connection to the pool. It must be called while holding j.mu.
+func (c *connection) releaseConn(j *job) {
+	c.runningMu.Lock()
+	delete(c.running, j)
+	c.runningMu.Unlock()
+
+	_ = j.conn.Close()
+	j.conn = nil
+}
+
+// QueuedStatements implements drivers.StatementQueue.
+// Statements holding a connection are reported as executing, followed by the statements waiting for a connection.
+func (c *connection) QueuedStatements() []*drivers.QueuedStatement {
+	c.runningMu.Lock()
+	running := make([]*job, 0, len(c.running))
+	for j := range c.running {
+		running = append(running, j)
+	}
+	c.runningMu.Unlock()
+	sort.Slice(running, func(i, k int) bool {
+		return running[i].enqueuedOn.Before(running[k].enqueuedOn)
+	})
+
+	jobs := c.worker.Jobs()
+	res := make([]*drivers.QueuedStatement, 0, len(running)+len(jobs))
+	for _, j := range running {
+		res = append(res, &drivers.QueuedStatement{
+			QueryID:    j.queryID,
+			Query:      j.stmt.Logged(),
+			Priority:   j.stmt.Priority,
+			EnqueuedOn: j.enqueuedOn,
+		})
+	}
+
+	pos := 1
+	for _, j := range jobs {
+		// The job that's waiting for a connection may have just received one
+		if containsJob(running, j.Value) {
+			continue
+		}
+		res = append(res, &drivers.QueuedStatement{
+			QueryID:    j.Value.queryID,
+			Query:      j.Value.stmt.Logged(),
+			Priority:   j.Priority,
+			Position:   pos,
+			EnqueuedOn: j.EnqueuedOn,
+		})
+		pos++
+	}
+
+	return res
+}
+
+func containsJob(jobs []*job, j *job) bool {
+	for _, x := range jobs {
+		if x == j {
+			return true
+		}
+	}
+	return false
+}
+
+// limitQuery limits a SELECT query to at most n+1 rows (the extra row is used to detect truncation).
+// DuckDB materializes the full result of a query in memory, so we can't rely on only reading the first n rows.
+//
+// A subquery's ORDER BY doesn't determine the order of the outer query, so if the query is ordered, the LIMIT is
+// added to the query itself (where it applies after the ORDER BY). If the query is ordered and already has a LIMIT,
+// it's returned unchanged (its own LIMIT bounds the result, and extra rows are skipped when reading the result).
+// Other statements are also returned unchanged.
+func limitQuery(sql string, n int64) string {
+	trimmed, ok := trimSelectQuery(sql)
+	if !ok {
+		return sql
+	}
+
+	words := topLevelWords(trimmed)
+	orderBy := -1
+	for i := 1; i < len(words); i++ {
+		if words[i-1] == "ORDER" && words[i] == "BY" {
+			orderBy = i
+		}
+	}
+
+	// The newlines guard against a trailing comment
+	if orderBy < 0 {
+		return fmt.Sprintf("SELECT * FROM (%s\n) LIMIT %d", trimmed, n+1)
+	}
+	for _, w := range words[orderBy:] {
+		if w == "LIMIT" || w == "OFFSET" || w == "FETCH" {
+			return sql
+		}
+	}
+	return fmt.Sprintf("%s\nLIMIT %d", trimmed, n+1)
+}
+
+// topLevelWords returns the upper-cased words of a query that are not nested in parentheses.
+// It skips string literals, quoted identifiers and comments.
+func topLevelWords(sql string) []string {
+	var words []string
+	depth := 0
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them, which this handles as two consecutive literals
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				return words
+			}
+			i += end + 2
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return words
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += end + 4
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			i++
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(sql) && (sql[i] == '_' || sql[i] == '$' || unicode.IsLetter(rune(sql[i])) || unicode.IsDigit(rune(sql[i]))) {
+				i++
+			}
+			if depth == 0 {
+				words = append(words, strings.ToUpper(sql[start:i]))
+			}
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+// castTypesQuery wraps a SELECT query so that its ENUM and DECIMAL columns (including ENUMs and DECIMALs nested in lists,
+// structs and maps) are cast to VARCHAR, and returns it along with the schema of the original query. It returns false if the
+// result has no such columns or if the query can't be wrapped.
+//
+// The column types are found with a LIMIT 0 probe of the query, which DuckDB optimizes to an empty result without executing the query.
+// If the probe fails, castTypesQuery returns false, so the error is reported by the original query.
+func castTypesQuery(ctx context.Context, conn *sqlx.Conn, sql string, args []any) (string, *runtimev1.StructType, bool) {
+	trimmed, ok := trimSelectQuery(sql)
+	if !ok {
+		return "", nil, false
+	}
+
+	probe, err := conn.QueryxContext(ctx, fmt.Sprintf("SELECT * FROM (%s\n) LIMIT 0", trimmed), args...)
+	if err != nil {
+		return "", nil, false
+	}
+	defer probe.Close()
+
+	cts, err := probe.ColumnTypes()
+	if err != nil {
+		return "", nil, false
+	}
+
+	var replacements []string
+	for _, ct := range cts {
+		dbt := ct.DatabaseTypeName()
+		if !castRegexp.MatchString(dbt) {
+			continue
+		}
+		col := safeName(ct.Name())
+		replacements = append(replacements, fmt.Sprintf("CAST(%s AS %s) AS %s", col, castRegexp.ReplaceAllString(dbt, "VARCHAR"), col))
+	}
+	if len(replacements) == 0 {
+		return "", nil, false
+	}
+
+	schema, err := rowsToSchema(probe)
+	if err != nil {
+		return "", nil, false
+	}
+
+	return fmt.Sprintf("SELECT * REPLACE (%s) FROM (%s\n)", strings.Join(replacements, ", "), trimmed), schema, true
+}
+
+// castRegexp matches ENUM and DECIMAL in a DuckDB type name such as "STRUCT(a ENUM, b DECIMAL(10,2))[]"
+var castRegexp = regexp.MustCompile(`\bENUM\b|\bDECIMAL\(\d+,\d+\)`)
+
+// trimSelectQuery trims whitespace and trailing semicolons from a query and returns false if it isn't a SELECT query
+// (which can be wrapped in a subquery).
+func trimSelectQuery(sql string) (string, bool) {
+	trimmed := strings.TrimRight(strings.TrimSpace(sql), "; \t\n")
+	word := trimmed
+	if i := strings.IndexFunc(trimmed, unicode.IsSpace); i >= 0 {
+		word = trimmed[:i]
+	}
+	switch strings.ToUpper(word) {
+	case "SELECT", "WITH", "VALUES":
+		return trimmed, true
+	default:
+		return "", false
+	}
+}
+
+func safeName(name string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(name, `"`, `""`))
 }
 
 func rowsToSchema(r *sqlx.Rows) (*runtimev1.StructType, error) {
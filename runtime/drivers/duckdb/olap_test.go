@@ -2,9 +2,11 @@ package duckdb
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +32,131 @@ func TestQuery(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestQueryMaxRows(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	rows, err := olap.Execute(context.Background(), &drivers.Statement{
+		Query:   "SELECT baz FROM foo ORDER BY baz DESC;",
+		MaxRows: 2,
+	})
+	require.NoError(t, err)
+	var vals []int
+	for rows.Next() {
+		var val int
+		require.NoError(t, rows.Scan(&val))
+		vals = append(vals, val)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []int{4, 3}, vals)
+	require.True(t, rows.Truncated())
+	require.NoError(t, rows.Close())
+
+	rows, err = olap.Execute(context.Background(), &drivers.Statement{
+		Query:   "SELECT baz FROM foo",
+		MaxRows: 4,
+	})
+	require.NoError(t, err)
+	n := 0
+	for rows.Next() {
+		n++
+	}
+	require.Equal(t, 4, n)
+	require.False(t, rows.Truncated())
+	require.NoError(t, rows.Close())
+}
+
+func TestQueryTimeout(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	_, err := olap.Execute(context.Background(), &drivers.Statement{
+		Query:            "SELECT count(*) FROM range(100000000) a CROSS JOIN range(10) b",
+		ExecutionTimeout: time.Millisecond,
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestQueryEnums(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	for _, qry := range []string{
+		"CREATE TYPE mood AS ENUM ('happy', 'sad')",
+		"CREATE TABLE moods (m mood, l mood[], s STRUCT(a mood, b INTEGER))",
+		"INSERT INTO moods VALUES ('sad', ['happy', 'sad'], {'a': 'happy', 'b': 1})",
+	} {
+		rows, err := olap.Execute(context.Background(), &drivers.Statement{Query: qry})
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+
+	rows, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT * FROM moods", MaxRows: 10})
+	require.NoError(t, err)
+	require.Equal(t, runtimev1.Type_CODE_ENUM, rows.Schema.Fields[0].Type.Code)
+	require.Equal(t, runtimev1.Type_CODE_ENUM, rows.Schema.Fields[1].Type.ArrayElementType.Code)
+	require.Equal(t, runtimev1.Type_CODE_ENUM, rows.Schema.Fields[2].Type.StructType.Fields[0].Type.Code)
+
+	require.True(t, rows.Next())
+	var m string
+	var l []any
+	var s map[string]any
+	require.NoError(t, rows.Scan(&m, &l, &s))
+	require.Equal(t, "sad", m)
+	require.Equal(t, []any{"happy", "sad"}, l)
+	require.Equal(t, map[string]any{"a": "happy", "b": int32(1)}, s)
+	require.NoError(t, rows.Close())
+
+	// The query is only executed once
+	rows, err = olap.Execute(context.Background(), &drivers.Statement{Query: "CREATE SEQUENCE seq"})
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	for i := 1; i <= 2; i++ {
+		rows, err = olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT nextval('seq') AS n, m FROM moods"})
+		require.NoError(t, err)
+		require.True(t, rows.Next())
+		var n int
+		require.NoError(t, rows.Scan(&n, &m))
+		require.Equal(t, i, n)
+		require.NoError(t, rows.Close())
+	}
+}
+
+func TestQueryDecimals(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	// DECIMAL values are returned as strings, so they don't lose precision
+	rows, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT '1234567890123456.78'::DECIMAL(18,2) AS d, [1.5::DECIMAL(4,1)] AS l"})
+	require.NoError(t, err)
+	require.Equal(t, runtimev1.Type_CODE_DECIMAL, rows.Schema.Fields[0].Type.Code)
+	require.Equal(t, int32(18), rows.Schema.Fields[0].Type.DecimalPrecision)
+	require.Equal(t, runtimev1.Type_CODE_DECIMAL, rows.Schema.Fields[1].Type.ArrayElementType.Code)
+	require.True(t, rows.Next())
+	var d any
+	var l []any
+	require.NoError(t, rows.Scan(&d, &l))
+	require.Equal(t, "1234567890123456.78", d)
+	require.Equal(t, []any{"1.5"}, l)
+	require.NoError(t, rows.Close())
+}
+
+func TestLimitQuery(t *testing.T) {
+	require.Equal(t, "SELECT * FROM (SELECT * FROM foo\n) LIMIT 11", limitQuery("SELECT * FROM foo;", 10))
+	require.Equal(t, "SELECT * FROM (with t as (select 1) select * from t -- comment\n) LIMIT 2", limitQuery(" with t as (select 1) select * from t -- comment\n", 1))
+	require.Equal(t, "CREATE TABLE t AS SELECT 1", limitQuery("CREATE TABLE t AS SELECT 1", 10))
+
+	// Ordered queries are limited at the top level, so the order applies to the limited result
+	require.Equal(t, "SELECT * FROM foo ORDER BY a DESC\nLIMIT 11", limitQuery("SELECT * FROM foo ORDER BY a DESC;", 10))
+	require.Equal(t, "SELECT * FROM (SELECT a, row_number() OVER (ORDER BY a) FROM foo\n) LIMIT 11", limitQuery("SELECT a, row_number() OVER (ORDER BY a) FROM foo", 10))
+	require.Equal(t, "SELECT * FROM foo ORDER BY a LIMIT 5", limitQuery("SELECT * FROM foo ORDER BY a LIMIT 5", 10))
+	require.Equal(t, "SELECT * FROM (SELECT 'order by' AS \"order\" FROM foo /* ORDER BY */\n) LIMIT 11", limitQuery("SELECT 'order by' AS \"order\" FROM foo /* ORDER BY */", 10))
+}
+
 func TestPriorityQueue(t *testing.T) {
 	if testing.Short() {
 		t.Skip("duckdb: skipping test in short mode")
@@ -80,6 +207,48 @@ func TestPriorityQueue(t *testing.T) {
 	}
 }
 
+func TestQueueKeys(t *testing.T) {
+	conn := prepareConn(t)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	conn.(*connection).worker.Pause()
+
+	var g errgroup.Group
+	execute := func(key, query string) {
+		n := len(conn.(*connection).QueuedStatements())
+		g.Go(func() error {
+			ctx := drivers.WithQueueKey(context.Background(), key)
+			res, err := olap.Execute(ctx, &drivers.Statement{Query: query})
+			if err != nil {
+				return err
+			}
+			return res.Close()
+		})
+		require.Eventually(t, func() bool {
+			return len(conn.(*connection).QueuedStatements()) == n+1
+		}, 5*time.Second, time.Millisecond)
+	}
+	execute("a", "SELECT 'a1'")
+	execute("a", "SELECT 'a2'")
+	execute("a", "SELECT 'a3'")
+	execute("b", "SELECT 'b1'")
+
+	// The statement of key b is interleaved with the statements of key a
+	var queries []string
+	for _, stmt := range conn.(*connection).QueuedStatements() {
+		queries = append(queries, stmt.Query)
+	}
+	require.Equal(t, []string{"SELECT 'a1'", "SELECT 'b1'", "SELECT 'a2'", "SELECT 'a3'"}, queries)
+
+	queued, _, oldestWait := queueStats()
+	require.GreaterOrEqual(t, queued, 4)
+	require.Greater(t, oldestWait, time.Duration(0))
+
+	conn.(*connection).worker.Unpause()
+	require.NoError(t, g.Wait())
+}
+
 func TestCancel(t *testing.T) {
 	if testing.Short() {
 		t.Skip("duckdb: skipping test in short mode")
@@ -185,7 +354,10 @@ func TestClose(t *testing.T) {
 		})
 	}
 
-	// unpause the queue, so it con process a bit before closing
+	// wait for the queue to fill up, then unpause it, so it can process a bit before closing
+	require.Eventually(t, func() bool {
+		return len(conn.(*connection).worker.Jobs()) == n
+	}, 5*time.Second, time.Millisecond)
 	conn.(*connection).worker.Unpause()
 
 	g.Go(func() error {
@@ -201,6 +373,110 @@ func TestClose(t *testing.T) {
 	require.Greater(t, x, 0)
 }
 
+func TestPoolSize(t *testing.T) {
+	dsn, n, err := parsePoolSize("stage.db?access_mode=read_only&pool_size=4")
+	require.NoError(t, err)
+	require.Equal(t, "stage.db?access_mode=read_only", dsn)
+	require.Equal(t, 4, n)
+
+	dsn, n, err = parsePoolSize("?access_mode=read_write")
+	require.NoError(t, err)
+	require.Equal(t, "?access_mode=read_write", dsn)
+	require.Equal(t, 1, n)
+
+	dsn, n, err = parsePoolSize("stage.db?pool_size=4")
+	require.NoError(t, err)
+	require.Equal(t, "stage.db", dsn)
+	require.Equal(t, 4, n)
+
+	_, _, err = parsePoolSize("stage.db?access_mode=read_only&pool_size=0")
+	require.Error(t, err)
+}
+
+func TestParseAttach(t *testing.T) {
+	dsn, attachments, err := parseAttach("stage.db?attach=data/events.db&attach=/tmp/users.duckdb&access_mode=read_write")
+	require.NoError(t, err)
+	require.Equal(t, "stage.db?access_mode=read_write", dsn)
+	require.Equal(t, []attachment{{path: "data/events.db", alias: "events"}, {path: "/tmp/users.duckdb", alias: "users"}}, attachments)
+	require.Equal(t, `SET search_path='main,"events".main,"users".main'`, searchPath(attachments))
+
+	dsn, attachments, err = parseAttach("stage.db")
+	require.NoError(t, err)
+	require.Equal(t, "stage.db", dsn)
+	require.Empty(t, attachments)
+
+	_, _, err = parseAttach("stage.db?attach=")
+	require.Error(t, err)
+}
+
+func TestPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pool.db")
+	conn, err := driver{}.Open(path)
+	require.NoError(t, err)
+	olap, _ := conn.OLAPStore()
+	res, err := olap.Execute(context.Background(), &drivers.Statement{Query: "CREATE TABLE foo AS SELECT * FROM range(10)"})
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+	require.NoError(t, conn.Close())
+
+	conn, err = driver{}.Open(path + "?access_mode=read_only&pool_size=2")
+	require.NoError(t, err)
+	olap, _ = conn.OLAPStore()
+	defer conn.Close()
+
+	// Hold on to two connections, so the next statement has to wait for one of them
+	res1, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT COUNT(*) FROM foo", Priority: 1})
+	require.NoError(t, err)
+	res2, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT COUNT(*) FROM foo", Priority: 1})
+	require.NoError(t, err)
+	require.Len(t, conn.(*connection).QueuedStatements(), 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = olap.Execute(ctx, &drivers.Statement{Query: "SELECT 1"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Releasing a connection lets the next statement run
+	require.NoError(t, res1.Close())
+	res3, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT COUNT(*) FROM foo"})
+	require.NoError(t, err)
+	var count int
+	require.True(t, res3.Next())
+	require.NoError(t, res3.Scan(&count))
+	require.Equal(t, 10, count)
+	require.NoError(t, res3.Close())
+	require.NoError(t, res2.Close())
+	require.Len(t, conn.(*connection).QueuedStatements(), 0)
+}
+
+func TestPoolWritable(t *testing.T) {
+	conn, err := driver{}.Open("?access_mode=read_write&pool_size=2")
+	require.NoError(t, err)
+	olap, _ := conn.OLAPStore()
+	defer conn.Close()
+
+	res, err := olap.Execute(context.Background(), &drivers.Statement{Query: "CREATE TABLE foo AS SELECT * FROM range(10)"})
+	require.NoError(t, err)
+	require.NoError(t, res.Close())
+
+	// While one connection is busy, the other connection writes to and reads from the same database
+	res1, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT COUNT(*) FROM foo"})
+	require.NoError(t, err)
+
+	res2, err := olap.Execute(context.Background(), &drivers.Statement{Query: "INSERT INTO foo SELECT * FROM range(5)"})
+	require.NoError(t, err)
+	require.NoError(t, res2.Close())
+
+	res3, err := olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT COUNT(*) FROM foo"})
+	require.NoError(t, err)
+	var count int
+	require.True(t, res3.Next())
+	require.NoError(t, res3.Scan(&count))
+	require.Equal(t, 15, count)
+	require.NoError(t, res3.Close())
+	require.NoError(t, res1.Close())
+}
+
 func prepareConn(t *testing.T) drivers.Connection {
 	conn, err := driver{}.Open("?access_mode=read_write")
 	require.NoError(t, err)
@@ -3,6 +3,7 @@ package drivers
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
@@ -12,12 +13,88 @@ import (
 // ErrUnsupportedConnector is returned from Ingest for unsupported connectors.
 var ErrUnsupportedConnector = errors.New("drivers: connector not supported")
 
+// ErrUnsupportedQuery is returned for queries that the OLAP store's capabilities don't include.
+var ErrUnsupportedQuery = errors.New("drivers: query not supported")
+
 // OLAPStore is implemented by drivers that are capable of storing, transforming and serving analytical queries.
 type OLAPStore interface {
 	Dialect() Dialect
+	// Capabilities describes the queries and features the store supports
+	Capabilities() Capabilities
 	Execute(ctx context.Context, stmt *Statement) (*Result, error)
 	Ingest(ctx context.Context, env *connectors.Env, source *connectors.Source) error
 	InformationSchema() InformationSchema
+	// Explain returns the engine's plan for a statement. If analyze is true, it also executes the statement
+	// and returns a profile of the execution. Drivers that can't profile queries return ErrExplainAnalyzeUnsupported.
+	Explain(ctx context.Context, stmt *Statement, analyze bool) (*QueryPlan, error)
+}
+
+// ErrExplainAnalyzeUnsupported is returned from Explain by drivers that can't profile queries.
+var ErrExplainAnalyzeUnsupported = errors.New("drivers: explain analyze not supported")
+
+// StatementQueue is implemented by OLAP stores that queue statements before executing them (such as DuckDB).
+type StatementQueue interface {
+	// QueuedStatements returns the executing statements followed by the queued statements in the order they will be executed
+	QueuedStatements() []*QueuedStatement
+}
+
+// QueuedStatement describes a statement that is executing or waiting to be executed.
+type QueuedStatement struct {
+	// QueryID is the ID set with WithQueryID on the context passed to Execute (if any)
+	QueryID  string
+	Query    string
+	Priority int
+	// Position is the statement's position in the queue, starting at 1 (0 means the statement is executing)
+	Position   int
+	EnqueuedOn time.Time
+}
+
+type queryIDCtxKey struct{}
+
+// WithQueryID returns a context that associates the statements executed with it with a query ID.
+func WithQueryID(ctx context.Context, queryID string) context.Context {
+	return context.WithValue(ctx, queryIDCtxKey{}, queryID)
+}
+
+// QueryIDFromContext returns the query ID set with WithQueryID (or an empty string).
+func QueryIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(queryIDCtxKey{}).(string)
+	return id
+}
+
+type queueKeyCtxKey struct{}
+
+// WithQueueKey returns a context that associates the statements executed with it with a fair queuing key.
+// Drivers that queue statements (such as DuckDB) interleave the statements of different keys, so one key can't monopolize the queue.
+func WithQueueKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, queueKeyCtxKey{}, key)
+}
+
+// QueueKeyFromContext returns the fair queuing key set with WithQueueKey (or an empty string).
+func QueueKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(queueKeyCtxKey{}).(string)
+	return key
+}
+
+// IngestionProgress describes the progress of a call to OLAPStore.Ingest for drivers that ingest data asynchronously.
+type IngestionProgress struct {
+	// State is the driver-specific state of the ingestion (e.g. "RUNNING")
+	State           string
+	RowsProcessed   int64
+	RowsUnparseable int64
+}
+
+type ingestionProgressCtxKey struct{}
+
+// WithIngestionProgress returns a context that makes OLAPStore.Ingest call fn when the ingestion's progress changes.
+func WithIngestionProgress(ctx context.Context, fn func(p IngestionProgress)) context.Context {
+	return context.WithValue(ctx, ingestionProgressCtxKey{}, fn)
+}
+
+// IngestionProgressFromContext returns the function set with WithIngestionProgress (or nil).
+func IngestionProgressFromContext(ctx context.Context) func(p IngestionProgress) {
+	fn, _ := ctx.Value(ingestionProgressCtxKey{}).(func(p IngestionProgress))
+	return fn
 }
 
 // Statement wraps a query to execute against an OLAP driver.
@@ -26,12 +103,121 @@ type Statement struct {
 	Args     []any
 	DryRun   bool
 	Priority int
+	// ExecutionTimeout is the max duration of the query, including reading its results (0 means no timeout)
+	ExecutionTimeout time.Duration
+	// MaxRows is the max number of rows to return. If the query returns more rows,
+	// Result.Truncated returns true after the first MaxRows rows have been read (0 means no limit).
+	MaxRows int64
+	// LoggedQuery is shown instead of Query in the query history and the list of running queries (if set).
+	// It's used for queries that inline values which shouldn't be disclosed, such as row filters rendered with a caller's attributes.
+	LoggedQuery string
+}
+
+// Logged returns the SQL to show for the statement in the query history and the list of running queries
+func (s *Statement) Logged() string {
+	if s.LoggedQuery != "" {
+		return s.LoggedQuery
+	}
+	return s.Query
+}
+
+// WithTimeout returns a context that applies the statement's ExecutionTimeout (if set) to ctx.
+// Drivers should return context.DeadlineExceeded when the timeout is exceeded.
+func (s *Statement) WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.ExecutionTimeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.ExecutionTimeout)
 }
 
 // Result wraps the results of query.
 type Result struct {
 	*sqlx.Rows
-	Schema *runtimev1.StructType
+	Schema    *runtimev1.StructType
+	maxRows   int64
+	rows      int64
+	truncated bool
+	cleanupFn func()
+}
+
+// SetMaxRows limits the number of rows returned by Next. See Statement.MaxRows.
+func (r *Result) SetMaxRows(n int64) {
+	r.maxRows = n
+}
+
+// SetCleanupFunc sets a function, which will be called when the Result is closed.
+func (r *Result) SetCleanupFunc(fn func()) {
+	r.cleanupFn = fn
+}
+
+// AddCleanupFunc adds a function, which will be called after the current cleanup function when the Result is closed.
+func (r *Result) AddCleanupFunc(fn func()) {
+	prev := r.cleanupFn
+	if prev == nil {
+		r.cleanupFn = fn
+		return
+	}
+	r.cleanupFn = func() {
+		prev()
+		fn()
+	}
+}
+
+// RowsRead returns the number of rows returned by Next so far.
+func (r *Result) RowsRead() int64 {
+	return r.rows
+}
+
+// Next wraps sqlx.Rows.Next to stop when the max number of rows has been read.
+func (r *Result) Next() bool {
+	if r.maxRows > 0 && r.rows == r.maxRows {
+		// Check if there are more rows
+		r.truncated = r.Rows.Next()
+		return false
+	}
+	if !r.Rows.Next() {
+		return false
+	}
+	r.rows++
+	return true
+}
+
+// Truncated returns true if rows were skipped because of the max number of rows.
+// It is only accurate after Next has returned false.
+func (r *Result) Truncated() bool {
+	return r.truncated
+}
+
+// Close wraps sqlx.Rows.Close to call the cleanup function (if set).
+func (r *Result) Close() error {
+	var err error
+	if r.Rows != nil {
+		err = r.Rows.Close()
+	}
+	if r.cleanupFn != nil {
+		r.cleanupFn()
+		r.cleanupFn = nil
+	}
+	return err
+}
+
+// QueryPlan is the result of OLAPStore.Explain.
+type QueryPlan struct {
+	// Plan is the engine's plan for the statement in its native text format
+	Plan string
+	// Profile is the executed plan with per-operator timings and row counts (only set when analyzing)
+	Profile *PlanNode
+	// RawProfile is the profile in the engine's native format (only set when analyzing)
+	RawProfile string
+}
+
+// PlanNode is an operator in an executed query plan.
+type PlanNode struct {
+	Name     string
+	Details  string
+	Duration time.Duration
+	Rows     int64
+	Children []*PlanNode
 }
 
 // InformationSchema contains information about existing tables in an OLAP driver.
@@ -46,6 +232,8 @@ type Table struct {
 	DatabaseSchema string
 	Name           string
 	Schema         *runtimev1.StructType
+	// Metadata is set by drivers that can describe the data stored in the table (such as Druid)
+	Metadata *runtimev1.TableMetadata
 }
 
 // Dialect enumerates OLAP query languages.
@@ -55,6 +243,8 @@ const (
 	DialectUnspecified Dialect = iota
 	DialectDuckDB
 	DialectDruid
+	DialectClickHouse
+	DialectPostgres
 )
 
 func (d Dialect) String() string {
@@ -65,6 +255,84 @@ func (d Dialect) String() string {
 		return "duckdb"
 	case DialectDruid:
 		return "druid"
+	case DialectClickHouse:
+		return "clickhouse"
+	case DialectPostgres:
+		return "postgres"
+	default:
+		panic("not implemented")
+	}
+}
+
+// Capabilities describes what an OLAP store supports. The queries package uses it to decide which queries to run
+// against the store, and clients can get it with the GetInstanceCapabilities RPC.
+type Capabilities struct {
+	// QueryKinds are the kinds of queries the runtime can run against the store
+	QueryKinds []QueryKind
+	// Connectors are the names of the source connectors that Ingest supports
+	Connectors []string
+	// ApproxQuantile is true if quantiles are computed with an approximate function (otherwise they're exact)
+	ApproxQuantile bool
+	// ApproxCountDistinct is true if distinct counts are computed with an approximate function (otherwise they're exact)
+	ApproxCountDistinct bool
+	// Sampling is true if profiling queries can sample large tables
+	Sampling bool
+	// MaxConcurrency is the number of statements the store executes concurrently (0 means the runtime doesn't limit it)
+	MaxConcurrency int
+}
+
+// Supports returns true if the store supports the kind of query.
+func (c Capabilities) Supports(kind QueryKind) bool {
+	for _, k := range c.QueryKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryKind enumerates the kinds of queries the runtime runs against OLAP stores.
+type QueryKind int
+
+const (
+	QueryKindUnspecified QueryKind = iota
+	// QueryKindMetricsView covers the toplist, timeseries, totals and dimension values queries of metrics views
+	QueryKindMetricsView
+	// QueryKindMetricsViewToplistOther covers toplists with an "Other" row or percentages of the total
+	QueryKindMetricsViewToplistOther
+	// QueryKindMetricsViewSQL covers SQL queries against metrics views
+	QueryKindMetricsViewSQL
+	// QueryKindColumnProfile covers the basic profiling queries (top K, null count, cardinality, numeric histogram and time range)
+	QueryKindColumnProfile
+	QueryKindDescriptiveStatistics
+	QueryKindRugHistogram
+	QueryKindTimeGrain
+	QueryKindColumnTimeseries
+	QueryKindTableColumns
+)
+
+func (k QueryKind) String() string {
+	switch k {
+	case QueryKindUnspecified:
+		return ""
+	case QueryKindMetricsView:
+		return "metrics_view"
+	case QueryKindMetricsViewToplistOther:
+		return "metrics_view_toplist_other"
+	case QueryKindMetricsViewSQL:
+		return "metrics_view_sql"
+	case QueryKindColumnProfile:
+		return "column_profile"
+	case QueryKindDescriptiveStatistics:
+		return "descriptive_statistics"
+	case QueryKindRugHistogram:
+		return "rug_histogram"
+	case QueryKindTimeGrain:
+		return "time_grain"
+	case QueryKindColumnTimeseries:
+		return "column_timeseries"
+	case QueryKindTableColumns:
+		return "table_columns"
 	default:
 		panic("not implemented")
 	}
@@ -1,6 +1,7 @@
 package drivers_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/rilldata/rill/runtime/drivers"
@@ -8,5 +9,21 @@ import (
 )
 
 func testOLAP(t *testing.T, olap drivers.OLAPStore) {
-	require.True(t, true)
+	ctx := context.Background()
+
+	res, err := olap.Execute(ctx, &drivers.Statement{Query: "SELECT 1 AS one, CAST(? AS VARCHAR) AS two", Args: []any{"two"}})
+	require.NoError(t, err)
+	require.Len(t, res.Schema.Fields, 2)
+	require.Equal(t, "one", res.Schema.Fields[0].Name)
+
+	var one int
+	var two string
+	require.True(t, res.Next())
+	require.NoError(t, res.Scan(&one, &two))
+	require.Equal(t, 1, one)
+	require.Equal(t, "two", two)
+	require.NoError(t, res.Close())
+
+	_, err = olap.Execute(ctx, &drivers.Statement{Query: "SELECT * FROM does_not_exist", DryRun: true})
+	require.Error(t, err)
 }
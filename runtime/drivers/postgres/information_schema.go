@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+type informationSchema struct {
+	c *connection
+}
+
+func (c *connection) InformationSchema() drivers.InformationSchema {
+	return informationSchema{c: c}
+}
+
+// All implements drivers.InformationSchema. It returns the tables and views in the current schema
+// (which is where models are created).
+func (i informationSchema) All(ctx context.Context) ([]*drivers.Table, error) {
+	q := `
+		SELECT
+			table_catalog AS database,
+			table_schema AS schema,
+			table_name AS name,
+			column_name AS column_name,
+			udt_name AS column_type,
+			is_nullable = 'YES' AS is_nullable
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		ORDER BY database, schema, name, ordinal_position
+	`
+
+	rows, err := i.c.db.QueryxContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables, err := i.scanTables(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
+
+func (i informationSchema) Lookup(ctx context.Context, name string) (*drivers.Table, error) {
+	q := `
+		SELECT
+			table_catalog AS database,
+			table_schema AS schema,
+			table_name AS name,
+			column_name AS column_name,
+			udt_name AS column_type,
+			is_nullable = 'YES' AS is_nullable
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY database, schema, name, ordinal_position
+	`
+
+	rows, err := i.c.db.QueryxContext(ctx, q, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables, err := i.scanTables(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tables) == 0 {
+		return nil, drivers.ErrNotFound
+	}
+
+	return tables[0], nil
+}
+
+func (i informationSchema) scanTables(rows *sqlx.Rows) ([]*drivers.Table, error) {
+	var res []*drivers.Table
+
+	for rows.Next() {
+		var database string
+		var schema string
+		var name string
+		var columnName string
+		var columnType string
+		var nullable bool
+
+		err := rows.Scan(&database, &schema, &name, &columnName, &columnType, &nullable)
+		if err != nil {
+			return nil, err
+		}
+
+		// set t to res[len(res)-1] if it's the same table, else set t to a new table and append it
+		var t *drivers.Table
+		if len(res) > 0 {
+			t = res[len(res)-1]
+			if !(t.Database == database && t.DatabaseSchema == schema && t.Name == name) {
+				t = nil
+			}
+		}
+		if t == nil {
+			t = &drivers.Table{
+				Database:       database,
+				DatabaseSchema: schema,
+				Name:           name,
+				Schema:         &runtimev1.StructType{},
+			}
+			res = append(res, t)
+		}
+
+		// parse column type
+		colType, err := databaseTypeToPB(columnType, nullable)
+		if err != nil {
+			return nil, err
+		}
+
+		// append column
+		t.Schema.Fields = append(t.Schema.Fields, &runtimev1.StructType_Field{
+			Name: columnName,
+			Type: colType,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/connectors"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+func (c *connection) Dialect() drivers.Dialect {
+	return drivers.DialectPostgres
+}
+
+// Capabilities implements drivers.OLAPStore.
+// Postgres doesn't have approximate functions, so the profiling queries compute exact quantiles and distinct counts.
+func (c *connection) Capabilities() drivers.Capabilities {
+	return drivers.Capabilities{
+		QueryKinds: []drivers.QueryKind{drivers.QueryKindMetricsView, drivers.QueryKindColumnProfile},
+	}
+}
+
+// Execute implements drivers.OLAPStore.
+// Statements use "?" placeholders like the other OLAP drivers, which are rebound to Postgres' "$1" placeholders if args are passed.
+func (c *connection) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
+	query := stmt.Query
+	if len(stmt.Args) > 0 {
+		query = c.db.Rebind(query)
+	}
+
+	if stmt.DryRun {
+		// Preparing the statement parses and plans it without executing it
+		prepared, err := c.db.PrepareContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return nil, prepared.Close()
+	}
+
+	// The timeout context must stay open until the result has been read, so it's cancelled when the result is closed
+	ctx, cancel := stmt.WithTimeout(ctx)
+
+	rows, err := c.db.QueryxContext(ctx, query, stmt.Args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	schema, err := rowsToSchema(rows)
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, err
+	}
+
+	// Postgres streams results, so we enforce the max rows while reading the result
+	res := &drivers.Result{Rows: rows, Schema: schema}
+	res.SetMaxRows(stmt.MaxRows)
+	res.SetCleanupFunc(cancel)
+	return res, nil
+}
+
+// Ingest implements drivers.OLAPStore.
+// Postgres is used to serve existing tables, so it doesn't support ingesting sources.
+func (c *connection) Ingest(ctx context.Context, env *connectors.Env, source *connectors.Source) error {
+	return drivers.ErrUnsupportedConnector
+}
+
+// Explain implements drivers.OLAPStore using Postgres' EXPLAIN statement.
+// When analyzing, it uses EXPLAIN ANALYZE with JSON output for the profile.
+func (c *connection) Explain(ctx context.Context, stmt *drivers.Statement, analyze bool) (*drivers.QueryPlan, error) {
+	ctx, cancel := stmt.WithTimeout(ctx)
+	defer cancel()
+
+	query := stmt.Query
+	if len(stmt.Args) > 0 {
+		query = c.db.Rebind(query)
+	}
+
+	plan := &drivers.QueryPlan{}
+	var err error
+	plan.Plan, err = c.explainValue(ctx, "EXPLAIN "+query, stmt.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if analyze {
+		plan.RawProfile, err = c.explainValue(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+query, stmt.Args)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.Profile, err = parseProfile(plan.RawProfile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// explainValue runs an EXPLAIN statement and joins the returned lines
+func (c *connection) explainValue(ctx context.Context, query string, args []any) (string, error) {
+	rows, err := c.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// profileNode is a node in the output of EXPLAIN (ANALYZE, FORMAT JSON)
+type profileNode struct {
+	NodeType     string         `json:"Node Type"`
+	RelationName string         `json:"Relation Name"`
+	Strategy     string         `json:"Strategy"`
+	JoinType     string         `json:"Join Type"`
+	TotalTime    float64        `json:"Actual Total Time"`
+	Rows         int64          `json:"Actual Rows"`
+	Loops        int64          `json:"Actual Loops"`
+	Plans        []*profileNode `json:"Plans"`
+}
+
+// parseProfile converts the output of EXPLAIN (ANALYZE, FORMAT JSON) to a tree of plan nodes
+func parseProfile(raw string) (*drivers.PlanNode, error) {
+	var res []struct {
+		Plan          *profileNode `json:"Plan"`
+		ExecutionTime float64      `json:"Execution Time"`
+	}
+	err := json.Unmarshal([]byte(raw), &res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile: %w", err)
+	}
+	if len(res) == 0 || res[0].Plan == nil {
+		return nil, fmt.Errorf("failed to parse profile: no plan")
+	}
+
+	root := &drivers.PlanNode{
+		Name:     "Query",
+		Duration: time.Duration(res[0].ExecutionTime * float64(time.Millisecond)),
+		Children: []*drivers.PlanNode{profileToPlanNode(res[0].Plan)},
+	}
+	root.Rows = root.Children[0].Rows
+	return root, nil
+}
+
+func profileToPlanNode(n *profileNode) *drivers.PlanNode {
+	var details []string
+	for _, d := range []string{n.RelationName, n.Strategy, n.JoinType} {
+		if d != "" {
+			details = append(details, d)
+		}
+	}
+
+	// Times and rows are averages per loop
+	loops := n.Loops
+	if loops == 0 {
+		loops = 1
+	}
+
+	node := &drivers.PlanNode{
+		Name:     n.NodeType,
+		Details:  strings.Join(details, " "),
+		Duration: time.Duration(n.TotalTime * float64(loops) * float64(time.Millisecond)),
+		Rows:     n.Rows * loops,
+	}
+	for _, child := range n.Plans {
+		node.Children = append(node.Children, profileToPlanNode(child))
+	}
+	return node
+}
+
+func rowsToSchema(r *sqlx.Rows) (*runtimev1.StructType, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	cts, err := r.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]*runtimev1.StructType_Field, len(cts))
+	for i, ct := range cts {
+		// pgx doesn't know if result columns are nullable
+		nullable, ok := ct.Nullable()
+		if !ok {
+			nullable = true
+		}
+
+		t, err := databaseTypeToPB(ct.DatabaseTypeName(), nullable)
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = &runtimev1.StructType_Field{
+			Name: ct.Name(),
+			Type: t,
+		}
+	}
+
+	return &runtimev1.StructType{Fields: fields}, nil
+}
+
+// databaseTypeToPB converts a Postgres type name (as in pg_type.typname, such as "INT8" or "_TEXT" for arrays) to a runtimev1.Type.
+// Unknown types (such as user-defined types) are returned with an unspecified type code.
+func databaseTypeToPB(dbt string, nullable bool) (*runtimev1.Type, error) {
+	dbt = strings.ToUpper(dbt)
+	t := &runtimev1.Type{Nullable: nullable}
+
+	if strings.HasPrefix(dbt, "_") {
+		elemType, err := databaseTypeToPB(dbt[1:], true)
+		if err != nil {
+			return nil, err
+		}
+		t.Code = runtimev1.Type_CODE_ARRAY
+		t.ArrayElementType = elemType
+		return t, nil
+	}
+
+	switch dbt {
+	case "BOOL":
+		t.Code = runtimev1.Type_CODE_BOOL
+	case "INT2":
+		t.Code = runtimev1.Type_CODE_INT16
+	case "INT4":
+		t.Code = runtimev1.Type_CODE_INT32
+	case "INT8":
+		t.Code = runtimev1.Type_CODE_INT64
+	case "OID":
+		t.Code = runtimev1.Type_CODE_UINT32
+	case "FLOAT4":
+		t.Code = runtimev1.Type_CODE_FLOAT32
+	case "FLOAT8":
+		t.Code = runtimev1.Type_CODE_FLOAT64
+	case "NUMERIC":
+		t.Code = runtimev1.Type_CODE_DECIMAL
+	case "TEXT", "VARCHAR", "BPCHAR", "CHAR", "NAME":
+		t.Code = runtimev1.Type_CODE_STRING
+	case "BYTEA":
+		t.Code = runtimev1.Type_CODE_BYTES
+	case "TIMESTAMP", "TIMESTAMPTZ":
+		t.Code = runtimev1.Type_CODE_TIMESTAMP
+	case "DATE":
+		t.Code = runtimev1.Type_CODE_DATE
+	case "TIME", "TIMETZ":
+		t.Code = runtimev1.Type_CODE_TIME
+	case "UUID":
+		t.Code = runtimev1.Type_CODE_UUID
+	case "JSON", "JSONB":
+		t.Code = runtimev1.Type_CODE_JSON
+	default:
+		t.Code = runtimev1.Type_CODE_UNSPECIFIED
+	}
+
+	return t, nil
+}
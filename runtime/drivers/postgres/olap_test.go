@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseTypeToPB(t *testing.T) {
+	cases := []struct {
+		dbt      string
+		expected *runtimev1.Type
+	}{
+		{"INT8", &runtimev1.Type{Code: runtimev1.Type_CODE_INT64}},
+		{"numeric", &runtimev1.Type{Code: runtimev1.Type_CODE_DECIMAL}},
+		{"TIMESTAMPTZ", &runtimev1.Type{Code: runtimev1.Type_CODE_TIMESTAMP}},
+		{"_text", &runtimev1.Type{
+			Code:             runtimev1.Type_CODE_ARRAY,
+			ArrayElementType: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING, Nullable: true},
+		}},
+		{"my_enum", &runtimev1.Type{Code: runtimev1.Type_CODE_UNSPECIFIED}},
+	}
+	for _, c := range cases {
+		typ, err := databaseTypeToPB(c.dbt, false)
+		require.NoError(t, err, c.dbt)
+		require.Equal(t, c.expected.String(), typ.String(), c.dbt)
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	raw := `[
+		{
+			"Plan": {
+				"Node Type": "Aggregate",
+				"Strategy": "Hashed",
+				"Actual Total Time": 1.5,
+				"Actual Rows": 3,
+				"Actual Loops": 1,
+				"Plans": [
+					{
+						"Node Type": "Seq Scan",
+						"Relation Name": "ad_bids",
+						"Actual Total Time": 0.25,
+						"Actual Rows": 50,
+						"Actual Loops": 2
+					}
+				]
+			},
+			"Planning Time": 0.1,
+			"Execution Time": 2.0
+		}
+	]`
+
+	profile, err := parseProfile(raw)
+	require.NoError(t, err)
+	require.Equal(t, "Query", profile.Name)
+	require.Equal(t, 2*time.Millisecond, profile.Duration)
+	require.Equal(t, int64(3), profile.Rows)
+
+	agg := profile.Children[0]
+	require.Equal(t, "Aggregate", agg.Name)
+	require.Equal(t, "Hashed", agg.Details)
+	require.Equal(t, 1500*time.Microsecond, agg.Duration)
+
+	scan := agg.Children[0]
+	require.Equal(t, "Seq Scan", scan.Name)
+	require.Equal(t, "ad_bids", scan.Details)
+	require.Equal(t, 500*time.Microsecond, scan.Duration)
+	require.Equal(t, int64(100), scan.Rows)
+
+	_, err = parseProfile("[]")
+	require.Error(t, err)
+}
@@ -1,11 +1,14 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/rilldata/rill/runtime/drivers"
-
-	// Load postgres driver
-	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
 func init() {
@@ -14,20 +17,42 @@ func init() {
 
 type driver struct{}
 
+// Open connects to Postgres using pgx.
+// Sessions use UTC as their time zone, so timestamps without a time zone are interpreted as UTC in OLAP queries.
 func (d driver) Open(dsn string) (drivers.Connection, error) {
-	db, err := sqlx.Connect("pgx", dsn)
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RuntimeParams["timezone"] = "UTC"
+
+	connStr := stdlib.RegisterConnConfig(cfg)
+	pgConnector, err := stdlib.GetDefaultDriver().(sqldriver.DriverContext).OpenConnector(connStr)
 	if err != nil {
+		stdlib.UnregisterConnConfig(connStr)
 		return nil, err
 	}
-	return &connection{db: db}, nil
+
+	db := sqlx.NewDb(sql.OpenDB(connector{pgConnector}), "pgx")
+	err = db.PingContext(context.Background())
+	if err != nil {
+		db.Close()
+		stdlib.UnregisterConnConfig(connStr)
+		return nil, err
+	}
+
+	return &connection{db: db, connStr: connStr}, nil
 }
 
 type connection struct {
 	db *sqlx.DB
+	// connStr is the key of the registered pgx config
+	connStr string
 }
 
 // Close implements drivers.Connection.
 func (c *connection) Close() error {
+	defer stdlib.UnregisterConnConfig(c.connStr)
 	return c.db.Close()
 }
 
@@ -48,5 +73,5 @@ func (c *connection) RepoStore() (drivers.RepoStore, bool) {
 
 // OLAP implements drivers.Connection.
 func (c *connection) OLAPStore() (drivers.OLAPStore, bool) {
-	return nil, false
+	return c, true
 }
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// connector wraps the pgx connector to normalize the values returned in query results.
+// pgx returns numerics as strings and timestamps in the local time zone, which doesn't match the other OLAP drivers.
+type connector struct {
+	sqldriver.Connector
+}
+
+func (c connector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc}, nil
+}
+
+type conn struct {
+	sqldriver.Conn
+}
+
+var (
+	_ sqldriver.QueryerContext     = &conn{}
+	_ sqldriver.ExecerContext      = &conn{}
+	_ sqldriver.ConnPrepareContext = &conn{}
+	_ sqldriver.ConnBeginTx        = &conn{}
+	_ sqldriver.Pinger             = &conn{}
+	_ sqldriver.NamedValueChecker  = &conn{}
+)
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	dr, err := c.Conn.(sqldriver.QueryerContext).QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{Rows: dr}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	return c.Conn.(sqldriver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (sqldriver.Stmt, error) {
+	return c.Conn.(sqldriver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	return c.Conn.(sqldriver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.Conn.(sqldriver.Pinger).Ping(ctx)
+}
+
+func (c *conn) CheckNamedValue(nv *sqldriver.NamedValue) error {
+	return c.Conn.(sqldriver.NamedValueChecker).CheckNamedValue(nv)
+}
+
+type rows struct {
+	sqldriver.Rows
+	// numerics flags the columns of type NUMERIC, which pgx returns as strings
+	numerics []bool
+}
+
+var (
+	_ sqldriver.RowsColumnTypeDatabaseTypeName = &rows{}
+	_ sqldriver.RowsColumnTypePrecisionScale   = &rows{}
+)
+
+func (r *rows) ColumnTypeDatabaseTypeName(idx int) string {
+	return r.Rows.(sqldriver.RowsColumnTypeDatabaseTypeName).ColumnTypeDatabaseTypeName(idx)
+}
+
+func (r *rows) ColumnTypePrecisionScale(idx int) (precision, scale int64, ok bool) {
+	return r.Rows.(sqldriver.RowsColumnTypePrecisionScale).ColumnTypePrecisionScale(idx)
+}
+
+func (r *rows) Next(dest []sqldriver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil {
+		return err
+	}
+
+	if r.numerics == nil {
+		r.numerics = make([]bool, len(dest))
+		for i := range dest {
+			r.numerics[i] = r.ColumnTypeDatabaseTypeName(i) == "NUMERIC"
+		}
+	}
+
+	for i, v := range dest {
+		switch v := v.(type) {
+		case string:
+			if r.numerics[i] {
+				// Numerics are returned as floats, like on DuckDB (NaN is returned as a float too)
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return fmt.Errorf("postgres: invalid numeric %q: %w", v, err)
+				}
+				dest[i] = f
+			}
+		case time.Time:
+			dest[i] = v.UTC()
+		}
+	}
+
+	return nil
+}
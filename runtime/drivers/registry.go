@@ -29,6 +29,14 @@ type Instance struct {
 	// EmbedCatalog tells the runtime to store the instance's catalog in its OLAP store instead
 	// of in the runtime's metadata store. Currently only supported for the duckdb driver.
 	EmbedCatalog bool `db:"embed_catalog"`
+	// QueryTimeoutSeconds is the max duration of ad-hoc queries against the instance's OLAP (0 means no limit)
+	QueryTimeoutSeconds uint32 `db:"query_timeout_seconds"`
+	// QueryMaxRows is the max number of rows returned by ad-hoc queries against the instance's OLAP (0 means no limit)
+	QueryMaxRows int64 `db:"query_max_rows"`
+	// OLAPMemoryLimitBytes is the max memory used by the OLAP store (0 means the store's default). Only supported for duckdb.
+	OLAPMemoryLimitBytes int64 `db:"olap_memory_limit_bytes"`
+	// OLAPThreads is the number of threads used by the OLAP store (0 means the store's default). Only supported for duckdb.
+	OLAPThreads uint32 `db:"olap_threads"`
 	// CreatedOn is when the instance was created
 	CreatedOn time.Time `db:"created_on"`
 	// UpdatedOn is when the instance was last updated in the registry
@@ -13,11 +13,15 @@ import (
 func testRegistry(t *testing.T, reg drivers.RegistryStore) {
 	ctx := context.Background()
 	inst := &drivers.Instance{
-		OLAPDriver:   "duckdb",
-		OLAPDSN:      ":memory:",
-		RepoDriver:   "file",
-		RepoDSN:      ".",
-		EmbedCatalog: true,
+		OLAPDriver:           "duckdb",
+		OLAPDSN:              ":memory:",
+		RepoDriver:           "file",
+		RepoDSN:              ".",
+		EmbedCatalog:         true,
+		QueryTimeoutSeconds:  30,
+		QueryMaxRows:         1000,
+		OLAPMemoryLimitBytes: 1 << 30,
+		OLAPThreads:          2,
 	}
 
 	err := reg.CreateInstance(ctx, inst)
@@ -39,6 +43,10 @@ func testRegistry(t *testing.T, reg drivers.RegistryStore) {
 	require.Equal(t, inst.RepoDriver, res.RepoDriver)
 	require.Equal(t, inst.RepoDSN, res.RepoDSN)
 	require.Equal(t, inst.EmbedCatalog, res.EmbedCatalog)
+	require.Equal(t, inst.QueryTimeoutSeconds, res.QueryTimeoutSeconds)
+	require.Equal(t, inst.QueryMaxRows, res.QueryMaxRows)
+	require.Equal(t, inst.OLAPMemoryLimitBytes, res.OLAPMemoryLimitBytes)
+	require.Equal(t, inst.OLAPThreads, res.OLAPThreads)
 
 	err = reg.CreateInstance(ctx, &drivers.Instance{OLAPDriver: "druid"})
 	require.NoError(t, err)
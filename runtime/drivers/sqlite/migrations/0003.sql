@@ -0,0 +1,2 @@
+ALTER TABLE instances ADD COLUMN query_timeout_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE instances ADD COLUMN query_max_rows INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE instances ADD COLUMN olap_memory_limit_bytes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE instances ADD COLUMN olap_threads INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,302 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/exportutil"
+	"go.uber.org/zap"
+)
+
+// defaultExportTTL is how long exported files are kept if Options.ExportTTL is not set
+const defaultExportTTL = time.Hour
+
+// maxConcurrentExports is the max number of export jobs that run at the same time (others wait in line)
+const maxConcurrentExports = 2
+
+// ErrExportNotCompleted is returned by OpenExport for export jobs that are still running or have failed
+var ErrExportNotCompleted = errors.New("export has not completed")
+
+// ExportOptions describes a query to export
+type ExportOptions struct {
+	Format   runtimev1.ExportFormat
+	SQL      string
+	Args     []any
+	Priority int
+	// LoggedSQL is shown instead of SQL in the query history (see drivers.Statement.LoggedQuery)
+	LoggedSQL string
+	// FileName is the suggested name of the file (without extension)
+	FileName string
+}
+
+// Export is an export job created with CreateExport
+type Export struct {
+	ID         string
+	InstanceID string
+	// Creator is the caller that created the export (see WithCaller), or an empty string for anonymous callers
+	Creator     string
+	Format      runtimev1.ExportFormat
+	Status      runtimev1.Export_Status
+	Error       string
+	FileName    string
+	Rows        int64
+	SizeBytes   int64
+	CreatedOn   time.Time
+	CompletedOn time.Time
+
+	path   string
+	cancel context.CancelFunc
+}
+
+// Export runs a query and writes its result to w in the given format.
+// It returns the number of rows written. It works for all OLAP drivers.
+func (r *Runtime) Export(ctx context.Context, instanceID string, opts *ExportOptions, w io.Writer) (int64, error) {
+	olap, err := r.OLAP(ctx, instanceID)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := olap.Execute(ctx, &drivers.Statement{
+		Query:       opts.SQL,
+		Args:        opts.Args,
+		Priority:    opts.Priority,
+		LoggedQuery: opts.LoggedSQL,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close()
+
+	ew, err := exportutil.NewWriter(opts.Format, w, res.Schema)
+	if err != nil {
+		return 0, err
+	}
+
+	return exportutil.WriteRows(ew, res)
+}
+
+// CreateExport starts an export job that writes the query result to a file in the background.
+// The file is kept for Options.ExportTTL after the job completes.
+func (r *Runtime) CreateExport(ctx context.Context, instanceID string, opts *ExportOptions) (*Export, error) {
+	if exportutil.FileExtension(opts.Format) == "" {
+		return nil, fmt.Errorf("unsupported export format '%s'", opts.Format)
+	}
+
+	// Check the instance exists before starting the job
+	_, err := r.OLAP(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.exports.start(r, instanceID, callerFromContext(ctx), opts)
+}
+
+// GetExport returns a snapshot of an export job
+func (r *Runtime) GetExport(instanceID, exportID string) (*Export, error) {
+	return r.exports.get(instanceID, exportID)
+}
+
+// OpenExport opens the file of a completed export job. The caller must close the file.
+func (r *Runtime) OpenExport(instanceID, exportID string) (*os.File, *Export, error) {
+	e, err := r.exports.get(instanceID, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Status != runtimev1.Export_STATUS_COMPLETED {
+		return nil, nil, ErrExportNotCompleted
+	}
+
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, e, nil
+}
+
+// exportJobs tracks export jobs and their files
+type exportJobs struct {
+	dir    string
+	ttl    time.Duration
+	logger *zap.Logger
+	sem    chan struct{}
+	mu     sync.Mutex
+	jobs   map[string]*Export
+}
+
+func newExportJobs(dir string, ttl time.Duration, logger *zap.Logger) *exportJobs {
+	if ttl == 0 {
+		ttl = defaultExportTTL
+	}
+	return &exportJobs{
+		dir:    dir,
+		ttl:    ttl,
+		logger: logger,
+		sem:    make(chan struct{}, maxConcurrentExports),
+		jobs:   make(map[string]*Export),
+	}
+}
+
+func (j *exportJobs) start(rt *Runtime, instanceID, creator string, opts *ExportOptions) (*Export, error) {
+	dir, err := j.ensureDir()
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	ext := exportutil.FileExtension(opts.Format)
+	name := opts.FileName
+	if name == "" {
+		name = "export"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Export{
+		ID:         id,
+		InstanceID: instanceID,
+		Creator:    creator,
+		Format:     opts.Format,
+		Status:     runtimev1.Export_STATUS_PENDING,
+		FileName:   fmt.Sprintf("%s.%s", name, ext),
+		CreatedOn:  time.Now(),
+		path:       filepath.Join(dir, fmt.Sprintf("%s.%s", id, ext)),
+		cancel:     cancel,
+	}
+
+	j.mu.Lock()
+	j.expire()
+	j.jobs[id] = e
+	snapshot := *e
+	j.mu.Unlock()
+
+	go j.run(ctx, rt, e, opts)
+
+	return &snapshot, nil
+}
+
+func (j *exportJobs) run(ctx context.Context, rt *Runtime, e *Export, opts *ExportOptions) {
+	select {
+	case j.sem <- struct{}{}:
+		defer func() { <-j.sem }()
+	case <-ctx.Done():
+		j.finish(e, 0, ctx.Err())
+		return
+	}
+
+	j.mu.Lock()
+	e.Status = runtimev1.Export_STATUS_RUNNING
+	j.mu.Unlock()
+
+	f, err := os.Create(e.path)
+	if err != nil {
+		j.finish(e, 0, err)
+		return
+	}
+
+	n, err := rt.Export(ctx, e.InstanceID, opts, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	j.finish(e, n, err)
+}
+
+func (j *exportJobs) finish(e *Export, rows int64, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e.CompletedOn = time.Now()
+	e.cancel()
+
+	// The job was removed while running (e.g. its instance was deleted)
+	if j.jobs[e.ID] != e {
+		os.Remove(e.path)
+		return
+	}
+
+	if err != nil {
+		j.logger.Info("export failed", zap.String("instance_id", e.InstanceID), zap.String("export_id", e.ID), zap.Error(err))
+		e.Status = runtimev1.Export_STATUS_FAILED
+		e.Error = err.Error()
+		os.Remove(e.path)
+		return
+	}
+
+	e.Status = runtimev1.Export_STATUS_COMPLETED
+	e.Rows = rows
+	if info, err := os.Stat(e.path); err == nil {
+		e.SizeBytes = info.Size()
+	}
+}
+
+func (j *exportJobs) get(instanceID, exportID string) (*Export, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.expire()
+	e, ok := j.jobs[exportID]
+	if !ok || e.InstanceID != instanceID {
+		return nil, drivers.ErrNotFound
+	}
+	snapshot := *e
+	return &snapshot, nil
+}
+
+// deleteInstance cancels and removes all export jobs for an instance
+func (j *exportJobs) deleteInstance(instanceID string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for id, e := range j.jobs {
+		if e.InstanceID == instanceID {
+			j.remove(id, e)
+		}
+	}
+}
+
+// expire removes finished jobs older than the TTL. It must be called while holding the lock.
+func (j *exportJobs) expire() {
+	cutoff := time.Now().Add(-j.ttl)
+	for id, e := range j.jobs {
+		if !e.CompletedOn.IsZero() && e.CompletedOn.Before(cutoff) {
+			j.remove(id, e)
+		}
+	}
+}
+
+// remove removes a job and its file. It must be called while holding the lock.
+func (j *exportJobs) remove(id string, e *Export) {
+	delete(j.jobs, id)
+	e.cancel()
+	if !e.CompletedOn.IsZero() {
+		os.Remove(e.path)
+	}
+}
+
+// ensureDir returns the directory for export files, creating a temporary directory if Options.ExportDir is not set
+func (j *exportJobs) ensureDir() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.dir == "" {
+		dir, err := os.MkdirTemp("", "rill_exports_")
+		if err != nil {
+			return "", err
+		}
+		j.dir = dir
+		return dir, nil
+	}
+
+	err := os.MkdirAll(j.dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	return j.dir, nil
+}
@@ -0,0 +1,113 @@
+package runtime_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/testruntime"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	rt, instanceID := testruntime.NewInstance(t)
+
+	var buf bytes.Buffer
+	n, err := rt.Export(context.Background(), instanceID, &runtime.ExportOptions{
+		Format: runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		SQL:    "SELECT range AS id, 'x' || range AS name FROM range(3) WHERE range >= ?",
+		Args:   []any{1},
+	}, &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	require.Equal(t, "id,name\n1,x1\n2,x2\n", buf.String())
+
+	_, err = rt.Export(context.Background(), instanceID, &runtime.ExportOptions{
+		Format: runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		SQL:    "SELECT * FROM missing",
+	}, &buf)
+	require.Error(t, err)
+}
+
+func TestExportParquet(t *testing.T) {
+	rt, instanceID := testruntime.NewInstance(t)
+
+	// Parquet is written natively from the result, so it doesn't depend on the OLAP driver
+	var buf bytes.Buffer
+	n, err := rt.Export(context.Background(), instanceID, &runtime.ExportOptions{
+		Format: runtimev1.ExportFormat_EXPORT_FORMAT_PARQUET,
+		SQL: `SELECT range AS id, 'x' || range AS "a name", range * 1.5 AS value, range % 2 = 0 AS even,
+			DATE '2022-01-02' + range::INTEGER AS day, TIMESTAMP '2022-01-02 03:04:05' AS ts, 1.25::DECIMAL(10,2) AS dec,
+			CASE WHEN range = 1 THEN NULL ELSE [range] END AS list
+			FROM range(3)`,
+	}, &buf)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+	require.True(t, bytes.HasPrefix(buf.Bytes(), []byte("PAR1")))
+	require.True(t, bytes.HasSuffix(buf.Bytes(), []byte("PAR1")))
+	require.Contains(t, buf.String(), "a name")
+}
+
+func TestExportJob(t *testing.T) {
+	ctx := context.Background()
+	rt, instanceID := testruntime.NewInstance(t)
+
+	e, err := rt.CreateExport(ctx, instanceID, &runtime.ExportOptions{
+		Format:   runtimev1.ExportFormat_EXPORT_FORMAT_JSON,
+		SQL:      "SELECT range AS id FROM range(2)",
+		FileName: "numbers",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "numbers.json", e.FileName)
+
+	e = waitForExport(t, rt, instanceID, e.ID)
+	require.Equal(t, runtimev1.Export_STATUS_COMPLETED, e.Status, e.Error)
+	require.Equal(t, int64(2), e.Rows)
+
+	f, _, err := rt.OpenExport(instanceID, e.ID)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Equal(t, "[\n{\"id\":0},\n{\"id\":1}\n]\n", string(data))
+	require.Equal(t, int64(len(data)), e.SizeBytes)
+
+	// Jobs are scoped to their instance
+	_, err = rt.GetExport("other", e.ID)
+	require.ErrorIs(t, err, drivers.ErrNotFound)
+
+	// Failed jobs report the error
+	e, err = rt.CreateExport(ctx, instanceID, &runtime.ExportOptions{
+		Format: runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		SQL:    "SELECT * FROM missing",
+	})
+	require.NoError(t, err)
+	e = waitForExport(t, rt, instanceID, e.ID)
+	require.Equal(t, runtimev1.Export_STATUS_FAILED, e.Status)
+	require.NotEmpty(t, e.Error)
+	_, _, err = rt.OpenExport(instanceID, e.ID)
+	require.ErrorIs(t, err, runtime.ErrExportNotCompleted)
+
+	// Deleting the instance removes its jobs
+	require.NoError(t, rt.DeleteInstance(ctx, instanceID))
+	_, err = rt.GetExport(instanceID, e.ID)
+	require.ErrorIs(t, err, drivers.ErrNotFound)
+}
+
+func waitForExport(t *testing.T, rt *runtime.Runtime, instanceID, exportID string) *runtime.Export {
+	for i := 0; i < 100; i++ {
+		e, err := rt.GetExport(instanceID, exportID)
+		require.NoError(t, err)
+		if e.Status == runtimev1.Export_STATUS_COMPLETED || e.Status == runtimev1.Export_STATUS_FAILED {
+			return e
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("export did not complete")
+	return nil
+}
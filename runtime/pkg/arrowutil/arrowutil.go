@@ -0,0 +1,537 @@
+// Package arrowutil encodes query results as Arrow IPC streams.
+package arrowutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/decimal128"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"github.com/google/uuid"
+	"github.com/marcboeker/go-duckdb"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultBatchSize is the number of rows in each record batch written by an Encoder
+const DefaultBatchSize = 10000
+
+// Rows is the subset of *drivers.Result used by EncodeRows
+type Rows interface {
+	Next() bool
+	SliceScan() ([]any, error)
+	Err() error
+}
+
+// EncodeRows reads all rows and returns them as an Arrow IPC stream
+func EncodeRows(schema *runtimev1.StructType, rows Rows) ([]byte, error) {
+	enc := NewEncoder(schema)
+	defer enc.Release()
+
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		if err := enc.Append(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return enc.Finish()
+}
+
+// EncodeStructs returns data as an Arrow IPC stream. Fields are looked up by name in the schema.
+func EncodeStructs(schema *runtimev1.StructType, data []*structpb.Struct) ([]byte, error) {
+	enc := NewEncoder(schema)
+	defer enc.Release()
+
+	for _, s := range data {
+		if err := enc.AppendStruct(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return enc.Finish()
+}
+
+// Encoder builds an Arrow IPC stream row by row.
+// Rows are buffered and written in record batches of BatchSize rows.
+type Encoder struct {
+	BatchSize int
+
+	schema  *runtimev1.StructType
+	builder *array.RecordBuilder
+	writer  *ipc.Writer
+	buf     bytes.Buffer
+}
+
+// NewEncoder creates an encoder for rows of the given schema
+func NewEncoder(schema *runtimev1.StructType) *Encoder {
+	mem := memory.NewGoAllocator()
+	s := Schema(schema)
+	e := &Encoder{
+		BatchSize: DefaultBatchSize,
+		schema:    schema,
+		builder:   array.NewRecordBuilder(mem, s),
+	}
+	e.writer = ipc.NewWriter(&e.buf, ipc.WithSchema(s), ipc.WithAllocator(mem))
+	return e
+}
+
+// Append adds a row of values in schema order (such as returned by sqlx.Rows.SliceScan)
+func (e *Encoder) Append(row []any) error {
+	if len(row) != len(e.schema.Fields) {
+		return fmt.Errorf("arrowutil: expected %d values, got %d", len(e.schema.Fields), len(row))
+	}
+	for i, v := range row {
+		err := appendValue(e.builder.Field(i), e.schema.Fields[i].Type, v)
+		if err != nil {
+			return fmt.Errorf("arrowutil: field %q: %w", e.schema.Fields[i].Name, err)
+		}
+	}
+	return e.flushIfFull()
+}
+
+// AppendStruct adds a row with the values of s. Fields missing from s are null.
+func (e *Encoder) AppendStruct(s *structpb.Struct) error {
+	for i, f := range e.schema.Fields {
+		var v any
+		if pv, ok := s.Fields[f.Name]; ok {
+			v = pv.AsInterface()
+		}
+		err := appendValue(e.builder.Field(i), f.Type, v)
+		if err != nil {
+			return fmt.Errorf("arrowutil: field %q: %w", f.Name, err)
+		}
+	}
+	return e.flushIfFull()
+}
+
+// Finish writes the remaining rows and returns the IPC stream
+func (e *Encoder) Finish() ([]byte, error) {
+	if err := e.flush(); err != nil {
+		return nil, err
+	}
+	if err := e.writer.Close(); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// Release frees the encoder's buffers
+func (e *Encoder) Release() {
+	e.builder.Release()
+}
+
+func (e *Encoder) flushIfFull() error {
+	if e.BatchSize > 0 && e.rows() >= e.BatchSize {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *Encoder) flush() error {
+	if e.rows() == 0 {
+		return nil
+	}
+	rec := e.builder.NewRecord()
+	defer rec.Release()
+	return e.writer.Write(rec)
+}
+
+// rows returns the number of buffered rows
+func (e *Encoder) rows() int {
+	if len(e.schema.Fields) == 0 {
+		return 0
+	}
+	return e.builder.Field(0).Len()
+}
+
+// Schema converts a schema to an Arrow schema
+func Schema(t *runtimev1.StructType) *arrow.Schema {
+	fields := make([]arrow.Field, len(t.Fields))
+	for i, f := range t.Fields {
+		fields[i] = arrow.Field{Name: f.Name, Type: DataType(f.Type), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// DataType converts a type to an Arrow type.
+// Types without an Arrow equivalent (such as JSON, UUID and intervals) are represented as strings.
+func DataType(t *runtimev1.Type) arrow.DataType {
+	switch t.GetCode() {
+	case runtimev1.Type_CODE_BOOL:
+		return arrow.FixedWidthTypes.Boolean
+	case runtimev1.Type_CODE_INT8:
+		return arrow.PrimitiveTypes.Int8
+	case runtimev1.Type_CODE_INT16:
+		return arrow.PrimitiveTypes.Int16
+	case runtimev1.Type_CODE_INT32:
+		return arrow.PrimitiveTypes.Int32
+	case runtimev1.Type_CODE_INT64:
+		return arrow.PrimitiveTypes.Int64
+	case runtimev1.Type_CODE_UINT8:
+		return arrow.PrimitiveTypes.Uint8
+	case runtimev1.Type_CODE_UINT16:
+		return arrow.PrimitiveTypes.Uint16
+	case runtimev1.Type_CODE_UINT32:
+		return arrow.PrimitiveTypes.Uint32
+	case runtimev1.Type_CODE_UINT64:
+		return arrow.PrimitiveTypes.Uint64
+	case runtimev1.Type_CODE_INT128, runtimev1.Type_CODE_UINT128:
+		return &arrow.Decimal128Type{Precision: 38, Scale: 0}
+	case runtimev1.Type_CODE_FLOAT32:
+		return arrow.PrimitiveTypes.Float32
+	case runtimev1.Type_CODE_DECIMAL:
+		// Decimals without a known precision are scanned into float64
+		if t.DecimalPrecision == 0 {
+			return arrow.PrimitiveTypes.Float64
+		}
+		return &arrow.Decimal128Type{Precision: t.DecimalPrecision, Scale: t.DecimalScale}
+	case runtimev1.Type_CODE_FLOAT64:
+		return arrow.PrimitiveTypes.Float64
+	case runtimev1.Type_CODE_TIMESTAMP:
+		return arrow.FixedWidthTypes.Timestamp_us
+	case runtimev1.Type_CODE_DATE:
+		return arrow.FixedWidthTypes.Date32
+	case runtimev1.Type_CODE_TIME:
+		return arrow.FixedWidthTypes.Time64us
+	case runtimev1.Type_CODE_BYTES:
+		return arrow.BinaryTypes.Binary
+	case runtimev1.Type_CODE_ARRAY:
+		if t.ArrayElementType != nil {
+			return arrow.ListOf(DataType(t.ArrayElementType))
+		}
+	case runtimev1.Type_CODE_STRUCT:
+		if t.StructType != nil {
+			return arrow.StructOf(Schema(t.StructType).Fields()...)
+		}
+	case runtimev1.Type_CODE_MAP:
+		if t.MapType != nil {
+			return arrow.MapOf(DataType(t.MapType.KeyType), DataType(t.MapType.ValueType))
+		}
+	}
+	return arrow.BinaryTypes.String
+}
+
+func appendValue(b array.Builder, t *runtimev1.Type, v any) error {
+	if v == nil {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		x, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to bool", v)
+		}
+		b.Append(x)
+	case *array.Int8Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(int8(x))
+	case *array.Int16Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(int16(x))
+	case *array.Int32Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(int32(x))
+	case *array.Int64Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(x)
+	case *array.Uint8Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(uint8(x))
+	case *array.Uint16Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(uint16(x))
+	case *array.Uint32Builder:
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(uint32(x))
+	case *array.Uint64Builder:
+		if x, ok := v.(uint64); ok {
+			b.Append(x)
+			return nil
+		}
+		x, err := toInt64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(uint64(x))
+	case *array.Float32Builder:
+		x, err := toFloat64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(float32(x))
+	case *array.Float64Builder:
+		x, err := toFloat64(v)
+		if err != nil {
+			return err
+		}
+		b.Append(x)
+	case *array.Decimal128Builder:
+		x, err := toDecimal(v, t.GetDecimalScale())
+		if err != nil {
+			return err
+		}
+		b.Append(decimal128.FromBigInt(x))
+	case *array.TimestampBuilder:
+		x, err := toTime(v)
+		if err != nil {
+			return err
+		}
+		b.Append(arrow.Timestamp(x.UnixMicro()))
+	case *array.Date32Builder:
+		x, err := toTime(v)
+		if err != nil {
+			return err
+		}
+		// Dates are stored as days since the Unix epoch
+		days := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		b.Append(arrow.Date32(days))
+	case *array.Time64Builder:
+		x, err := toTime(v)
+		if err != nil {
+			return err
+		}
+		midnight := time.Date(x.Year(), x.Month(), x.Day(), 0, 0, 0, 0, x.Location())
+		b.Append(arrow.Time64(x.Sub(midnight).Microseconds()))
+	case *array.BinaryBuilder:
+		switch x := v.(type) {
+		case []byte:
+			b.Append(x)
+		case string:
+			// Bytes are base64 encoded in protobuf structs
+			data, err := base64.StdEncoding.DecodeString(x)
+			if err != nil {
+				return err
+			}
+			b.Append(data)
+		default:
+			return fmt.Errorf("cannot convert %T to bytes", v)
+		}
+	case *array.StringBuilder:
+		x, err := toString(t, v)
+		if err != nil {
+			return err
+		}
+		b.Append(x)
+	case *array.MapBuilder:
+		m, ok := v.(duckdb.Map)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to map", v)
+		}
+		b.Append(true)
+		for k, v := range m {
+			if err := appendValue(b.KeyBuilder(), t.MapType.KeyType, k); err != nil {
+				return err
+			}
+			if err := appendValue(b.ItemBuilder(), t.MapType.ValueType, v); err != nil {
+				return err
+			}
+		}
+	case *array.ListBuilder:
+		xs, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to list", v)
+		}
+		b.Append(true)
+		for _, x := range xs {
+			if err := appendValue(b.ValueBuilder(), t.ArrayElementType, x); err != nil {
+				return err
+			}
+		}
+	case *array.StructBuilder:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to struct", v)
+		}
+		b.Append(true)
+		for i, f := range t.StructType.Fields {
+			if err := appendValue(b.FieldBuilder(i), f.Type, m[f.Name]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported arrow builder %T", b)
+	}
+
+	return nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint:
+		return int64(x), nil
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint64:
+		return int64(x), nil
+	case float64:
+		// Numbers are float64 in protobuf structs
+		return int64(x), nil
+	case *big.Int:
+		return x.Int64(), nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to integer", v)
+	}
+}
+
+func toFloat64(v any) (float64, error) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return f, nil
+	default:
+		i, err := toInt64(v)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %T to float", v)
+		}
+		return float64(i), nil
+	}
+}
+
+func toBigInt(v any) (*big.Int, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		return x, nil
+	case uint64:
+		return new(big.Int).SetUint64(x), nil
+	case float64:
+		// Numbers are float64 in protobuf structs
+		i, _ := big.NewFloat(x).Int(nil)
+		return i, nil
+	case string:
+		i, ok := new(big.Int).SetString(x, 10)
+		if !ok {
+			return nil, fmt.Errorf("cannot convert %q to integer", x)
+		}
+		return i, nil
+	default:
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return big.NewInt(i), nil
+	}
+}
+
+// toDecimal returns the unscaled value of a decimal with the given scale (for example 1.25 with scale 2 is 125).
+// Decimals are scanned into strings, so they don't lose precision.
+func toDecimal(v any, scale int32) (*big.Int, error) {
+	if scale == 0 {
+		return toBigInt(v)
+	}
+
+	r := new(big.Rat)
+	switch x := v.(type) {
+	case string:
+		if _, ok := r.SetString(x); !ok {
+			return nil, fmt.Errorf("cannot convert %q to decimal", x)
+		}
+	case float64:
+		r.SetFloat64(x)
+	default:
+		i, err := toBigInt(v)
+		if err != nil {
+			return nil, err
+		}
+		r.SetInt(i)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	return new(big.Int).Quo(r.Num(), r.Denom()), nil
+}
+
+func toTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		// Times are RFC3339 strings in protobuf structs
+		return time.Parse(time.RFC3339Nano, x)
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %T to time", v)
+	}
+}
+
+func toString(t *runtimev1.Type, v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		if t.GetCode() == runtimev1.Type_CODE_UUID && len(x) == 16 {
+			u, err := uuid.FromBytes(x)
+			if err != nil {
+				return "", err
+			}
+			return u.String(), nil
+		}
+		return string(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case fmt.Stringer:
+		return x.String(), nil
+	case duckdb.Interval:
+		return fmt.Sprintf("%d months %d days %d microseconds", x.Months, x.Days, x.Micros), nil
+	}
+
+	// Nested values without a known type are encoded as JSON
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,136 @@
+package arrowutil
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var schema = &runtimev1.StructType{
+	Fields: []*runtimev1.StructType_Field{
+		{Name: "id", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_INT32}},
+		{Name: "name", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING}},
+		{Name: "ts", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_TIMESTAMP}},
+		{Name: "big", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_INT128}},
+		{Name: "tags", Type: &runtimev1.Type{
+			Code:             runtimev1.Type_CODE_ARRAY,
+			ArrayElementType: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING},
+		}},
+	},
+}
+
+func TestEncoder(t *testing.T) {
+	ts := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	enc := NewEncoder(schema)
+	defer enc.Release()
+	enc.BatchSize = 2
+
+	require.NoError(t, enc.Append([]any{int32(1), "a", ts, big.NewInt(10), []any{"x", "y"}}))
+	require.NoError(t, enc.Append([]any{int32(2), nil, ts, nil, nil}))
+	require.NoError(t, enc.Append([]any{int32(3), "c", nil, big.NewInt(30), []any{}}))
+	require.Error(t, enc.Append([]any{int32(4)}))
+
+	data, err := enc.Finish()
+	require.NoError(t, err)
+
+	recs := readRecords(t, data)
+	require.Len(t, recs, 2)
+	require.Equal(t, int64(2), recs[0].NumRows())
+	require.Equal(t, int64(1), recs[1].NumRows())
+
+	rec := recs[0]
+	require.Equal(t, arrow.PrimitiveTypes.Int32, rec.Schema().Field(0).Type)
+	require.Equal(t, []int32{1, 2}, rec.Column(0).(*array.Int32).Int32Values())
+	require.Equal(t, "a", rec.Column(1).(*array.String).Value(0))
+	require.True(t, rec.Column(1).IsNull(1))
+	require.Equal(t, arrow.Timestamp(ts.UnixMicro()), rec.Column(2).(*array.Timestamp).Value(0))
+	require.Equal(t, int64(10), rec.Column(3).(*array.Decimal128).Value(0).BigInt().Int64())
+	require.True(t, rec.Column(3).IsNull(1))
+
+	tags := rec.Column(4).(*array.List)
+	require.Equal(t, 2, tags.ListValues().Len())
+	require.True(t, tags.IsNull(1))
+}
+
+func TestEncodeStructs(t *testing.T) {
+	row, err := structpb.NewStruct(map[string]any{
+		"id":   1.0,
+		"name": "a",
+		"ts":   "2022-01-01T12:00:00+01:00",
+		"big":  20.0,
+	})
+	require.NoError(t, err)
+
+	data, err := EncodeStructs(schema, []*structpb.Struct{row})
+	require.NoError(t, err)
+
+	recs := readRecords(t, data)
+	require.Len(t, recs, 1)
+	rec := recs[0]
+	require.Equal(t, int32(1), rec.Column(0).(*array.Int32).Value(0))
+	require.Equal(t, "a", rec.Column(1).(*array.String).Value(0))
+	require.Equal(t, arrow.Timestamp(time.Date(2022, 1, 1, 11, 0, 0, 0, time.UTC).UnixMicro()), rec.Column(2).(*array.Timestamp).Value(0))
+	require.Equal(t, int64(20), rec.Column(3).(*array.Decimal128).Value(0).BigInt().Int64())
+	require.True(t, rec.Column(4).IsNull(0))
+}
+
+func TestEncodeDecimals(t *testing.T) {
+	schema := &runtimev1.StructType{
+		Fields: []*runtimev1.StructType_Field{
+			{Name: "d", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_DECIMAL, DecimalPrecision: 18, DecimalScale: 2}},
+		},
+	}
+
+	enc := NewEncoder(schema)
+	defer enc.Release()
+	require.NoError(t, enc.Append([]any{"1234567890123456.78"}))
+	require.NoError(t, enc.Append([]any{1.5}))
+	require.Error(t, enc.Append([]any{"x"}))
+
+	data, err := enc.Finish()
+	require.NoError(t, err)
+
+	recs := readRecords(t, data)
+	require.Len(t, recs, 1)
+	col := recs[0].Column(0).(*array.Decimal128)
+	require.Equal(t, &arrow.Decimal128Type{Precision: 18, Scale: 2}, col.DataType())
+	require.Equal(t, int64(123456789012345678), col.Value(0).BigInt().Int64())
+	require.Equal(t, int64(150), col.Value(1).BigInt().Int64())
+}
+
+func TestDataType(t *testing.T) {
+	require.Equal(t, arrow.FixedWidthTypes.Date32, DataType(&runtimev1.Type{Code: runtimev1.Type_CODE_DATE}))
+	require.Equal(t, arrow.BinaryTypes.String, DataType(&runtimev1.Type{Code: runtimev1.Type_CODE_UUID}))
+	require.Equal(t, arrow.BinaryTypes.String, DataType(&runtimev1.Type{Code: runtimev1.Type_CODE_ARRAY}))
+	require.Equal(t, arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64), DataType(&runtimev1.Type{
+		Code: runtimev1.Type_CODE_MAP,
+		MapType: &runtimev1.MapType{
+			KeyType:   &runtimev1.Type{Code: runtimev1.Type_CODE_STRING},
+			ValueType: &runtimev1.Type{Code: runtimev1.Type_CODE_INT64},
+		},
+	}))
+}
+
+func readRecords(t *testing.T, data []byte) []array.Record {
+	r, err := ipc.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	defer r.Release()
+
+	var recs []array.Record
+	for r.Next() {
+		rec := r.Record()
+		rec.Retain()
+		t.Cleanup(rec.Release)
+		recs = append(recs, rec)
+	}
+	return recs
+}
@@ -0,0 +1,321 @@
+// Package exportutil writes query results to files in formats suitable for downloads.
+package exportutil
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/marcboeker/go-duckdb"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+)
+
+// ErrUnsupportedFormat is returned by NewWriter for unknown formats
+var ErrUnsupportedFormat = errors.New("exportutil: unsupported format")
+
+// Writer writes rows to a file
+type Writer interface {
+	// WriteRow writes a row of values in schema order (such as returned by sqlx.Rows.SliceScan)
+	WriteRow(row []any) error
+	// Close writes any buffered data. It doesn't close the underlying io.Writer.
+	Close() error
+}
+
+// Rows is the subset of *drivers.Result used by WriteRows
+type Rows interface {
+	Next() bool
+	SliceScan() ([]any, error)
+	Err() error
+}
+
+// NewWriter returns a writer for the given format. The header (if any) is written immediately.
+func NewWriter(format runtimev1.ExportFormat, w io.Writer, schema *runtimev1.StructType) (Writer, error) {
+	switch format {
+	case runtimev1.ExportFormat_EXPORT_FORMAT_CSV:
+		return newCSVWriter(w, schema)
+	case runtimev1.ExportFormat_EXPORT_FORMAT_JSON:
+		return newJSONWriter(w, schema)
+	case runtimev1.ExportFormat_EXPORT_FORMAT_XLSX:
+		return newXLSXWriter(w, schema)
+	case runtimev1.ExportFormat_EXPORT_FORMAT_PARQUET:
+		return newParquetWriter(w, schema)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// WriteRows writes all rows to w and returns the number of rows written
+func WriteRows(w Writer, rows Rows) (int64, error) {
+	var n int64
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return n, err
+		}
+		if err := w.WriteRow(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// FileExtension returns the file extension (without a dot) for the format
+func FileExtension(format runtimev1.ExportFormat) string {
+	switch format {
+	case runtimev1.ExportFormat_EXPORT_FORMAT_CSV:
+		return "csv"
+	case runtimev1.ExportFormat_EXPORT_FORMAT_PARQUET:
+		return "parquet"
+	case runtimev1.ExportFormat_EXPORT_FORMAT_JSON:
+		return "json"
+	case runtimev1.ExportFormat_EXPORT_FORMAT_XLSX:
+		return "xlsx"
+	default:
+		return ""
+	}
+}
+
+// ContentType returns the MIME type for the format
+func ContentType(format runtimev1.ExportFormat) string {
+	switch format {
+	case runtimev1.ExportFormat_EXPORT_FORMAT_CSV:
+		return "text/csv"
+	case runtimev1.ExportFormat_EXPORT_FORMAT_JSON:
+		return "application/json"
+	case runtimev1.ExportFormat_EXPORT_FORMAT_XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	schema *runtimev1.StructType
+	record []string
+}
+
+func newCSVWriter(w io.Writer, schema *runtimev1.StructType) (*csvWriter, error) {
+	cw := &csvWriter{
+		w:      csv.NewWriter(w),
+		schema: schema,
+		record: make([]string, len(schema.Fields)),
+	}
+	for i, f := range schema.Fields {
+		cw.record[i] = f.Name
+	}
+	if err := cw.w.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (w *csvWriter) WriteRow(row []any) error {
+	if len(row) != len(w.schema.Fields) {
+		return fmt.Errorf("exportutil: expected %d values, got %d", len(w.schema.Fields), len(row))
+	}
+	for i, v := range row {
+		s, err := formatValue(w.schema.Fields[i].Type, v)
+		if err != nil {
+			return err
+		}
+		w.record[i] = s
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// jsonWriter writes a JSON array with an object per row.
+// Each row is written on a separate line, so large files can also be processed line by line.
+type jsonWriter struct {
+	w      io.Writer
+	schema *runtimev1.StructType
+	keys   [][]byte
+	rows   int64
+}
+
+func newJSONWriter(w io.Writer, schema *runtimev1.StructType) (*jsonWriter, error) {
+	jw := &jsonWriter{w: w, schema: schema, keys: make([][]byte, len(schema.Fields))}
+	for i, f := range schema.Fields {
+		k, err := json.Marshal(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		jw.keys[i] = k
+	}
+	_, err := io.WriteString(w, "[")
+	return jw, err
+}
+
+func (w *jsonWriter) WriteRow(row []any) error {
+	if len(row) != len(w.schema.Fields) {
+		return fmt.Errorf("exportutil: expected %d values, got %d", len(w.schema.Fields), len(row))
+	}
+
+	buf := make([]byte, 0, 256)
+	if w.rows > 0 {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, "\n{"...)
+	for i, v := range row {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, w.keys[i]...)
+		buf = append(buf, ':')
+		jv, err := jsonValue(w.schema.Fields[i].Type, v)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(jv)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, data...)
+	}
+	buf = append(buf, '}')
+
+	w.rows++
+	_, err := w.w.Write(buf)
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	_, err := io.WriteString(w.w, "\n]\n")
+	return err
+}
+
+// formatValue formats a value as text (for CSV and XLSX)
+func formatValue(t *runtimev1.Type, v any) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return x, nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case *big.Int:
+		return x.String(), nil
+	case time.Time:
+		return formatTime(t, x), nil
+	case []byte:
+		return formatBytes(t, x), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(x), nil
+	}
+
+	// Nested values are formatted as JSON
+	jv, err := jsonValue(t, v)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(jv)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// jsonValue converts a value to a value that encoding/json can marshal
+func jsonValue(t *runtimev1.Type, v any) (any, error) {
+	switch x := v.(type) {
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return nil, nil
+		}
+	case float64:
+		// NaNs and Infs don't have a JSON representation
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, nil
+		}
+	case time.Time:
+		return formatTime(t, x), nil
+	case []byte:
+		return formatBytes(t, x), nil
+	case duckdb.Interval:
+		return map[string]any{"months": x.Months, "days": x.Days, "micros": x.Micros}, nil
+	case []any:
+		res := make([]any, len(x))
+		for i, e := range x {
+			var err error
+			res[i], err = jsonValue(t.GetArrayElementType(), e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case map[string]any:
+		res := make(map[string]any, len(x))
+		for k, e := range x {
+			var ft *runtimev1.Type
+			for _, f := range t.GetStructType().GetFields() {
+				if f.Name == k {
+					ft = f.Type
+					break
+				}
+			}
+			var err error
+			res[k], err = jsonValue(ft, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case duckdb.Map:
+		// JSON object keys must be strings
+		res := make(map[string]any, len(x))
+		for k, e := range x {
+			ks, err := formatValue(t.GetMapType().GetKeyType(), k)
+			if err != nil {
+				return nil, err
+			}
+			res[ks], err = jsonValue(t.GetMapType().GetValueType(), e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+	return v, nil
+}
+
+func formatTime(t *runtimev1.Type, v time.Time) string {
+	switch t.GetCode() {
+	case runtimev1.Type_CODE_DATE:
+		return v.Format("2006-01-02")
+	case runtimev1.Type_CODE_TIME:
+		return v.Format("15:04:05.999999")
+	default:
+		return v.Format(time.RFC3339Nano)
+	}
+}
+
+// formatBytes formats UUIDs in their canonical form and other binary values as base64
+func formatBytes(t *runtimev1.Type, v []byte) string {
+	if t.GetCode() == runtimev1.Type_CODE_UUID && len(v) == 16 {
+		if u, err := uuid.FromBytes(v); err == nil {
+			return u.String()
+		}
+	}
+	return base64.StdEncoding.EncodeToString(v)
+}
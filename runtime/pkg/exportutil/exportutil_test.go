@@ -0,0 +1,122 @@
+package exportutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+var schema = &runtimev1.StructType{
+	Fields: []*runtimev1.StructType_Field{
+		{Name: "id", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_INT64}},
+		{Name: "name", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_STRING}},
+		{Name: "day", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_DATE}},
+		{Name: "value", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_FLOAT64}},
+		{Name: "tags", Type: &runtimev1.Type{Code: runtimev1.Type_CODE_ARRAY}},
+	},
+}
+
+var day = time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+var rows = [][]any{
+	{int64(1), "a, \"b\"", day, 1.5, []any{"x"}},
+	{int64(2), nil, nil, math.NaN(), nil},
+}
+
+func TestCSV(t *testing.T) {
+	out := write(t, runtimev1.ExportFormat_EXPORT_FORMAT_CSV)
+	require.Equal(t, "id,name,day,value,tags\n1,\"a, \"\"b\"\"\",2022-01-02,1.5,\"[\"\"x\"\"]\"\n2,,,NaN,\n", out)
+}
+
+func TestJSON(t *testing.T) {
+	out := write(t, runtimev1.ExportFormat_EXPORT_FORMAT_JSON)
+
+	// Keys are in schema order
+	require.True(t, strings.HasPrefix(out, "[\n{\"id\":1,\"name\":"))
+
+	var res []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &res))
+	require.Len(t, res, 2)
+	require.Equal(t, "2022-01-02", res[0]["day"])
+	require.Equal(t, []any{"x"}, res[0]["tags"])
+	require.Nil(t, res[1]["value"])
+}
+
+func TestXLSX(t *testing.T) {
+	out := write(t, runtimev1.ExportFormat_EXPORT_FORMAT_XLSX)
+
+	zr, err := zip.NewReader(strings.NewReader(out), int64(len(out)))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(data)
+	}
+	require.Contains(t, files, "[Content_Types].xml")
+	require.Contains(t, files, "xl/workbook.xml")
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	require.Equal(t, 3, strings.Count(sheet, "<row>"))
+	require.Contains(t, sheet, `<c t="n"><v>1.5</v></c>`)
+	require.Contains(t, sheet, `<t xml:space="preserve">a, &#34;b&#34;</t>`)
+}
+
+func TestParquet(t *testing.T) {
+	out := write(t, runtimev1.ExportFormat_EXPORT_FORMAT_PARQUET)
+
+	pf, err := buffer.NewBufferFile([]byte(out))
+	require.NoError(t, err)
+	pr, err := reader.NewParquetColumnReader(pf, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+	require.Equal(t, int64(2), pr.GetNumRows())
+
+	// The file has the schema's column names
+	var names []string
+	for _, info := range pr.SchemaHandler.Infos[1:] {
+		names = append(names, info.ExName)
+	}
+	require.Equal(t, []string{"id", "name", "day", "value", "tags"}, names)
+
+	col := func(i int64) []any {
+		vals, _, _, err := pr.ReadColumnByIndex(i, 2)
+		require.NoError(t, err)
+		return vals
+	}
+	require.Equal(t, []any{int64(1), int64(2)}, col(0))
+	require.Equal(t, []any{"a, \"b\"", nil}, col(1))
+	require.Equal(t, []any{int32(18994), nil}, col(2))
+	require.Equal(t, 1.5, col(3)[0])
+	require.Equal(t, []any{`["x"]`, nil}, col(4))
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := NewWriter(runtimev1.ExportFormat_EXPORT_FORMAT_UNSPECIFIED, io.Discard, schema)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func write(t *testing.T, format runtimev1.ExportFormat) string {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, schema)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.WriteRow(row))
+	}
+	require.Error(t, w.WriteRow([]any{1}))
+	require.NoError(t, w.Close())
+	return buf.String()
+}
@@ -0,0 +1,216 @@
+package exportutil
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRowGroupSize is the max size of the row groups buffered in memory before they're written
+const parquetRowGroupSize = 16 * 1024 * 1024
+
+// parquetWriter writes a Parquet file with a flat schema.
+// Numbers, booleans, dates and timestamps are written as the corresponding Parquet types.
+// Other values (such as decimals, huge integers and nested values) are written as text, formatted like in CSV exports.
+type parquetWriter struct {
+	pw     *writer.CSVWriter
+	schema *runtimev1.StructType
+	kinds  []parquet.Type
+}
+
+func newParquetWriter(w io.Writer, schema *runtimev1.StructType) (*parquetWriter, error) {
+	// The columns are declared with placeholder names, since the metadata syntax can't express arbitrary column names.
+	// The real names are set as the external names, which are used in the written file.
+	md := make([]string, len(schema.Fields))
+	kinds := make([]parquet.Type, len(schema.Fields))
+	for i, f := range schema.Fields {
+		kind, convertedType := parquetType(f.Type)
+		kinds[i] = kind
+		md[i] = fmt.Sprintf("name=col_%d, type=%s, repetitiontype=OPTIONAL", i, kind)
+		if convertedType != "" {
+			md[i] += ", convertedtype=" + convertedType
+		}
+	}
+
+	pw, err := writer.NewCSVWriterFromWriter(md, w, 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+	for i, f := range schema.Fields {
+		pw.SchemaHandler.Infos[i+1].ExName = f.Name
+	}
+	pw.SchemaHandler.CreateInExMap()
+
+	return &parquetWriter{
+		pw:     pw,
+		schema: schema,
+		kinds:  kinds,
+	}, nil
+}
+
+func (w *parquetWriter) WriteRow(row []any) error {
+	if len(row) != len(w.schema.Fields) {
+		return fmt.Errorf("exportutil: expected %d values, got %d", len(w.schema.Fields), len(row))
+	}
+
+	// The writer buffers records until a row group is flushed, so each row needs its own slice
+	rec := make([]any, len(row))
+	for i, v := range row {
+		pv, err := parquetValue(w.kinds[i], w.schema.Fields[i].Type, v)
+		if err != nil {
+			return fmt.Errorf("exportutil: column %q: %w", w.schema.Fields[i].Name, err)
+		}
+		rec[i] = pv
+	}
+	return w.pw.Write(rec)
+}
+
+func (w *parquetWriter) Close() error {
+	return w.pw.WriteStop()
+}
+
+// parquetType returns the Parquet physical type and converted type (if any) for a column
+func parquetType(t *runtimev1.Type) (parquet.Type, string) {
+	switch t.GetCode() {
+	case runtimev1.Type_CODE_BOOL:
+		return parquet.Type_BOOLEAN, ""
+	case runtimev1.Type_CODE_INT8, runtimev1.Type_CODE_INT16, runtimev1.Type_CODE_INT32, runtimev1.Type_CODE_UINT8, runtimev1.Type_CODE_UINT16:
+		return parquet.Type_INT32, ""
+	case runtimev1.Type_CODE_INT64, runtimev1.Type_CODE_UINT32:
+		return parquet.Type_INT64, ""
+	case runtimev1.Type_CODE_FLOAT32:
+		return parquet.Type_FLOAT, ""
+	case runtimev1.Type_CODE_FLOAT64:
+		return parquet.Type_DOUBLE, ""
+	case runtimev1.Type_CODE_TIMESTAMP:
+		return parquet.Type_INT64, "TIMESTAMP_MICROS"
+	case runtimev1.Type_CODE_DATE:
+		return parquet.Type_INT32, "DATE"
+	case runtimev1.Type_CODE_BYTES:
+		return parquet.Type_BYTE_ARRAY, ""
+	default:
+		return parquet.Type_BYTE_ARRAY, "UTF8"
+	}
+}
+
+// parquetValue converts a value to the Go type that the writer expects for the column's physical type
+func parquetValue(kind parquet.Type, t *runtimev1.Type, v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case parquet.Type_BOOLEAN:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v for a boolean", v)
+		}
+		return b, nil
+	case parquet.Type_INT32:
+		if t.GetCode() == runtimev1.Type_CODE_DATE {
+			ts, err := parquetTime(v)
+			if err != nil {
+				return nil, err
+			}
+			return int32(math.Floor(float64(ts.Unix()) / 86400)), nil
+		}
+		n, err := parquetInt(v)
+		if err != nil {
+			return nil, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("value %d out of range for a 32-bit integer", n)
+		}
+		return int32(n), nil
+	case parquet.Type_INT64:
+		if t.GetCode() == runtimev1.Type_CODE_TIMESTAMP {
+			ts, err := parquetTime(v)
+			if err != nil {
+				return nil, err
+			}
+			return ts.UnixMicro(), nil
+		}
+		return parquetInt(v)
+	case parquet.Type_FLOAT:
+		f, err := parquetFloat(v)
+		return float32(f), err
+	case parquet.Type_DOUBLE:
+		return parquetFloat(v)
+	}
+
+	// Byte arrays are passed as strings
+	if b, ok := v.([]byte); ok && t.GetCode() == runtimev1.Type_CODE_BYTES {
+		return string(b), nil
+	}
+	return formatValue(t, v)
+}
+
+func parquetInt(v any) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint64:
+		if x > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d out of range for a 64-bit integer", x)
+		}
+		return int64(x), nil
+	case *big.Int:
+		if !x.IsInt64() {
+			return 0, fmt.Errorf("value %s out of range for a 64-bit integer", x.String())
+		}
+		return x.Int64(), nil
+	case float64:
+		// Some drivers return all numbers as floats
+		if x != math.Trunc(x) || x < math.MinInt64 || x > math.MaxInt64 {
+			return 0, fmt.Errorf("unexpected value %v for an integer", x)
+		}
+		return int64(x), nil
+	}
+	return 0, fmt.Errorf("unexpected value %v for an integer", v)
+}
+
+func parquetFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case float32:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	}
+	n, err := parquetInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected value %v for a float", v)
+	}
+	return float64(n), nil
+}
+
+func parquetTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		// Some drivers return timestamps as strings
+		return time.Parse(time.RFC3339Nano, x)
+	}
+	return time.Time{}, fmt.Errorf("unexpected value %v for a timestamp", v)
+}
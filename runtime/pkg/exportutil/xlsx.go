@@ -0,0 +1,172 @@
+package exportutil
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+)
+
+// xlsxMaxRows is the max number of rows in an Excel worksheet (including the header)
+const xlsxMaxRows = 1048576
+
+// xlsxWriter writes a workbook with a single worksheet.
+// The worksheet is streamed into the zip archive, so memory use doesn't depend on the number of rows.
+// Cells use inline strings instead of a shared strings table for the same reason.
+type xlsxWriter struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	schema *runtimev1.StructType
+	rows   int
+}
+
+func newXLSXWriter(w io.Writer, schema *runtimev1.StructType) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	xw := &xlsxWriter{
+		zw:     zw,
+		sheet:  bufio.NewWriter(f),
+		schema: schema,
+	}
+
+	_, err = xw.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(schema.Fields))
+	for i, f := range schema.Fields {
+		header[i] = f.Name
+	}
+	if err := xw.writeRow(header, nil); err != nil {
+		return nil, err
+	}
+
+	return xw, nil
+}
+
+func (w *xlsxWriter) WriteRow(row []any) error {
+	if len(row) != len(w.schema.Fields) {
+		return fmt.Errorf("exportutil: expected %d values, got %d", len(w.schema.Fields), len(row))
+	}
+	if w.rows >= xlsxMaxRows {
+		return fmt.Errorf("exportutil: XLSX files can't have more than %d rows", xlsxMaxRows)
+	}
+	return w.writeRow(row, w.schema)
+}
+
+func (w *xlsxWriter) writeRow(row []any, schema *runtimev1.StructType) error {
+	w.rows++
+	w.sheet.WriteString("<row>")
+	for i, v := range row {
+		var t *runtimev1.Type
+		if schema != nil {
+			t = schema.Fields[i].Type
+		}
+		if err := w.writeCell(t, v); err != nil {
+			return err
+		}
+	}
+	_, err := w.sheet.WriteString("</row>")
+	return err
+}
+
+func (w *xlsxWriter) writeCell(t *runtimev1.Type, v any) error {
+	var num string
+	switch x := v.(type) {
+	case nil:
+		_, err := w.sheet.WriteString("<c/>")
+		return err
+	case bool:
+		if x {
+			_, err := w.sheet.WriteString(`<c t="b"><v>1</v></c>`)
+			return err
+		}
+		_, err := w.sheet.WriteString(`<c t="b"><v>0</v></c>`)
+		return err
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		num = fmt.Sprint(x)
+	case float32:
+		if !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0) {
+			num = strconv.FormatFloat(float64(x), 'g', -1, 32)
+		}
+	case float64:
+		if !math.IsNaN(x) && !math.IsInf(x, 0) {
+			num = strconv.FormatFloat(x, 'g', -1, 64)
+		}
+	case *big.Int:
+		if x.IsInt64() {
+			num = x.String()
+		}
+	}
+
+	if num != "" {
+		_, err := fmt.Fprintf(w.sheet, `<c t="n"><v>%s</v></c>`, num)
+		return err
+	}
+
+	s, err := formatValue(t, v)
+	if err != nil {
+		return err
+	}
+	w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	if err := xml.EscapeText(w.sheet, []byte(s)); err != nil {
+		return err
+	}
+	_, err = w.sheet.WriteString(`</t></is></c>`)
+	return err
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := w.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+
+	for _, f := range xlsxStaticFiles {
+		fw, err := w.zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, xml.Header+f.content); err != nil {
+			return err
+		}
+	}
+
+	return w.zw.Close()
+}
+
+// xlsxStaticFiles are the parts of the workbook that don't depend on the data
+var xlsxStaticFiles = []struct {
+	name    string
+	content string
+}{
+	{
+		name:    "[Content_Types].xml",
+		content: `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`,
+	},
+	{
+		name:    "_rels/.rels",
+		content: `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`,
+	},
+	{
+		name:    "xl/workbook.xml",
+		content: `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+	},
+	{
+		name:    "xl/_rels/workbook.xml.rels",
+		content: `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+	},
+}
@@ -4,7 +4,9 @@ import (
 	"container/heap"
 	"context"
 	"errors"
+	"sort"
 	"sync"
+	"time"
 )
 
 // ErrStopped is returned for outstanding items when the queue is stopped.
@@ -13,29 +15,66 @@ var ErrStopped = errors.New("priorityqueue: stopped")
 // Handler is a callback called by PriorityWorker to process an item.
 type Handler[V any] func(context.Context, V) error
 
+// Options configures a PriorityWorker.
+type Options[V any] struct {
+	// Concurrency is the max number of items processed at the same time (defaults to 1).
+	Concurrency int
+	// AgingInterval enables priority aging: an item's priority is increased by one for every AgingInterval it has waited
+	// in the queue, which prevents low priority items from starving. Aging is disabled if zero.
+	AgingInterval time.Duration
+	// FairKey enables fair queuing between keys (such as instance or user IDs). When the next items of several keys
+	// have the same (aged) priority, the item of the key with the fewest running items is processed first,
+	// and after that the item of the key that was least recently served.
+	FairKey func(V) string
+}
+
 // PriorityWorker implements a concurrency-safe worker that prioritizes work
 // using a priority queue.
 type PriorityWorker[V any] struct {
 	handler        Handler[V]
+	opts           Options[V]
+	now            func() time.Time
 	enqueueJobCh   chan *item[V]
 	cancelJobCh    chan *item[V]
+	finishedJobCh  chan *item[V]
 	paused         bool
 	pausedToggleCh chan bool
+	jobsCh         chan chan []JobInfo[V]
+	statsCh        chan chan Stats
 	stopped        bool
 	stoppedMu      sync.RWMutex
 	stopDoneCh     chan struct{}
 }
 
 // New creates a new PriorityWorker that calls the provided handler for every
-// item submitted using Process. It starts a goroutine for the worker's event
-// loop. You must call Stop on the worker when you're done using it.
+// item submitted using Process. It processes one item at a time.
+// It starts a goroutine for the worker's event loop. You must call Stop on the worker when you're done using it.
 func New[V any](handler Handler[V]) *PriorityWorker[V] {
+	return NewWithOptions(handler, Options[V]{})
+}
+
+// NewWithOptions creates a new PriorityWorker like New, but with support for concurrency, aging and fair queuing.
+func NewWithOptions[V any](handler Handler[V], opts Options[V]) *PriorityWorker[V] {
+	return newWorker(handler, opts, time.Now)
+}
+
+// newWorker creates a PriorityWorker that uses now to get the current time (overridden in tests)
+func newWorker[V any](handler Handler[V], opts Options[V], now func() time.Time) *PriorityWorker[V] {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
 	pw := &PriorityWorker[V]{
 		handler:        handler,
+		opts:           opts,
+		now:            now,
 		enqueueJobCh:   make(chan *item[V]),
 		cancelJobCh:    make(chan *item[V]),
+		finishedJobCh:  make(chan *item[V]),
 		paused:         false,
 		pausedToggleCh: make(chan bool),
+		jobsCh:         make(chan chan []JobInfo[V]),
+		statsCh:        make(chan chan Stats),
 		stopped:        false,
 		stoppedMu:      sync.RWMutex{},
 		stopDoneCh:     make(chan struct{}),
@@ -50,11 +89,15 @@ func New[V any](handler Handler[V]) *PriorityWorker[V] {
 // It returns early if ctx is cancelled or the worker is stopped.
 func (pw *PriorityWorker[V]) Process(ctx context.Context, priority int, val V) error {
 	job := &item[V]{
-		ctx:      ctx,
-		doneCh:   make(chan struct{}),
-		val:      val,
-		priority: priority,
-		index:    -1,
+		ctx:        ctx,
+		doneCh:     make(chan struct{}),
+		val:        val,
+		enqueuedOn: pw.now(),
+		priority:   priority,
+		index:      -1,
+	}
+	if pw.opts.FairKey != nil {
+		job.key = pw.opts.FairKey(val)
 	}
 
 	pw.stoppedMu.RLock()
@@ -68,12 +111,14 @@ func (pw *PriorityWorker[V]) Process(ctx context.Context, priority int, val V) e
 
 	select {
 	case <-job.doneCh:
+		return job.err
 	case <-ctx.Done():
-		pw.cancelJobCh <- job
-		job.err = context.Canceled
+		select {
+		case pw.cancelJobCh <- job:
+		case <-pw.stopDoneCh:
+		}
+		return context.Canceled
 	}
-
-	return job.err
 }
 
 // Pause keeps the queue open for new jobs, but won't process them until Unpause is called.
@@ -87,7 +132,76 @@ func (pw *PriorityWorker[V]) Unpause() {
 	pw.pausedToggleCh <- false
 }
 
-// Stop cancels all jobs that haven't started, and returns once the current job has finished.
+// JobInfo describes a job that is running or waiting in the queue.
+type JobInfo[V any] struct {
+	Value    V
+	Priority int
+	// Key is the job's fair queuing key (empty if fair queuing is disabled)
+	Key string
+	// Position is the job's position in the queue, starting at 1 (0 means the job is running)
+	Position   int
+	EnqueuedOn time.Time
+	// StartedOn is zero for jobs that haven't started
+	StartedOn time.Time
+}
+
+// Jobs returns the running jobs (in the order they started) followed by the queued jobs in the order they will be processed.
+// It returns nil if the worker has been stopped.
+func (pw *PriorityWorker[V]) Jobs() []JobInfo[V] {
+	resCh := make(chan []JobInfo[V], 1)
+
+	pw.stoppedMu.RLock()
+	if pw.stopped {
+		pw.stoppedMu.RUnlock()
+		return nil
+	}
+	pw.jobsCh <- resCh
+	pw.stoppedMu.RUnlock()
+
+	return <-resCh
+}
+
+// Stats describes the worker's queue and how long jobs have waited in it.
+type Stats struct {
+	// Running is the number of jobs being processed
+	Running int
+	// Queued is the number of jobs waiting in the queue (the queue depth)
+	Queued int
+	// QueuedByKey is the number of queued jobs for each fair queuing key (nil if fair queuing is disabled)
+	QueuedByKey map[string]int
+	// OldestWait is how long the longest waiting queued job has waited so far
+	OldestWait time.Duration
+	// Started is the number of jobs that have been dequeued for processing
+	Started int64
+	// TotalWait and MaxWait are computed over the time started jobs spent in the queue
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+// AvgWait returns the average time started jobs spent in the queue.
+func (s Stats) AvgWait() time.Duration {
+	if s.Started == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Started)
+}
+
+// Stats returns the current stats for the worker. It returns zero stats if the worker has been stopped.
+func (pw *PriorityWorker[V]) Stats() Stats {
+	resCh := make(chan Stats, 1)
+
+	pw.stoppedMu.RLock()
+	if pw.stopped {
+		pw.stoppedMu.RUnlock()
+		return Stats{}
+	}
+	pw.statsCh <- resCh
+	pw.stoppedMu.RUnlock()
+
+	return <-resCh
+}
+
+// Stop cancels all jobs that haven't started, and returns once the running jobs have finished.
 func (pw *PriorityWorker[V]) Stop() {
 	pw.stoppedMu.Lock()
 	pw.stopped = true
@@ -97,10 +211,28 @@ func (pw *PriorityWorker[V]) Stop() {
 }
 
 func (pw *PriorityWorker[V]) work() {
-	pq := priorityQueue[V]{}
-	heap.Init(&pq)
+	q := newQueue[V](pw.opts.AgingInterval)
+	var running []*item[V]
+	var stats Stats
+
+	// startJobs starts queued jobs until the concurrency limit is reached
+	startJobs := func() {
+		for !pw.paused && len(running) < pw.opts.Concurrency && q.len() > 0 {
+			now := pw.now()
+			job := q.pop(now)
+			job.startedOn = now
+			running = append(running, job)
 
-	var currentDoneCh chan struct{}
+			wait := now.Sub(job.enqueuedOn)
+			stats.Started++
+			stats.TotalWait += wait
+			if wait > stats.MaxWait {
+				stats.MaxWait = wait
+			}
+
+			go pw.handle(job)
+		}
+	}
 
 	for {
 		select {
@@ -108,53 +240,72 @@ func (pw *PriorityWorker[V]) work() {
 			// If enqueueJobCh was stopped, it means it's time to stop
 			if !ok {
 				// Cancel all enqueued items
-				for _, job := range pq {
+				for _, job := range q.items() {
 					job.err = ErrStopped
 					close(job.doneCh)
 				}
-				// Let the current item finish
-				if currentDoneCh != nil {
-					<-currentDoneCh
+				// Let the running items finish
+				for len(running) > 0 {
+					job := <-pw.finishedJobCh
+					running = removeItem(running, job)
 				}
 				// Exit
 				close(pw.stopDoneCh)
 				return
 			}
 
-			// Process or enqueue item
-			if !pw.paused && currentDoneCh == nil {
-				// If we're currently idle, we process the item directly
-				currentDoneCh = job.doneCh
-				go pw.handle(job)
-			} else {
-				// Else we add it to the priority queue
-				heap.Push(&pq, job)
-			}
+			// Enqueue the item and start it if there's capacity
+			q.push(job)
+			startJobs()
 		case job := <-pw.cancelJobCh:
-			// Remove item from heap if it hasn't already been dequeued
-			if job.index >= 0 {
-				heap.Remove(&pq, job.index)
-			}
-		case <-currentDoneCh:
-			if !pw.paused && pq.Len() > 0 {
-				// If the queue isn't empty, start a new item
-				job := heap.Pop(&pq).(*item[V])
-				currentDoneCh = job.doneCh
-				go pw.handle(job)
-			} else {
-				// Else, go idle until next query
-				currentDoneCh = nil
-			}
+			// Remove item from queue if it hasn't already been dequeued
+			q.remove(job)
+		case job := <-pw.finishedJobCh:
+			running = removeItem(running, job)
+			q.finished(job)
+			startJobs()
 		case p := <-pw.pausedToggleCh:
 			pw.paused = p
-			if !pw.paused && currentDoneCh == nil && pq.Len() > 0 {
-				// We just unpaused, we're idle, and the queue is not empty – start the next job
-				job := heap.Pop(&pq).(*item[V])
-				currentDoneCh = job.doneCh
-				go pw.handle(job)
-			}
+			startJobs()
+		case resCh := <-pw.jobsCh:
+			resCh <- jobInfos(running, q.ordered(pw.now()))
+		case resCh := <-pw.statsCh:
+			resCh <- pw.stats(stats, running, q)
+		}
+	}
+}
+
+// stats completes the cumulative stats with the current state of the queue
+func (pw *PriorityWorker[V]) stats(s Stats, running []*item[V], q *queue[V]) Stats {
+	s.Running = len(running)
+	s.Queued = q.len()
+	if pw.opts.FairKey != nil {
+		s.QueuedByKey = make(map[string]int)
+	}
+
+	now := pw.now()
+	for _, job := range q.items() {
+		if wait := now.Sub(job.enqueuedOn); wait > s.OldestWait {
+			s.OldestWait = wait
+		}
+		if s.QueuedByKey != nil {
+			s.QueuedByKey[job.key]++
 		}
 	}
+
+	return s
+}
+
+// jobInfos describes the running jobs and the queued jobs in the order they will be processed
+func jobInfos[V any](running, queued []*item[V]) []JobInfo[V] {
+	res := make([]JobInfo[V], 0, len(running)+len(queued))
+	for _, job := range running {
+		res = append(res, job.info(0))
+	}
+	for i, job := range queued {
+		res = append(res, job.info(i+1))
+	}
+	return res
 }
 
 func (pw *PriorityWorker[V]) handle(job *item[V]) {
@@ -162,14 +313,12 @@ func (pw *PriorityWorker[V]) handle(job *item[V]) {
 	// (Unlikely to happen given other safeguards)
 	if job.ctx.Err() != nil {
 		job.err = job.ctx.Err()
-		close(job.doneCh)
-		return
+	} else {
+		job.err = pw.handler(job.ctx, job.val)
 	}
 
-	// Run
-	err := pw.handler(job.ctx, job.val)
-	job.err = err
 	close(job.doneCh)
+	pw.finishedJobCh <- job
 }
 
 // item represents a job enqueued in priorityQueue.
@@ -180,41 +329,249 @@ type item[V any] struct {
 	val    V               // value the job should work on
 	err    error           // err to return for the job
 
+	// Introspection related fields
+	enqueuedOn time.Time
+	startedOn  time.Time
+
 	// Priority queue related fields
 	priority int
+	key      string
+	seq      uint64
 	index    int
 }
 
+func (itm *item[V]) info(position int) JobInfo[V] {
+	return JobInfo[V]{
+		Value:      itm.val,
+		Priority:   itm.priority,
+		Key:        itm.key,
+		Position:   position,
+		EnqueuedOn: itm.enqueuedOn,
+		StartedOn:  itm.startedOn,
+	}
+}
+
+func removeItem[V any](items []*item[V], itm *item[V]) []*item[V] {
+	for i, x := range items {
+		if x == itm {
+			return append(items[:i], items[i+1:]...)
+		}
+	}
+	return items
+}
+
+// queue holds the queued items in a priority queue per fair queuing key.
+// If fair queuing is disabled, all items have the empty key.
+type queue[V any] struct {
+	agingInterval time.Duration
+	keys          map[string]*keyQueue[V]
+	// seq is incremented for every item pushed and every item popped. It's used to order items with the same
+	// priority by arrival, and to track when keys were last served.
+	seq uint64
+}
+
+// keyQueue holds the queued items for a fair queuing key
+type keyQueue[V any] struct {
+	pq         priorityQueue[V]
+	running    int
+	lastServed uint64
+}
+
+func newQueue[V any](agingInterval time.Duration) *queue[V] {
+	return &queue[V]{
+		agingInterval: agingInterval,
+		keys:          make(map[string]*keyQueue[V]),
+	}
+}
+
+func (q *queue[V]) len() int {
+	n := 0
+	for _, kq := range q.keys {
+		n += kq.pq.Len()
+	}
+	return n
+}
+
+func (q *queue[V]) push(itm *item[V]) {
+	kq, ok := q.keys[itm.key]
+	if !ok {
+		kq = &keyQueue[V]{pq: priorityQueue[V]{agingInterval: q.agingInterval}}
+		q.keys[itm.key] = kq
+	}
+
+	q.seq++
+	itm.seq = q.seq
+	heap.Push(&kq.pq, itm)
+}
+
+// pop removes and returns the next item to process. It must only be called if the queue is not empty.
+func (q *queue[V]) pop(now time.Time) *item[V] {
+	key := q.next(q.keys, now)
+	kq := q.keys[key]
+
+	q.seq++
+	kq.running++
+	kq.lastServed = q.seq
+
+	return heap.Pop(&kq.pq).(*item[V])
+}
+
+// remove removes an item from the queue if it hasn't already been dequeued
+func (q *queue[V]) remove(itm *item[V]) {
+	if itm.index < 0 {
+		return
+	}
+	kq := q.keys[itm.key]
+	heap.Remove(&kq.pq, itm.index)
+	q.cleanup(itm.key)
+}
+
+// finished must be called when an item returned from pop has been processed
+func (q *queue[V]) finished(itm *item[V]) {
+	if kq, ok := q.keys[itm.key]; ok {
+		kq.running--
+		q.cleanup(itm.key)
+	}
+}
+
+// cleanup forgets keys that have no queued or running items
+func (q *queue[V]) cleanup(key string) {
+	kq := q.keys[key]
+	if kq.pq.Len() == 0 && kq.running == 0 {
+		delete(q.keys, key)
+	}
+}
+
+// next returns the key of the next item to process.
+// It compares the first item of every key by aged priority, and uses fairness to break ties.
+func (q *queue[V]) next(keys map[string]*keyQueue[V], now time.Time) string {
+	var best string
+	var bestKQ *keyQueue[V]
+	var bestPriority int
+	for key, kq := range keys {
+		if kq.pq.Len() == 0 {
+			continue
+		}
+		head := kq.pq.items[0]
+		priority := q.agedPriority(head, now)
+
+		if bestKQ != nil {
+			if priority != bestPriority {
+				if priority < bestPriority {
+					continue
+				}
+			} else if kq.running != bestKQ.running {
+				if kq.running > bestKQ.running {
+					continue
+				}
+			} else if kq.lastServed != bestKQ.lastServed {
+				if kq.lastServed > bestKQ.lastServed {
+					continue
+				}
+			} else if head.seq > bestKQ.pq.items[0].seq {
+				continue
+			}
+		}
+
+		best, bestKQ, bestPriority = key, kq, priority
+	}
+	return best
+}
+
+// agedPriority returns an item's priority including the priority it has gained from waiting
+func (q *queue[V]) agedPriority(itm *item[V], now time.Time) int {
+	if q.agingInterval <= 0 {
+		return itm.priority
+	}
+	return itm.priority + int(now.Sub(itm.enqueuedOn)/q.agingInterval)
+}
+
+// items returns the queued items in no particular order
+func (q *queue[V]) items() []*item[V] {
+	var res []*item[V]
+	for _, kq := range q.keys {
+		res = append(res, kq.pq.items...)
+	}
+	return res
+}
+
+// ordered returns the queued items in the order they will be processed if no other items are enqueued and time stands still at now.
+// It simulates calls to pop on a copy of the queue.
+func (q *queue[V]) ordered(now time.Time) []*item[V] {
+	keys := make(map[string]*keyQueue[V], len(q.keys))
+	n := 0
+	for key, kq := range q.keys {
+		cp := &keyQueue[V]{
+			pq:         priorityQueue[V]{agingInterval: q.agingInterval, items: make([]*item[V], kq.pq.Len())},
+			running:    kq.running,
+			lastServed: kq.lastServed,
+		}
+		copy(cp.pq.items, kq.pq.items)
+		sort.Slice(cp.pq.items, func(i, j int) bool { return cp.pq.Less(i, j) })
+		keys[key] = cp
+		n += cp.pq.Len()
+	}
+
+	seq := q.seq
+	res := make([]*item[V], 0, n)
+	for len(res) < n {
+		key := q.next(keys, now)
+		kq := keys[key]
+		seq++
+		kq.running++
+		kq.lastServed = seq
+		res = append(res, kq.pq.items[0])
+		kq.pq.items = kq.pq.items[1:]
+	}
+	return res
+}
+
 // priorityQueue implements heap.Interface to serve as a priority queue of jobs
 // See the docs for details: https://pkg.go.dev/container/heap#example-package-PriorityQueue
-type priorityQueue[V any] []*item[V]
+type priorityQueue[V any] struct {
+	items         []*item[V]
+	agingInterval time.Duration
+}
 
-func (pq priorityQueue[V]) Len() int { return len(pq) }
+func (pq priorityQueue[V]) Len() int { return len(pq.items) }
 
 func (pq priorityQueue[V]) Less(i, j int) bool {
-	// We use greater than here so that Pop gives us the highest priority item (not lowest)
-	return pq[i].priority > pq[j].priority
+	a, b := pq.items[i], pq.items[j]
+	if pq.agingInterval > 0 {
+		// With aging, a is ahead of b if a.priority + (now - a.enqueuedOn)/interval > b.priority + (now - b.enqueuedOn)/interval,
+		// which doesn't depend on now, so the heap stays valid as time passes.
+		diff := time.Duration(a.priority-b.priority) * pq.agingInterval
+		since := a.enqueuedOn.Sub(b.enqueuedOn)
+		if diff != since {
+			return diff > since
+		}
+	} else if a.priority != b.priority {
+		// We use greater than here so that Pop gives us the highest priority item (not lowest)
+		return a.priority > b.priority
+	}
+	// Items with the same priority are processed in the order they were enqueued
+	return a.seq < b.seq
 }
 
 func (pq priorityQueue[V]) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
 }
 
 func (pq *priorityQueue[V]) Push(x any) {
-	n := len(*pq)
+	n := len(pq.items)
 	itm := x.(*item[V])
 	itm.index = n
-	*pq = append(*pq, itm)
+	pq.items = append(pq.items, itm)
 }
 
 func (pq *priorityQueue[V]) Pop() any {
-	old := *pq
+	old := pq.items
 	n := len(old)
 	itm := old[n-1]
 	old[n-1] = nil // avoid memory leak
 	itm.index = -1 // for safety
-	*pq = old[0 : n-1]
+	pq.items = old[0 : n-1]
 	return itm
 }
@@ -2,6 +2,7 @@ package priorityworker
 
 import (
 	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -27,8 +28,10 @@ func TestPriorityQueue(t *testing.T) {
 		})
 	}
 
-	// give the queue plenty of time to fill up, then unpause
-	time.Sleep(100 * time.Millisecond)
+	// wait for the queue to fill up, then unpause
+	require.Eventually(t, func() bool {
+		return len(pw.Jobs()) == n
+	}, time.Second, time.Millisecond)
 	pw.Unpause()
 
 	err := g.Wait()
@@ -120,3 +123,179 @@ func TestStop(t *testing.T) {
 	require.Greater(t, len(results), 0)
 	require.Less(t, len(results), n)
 }
+
+func TestJobs(t *testing.T) {
+	release := make(chan struct{})
+	pw := New(func(ctx context.Context, i int) error {
+		<-release
+		return nil
+	})
+	defer pw.Stop()
+
+	var g errgroup.Group
+	for _, p := range []int{0, 1, 3, 2} {
+		enqueue(t, &g, pw, p, p)
+	}
+
+	jobs := pw.Jobs()
+	require.Len(t, jobs, 4)
+	for i, v := range []int{0, 3, 2, 1} {
+		require.Equal(t, v, jobs[i].Value)
+		require.Equal(t, i, jobs[i].Position)
+	}
+	require.False(t, jobs[0].StartedOn.IsZero())
+	require.True(t, jobs[1].StartedOn.IsZero())
+
+	close(release)
+	require.NoError(t, g.Wait())
+	require.Empty(t, pw.Jobs())
+}
+
+func TestConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	pw := NewWithOptions(func(ctx context.Context, i int) error {
+		<-release
+		return nil
+	}, Options[int]{Concurrency: 3})
+	defer pw.Stop()
+
+	var g errgroup.Group
+	for i := 0; i < 5; i++ {
+		enqueue(t, &g, pw, i, i)
+	}
+
+	stats := pw.Stats()
+	require.Equal(t, 3, stats.Running)
+	require.Equal(t, 2, stats.Queued)
+
+	jobs := pw.Jobs()
+	require.Len(t, jobs, 5)
+	for i, v := range []int{0, 1, 2, 4, 3} {
+		require.Equal(t, v, jobs[i].Value)
+	}
+	require.Equal(t, []int{0, 0, 0, 1, 2}, []int{jobs[0].Position, jobs[1].Position, jobs[2].Position, jobs[3].Position, jobs[4].Position})
+
+	close(release)
+	require.NoError(t, g.Wait())
+	require.Equal(t, int64(5), pw.Stats().Started)
+}
+
+func TestAging(t *testing.T) {
+	c := &clock{t: time.Unix(0, 0)}
+	results := make(chan int, 3)
+	pw := newWorker(func(ctx context.Context, i int) error {
+		results <- i
+		return nil
+	}, Options[int]{AgingInterval: time.Second}, c.now)
+	defer pw.Stop()
+	pw.Pause()
+
+	// The low priority job has waited long enough to overtake the first high priority job, but not the second
+	var g errgroup.Group
+	enqueue(t, &g, pw, 0, 0)
+	c.advance(10 * time.Second)
+	enqueue(t, &g, pw, 5, 5)
+	enqueue(t, &g, pw, 11, 11)
+
+	jobs := pw.Jobs()
+	require.Equal(t, []int{11, 0, 5}, []int{jobs[0].Value, jobs[1].Value, jobs[2].Value})
+
+	pw.Unpause()
+	require.NoError(t, g.Wait())
+	require.Equal(t, []int{11, 0, 5}, []int{<-results, <-results, <-results})
+}
+
+func TestFairQueuing(t *testing.T) {
+	results := make(chan string, 7)
+	pw := NewWithOptions(func(ctx context.Context, v string) error {
+		results <- v
+		return nil
+	}, Options[string]{FairKey: func(v string) string { return v[:1] }})
+	defer pw.Stop()
+	pw.Pause()
+
+	var g errgroup.Group
+	for _, v := range []string{"a1", "a2", "a3", "a4", "b1", "b2"} {
+		enqueue(t, &g, pw, 0, v)
+	}
+	// Higher priority still comes first
+	enqueue(t, &g, pw, 1, "b3")
+
+	var order []string
+	for _, j := range pw.Jobs() {
+		order = append(order, j.Value)
+	}
+	expected := []string{"b3", "a1", "b1", "a2", "b2", "a3", "a4"}
+	require.Equal(t, expected, order)
+
+	stats := pw.Stats()
+	require.Equal(t, map[string]int{"a": 4, "b": 3}, stats.QueuedByKey)
+
+	pw.Unpause()
+	require.NoError(t, g.Wait())
+	for _, v := range expected {
+		require.Equal(t, v, <-results)
+	}
+}
+
+func TestStats(t *testing.T) {
+	c := &clock{t: time.Unix(0, 0)}
+	pw := newWorker(func(ctx context.Context, i int) error {
+		return nil
+	}, Options[int]{}, c.now)
+	defer pw.Stop()
+	pw.Pause()
+
+	var g errgroup.Group
+	enqueue(t, &g, pw, 0, 0)
+	c.advance(2 * time.Second)
+	enqueue(t, &g, pw, 0, 1)
+	c.advance(4 * time.Second)
+
+	stats := pw.Stats()
+	require.Equal(t, 0, stats.Running)
+	require.Equal(t, 2, stats.Queued)
+	require.Nil(t, stats.QueuedByKey)
+	require.Equal(t, 6*time.Second, stats.OldestWait)
+	require.Equal(t, int64(0), stats.Started)
+	require.Equal(t, time.Duration(0), stats.AvgWait())
+
+	pw.Unpause()
+	require.NoError(t, g.Wait())
+
+	stats = pw.Stats()
+	require.Equal(t, 0, stats.Queued)
+	require.Equal(t, int64(2), stats.Started)
+	require.Equal(t, 10*time.Second, stats.TotalWait)
+	require.Equal(t, 6*time.Second, stats.MaxWait)
+	require.Equal(t, 5*time.Second, stats.AvgWait())
+}
+
+// enqueue calls Process in a goroutine and waits for the job to show up in the worker's jobs
+func enqueue[V any](t *testing.T, g *errgroup.Group, pw *PriorityWorker[V], priority int, val V) {
+	n := len(pw.Jobs())
+	g.Go(func() error {
+		return pw.Process(context.Background(), priority, val)
+	})
+	require.Eventually(t, func() bool {
+		return len(pw.Jobs()) == n+1
+	}, time.Second, time.Millisecond)
+}
+
+// clock is a fake clock for deterministic tests
+type clock struct {
+	t  time.Time
+	mu sync.Mutex
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,363 @@
+// Package securitypolicy resolves the security policies of metrics views for a specific caller.
+//
+// Policies are templated on the caller's attributes (for example the claims of a JWT),
+// which are available in templates as ".user", e.g. "region = {{ .user.region | sqlString }}".
+// Row filters are inlined in SQL, so every value they print must be escaped with sqlString, sqlNumber or sqlList.
+package securitypolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+)
+
+// Policy is a metrics view security policy resolved for a specific caller.
+type Policy struct {
+	// RowFilter is a SQL expression that should be applied as a filter to every query.
+	// It's empty if all rows are visible.
+	RowFilter          string
+	rowFilterTemplate  string
+	restrictedDims     map[string]bool
+	restrictedMeasures map[string]bool
+}
+
+// Resolve renders the metrics view's security policy with the caller's attributes.
+// If the metrics view doesn't have a security policy, the resulting policy allows everything.
+func Resolve(mv *runtimev1.MetricsView, attributes map[string]any) (*Policy, error) {
+	p := &Policy{
+		restrictedDims:     make(map[string]bool),
+		restrictedMeasures: make(map[string]bool),
+	}
+
+	sec := mv.Security
+	if sec == nil {
+		return p, nil
+	}
+
+	data := map[string]any{"user": attributes}
+
+	if sec.RowFilter != "" {
+		// Attributes referenced by the row filter must be present, otherwise we can't tell which rows to show
+		filter, err := renderRowFilter(sec.RowFilter, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve row filter: %w", err)
+		}
+		p.RowFilter = strings.TrimSpace(filter)
+		p.rowFilterTemplate = strings.TrimSpace(sec.RowFilter)
+	}
+
+	for _, rule := range sec.Dimensions {
+		ok, err := evaluate(rule.Condition, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		for _, name := range rule.Names {
+			for _, d := range mv.Dimensions {
+				if d.Name == name || d.Label == name {
+					p.restrictedDims[d.Name] = true
+				}
+			}
+		}
+	}
+
+	for _, rule := range sec.Measures {
+		ok, err := evaluate(rule.Condition, data)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		for _, name := range rule.Names {
+			for _, m := range mv.Measures {
+				if m.Name == name || m.Label == name {
+					p.restrictedMeasures[m.Name] = true
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Validate checks that the templates in the metrics view's security policy are valid
+// and that the access rules reference existing dimensions and measures.
+func Validate(mv *runtimev1.MetricsView) error {
+	sec := mv.Security
+	if sec == nil {
+		return nil
+	}
+
+	if _, err := parseRowFilter(sec.RowFilter); err != nil {
+		return fmt.Errorf("invalid row filter: %w", err)
+	}
+
+	for _, rule := range sec.Dimensions {
+		if _, err := template.New("").Parse(rule.Condition); err != nil {
+			return fmt.Errorf("invalid condition: %w", err)
+		}
+		for _, name := range rule.Names {
+			found := false
+			for _, d := range mv.Dimensions {
+				if d.Name == name || d.Label == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("dimension not found: %s", name)
+			}
+		}
+	}
+
+	for _, rule := range sec.Measures {
+		if _, err := template.New("").Parse(rule.Condition); err != nil {
+			return fmt.Errorf("invalid condition: %w", err)
+		}
+		for _, name := range rule.Names {
+			found := false
+			for _, m := range mv.Measures {
+				if m.Name == name || m.Label == name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("measure not found: %s", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Unrendered returns a copy of the policy with the row filter's template in place of the rendered row filter.
+// SQL built with it doesn't contain the caller's attributes, so it can be shown to other callers (e.g. in the query history).
+func (p *Policy) Unrendered() *Policy {
+	c := *p
+	c.RowFilter = p.rowFilterTemplate
+	return &c
+}
+
+// CanAccessDimension returns true if the caller is allowed to query the dimension
+func (p *Policy) CanAccessDimension(name string) bool {
+	return !p.restrictedDims[name]
+}
+
+// CanAccessMeasure returns true if the caller is allowed to query the measure
+func (p *Policy) CanAccessMeasure(name string) bool {
+	return !p.restrictedMeasures[name]
+}
+
+// CanAccessField returns true if the caller is allowed to query the dimension or measure
+func (p *Policy) CanAccessField(name string) bool {
+	return p.CanAccessDimension(name) && p.CanAccessMeasure(name)
+}
+
+// Key returns a string that identifies the policy, i.e. its rendered row filter and the fields it restricts.
+// Callers with the same key see the same data, so it can be used in cache keys instead of the caller's attributes.
+func (p *Policy) Key() string {
+	data, err := json.Marshal(map[string]any{
+		"row_filter": p.RowFilter,
+		"dimensions": sortedKeys(p.restrictedDims),
+		"measures":   sortedKeys(p.restrictedMeasures),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// rowFilterFuncs escape values for inclusion in a row filter. Row filters must pass every value they print through one of them.
+var rowFilterFuncs = template.FuncMap{
+	"sqlString": sqlString,
+	"sqlNumber": sqlNumber,
+	"sqlList":   sqlList,
+}
+
+// parseRowFilter parses a row filter template and checks that every value it prints is escaped.
+// Attributes referenced by the row filter must be present, otherwise we can't tell which rows to show.
+func parseRowFilter(text string) (*template.Template, error) {
+	t, err := template.New("").Funcs(rowFilterFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Tree != nil {
+		if err := checkEscaped(t.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+func renderRowFilter(text string, data map[string]any) (string, error) {
+	t, err := parseRowFilter(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = t.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// checkEscaped returns an error if a node of a row filter prints a value that isn't escaped by one of rowFilterFuncs.
+// Control structures (such as if and range) don't print their pipelines, so only their bodies are checked.
+func checkEscaped(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkEscaped(c); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		// Variable declarations don't print anything
+		if len(n.Pipe.Decl) > 0 {
+			return nil
+		}
+		cmd := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && rowFilterFuncs[id.Ident] != nil {
+			return nil
+		}
+		return fmt.Errorf("%s must be escaped with sqlString, sqlNumber or sqlList", n.String())
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		return fmt.Errorf("%s is not supported in row filters", n.String())
+	}
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode) error {
+	if err := checkEscaped(n.List); err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return checkEscaped(n.ElseList)
+	}
+	return nil
+}
+
+// sqlString formats a value as a SQL string literal.
+// Backslashes are rejected since some dialects (such as ClickHouse) treat them as escape characters.
+func sqlString(v any) (string, error) {
+	if v == nil {
+		return "NULL", nil
+	}
+	s := fmt.Sprint(v)
+	if strings.ContainsAny(s, "\\\x00") {
+		return "", fmt.Errorf("unsupported character in attribute value %q", s)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'", nil
+}
+
+// sqlNumber formats a numeric value as a SQL number literal.
+func sqlNumber(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("attribute value %v is not a finite number", v)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("attribute value %v is not a number", v)
+	}
+}
+
+// sqlList formats a list of values as comma-separated SQL string literals, e.g. for "region IN ({{ .user.regions | sqlList }})".
+// An empty list is formatted as NULL, which doesn't match any rows.
+func sqlList(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", fmt.Errorf("attribute value %v is not a list", v)
+	}
+	if rv.Len() == 0 {
+		return "NULL", nil
+	}
+
+	vals := make([]string, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		s, err := sqlString(rv.Index(i).Interface())
+		if err != nil {
+			return "", err
+		}
+		vals[i] = s
+	}
+	return strings.Join(vals, ", "), nil
+}
+
+func render(text string, data map[string]any, opts ...string) (string, error) {
+	t, err := template.New("").Option(opts...).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	err = t.Execute(&b, data)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// evaluate renders a condition and parses the result as a bool.
+// Empty conditions never grant access. Missing attributes render as nil, so "{{ eq .user.role "admin" }}"
+// evaluates to false for callers without a role.
+func evaluate(condition string, data map[string]any) (bool, error) {
+	res, err := render(condition, data)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition: %w", err)
+	}
+
+	res = strings.TrimSpace(res)
+	if res == "" {
+		return false, nil
+	}
+
+	ok, err := strconv.ParseBool(res)
+	if err != nil {
+		return false, fmt.Errorf("condition %q did not evaluate to a boolean: %w", condition, err)
+	}
+	return ok, nil
+}
@@ -0,0 +1,105 @@
+package securitypolicy
+
+import (
+	"testing"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+)
+
+var mv = &runtimev1.MetricsView{
+	Dimensions: []*runtimev1.MetricsView_Dimension{
+		{Name: "region", Label: "Region"},
+		{Name: "publisher", Label: "Publisher"},
+	},
+	Measures: []*runtimev1.MetricsView_Measure{
+		{Name: "measure_0", Label: "Count"},
+		{Name: "measure_1", Label: "Revenue"},
+	},
+	Security: &runtimev1.MetricsView_Security{
+		RowFilter: "region = {{ .user.region | sqlString }}",
+		Dimensions: []*runtimev1.MetricsView_FieldAccess{
+			{Names: []string{"publisher"}, Condition: `{{ eq .user.role "admin" }}`},
+		},
+		Measures: []*runtimev1.MetricsView_FieldAccess{
+			{Names: []string{"Revenue"}, Condition: `{{ eq .user.role "admin" }}`},
+		},
+	},
+}
+
+func TestResolve(t *testing.T) {
+	p, err := Resolve(mv, map[string]any{"region": "EU", "role": "admin"})
+	require.NoError(t, err)
+	require.Equal(t, "region = 'EU'", p.RowFilter)
+	require.True(t, p.CanAccessDimension("publisher"))
+	require.True(t, p.CanAccessMeasure("measure_1"))
+
+	p, err = Resolve(mv, map[string]any{"region": "EU"})
+	require.NoError(t, err)
+	require.Equal(t, "region = 'EU'", p.RowFilter)
+	require.True(t, p.CanAccessDimension("region"))
+	require.False(t, p.CanAccessDimension("publisher"))
+	require.True(t, p.CanAccessMeasure("measure_0"))
+	require.False(t, p.CanAccessMeasure("measure_1"))
+
+	u := p.Unrendered()
+	require.Equal(t, "region = {{ .user.region | sqlString }}", u.RowFilter)
+	require.False(t, u.CanAccessDimension("publisher"))
+	require.Equal(t, "region = 'EU'", p.RowFilter)
+
+	_, err = Resolve(mv, nil)
+	require.Error(t, err)
+}
+
+func TestResolveWithoutPolicy(t *testing.T) {
+	p, err := Resolve(&runtimev1.MetricsView{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "", p.RowFilter)
+	require.True(t, p.CanAccessField("anything"))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(mv))
+
+	invalid := &runtimev1.MetricsView{
+		Dimensions: mv.Dimensions,
+		Security: &runtimev1.MetricsView_Security{
+			Dimensions: []*runtimev1.MetricsView_FieldAccess{
+				{Names: []string{"domain"}, Condition: "true"},
+			},
+		},
+	}
+	require.ErrorContains(t, Validate(invalid), "dimension not found")
+
+	invalid.Security = &runtimev1.MetricsView_Security{RowFilter: "region = '{{ .user.region '"}
+	require.ErrorContains(t, Validate(invalid), "invalid row filter")
+
+	invalid.Security = &runtimev1.MetricsView_Security{RowFilter: "region = '{{ .user.region }}'"}
+	require.ErrorContains(t, Validate(invalid), "must be escaped")
+
+	invalid.Security = &runtimev1.MetricsView_Security{RowFilter: `{{ if .user.admin }}1=1{{ else }}region = {{ .user.region | printf "%s" }}{{ end }}`}
+	require.ErrorContains(t, Validate(invalid), "must be escaped")
+}
+
+func TestResolveEscapesAttributes(t *testing.T) {
+	p, err := Resolve(mv, map[string]any{"region": "x' OR '1'='1"})
+	require.NoError(t, err)
+	require.Equal(t, "region = 'x'' OR ''1''=''1'", p.RowFilter)
+
+	_, err = Resolve(mv, map[string]any{"region": `x\' OR 1=1 --`})
+	require.ErrorContains(t, err, "unsupported character")
+
+	unescaped := &runtimev1.MetricsView{Security: &runtimev1.MetricsView_Security{RowFilter: "region = '{{ .user.region }}'"}}
+	_, err = Resolve(unescaped, map[string]any{"region": "x' OR '1'='1"})
+	require.ErrorContains(t, err, "must be escaped")
+
+	funcs := &runtimev1.MetricsView{Security: &runtimev1.MetricsView_Security{
+		RowFilter: `{{ if eq .user.role "admin" }}TRUE{{ else }}tenant = {{ sqlNumber .user.tenant }} AND region IN ({{ sqlList .user.regions }}){{ end }}`,
+	}}
+	p, err = Resolve(funcs, map[string]any{"role": "viewer", "tenant": float64(42), "regions": []any{"EU", "it's"}})
+	require.NoError(t, err)
+	require.Equal(t, "tenant = 42 AND region IN ('EU', 'it''s')", p.RowFilter)
+
+	_, err = Resolve(funcs, map[string]any{"role": "viewer", "tenant": "42 OR 1=1", "regions": []any{}})
+	require.ErrorContains(t, err, "not a number")
+}
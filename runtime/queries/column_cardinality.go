@@ -15,7 +15,7 @@ type ColumnCardinality struct {
 	Result     float64
 }
 
-var _ runtime.Query = &ColumnCardinality{}
+var _ runtime.PersistentQuery = &ColumnCardinality{}
 
 func (q *ColumnCardinality) Key() string {
 	return fmt.Sprintf("ColumnCardinality:%s:%s", q.TableName, q.ColumnName)
@@ -25,8 +25,11 @@ func (q *ColumnCardinality) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnCardinality) MarshalResult() any {
-	return q.Result
+func (q *ColumnCardinality) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnCardinality) UnmarshalResult(v any) error {
@@ -38,17 +41,25 @@ func (q *ColumnCardinality) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnCardinality) MarshalResultBytes() ([]byte, error) {
+	return marshalScalarResult(q.Result)
+}
+
+func (q *ColumnCardinality) UnmarshalResultBytes(data []byte) error {
+	return unmarshalScalarResult(data, &q.Result)
+}
+
 func (q *ColumnCardinality) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
 	}
 
-	requestSQL := fmt.Sprintf("SELECT approx_count_distinct(%s) as count from %s", safeName(q.ColumnName), safeName(q.TableName))
+	requestSQL := fmt.Sprintf("SELECT %s as count from %s", approxCountDistinctExpr(olap.Dialect(), safeName(q.ColumnName)), safeName(q.TableName))
 
 	rows, err := olap.Execute(ctx, &drivers.Statement{
 		Query:    requestSQL,
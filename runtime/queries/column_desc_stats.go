@@ -16,7 +16,7 @@ type ColumnDescriptiveStatistics struct {
 	Result     *runtimev1.NumericStatistics
 }
 
-var _ runtime.Query = &ColumnDescriptiveStatistics{}
+var _ runtime.PersistentQuery = &ColumnDescriptiveStatistics{}
 
 func (q *ColumnDescriptiveStatistics) Key() string {
 	return fmt.Sprintf("ColumnDescriptiveStatistics:%s:%s", q.TableName, q.ColumnName)
@@ -26,8 +26,11 @@ func (q *ColumnDescriptiveStatistics) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnDescriptiveStatistics) MarshalResult() any {
-	return q.Result
+func (q *ColumnDescriptiveStatistics) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnDescriptiveStatistics) UnmarshalResult(v any) error {
@@ -39,14 +42,32 @@ func (q *ColumnDescriptiveStatistics) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnDescriptiveStatistics) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *ColumnDescriptiveStatistics) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.NumericStatistics](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnDescriptiveStatistics) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	// Return an empty result for OLAP stores that don't support the query
+	if !olap.Capabilities().Supports(drivers.QueryKindDescriptiveStatistics) {
+		return nil
+	}
+
+	if !isNumericProfilingSupported(ctx, olap, q.TableName, q.ColumnName) {
+		return nil
 	}
 
 	sanitizedColumnName := safeName(q.ColumnName)
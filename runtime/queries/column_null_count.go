@@ -14,7 +14,7 @@ type ColumnNullCount struct {
 	Result     float64
 }
 
-var _ runtime.Query = &ColumnNullCount{}
+var _ runtime.PersistentQuery = &ColumnNullCount{}
 
 func (q *ColumnNullCount) Key() string {
 	return fmt.Sprintf("ColumnNullCount:%s:%s", q.TableName, q.ColumnName)
@@ -24,8 +24,11 @@ func (q *ColumnNullCount) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnNullCount) MarshalResult() any {
-	return q.Result
+func (q *ColumnNullCount) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnNullCount) UnmarshalResult(v any) error {
@@ -37,14 +40,22 @@ func (q *ColumnNullCount) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnNullCount) MarshalResultBytes() ([]byte, error) {
+	return marshalScalarResult(q.Result)
+}
+
+func (q *ColumnNullCount) UnmarshalResultBytes(data []byte) error {
+	return unmarshalScalarResult(data, &q.Result)
+}
+
 func (q *ColumnNullCount) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
 	}
 
 	nullCountSQL := fmt.Sprintf("SELECT count(*) as count from %s WHERE %s IS NULL",
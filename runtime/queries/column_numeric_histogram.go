@@ -17,7 +17,7 @@ type ColumnNumericHistogram struct {
 	Result     []*runtimev1.NumericHistogramBins_Bin
 }
 
-var _ runtime.Query = &ColumnNumericHistogram{}
+var _ runtime.PersistentQuery = &ColumnNumericHistogram{}
 
 func (q *ColumnNumericHistogram) Key() string {
 	return fmt.Sprintf("ColumnNumericHistogram:%s:%s", q.TableName, q.ColumnName)
@@ -27,8 +27,11 @@ func (q *ColumnNumericHistogram) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnNumericHistogram) MarshalResult() any {
-	return q.Result
+func (q *ColumnNumericHistogram) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnNumericHistogram) UnmarshalResult(v any) error {
@@ -40,13 +43,26 @@ func (q *ColumnNumericHistogram) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnNumericHistogram) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResults(q.Result)
+}
+
+func (q *ColumnNumericHistogram) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResults[runtimev1.NumericHistogramBins_Bin](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnNumericHistogram) calculateBucketSize(ctx context.Context, olap drivers.OLAPStore, instanceID string, priority int) (float64, error) {
 	sanitizedColumnName := safeName(q.ColumnName)
 	querySQL := fmt.Sprintf(
-		"SELECT approx_quantile(%s, 0.75)-approx_quantile(%s, 0.25) AS iqr, approx_count_distinct(%s) AS count, max(%s) - min(%s) AS range FROM %s",
-		sanitizedColumnName,
-		sanitizedColumnName,
-		sanitizedColumnName,
+		"SELECT %s-%s AS iqr, %s AS count, max(%s) - min(%s) AS range FROM %s",
+		approxQuantileExpr(olap.Dialect(), sanitizedColumnName, 0.75),
+		approxQuantileExpr(olap.Dialect(), sanitizedColumnName, 0.25),
+		approxCountDistinctExpr(olap.Dialect(), sanitizedColumnName),
 		sanitizedColumnName,
 		sanitizedColumnName,
 		safeName(q.TableName),
@@ -92,8 +108,12 @@ func (q *ColumnNumericHistogram) Resolve(ctx context.Context, rt *runtime.Runtim
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
+	}
+
+	if !isNumericProfilingSupported(ctx, olap, q.TableName, q.ColumnName) {
+		return nil
 	}
 
 	sanitizedColumnName := safeName(q.ColumnName)
@@ -105,6 +125,11 @@ func (q *ColumnNumericHistogram) Resolve(ctx context.Context, rt *runtime.Runtim
 		return nil
 	}
 
+	if olap.Dialect() != drivers.DialectDuckDB {
+		q.Result, err = q.resolveBuckets(ctx, olap, priority, bucketSize)
+		return err
+	}
+
 	selectColumn := fmt.Sprintf("%s::DOUBLE", sanitizedColumnName)
 	histogramSQL := fmt.Sprintf(
 		`
@@ -186,3 +211,73 @@ func (q *ColumnNumericHistogram) Resolve(ctx context.Context, rt *runtime.Runtim
 	q.Result = histogramBins
 	return nil
 }
+
+// resolveBuckets computes the histogram on dialects that don't support generating the buckets with range() (such as Druid).
+// Instead, it counts the values per bucket number and fills in the bucket edges (and empty buckets) in Go.
+func (q *ColumnNumericHistogram) resolveBuckets(ctx context.Context, olap drivers.OLAPStore, priority int, bucketSize float64) ([]*runtimev1.NumericHistogramBins_Bin, error) {
+	sanitizedColumnName := safeName(q.ColumnName)
+	minMaxRows, err := olap.Execute(ctx, &drivers.Statement{
+		Query:    fmt.Sprintf("SELECT min(%[1]s), max(%[1]s) FROM %[2]s", castDoubleExpr(olap.Dialect(), sanitizedColumnName), safeName(q.TableName)),
+		Priority: priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var minVal, maxVal sql.NullFloat64
+	if minMaxRows.Next() {
+		err = minMaxRows.Scan(&minVal, &maxVal)
+	}
+	minMaxRows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !minVal.Valid || !maxVal.Valid || minVal.Float64 == maxVal.Float64 {
+		return nil, nil
+	}
+	rangeVal := maxVal.Float64 - minVal.Float64
+
+	histogramSQL := fmt.Sprintf(
+		"SELECT FLOOR((%[6]s - %[3]v) / %[4]v * %[5]v) AS bucket, count(*) AS count FROM %[2]s WHERE %[1]s IS NOT NULL GROUP BY 1",
+		sanitizedColumnName,
+		safeName(q.TableName),
+		minVal.Float64,
+		rangeVal,
+		bucketSize,
+		castDoubleExpr(olap.Dialect(), sanitizedColumnName),
+	)
+	rows, err := olap.Execute(ctx, &drivers.Statement{
+		Query:    histogramSQL,
+		Priority: priority,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	n := int(bucketSize)
+	counts := make([]float64, n)
+	for rows.Next() {
+		var bucket, count float64
+		err = rows.Scan(&bucket, &count)
+		if err != nil {
+			return nil, err
+		}
+		// The max value falls in the bucket after the last one, so it's counted in the last bucket (like the right edge on DuckDB)
+		i := int(math.Min(math.Max(bucket, 0), float64(n-1)))
+		counts[i] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	bins := make([]*runtimev1.NumericHistogramBins_Bin, n)
+	for i := range bins {
+		bins[i] = &runtimev1.NumericHistogramBins_Bin{
+			Bucket: int32(i),
+			Low:    float64(i)*rangeVal/bucketSize + minVal.Float64,
+			High:   float64(i+1)*rangeVal/bucketSize + minVal.Float64,
+			Count:  counts[i],
+		}
+	}
+	return bins, nil
+}
@@ -15,7 +15,7 @@ type ColumnRugHistogram struct {
 	Result     []*runtimev1.NumericOutliers_Outlier
 }
 
-var _ runtime.Query = &ColumnRugHistogram{}
+var _ runtime.PersistentQuery = &ColumnRugHistogram{}
 
 func (q *ColumnRugHistogram) Key() string {
 	return fmt.Sprintf("ColumnRugHistogram:%s:%s", q.TableName, q.ColumnName)
@@ -25,8 +25,11 @@ func (q *ColumnRugHistogram) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnRugHistogram) MarshalResult() any {
-	return q.Result
+func (q *ColumnRugHistogram) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnRugHistogram) UnmarshalResult(v any) error {
@@ -38,14 +41,32 @@ func (q *ColumnRugHistogram) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnRugHistogram) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResults(q.Result)
+}
+
+func (q *ColumnRugHistogram) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResults[runtimev1.NumericOutliers_Outlier](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnRugHistogram) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	// Return an empty result for OLAP stores that don't support the query
+	if !olap.Capabilities().Supports(drivers.QueryKindRugHistogram) {
+		return nil
+	}
+
+	if !isNumericProfilingSupported(ctx, olap, q.TableName, q.ColumnName) {
+		return nil
 	}
 
 	sanitizedColumnName := safeName(q.ColumnName)
@@ -16,7 +16,7 @@ type ColumnTimeGrain struct {
 	Result     runtimev1.TimeGrain
 }
 
-var _ runtime.Query = &ColumnTimeGrain{}
+var _ runtime.PersistentQuery = &ColumnTimeGrain{}
 
 func (q *ColumnTimeGrain) Key() string {
 	return fmt.Sprintf("ColumnTimeGrain:%s:%s", q.TableName, q.ColumnName)
@@ -26,8 +26,11 @@ func (q *ColumnTimeGrain) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnTimeGrain) MarshalResult() any {
-	return q.Result
+func (q *ColumnTimeGrain) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnTimeGrain) UnmarshalResult(v any) error {
@@ -39,17 +42,36 @@ func (q *ColumnTimeGrain) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnTimeGrain) MarshalResultBytes() ([]byte, error) {
+	return marshalScalarResult(q.Result)
+}
+
+func (q *ColumnTimeGrain) UnmarshalResultBytes(data []byte) error {
+	return unmarshalScalarResult(data, &q.Result)
+}
+
 func (q *ColumnTimeGrain) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
+	olap, err := rt.OLAP(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	// Return an empty result for OLAP stores that don't support the query
+	caps := olap.Capabilities()
+	if !caps.Supports(drivers.QueryKindTimeGrain) {
+		return nil
+	}
+
 	sampleSize := int64(500000)
 	cq := &TableCardinality{
 		TableName: q.TableName,
 	}
-	err := rt.Query(ctx, instanceID, cq, priority)
+	err = rt.Query(ctx, instanceID, cq, priority)
 	if err != nil {
 		return err
 	}
 	var useSample string
-	if sampleSize > cq.Result {
+	if sampleSize > cq.Result || !caps.Sampling {
 		useSample = ""
 	} else {
 		useSample = fmt.Sprintf("USING SAMPLE %d ROWS", sampleSize)
@@ -97,15 +119,6 @@ func (q *ColumnTimeGrain) Resolve(ctx context.Context, rt *runtime.Runtime, inst
 		useSample,
 	)
 
-	olap, err := rt.OLAP(ctx, instanceID)
-	if err != nil {
-		return err
-	}
-
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
-	}
-
 	rows, err := olap.Execute(ctx, &drivers.Statement{
 		Query:    estimateSQL,
 		Priority: priority,
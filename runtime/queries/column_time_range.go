@@ -19,7 +19,7 @@ type ColumnTimeRange struct {
 	Result     *runtimev1.TimeRangeSummary
 }
 
-var _ runtime.Query = &ColumnTimeRange{}
+var _ runtime.PersistentQuery = &ColumnTimeRange{}
 
 func (q *ColumnTimeRange) Key() string {
 	return fmt.Sprintf("ColumnTimeRange:%s:%s", q.TableName, q.ColumnName)
@@ -29,8 +29,11 @@ func (q *ColumnTimeRange) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnTimeRange) MarshalResult() any {
-	return q.Result
+func (q *ColumnTimeRange) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnTimeRange) UnmarshalResult(v any) error {
@@ -42,6 +45,19 @@ func (q *ColumnTimeRange) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnTimeRange) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *ColumnTimeRange) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.TimeRangeSummary](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnTimeRange) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	rangeSQL := fmt.Sprintf(
 		"SELECT min(%[1]s) as min, max(%[1]s) as max, max(%[1]s) - min(%[1]s) as interval FROM %[2]s",
@@ -54,8 +70,17 @@ func (q *ColumnTimeRange) Resolve(ctx context.Context, rt *runtime.Runtime, inst
 		return err
 	}
 
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
+	}
+
+	// Only DuckDB returns an interval when subtracting timestamps, so on other dialects the interval is computed from min and max
 	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+		rangeSQL = fmt.Sprintf(
+			"SELECT min(%[1]s) as min, max(%[1]s) as max FROM %[2]s",
+			safeName(q.ColumnName),
+			safeName(q.TableName),
+		)
 	}
 
 	rows, err := olap.Execute(ctx, &drivers.Statement{
@@ -80,10 +105,15 @@ func (q *ColumnTimeRange) Resolve(ctx context.Context, rt *runtime.Runtime, inst
 				return fmt.Errorf("not a timestamp column")
 			}
 			summary.Min = timestamppb.New(minTime)
-			summary.Max = timestamppb.New(rowMap["max"].(time.Time))
-			summary.Interval, err = handleInterval(rowMap["interval"])
-			if err != nil {
-				return err
+			maxTime := rowMap["max"].(time.Time)
+			summary.Max = timestamppb.New(maxTime)
+			if olap.Dialect() != drivers.DialectDuckDB {
+				summary.Interval = durationToInterval(maxTime.Sub(minTime))
+			} else {
+				summary.Interval, err = handleInterval(rowMap["interval"])
+				if err != nil {
+					return err
+				}
 			}
 		}
 		q.Result = summary
@@ -108,3 +138,12 @@ func handleInterval(interval any) (*runtimev1.TimeRangeSummary_Interval, error)
 	}
 	return nil, fmt.Errorf("cannot handle interval type %T", interval)
 }
+
+// durationToInterval converts d to an interval of days and micros (like the difference of two timestamps in DuckDB)
+func durationToInterval(d time.Duration) *runtimev1.TimeRangeSummary_Interval {
+	day := 24 * time.Hour
+	return &runtimev1.TimeRangeSummary_Interval{
+		Days:   int32(d / day),
+		Micros: (d % day).Microseconds(),
+	}
+}
@@ -24,10 +24,13 @@ type ColumnTimeseries struct {
 	Filters             *runtimev1.MetricsViewRequestFilter                 `json:"filters"`
 	Pixels              int32                                               `json:"pixels"`
 	SampleSize          int32                                               `json:"sample_size"`
+	TimeZone            string                                              `json:"time_zone"`
+	FirstDayOfWeek      uint32                                              `json:"first_day_of_week"`
+	FirstMonthOfYear    uint32                                              `json:"first_month_of_year"`
 	Result              *runtimev1.TimeSeriesResponse                       `json:"-"`
 }
 
-var _ runtime.Query = &ColumnTimeseries{}
+var _ runtime.PersistentQuery = &ColumnTimeseries{}
 
 func (q *ColumnTimeseries) Key() string {
 	r, err := json.Marshal(q)
@@ -41,8 +44,11 @@ func (q *ColumnTimeseries) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnTimeseries) MarshalResult() any {
-	return q.Result
+func (q *ColumnTimeseries) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnTimeseries) UnmarshalResult(v any) error {
@@ -54,14 +60,28 @@ func (q *ColumnTimeseries) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnTimeseries) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *ColumnTimeseries) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.TimeSeriesResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnTimeseries) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	// Return an empty result for OLAP stores that don't support the query
+	if !olap.Capabilities().Supports(drivers.QueryKindColumnTimeseries) {
+		return nil
 	}
 
 	timeRange, err := q.resolveNormaliseTimeRange(ctx, rt, instanceID, priority)
@@ -74,6 +94,15 @@ func (q *ColumnTimeseries) Resolve(ctx context.Context, rt *runtime.Runtime, ins
 		return nil
 	}
 
+	tz, err := newTimeZone(q.TimeZone, timeRange.Start.AsTime(), timeRange.End.AsTime())
+	if err != nil {
+		return err
+	}
+	tz.calendar = calendar{
+		firstDayOfWeek:   q.FirstDayOfWeek,
+		firstMonthOfYear: q.FirstMonthOfYear,
+	}
+
 	measures := normaliseMeasures(q.Measures, true)
 	filter, args := getFilterFromMetricsViewFilters(q.Filters)
 	dateTruncSpecifier := convertToDateTruncSpecifier(timeRange.Interval)
@@ -85,18 +114,12 @@ func (q *ColumnTimeseries) Resolve(ctx context.Context, rt *runtime.Runtime, ins
 	sql := `CREATE TEMPORARY TABLE ` + temporaryTableName + ` AS (
         -- generate a time series column that has the intended range
         WITH template as (
-          SELECT 
-            generate_series as ` + tsAlias + `
-          FROM 
-            generate_series(
-              date_trunc('` + dateTruncSpecifier + `', TIMESTAMP '` + timeRange.Start.AsTime().Format(IsoFormat) + `'),
-              date_trunc('` + dateTruncSpecifier + `', TIMESTAMP '` + timeRange.End.AsTime().Format(IsoFormat) + `'),
-              interval '1 ` + dateTruncSpecifier + `')
+          ` + tz.seriesSQL(dateTruncSpecifier, timeRange.Start.AsTime(), timeRange.End.AsTime(), tsAlias) + `
         ),
         -- transform the original data, and optionally sample it.
         series AS (
           SELECT 
-            date_trunc('` + dateTruncSpecifier + `', ` + safeName(q.TimestampColumnName) + `) as ` + tsAlias + `,` + getExpressionColumnsFromMeasures(measures) + `
+            ` + tz.truncateExpr(dateTruncSpecifier, safeName(q.TimestampColumnName)) + ` as ` + tsAlias + `,` + getExpressionColumnsFromMeasures(measures) + `
           FROM ` + safeName(q.TableName) + ` ` + filter + `
           GROUP BY ` + tsAlias + ` ORDER BY ` + tsAlias + `
         )
@@ -129,7 +152,7 @@ func (q *ColumnTimeseries) Resolve(ctx context.Context, rt *runtime.Runtime, ins
 		return err
 	}
 
-	results, err := convertRowsToTimeSeriesValues(rows, len(measures)+1, tsAlias)
+	results, err := convertRowsToTimeSeriesValues(rows, len(measures)+1, tsAlias, tz)
 	rows.Close()
 	if err != nil {
 		return err
@@ -137,7 +160,7 @@ func (q *ColumnTimeseries) Resolve(ctx context.Context, rt *runtime.Runtime, ins
 
 	var sparkValues []*runtimev1.TimeSeriesValue
 	if q.Pixels != 0 {
-		sparkValues, err = q.createTimestampRollupReduction(ctx, rt, olap, instanceID, priority, temporaryTableName, tsAlias, "count")
+		sparkValues, err = q.createTimestampRollupReduction(ctx, rt, olap, instanceID, priority, temporaryTableName, tsAlias, "count", tz)
 		if err != nil {
 			return err
 		}
@@ -235,6 +258,7 @@ func (q *ColumnTimeseries) createTimestampRollupReduction(
 	tableName string,
 	timestampColumnName string,
 	valueColumn string,
+	tz *timeZone,
 ) ([]*runtimev1.TimeSeriesValue, error) {
 	safeTimestampColumnName := safeName(timestampColumnName)
 	tc := &TableCardinality{
@@ -263,7 +287,7 @@ func (q *ColumnTimeseries) createTimestampRollupReduction(
 				return nil, err
 			}
 			results = append(results, &runtimev1.TimeSeriesValue{
-				Ts:      ts.Format(IsoFormat),
+				Ts:      tz.format(ts),
 				Records: sMap("count", count),
 			})
 		}
@@ -320,19 +344,19 @@ func (q *ColumnTimeseries) createTimestampRollupReduction(
 			return nil, err
 		}
 		results = append(results, &runtimev1.TimeSeriesValue{
-			Ts:      time.UnixMilli(minT).Format(IsoFormat),
+			Ts:      tz.format(time.UnixMilli(minT)),
 			Bin:     &bin,
 			Records: sMap("count", argminTV),
 		}, &runtimev1.TimeSeriesValue{
-			Ts:      time.UnixMilli(argminVT).Format(IsoFormat),
+			Ts:      tz.format(time.UnixMilli(argminVT)),
 			Bin:     &bin,
 			Records: sMap("count", minV),
 		}, &runtimev1.TimeSeriesValue{
-			Ts:      time.UnixMilli(argmaxVT).Format(IsoFormat),
+			Ts:      tz.format(time.UnixMilli(argmaxVT)),
 			Bin:     &bin,
 			Records: sMap("count", maxV),
 		}, &runtimev1.TimeSeriesValue{
-			Ts:      time.UnixMilli(maxT).Format(IsoFormat),
+			Ts:      tz.format(time.UnixMilli(maxT)),
 			Bin:     &bin,
 			Records: sMap("count", argmaxTV),
 		})
@@ -501,7 +525,7 @@ func sMap(k string, v float64) map[string]float64 {
 	return m
 }
 
-func convertRowsToTimeSeriesValues(rows *drivers.Result, rowLength int, tsAlias string) ([]*runtimev1.TimeSeriesValue, error) {
+func convertRowsToTimeSeriesValues(rows *drivers.Result, rowLength int, tsAlias string, tz *timeZone) ([]*runtimev1.TimeSeriesValue, error) {
 	results := make([]*runtimev1.TimeSeriesValue, 0)
 	for rows.Next() {
 		value := runtimev1.TimeSeriesValue{}
@@ -511,7 +535,7 @@ func convertRowsToTimeSeriesValues(rows *drivers.Result, rowLength int, tsAlias
 		if err != nil {
 			return results, err
 		}
-		value.Ts = row[tsAlias].(time.Time).Format(IsoFormat)
+		value.Ts = tz.format(row[tsAlias].(time.Time))
 		value.Records = make(map[string]float64, len(row))
 		for k, v := range row {
 			if k == tsAlias {
@@ -526,6 +550,13 @@ func convertRowsToTimeSeriesValues(rows *drivers.Result, rowLength int, tsAlias
 				value.Records[k] = float64(x)
 			case float64:
 				value.Records[k] = x
+			case string:
+				// DECIMAL values are returned as strings
+				f, err := strconv.ParseFloat(x, 64)
+				if err != nil {
+					return nil, err
+				}
+				value.Records[k] = f
 			default:
 				return nil, fmt.Errorf("unknown type %T ", v)
 			}
@@ -118,7 +118,9 @@ func TestTimeseries_SparkOnly(t *testing.T) {
 	ctx := context.Background()
 	olap, err := rt.OLAP(ctx, instanceID)
 	require.NoError(t, err)
-	values, err := q.createTimestampRollupReduction(context.Background(), rt, olap, instanceID, 0, "test", "time", "clicks")
+	tz, err := newTimeZone("", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	values, err := q.createTimestampRollupReduction(context.Background(), rt, olap, instanceID, 0, "test", "time", "clicks", tz)
 	require.NoError(t, err)
 
 	require.Equal(t, 12, len(values))
@@ -162,6 +164,136 @@ func TestTimeseries_SparkOnly(t *testing.T) {
 	require.Equal(t, 1.0, values[11].Records["count"])
 }
 
+func TestTimeseries_TimeZone_DST(t *testing.T) {
+	// Clocks in New York move forward at 2019-03-10 07:00 UTC
+	rt, instanceID := testruntime.NewInstanceWithModel(t, "test", `
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-03-10 04:30:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-03-10 06:00:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-03-11 03:30:00' AS time
+	`)
+
+	q := &ColumnTimeseries{
+		TableName:           "test",
+		TimestampColumnName: "time",
+		TimeRange: &runtimev1.TimeSeriesTimeRange{
+			Interval: runtimev1.TimeGrain_TIME_GRAIN_DAY,
+		},
+		TimeZone: "America/New_York",
+	}
+	err := rt.Query(context.Background(), instanceID, q, 0)
+	require.NoError(t, err)
+
+	results := q.Result.Results
+	require.Equal(t, 2, len(results))
+	require.Equal(t, "2019-03-09T00:00:00.000-05:00", results[0].Ts)
+	require.Equal(t, 1.0, results[0].Records["count"])
+	require.Equal(t, "2019-03-10T00:00:00.000-05:00", results[1].Ts)
+	require.Equal(t, 2.0, results[1].Records["count"])
+}
+
+func TestTimeseries_TimeZone_DSTHours(t *testing.T) {
+	// Clocks in New York move back at 2019-11-03 06:00 UTC, so the local hour 01:00 occurs twice
+	rt, instanceID := testruntime.NewInstanceWithModel(t, "test", `
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-11-03 05:15:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-11-03 06:15:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-11-03 06:45:00' AS time
+	`)
+
+	q := &ColumnTimeseries{
+		TableName:           "test",
+		TimestampColumnName: "time",
+		TimeRange: &runtimev1.TimeSeriesTimeRange{
+			Interval: runtimev1.TimeGrain_TIME_GRAIN_HOUR,
+		},
+		TimeZone: "America/New_York",
+	}
+	err := rt.Query(context.Background(), instanceID, q, 0)
+	require.NoError(t, err)
+
+	results := q.Result.Results
+	require.Equal(t, 2, len(results))
+	require.Equal(t, "2019-11-03T01:00:00.000-04:00", results[0].Ts)
+	require.Equal(t, 1.0, results[0].Records["count"])
+	require.Equal(t, "2019-11-03T01:00:00.000-05:00", results[1].Ts)
+	require.Equal(t, 2.0, results[1].Records["count"])
+}
+
+func TestTimeseries_FirstDayOfWeek(t *testing.T) {
+	// 2019-01-05 is a Saturday and 2019-01-06 is a Sunday
+	rt, instanceID := testruntime.NewInstanceWithModel(t, "test", `
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-01-05 12:00:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-01-06 12:00:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-01-07 12:00:00' AS time
+	`)
+
+	q := &ColumnTimeseries{
+		TableName:           "test",
+		TimestampColumnName: "time",
+		TimeRange: &runtimev1.TimeSeriesTimeRange{
+			Interval: runtimev1.TimeGrain_TIME_GRAIN_WEEK,
+		},
+		FirstDayOfWeek: 7,
+	}
+	err := rt.Query(context.Background(), instanceID, q, 0)
+	require.NoError(t, err)
+
+	results := q.Result.Results
+	require.Equal(t, 2, len(results))
+	require.Equal(t, "2018-12-30T00:00:00.000Z", results[0].Ts)
+	require.Equal(t, 1.0, results[0].Records["count"])
+	require.Equal(t, "2019-01-06T00:00:00.000Z", results[1].Ts)
+	require.Equal(t, 2.0, results[1].Records["count"])
+}
+
+func TestTimeseries_FirstMonthOfYear(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceWithModel(t, "test", `
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-01-15 00:00:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-02-15 00:00:00' AS time
+		UNION ALL
+		SELECT 1.0 AS clicks, TIMESTAMP '2019-12-15 00:00:00' AS time
+	`)
+
+	q := &ColumnTimeseries{
+		TableName:           "test",
+		TimestampColumnName: "time",
+		TimeRange: &runtimev1.TimeSeriesTimeRange{
+			Interval: runtimev1.TimeGrain_TIME_GRAIN_YEAR,
+		},
+		FirstMonthOfYear: 2,
+	}
+	err := rt.Query(context.Background(), instanceID, q, 0)
+	require.NoError(t, err)
+
+	results := q.Result.Results
+	require.Equal(t, 2, len(results))
+	require.Equal(t, "2018-02-01T00:00:00.000Z", results[0].Ts)
+	require.Equal(t, 1.0, results[0].Records["count"])
+	require.Equal(t, "2019-02-01T00:00:00.000Z", results[1].Ts)
+	require.Equal(t, 2.0, results[1].Records["count"])
+}
+
+func TestTimeseries_TimeZone_Invalid(t *testing.T) {
+	rt, instanceID := instanceWith2RowsModel(t)
+
+	q := &ColumnTimeseries{
+		TableName:           "test",
+		TimestampColumnName: "time",
+		TimeRange: &runtimev1.TimeSeriesTimeRange{
+			Interval: runtimev1.TimeGrain_TIME_GRAIN_DAY,
+		},
+		TimeZone: "Mars/Olympus_Mons",
+	}
+	err := rt.Query(context.Background(), instanceID, q, 0)
+	require.Error(t, err)
+}
+
 func TestTimeseries_Key(t *testing.T) {
 	q := &ColumnTimeseries{
 		TableName:           "test",
@@ -19,7 +19,7 @@ type ColumnTopK struct {
 	Result     *runtimev1.TopK
 }
 
-var _ runtime.Query = &ColumnTopK{}
+var _ runtime.PersistentQuery = &ColumnTopK{}
 
 func (q *ColumnTopK) Key() string {
 	return fmt.Sprintf("ColumnTopK:%s:%s:%s:%d", q.TableName, q.ColumnName, q.Agg, q.K)
@@ -29,8 +29,11 @@ func (q *ColumnTopK) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *ColumnTopK) MarshalResult() any {
-	return q.Result
+func (q *ColumnTopK) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *ColumnTopK) UnmarshalResult(v any) error {
@@ -42,6 +45,19 @@ func (q *ColumnTopK) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *ColumnTopK) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *ColumnTopK) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.TopK](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *ColumnTopK) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	// Get OLAP connection
 	olap, err := rt.OLAP(ctx, instanceID)
@@ -50,13 +66,13 @@ func (q *ColumnTopK) Resolve(ctx context.Context, rt *runtime.Runtime, instanceI
 	}
 
 	// Check dialect
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
 	}
 
 	// Build SQL
-	qry := fmt.Sprintf("SELECT CAST(%s as VARCHAR) AS value, %s AS count FROM %s GROUP BY %s ORDER BY count DESC, value ASC LIMIT %d",
-		safeName(q.ColumnName),
+	qry := fmt.Sprintf("SELECT %s AS value, %s AS count FROM %s GROUP BY %s ORDER BY count DESC, value ASC LIMIT %d",
+		castVarcharExpr(olap.Dialect(), safeName(q.ColumnName)),
 		q.Agg,
 		safeName(q.TableName),
 		safeName(q.ColumnName),
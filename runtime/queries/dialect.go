@@ -0,0 +1,201 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+// checkQueryKind returns an error wrapping drivers.ErrUnsupportedQuery if the OLAP store doesn't support the kind of query.
+// Optional profiling queries don't call it, and instead return an empty result for stores that don't support them.
+func checkQueryKind(olap drivers.OLAPStore, kind drivers.QueryKind) error {
+	if olap.Capabilities().Supports(kind) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s queries are not available for dialect '%s'", drivers.ErrUnsupportedQuery, kind, olap.Dialect())
+}
+
+// ilikeExpr returns a case-insensitive LIKE comparison of expr against a placeholder.
+// Druid doesn't support ILIKE, so both sides are lowercased instead.
+func ilikeExpr(dialect drivers.Dialect, expr string, not bool) string {
+	var prefix string
+	if not {
+		prefix = "NOT "
+	}
+	if dialect == drivers.DialectDruid {
+		return fmt.Sprintf("LOWER(%s) %sLIKE LOWER(?)", expr, prefix)
+	}
+	return fmt.Sprintf("%s %sILIKE ?", expr, prefix)
+}
+
+// orderByExpr returns a sort expression for a column. Druid doesn't support NULLS LAST
+// (and sorts nulls first in ascending order).
+func orderByExpr(dialect drivers.Dialect, name string, ascending bool) string {
+	expr := safeName(name)
+	if !ascending {
+		expr += " DESC"
+	}
+	if dialect != drivers.DialectDruid {
+		expr += " NULLS LAST"
+	}
+	return expr
+}
+
+// approxQuantileExpr returns an approximate quantile aggregation. On Druid, it requires the druid-datasketches extension.
+// Postgres doesn't have approximate quantiles, so the exact quantile is used.
+func approxQuantileExpr(dialect drivers.Dialect, expr string, quantile float64) string {
+	switch dialect {
+	case drivers.DialectDruid:
+		return fmt.Sprintf("APPROX_QUANTILE_DS(%s, %v)", expr, quantile)
+	case drivers.DialectClickHouse:
+		return fmt.Sprintf("quantile(%v)(%s)", quantile, expr)
+	case drivers.DialectPostgres:
+		return fmt.Sprintf("percentile_cont(%v) WITHIN GROUP (ORDER BY %s)", quantile, expr)
+	}
+	return fmt.Sprintf("approx_quantile(%s, %v)", expr, quantile)
+}
+
+// approxCountDistinctExpr returns an approximate count of the distinct values of expr.
+// Postgres doesn't have approximate distinct counts, so the exact count is used.
+func approxCountDistinctExpr(dialect drivers.Dialect, expr string) string {
+	switch dialect {
+	case drivers.DialectClickHouse:
+		return fmt.Sprintf("uniq(%s)", expr)
+	case drivers.DialectPostgres:
+		return fmt.Sprintf("count(DISTINCT %s)", expr)
+	}
+	return fmt.Sprintf("approx_count_distinct(%s)", expr)
+}
+
+// castDoubleExpr returns a cast of expr to a 64-bit float. Postgres doesn't have the DOUBLE alias.
+func castDoubleExpr(dialect drivers.Dialect, expr string) string {
+	if dialect == drivers.DialectPostgres {
+		return fmt.Sprintf("CAST(%s AS DOUBLE PRECISION)", expr)
+	}
+	return fmt.Sprintf("CAST(%s AS DOUBLE)", expr)
+}
+
+// castVarcharExpr returns a cast of expr to a string. ClickHouse can't cast nulls to non-nullable types, so toString is used instead.
+func castVarcharExpr(dialect drivers.Dialect, expr string) string {
+	if dialect == drivers.DialectClickHouse {
+		return fmt.Sprintf("toString(%s)", expr)
+	}
+	return fmt.Sprintf("CAST(%s as VARCHAR)", expr)
+}
+
+// druidTimeFloorExpr returns a TIME_FLOOR expression that truncates expr to the start of its grain-sized bucket in the
+// time zone. Druid resolves time zones (including DST transitions) natively, so unlike DuckDB, no offsets are inlined.
+func druidTimeFloorExpr(grain, expr string, loc *time.Location, cal calendar) (string, error) {
+	var period string
+	switch strings.ToUpper(grain) {
+	case "MILLISECOND":
+		period = "PT0.001S"
+	case "SECOND":
+		period = "PT1S"
+	case "MINUTE":
+		period = "PT1M"
+	case "HOUR":
+		period = "PT1H"
+	case "DAY":
+		period = "P1D"
+	case "WEEK":
+		period = "P1W"
+	case "MONTH":
+		period = "P1M"
+	case "QUARTER":
+		period = "P3M"
+	case "YEAR":
+		period = "P1Y"
+	default:
+		return "", fmt.Errorf("unsupported time grain '%s'", grain)
+	}
+
+	// Weeks and years that don't start on the ISO boundaries are aligned to an origin in the time zone.
+	// 1970-01-05 is a Monday.
+	origin := "NULL"
+	switch strings.ToUpper(grain) {
+	case "WEEK":
+		if cal.firstDayOfWeek > 1 {
+			t := time.Date(1970, 1, 5+int(cal.firstDayOfWeek)-1, 0, 0, 0, 0, loc)
+			origin = fmt.Sprintf("TIMESTAMP '%s'", t.UTC().Format(sqlTimestampFormat))
+		}
+	case "QUARTER", "YEAR":
+		if cal.firstMonthOfYear > 1 {
+			t := time.Date(1970, time.Month(cal.firstMonthOfYear), 1, 0, 0, 0, 0, loc)
+			origin = fmt.Sprintf("TIMESTAMP '%s'", t.UTC().Format(sqlTimestampFormat))
+		}
+	}
+
+	return fmt.Sprintf("TIME_FLOOR(%s, '%s', %s, '%s')", expr, period, origin, loc.String()), nil
+}
+
+// clickHouseTimeFloorExpr returns an expression that truncates expr to the start of its grain-sized bucket in the time zone.
+// ClickHouse resolves time zones natively, so the value is converted to the time zone before truncating.
+// Weeks and years that don't start on the ISO boundaries are truncated after shifting by the calendar's offset.
+func clickHouseTimeFloorExpr(grain, expr string, loc *time.Location, cal calendar) (string, error) {
+	local := fmt.Sprintf("toTimeZone(%s, '%s')", expr, loc.String())
+
+	var shift int
+	var shiftUnit string
+	switch strings.ToUpper(grain) {
+	case "MILLISECOND":
+		return fmt.Sprintf("toDateTime64(%s, 3, '%s')", expr, loc.String()), nil
+	case "SECOND", "MINUTE", "HOUR", "DAY", "MONTH":
+	case "WEEK":
+		if cal.firstDayOfWeek > 1 {
+			shift, shiftUnit = int(cal.firstDayOfWeek)-1, "DAY"
+		}
+	case "QUARTER", "YEAR":
+		if cal.firstMonthOfYear > 1 {
+			shift, shiftUnit = int(cal.firstMonthOfYear)-1, "MONTH"
+		}
+	default:
+		return "", fmt.Errorf("unsupported time grain '%s'", grain)
+	}
+
+	// date_trunc returns a Date for grains of a day or more, so the result is converted back to a timestamp
+	unit := strings.ToLower(grain)
+	if shift == 0 {
+		return fmt.Sprintf("toDateTime(date_trunc('%s', %s), '%s')", unit, local, loc.String()), nil
+	}
+	return fmt.Sprintf(
+		"(toDateTime(date_trunc('%s', %s - INTERVAL %d %s), '%s') + INTERVAL %d %s)",
+		unit, local, shift, shiftUnit, loc.String(), shift, shiftUnit,
+	), nil
+}
+
+// postgresTimeFloorExpr returns an expression that truncates expr to the start of its grain-sized bucket in the time zone.
+// The value is converted to a local timestamp in the time zone, truncated, then converted back (which resolves DST transitions).
+// Timestamps without a time zone are interpreted as UTC since the postgres driver sets the session time zone to UTC.
+func postgresTimeFloorExpr(grain, expr string, loc *time.Location, cal calendar) (string, error) {
+	local := fmt.Sprintf("CAST(%s AS TIMESTAMPTZ) AT TIME ZONE '%s'", expr, loc.String())
+
+	unit := strings.ToLower(grain)
+	var shift int
+	var shiftUnit string
+	switch strings.ToUpper(grain) {
+	case "MILLISECOND":
+		unit = "milliseconds"
+	case "SECOND", "MINUTE", "HOUR", "DAY", "MONTH":
+	case "WEEK":
+		if cal.firstDayOfWeek > 1 {
+			shift, shiftUnit = int(cal.firstDayOfWeek)-1, "DAY"
+		}
+	case "QUARTER", "YEAR":
+		if cal.firstMonthOfYear > 1 {
+			shift, shiftUnit = int(cal.firstMonthOfYear)-1, "MONTH"
+		}
+	default:
+		return "", fmt.Errorf("unsupported time grain '%s'", grain)
+	}
+
+	if shift == 0 {
+		return fmt.Sprintf("(date_trunc('%s', %s) AT TIME ZONE '%s')", unit, local, loc.String()), nil
+	}
+	return fmt.Sprintf(
+		"((date_trunc('%s', %s - INTERVAL '%d %s') + INTERVAL '%d %s') AT TIME ZONE '%s')",
+		unit, local, shift, shiftUnit, shift, shiftUnit, loc.String(),
+	), nil
+}
@@ -0,0 +1,122 @@
+package queries
+
+import (
+	"testing"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDruidMetricsViewSQL(t *testing.T) {
+	mv := &runtimev1.MetricsView{
+		Model:         "ad_bids",
+		TimeDimension: "__time",
+		Measures:      []*runtimev1.MetricsView_Measure{{Name: "bids", Expression: "count(*)"}},
+	}
+	filter := &runtimev1.MetricsViewFilter{
+		Include: []*runtimev1.MetricsViewFilter_Cond{{Name: "domain", Like: []*structpb.Value{structpb.NewStringValue("%.COM")}}},
+		Exclude: []*runtimev1.MetricsViewFilter_Cond{{Name: "publisher", Like: []*structpb.Value{structpb.NewStringValue("yahoo%")}}},
+	}
+
+	toplist := &MetricsViewToplist{
+		DimensionName: "domain",
+		MeasureNames:  []string{"bids"},
+		Sort:          []*runtimev1.MetricsViewSort{{Name: "bids"}},
+		Filter:        filter,
+	}
+	sql, args, err := toplist.buildMetricsTopListSQL(mv, &securitypolicy.Policy{}, drivers.DialectDruid)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT "domain", count(*) as "bids" FROM ad_bids WHERE 1=1  AND (LOWER(domain) LIKE LOWER(?)) AND (LOWER(publisher) NOT LIKE LOWER(?)) GROUP BY "domain" ORDER BY "bids" DESC LIMIT 100`, sql)
+	require.Equal(t, []any{"%.COM", "yahoo%"}, args)
+
+	sql, _, err = toplist.buildMetricsTopListSQL(mv, &securitypolicy.Policy{}, drivers.DialectDuckDB)
+	require.NoError(t, err)
+	require.Contains(t, sql, `domain ILIKE ?`)
+	require.Contains(t, sql, `ORDER BY true, "bids" DESC NULLS LAST`)
+
+	toplist.IncludeOther = true
+	_, _, err = toplist.buildMetricsTopListSQL(mv, &securitypolicy.Policy{}, drivers.DialectDruid)
+	require.Error(t, err)
+
+	tz, err := newTimeZone("Europe/Copenhagen", time.Now(), time.Now())
+	require.NoError(t, err)
+	tz.calendar = calendar{firstDayOfWeek: 7}
+	timeseries := &MetricsViewTimeSeries{
+		MeasureNames:    []string{"bids"},
+		TimeGranularity: "WEEK",
+	}
+	sql, _, err = timeseries.buildMetricsTimeSeriesSQL(mv, tz, &securitypolicy.Policy{}, drivers.DialectDruid)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT TIME_FLOOR("__time", 'P1W', TIMESTAMP '1970-01-10 23:00:00', 'Europe/Copenhagen') AS "__time", count(*) as "bids" FROM ad_bids WHERE 1=1 GROUP BY 1 ORDER BY "__time" LIMIT 1000`, sql)
+}
+
+func TestDruidTimeFloorExpr(t *testing.T) {
+	cases := []struct {
+		grain    string
+		calendar calendar
+		expr     string
+	}{
+		{"HOUR", calendar{}, `TIME_FLOOR(t, 'PT1H', NULL, 'UTC')`},
+		{"WEEK", calendar{}, `TIME_FLOOR(t, 'P1W', NULL, 'UTC')`},
+		{"WEEK", calendar{firstDayOfWeek: 3}, `TIME_FLOOR(t, 'P1W', TIMESTAMP '1970-01-07 00:00:00', 'UTC')`},
+		{"QUARTER", calendar{}, `TIME_FLOOR(t, 'P3M', NULL, 'UTC')`},
+		{"YEAR", calendar{firstMonthOfYear: 4}, `TIME_FLOOR(t, 'P1Y', TIMESTAMP '1970-04-01 00:00:00', 'UTC')`},
+	}
+	for _, c := range cases {
+		expr, err := druidTimeFloorExpr(c.grain, "t", time.UTC, c.calendar)
+		require.NoError(t, err)
+		require.Equal(t, c.expr, expr, c.grain)
+	}
+
+	_, err := druidTimeFloorExpr("DECADE", "t", time.UTC, calendar{})
+	require.Error(t, err)
+}
+
+func TestClickHouseTimeFloorExpr(t *testing.T) {
+	cases := []struct {
+		grain    string
+		calendar calendar
+		expr     string
+	}{
+		{"HOUR", calendar{}, `toDateTime(date_trunc('hour', toTimeZone(t, 'UTC')), 'UTC')`},
+		{"WEEK", calendar{}, `toDateTime(date_trunc('week', toTimeZone(t, 'UTC')), 'UTC')`},
+		{"WEEK", calendar{firstDayOfWeek: 3}, `(toDateTime(date_trunc('week', toTimeZone(t, 'UTC') - INTERVAL 2 DAY), 'UTC') + INTERVAL 2 DAY)`},
+		{"YEAR", calendar{firstMonthOfYear: 4}, `(toDateTime(date_trunc('year', toTimeZone(t, 'UTC') - INTERVAL 3 MONTH), 'UTC') + INTERVAL 3 MONTH)`},
+	}
+	for _, c := range cases {
+		expr, err := clickHouseTimeFloorExpr(c.grain, "t", time.UTC, c.calendar)
+		require.NoError(t, err)
+		require.Equal(t, c.expr, expr, c.grain)
+	}
+
+	_, err := clickHouseTimeFloorExpr("DECADE", "t", time.UTC, calendar{})
+	require.Error(t, err)
+}
+
+func TestPostgresTimeFloorExpr(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Copenhagen")
+	require.NoError(t, err)
+
+	cases := []struct {
+		grain    string
+		calendar calendar
+		expr     string
+	}{
+		{"MILLISECOND", calendar{}, `(date_trunc('milliseconds', CAST(t AS TIMESTAMPTZ) AT TIME ZONE 'Europe/Copenhagen') AT TIME ZONE 'Europe/Copenhagen')`},
+		{"DAY", calendar{}, `(date_trunc('day', CAST(t AS TIMESTAMPTZ) AT TIME ZONE 'Europe/Copenhagen') AT TIME ZONE 'Europe/Copenhagen')`},
+		{"WEEK", calendar{firstDayOfWeek: 7}, `((date_trunc('week', CAST(t AS TIMESTAMPTZ) AT TIME ZONE 'Europe/Copenhagen' - INTERVAL '6 DAY') + INTERVAL '6 DAY') AT TIME ZONE 'Europe/Copenhagen')`},
+		{"QUARTER", calendar{firstMonthOfYear: 2}, `((date_trunc('quarter', CAST(t AS TIMESTAMPTZ) AT TIME ZONE 'Europe/Copenhagen' - INTERVAL '1 MONTH') + INTERVAL '1 MONTH') AT TIME ZONE 'Europe/Copenhagen')`},
+	}
+	for _, c := range cases {
+		expr, err := postgresTimeFloorExpr(c.grain, "t", loc, c.calendar)
+		require.NoError(t, err)
+		require.Equal(t, c.expr, expr, c.grain)
+	}
+
+	_, err = postgresTimeFloorExpr("DECADE", "t", loc, calendar{})
+	require.Error(t, err)
+}
@@ -8,6 +8,8 @@ import (
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/arrowutil"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
 	"github.com/rilldata/rill/runtime/server/pbutil"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -27,23 +29,164 @@ func lookupMetricsView(ctx context.Context, rt *runtime.Runtime, instanceID, nam
 	return obj.GetMetricsView(), nil
 }
 
-func metricsQuery(ctx context.Context, olap drivers.OLAPStore, priority int, sql string, args []any) ([]*runtimev1.MetricsViewColumn, []*structpb.Struct, error) {
-	rows, err := olap.Execute(ctx, &drivers.Statement{
-		Query:    sql,
-		Args:     args,
-		Priority: priority,
-	})
+// resolveSecurityPolicy resolves the metrics view's security policy for a caller with the given attributes
+// and checks that the caller is allowed to access the dimensions and measures used in the query.
+// securityPolicyKey resolves the caller's security policy for a metrics view and returns its key.
+// Queries against metrics views add it to their cache keys instead of the caller's attributes,
+// which may contain claims that differ between tokens (like "exp") or that shouldn't be persisted.
+func securityPolicyKey(ctx context.Context, rt *runtime.Runtime, instanceID, metricsViewName string, attributes map[string]any) (string, error) {
+	mv, err := lookupMetricsView(ctx, rt, instanceID, metricsViewName)
 	if err != nil {
-		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		return "", err
+	}
+	if mv.Security == nil {
+		return "", nil
+	}
+	policy, err := securitypolicy.Resolve(mv, attributes)
+	if err != nil {
+		return "", status.Error(codes.PermissionDenied, err.Error())
+	}
+	return policy.Key(), nil
+}
+
+func resolveSecurityPolicy(mv *runtimev1.MetricsView, attributes map[string]any, dims, measures []string, filter *runtimev1.MetricsViewFilter) (*securitypolicy.Policy, error) {
+	policy, err := securitypolicy.Resolve(mv, attributes)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if filter != nil {
+		for _, cond := range filter.Include {
+			dims = append(dims, cond.Name)
+		}
+		for _, cond := range filter.Exclude {
+			dims = append(dims, cond.Name)
+		}
+	}
+
+	for _, d := range dims {
+		// Filters are added to the query as-is, so with a policy in place, only known dimensions are allowed
+		if mv.Security != nil && !hasDimension(mv, d) {
+			return nil, status.Errorf(codes.InvalidArgument, "dimension does not exist: '%s'", d)
+		}
+		if !policy.CanAccessDimension(d) {
+			return nil, status.Errorf(codes.PermissionDenied, "access to dimension '%s' is not allowed", d)
+		}
+	}
+
+	for _, m := range measures {
+		if !policy.CanAccessMeasure(m) {
+			return nil, status.Errorf(codes.PermissionDenied, "access to measure '%s' is not allowed", m)
+		}
+	}
+
+	return policy, nil
+}
+
+// metricsViewStatement builds the statement for a query against a metrics view.
+// If the policy has a row filter, build is called again with the unrendered policy to get the statement's LoggedQuery,
+// so the query history and the list of running queries show the row filter's template instead of the caller's attributes.
+func metricsViewStatement(policy *securitypolicy.Policy, build func(policy *securitypolicy.Policy) (string, []any, error)) (*drivers.Statement, error) {
+	sql, args, err := build(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &drivers.Statement{Query: sql, Args: args}
+	if policy.RowFilter != "" {
+		stmt.LoggedQuery, _, err = build(policy.Unrendered())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// metricsViewFrom returns the relation to select from when querying the metrics view.
+// If the policy has a row filter, the model is wrapped in a subquery so the filter applies to the entire query.
+func metricsViewFrom(mv *runtimev1.MetricsView, policy *securitypolicy.Policy) string {
+	if policy.RowFilter == "" {
+		return mv.Model
+	}
+	return fmt.Sprintf("(SELECT * FROM %s WHERE %s) AS %s", mv.Model, policy.RowFilter, safeName(mv.Model))
+}
+
+func hasDimension(mv *runtimev1.MetricsView, name string) bool {
+	for _, d := range mv.Dimensions {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// metricsQueryResult is the result of a metrics view query.
+// If the query was executed with arrow set, Data is nil and the rows are in ArrowData.
+type metricsQueryResult struct {
+	Schema    *runtimev1.StructType
+	Meta      []*runtimev1.MetricsViewColumn
+	Data      []*structpb.Struct
+	ArrowData []byte
+	// NumRows is the number of rows in Data or ArrowData
+	NumRows int
+}
+
+// metricsQuery executes a metrics view query. If arrow is true, the rows are encoded as an Arrow IPC stream as they're
+// read, using the types reported by the driver (so values don't go through a protobuf struct).
+func metricsQuery(ctx context.Context, olap drivers.OLAPStore, priority int, stmt *drivers.Statement, arrow bool) (*metricsQueryResult, error) {
+	stmt.Priority = priority
+	rows, err := olap.Execute(ctx, stmt)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 	defer rows.Close()
 
-	data, err := rowsToData(rows)
+	res := &metricsQueryResult{
+		Schema: rows.Schema,
+		Meta:   structTypeToMetricsViewColumn(rows.Schema),
+	}
+
+	if arrow {
+		res.ArrowData, res.NumRows, err = rowsToArrow(rows)
+	} else {
+		res.Data, err = rowsToData(rows)
+		res.NumRows = len(res.Data)
+	}
 	if err != nil {
-		return nil, nil, status.Error(codes.Internal, err.Error())
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	return structTypeToMetricsViewColumn(rows.Schema), data, nil
+	return res, nil
+}
+
+// rowsToArrow encodes rows as an Arrow IPC stream and returns it along with the number of rows
+func rowsToArrow(rows *drivers.Result) ([]byte, int, error) {
+	enc := arrowutil.NewEncoder(rows.Schema)
+	defer enc.Release()
+
+	n := 0
+	for rows.Next() {
+		row, err := rows.SliceScan()
+		if err != nil {
+			return nil, 0, err
+		}
+		err = enc.Append(row)
+		if err != nil {
+			return nil, 0, err
+		}
+		n++
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := enc.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, n, nil
 }
 
 func rowsToData(rows *drivers.Result) ([]*structpb.Struct, error) {
@@ -71,6 +214,16 @@ func rowsToData(rows *drivers.Result) ([]*structpb.Struct, error) {
 	return data, nil
 }
 
+// metricsViewArrowData encodes rows that were post-processed after being read as an Arrow IPC stream.
+// The schema should be the query's schema (see metricsQueryResult). Fields missing from a row are null.
+func metricsViewArrowData(schema *runtimev1.StructType, data []*structpb.Struct) ([]byte, error) {
+	res, err := arrowutil.EncodeStructs(schema, data)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return res, nil
+}
+
 func structTypeToMetricsViewColumn(v *runtimev1.StructType) []*runtimev1.MetricsViewColumn {
 	res := make([]*runtimev1.MetricsViewColumn, len(v.Fields))
 	for i, f := range v.Fields {
@@ -84,12 +237,12 @@ func structTypeToMetricsViewColumn(v *runtimev1.StructType) []*runtimev1.Metrics
 }
 
 // Builds clause and args for runtimev1.MetricsViewFilter
-func buildFilterClauseForMetricsViewFilter(filter *runtimev1.MetricsViewFilter) (string, []any, error) {
+func buildFilterClauseForMetricsViewFilter(filter *runtimev1.MetricsViewFilter, dialect drivers.Dialect) (string, []any, error) {
 	whereClause := ""
 	var args []any
 
 	if filter != nil && filter.Include != nil {
-		clause, clauseArgs, err := buildFilterClauseForConditions(filter.Include, false)
+		clause, clauseArgs, err := buildFilterClauseForConditions(filter.Include, false, dialect)
 		if err != nil {
 			return "", nil, err
 		}
@@ -98,7 +251,7 @@ func buildFilterClauseForMetricsViewFilter(filter *runtimev1.MetricsViewFilter)
 	}
 
 	if filter != nil && filter.Exclude != nil {
-		clause, clauseArgs, err := buildFilterClauseForConditions(filter.Exclude, true)
+		clause, clauseArgs, err := buildFilterClauseForConditions(filter.Exclude, true, dialect)
 		if err != nil {
 			return "", nil, err
 		}
@@ -109,12 +262,12 @@ func buildFilterClauseForMetricsViewFilter(filter *runtimev1.MetricsViewFilter)
 	return whereClause, args, nil
 }
 
-func buildFilterClauseForConditions(conds []*runtimev1.MetricsViewFilter_Cond, exclude bool) (string, []any, error) {
+func buildFilterClauseForConditions(conds []*runtimev1.MetricsViewFilter_Cond, exclude bool, dialect drivers.Dialect) (string, []any, error) {
 	clause := ""
 	var args []any
 
 	for _, cond := range conds {
-		condClause, condArgs, err := buildFilterClauseForCondition(cond, exclude)
+		condClause, condArgs, err := buildFilterClauseForCondition(cond, exclude, dialect)
 		if err != nil {
 			return "", nil, fmt.Errorf("filter error: %w", err)
 		}
@@ -128,7 +281,7 @@ func buildFilterClauseForConditions(conds []*runtimev1.MetricsViewFilter_Cond, e
 	return clause, args, nil
 }
 
-func buildFilterClauseForCondition(cond *runtimev1.MetricsViewFilter_Cond, exclude bool) (string, []any, error) {
+func buildFilterClauseForCondition(cond *runtimev1.MetricsViewFilter_Cond, exclude bool, dialect drivers.Dialect) (string, []any, error) {
 	var clauses []string
 	var args []any
 
@@ -176,7 +329,7 @@ func buildFilterClauseForCondition(cond *runtimev1.MetricsViewFilter_Cond, exclu
 			}
 			args = append(args, arg)
 			// <dimension> (NOT) ILIKE ?
-			clauses = append(clauses, fmt.Sprintf("%s %s ILIKE ?", cond.Name, operatorPrefix))
+			clauses = append(clauses, ilikeExpr(dialect, cond.Name, exclude))
 		}
 	}
 
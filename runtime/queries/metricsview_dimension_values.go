@@ -0,0 +1,200 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type MetricsViewDimensionValues struct {
+	MetricsViewName string                       `json:"metrics_view_name,omitempty"`
+	DimensionName   string                       `json:"dimension_name,omitempty"`
+	Search          string                       `json:"search,omitempty"`
+	SearchPrefix    bool                         `json:"search_prefix,omitempty"`
+	TimeStart       *timestamppb.Timestamp       `json:"time_start,omitempty"`
+	TimeEnd         *timestamppb.Timestamp       `json:"time_end,omitempty"`
+	Filter          *runtimev1.MetricsViewFilter `json:"filter,omitempty"`
+	Limit           int64                        `json:"limit,omitempty"`
+	Offset          int64                        `json:"offset,omitempty"`
+	// Attributes are used to resolve the caller\'s security policy. The resolved policy is part of the cache key instead.
+	Attributes map[string]any `json:"-"`
+
+	Result *runtimev1.MetricsViewDimensionValuesResponse `json:"-"`
+
+	policyKey string
+}
+
+var (
+	_ runtime.PersistentQuery = &MetricsViewDimensionValues{}
+	_ runtime.PreparedQuery   = &MetricsViewDimensionValues{}
+)
+
+func (q *MetricsViewDimensionValues) Key() string {
+	r, err := json.Marshal(q)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("MetricsViewDimensionValues:%s:%s", string(r), q.policyKey)
+}
+
+// Prepare resolves the caller's security policy, which is part of the cache key (see securityPolicyKey)
+func (q *MetricsViewDimensionValues) Prepare(ctx context.Context, rt *runtime.Runtime, instanceID string) error {
+	key, err := securityPolicyKey(ctx, rt, instanceID, q.MetricsViewName, q.Attributes)
+	if err != nil {
+		return err
+	}
+	q.policyKey = key
+	return nil
+}
+
+func (q *MetricsViewDimensionValues) Deps() []string {
+	return []string{q.MetricsViewName}
+}
+
+func (q *MetricsViewDimensionValues) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
+}
+
+func (q *MetricsViewDimensionValues) UnmarshalResult(v any) error {
+	res, ok := v.(*runtimev1.MetricsViewDimensionValuesResponse)
+	if !ok {
+		return fmt.Errorf("MetricsViewDimensionValues: mismatched unmarshal input")
+	}
+	q.Result = res
+	return nil
+}
+
+func (q *MetricsViewDimensionValues) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *MetricsViewDimensionValues) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.MetricsViewDimensionValuesResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
+func (q *MetricsViewDimensionValues) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
+	olap, err := rt.OLAP(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkQueryKind(olap, drivers.QueryKindMetricsView); err != nil {
+		return err
+	}
+
+	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
+	if err != nil {
+		return err
+	}
+
+	if !hasDimension(mv, q.DimensionName) {
+		return status.Errorf(codes.InvalidArgument, "dimension does not exist: '%s'", q.DimensionName)
+	}
+
+	if mv.TimeDimension == "" && (q.TimeStart != nil || q.TimeEnd != nil) {
+		return fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
+	}
+
+	policy, err := resolveSecurityPolicy(mv, q.Attributes, []string{q.DimensionName}, nil, q.Filter)
+	if err != nil {
+		return err
+	}
+
+	// Build query
+	stmt, err := metricsViewStatement(policy, func(policy *securitypolicy.Policy) (string, []any, error) {
+		return q.buildDimensionValuesSQL(mv, policy, olap.Dialect())
+	})
+	if err != nil {
+		return fmt.Errorf("error building query: %w", err)
+	}
+
+	// Execute
+	qr, err := metricsQuery(ctx, olap, priority, stmt, false)
+	if err != nil {
+		return err
+	}
+
+	res := &runtimev1.MetricsViewDimensionValuesResponse{}
+	for i, row := range qr.Data {
+		// We fetch one extra row to tell if there are more values
+		if int64(i) == q.Limit {
+			res.HasMore = true
+			break
+		}
+		res.Entries = append(res.Entries, &runtimev1.MetricsViewDimensionValuesResponse_Entry{
+			Value: row.Fields["value"],
+			Count: int64(row.Fields["count"].GetNumberValue()),
+		})
+	}
+
+	q.Result = res
+	return nil
+}
+
+func (q *MetricsViewDimensionValues) buildDimensionValuesSQL(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, dialect drivers.Dialect) (string, []any, error) {
+	dimName := safeName(q.DimensionName)
+
+	whereClause := "1=1"
+	args := []any{}
+	if mv.TimeDimension != "" {
+		if q.TimeStart != nil {
+			whereClause += fmt.Sprintf(" AND %s >= ?", safeName(mv.TimeDimension))
+			args = append(args, q.TimeStart.AsTime())
+		}
+		if q.TimeEnd != nil {
+			whereClause += fmt.Sprintf(" AND %s < ?", safeName(mv.TimeDimension))
+			args = append(args, q.TimeEnd.AsTime())
+		}
+	}
+
+	if q.Search != "" {
+		// strpos avoids having to escape wildcards in the search string
+		if q.SearchPrefix {
+			whereClause += fmt.Sprintf(" AND strpos(lower(CAST(%s AS VARCHAR)), lower(?)) = 1", dimName)
+		} else {
+			whereClause += fmt.Sprintf(" AND strpos(lower(CAST(%s AS VARCHAR)), lower(?)) > 0", dimName)
+		}
+		args = append(args, q.Search)
+	}
+
+	if q.Filter != nil {
+		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter, dialect)
+		if err != nil {
+			return "", nil, err
+		}
+		whereClause += clause
+		args = append(args, clauseArgs...)
+	}
+
+	if q.Limit == 0 {
+		q.Limit = 100
+	}
+
+	sql := fmt.Sprintf("SELECT %s AS value, count(*) AS count FROM %s WHERE %s GROUP BY %s ORDER BY count DESC, %s LIMIT %d OFFSET %d",
+		dimName,
+		metricsViewFrom(mv, policy),
+		whereClause,
+		dimName,
+		orderByExpr(dialect, "value", true),
+		q.Limit+1,
+		q.Offset,
+	)
+
+	return sql, args, nil
+}
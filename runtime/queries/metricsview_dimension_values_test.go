@@ -0,0 +1,122 @@
+package queries
+
+import (
+	"context"
+	"testing"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/testruntime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestMetricsViewDimensionValues(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	q := &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+	}
+	err := q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(q.Result.Entries))
+	require.False(t, q.Result.HasMore)
+	require.Equal(t, "msn.com", q.Result.Entries[0].Value.GetStringValue())
+	require.Equal(t, int64(1), q.Result.Entries[0].Count)
+	require.Equal(t, "yahoo.com", q.Result.Entries[1].Value.GetStringValue())
+
+	// Pagination
+	q = &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		Limit:           1,
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Entries))
+	require.True(t, q.Result.HasMore)
+	require.Equal(t, "msn.com", q.Result.Entries[0].Value.GetStringValue())
+
+	q = &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		Limit:           1,
+		Offset:          1,
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Entries))
+	require.False(t, q.Result.HasMore)
+	require.Equal(t, "yahoo.com", q.Result.Entries[0].Value.GetStringValue())
+}
+
+func TestMetricsViewDimensionValues_Search(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	q := &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		Search:          "HOO",
+	}
+	err := q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Entries))
+	require.Equal(t, "yahoo.com", q.Result.Entries[0].Value.GetStringValue())
+
+	q = &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		Search:          "hoo",
+		SearchPrefix:    true,
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(q.Result.Entries))
+
+	// Searches for wildcard characters are literal
+	q = &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		Search:          "%",
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(q.Result.Entries))
+}
+
+func TestMetricsViewDimensionValues_Filter(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	q := &MetricsViewDimensionValues{
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "publisher",
+		Filter: &runtimev1.MetricsViewFilter{
+			Include: []*runtimev1.MetricsViewFilter_Cond{
+				{
+					Name: "domain",
+					In:   []*structpb.Value{structpb.NewStringValue("msn.com")},
+				},
+			},
+		},
+	}
+	err := q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Entries))
+	require.Equal(t, structpb.NewNullValue(), q.Result.Entries[0].Value)
+	require.Equal(t, int64(1), q.Result.Entries[0].Count)
+}
+
+func BenchmarkMetricsViewDimensionValues(b *testing.B) {
+	rt, instanceID := testruntime.NewInstanceForProject(b, "ad_bids")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := &MetricsViewDimensionValues{
+			MetricsViewName: "ad_bids_metrics",
+			DimensionName:   "domain",
+			Search:          "com",
+		}
+		err := q.Resolve(context.Background(), rt, instanceID, 0)
+		require.NoError(b, err)
+		require.NotEmpty(b, q.Result)
+	}
+}
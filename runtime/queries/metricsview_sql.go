@@ -0,0 +1,418 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/arrowutil"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsViewSQL runs a SQL query against a metrics view as if it was a table with a column for each dimension and measure.
+// Measures are expanded to their expressions and the result is grouped by the selected dimensions.
+// See metricsview_sql_parser.go for the supported SQL.
+type MetricsViewSQL struct {
+	MetricsViewName string                 `json:"metrics_view_name,omitempty"`
+	SQL             string                 `json:"sql,omitempty"`
+	Args            []any                  `json:"args,omitempty"`
+	DryRun          bool                   `json:"dry_run,omitempty"`
+	MaxRows         int64                  `json:"max_rows,omitempty"`
+	ResultFormat    runtimev1.ResultFormat `json:"result_format,omitempty"`
+	// Attributes are used to resolve the caller\'s security policy. The resolved policy is part of the cache key instead.
+	Attributes map[string]any `json:"-"`
+	// Timeout is the max duration of the query (it's not part of the cache key)
+	Timeout time.Duration `json:"-"`
+
+	Result *runtimev1.QueryResponse `json:"-"`
+
+	policyKey string
+}
+
+var (
+	_ runtime.PersistentQuery = &MetricsViewSQL{}
+	_ runtime.PreparedQuery   = &MetricsViewSQL{}
+)
+
+// MetricsViewNameFromSQL returns the name of the table or view queried by sql if it has the form of a metrics view query.
+// Callers should check that the name refers to a metrics view before running it with MetricsViewSQL.
+func MetricsViewNameFromSQL(sql string) (string, bool) {
+	stmt, err := parseMetricsViewSQL(sql)
+	if err != nil {
+		return "", false
+	}
+	return stmt.from, true
+}
+
+func (q *MetricsViewSQL) Key() string {
+	r, err := json.Marshal(q)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("MetricsViewSQL:%s:%s", string(r), q.policyKey)
+}
+
+// Prepare resolves the caller's security policy, which is part of the cache key (see securityPolicyKey)
+func (q *MetricsViewSQL) Prepare(ctx context.Context, rt *runtime.Runtime, instanceID string) error {
+	key, err := securityPolicyKey(ctx, rt, instanceID, q.MetricsViewName, q.Attributes)
+	if err != nil {
+		return err
+	}
+	q.policyKey = key
+	return nil
+}
+
+func (q *MetricsViewSQL) Deps() []string {
+	return []string{q.MetricsViewName}
+}
+
+func (q *MetricsViewSQL) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
+}
+
+func (q *MetricsViewSQL) UnmarshalResult(v any) error {
+	res, ok := v.(*runtimev1.QueryResponse)
+	if !ok {
+		return fmt.Errorf("MetricsViewSQL: mismatched unmarshal input")
+	}
+	q.Result = res
+	return nil
+}
+
+func (q *MetricsViewSQL) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *MetricsViewSQL) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.QueryResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
+func (q *MetricsViewSQL) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
+	olap, err := rt.OLAP(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := q.ResolveStatement(ctx, rt, instanceID)
+	if err != nil {
+		return err
+	}
+	stmt.DryRun = q.DryRun
+	stmt.Priority = priority
+	stmt.ExecutionTimeout = q.Timeout
+	stmt.MaxRows = q.MaxRows
+
+	rows, err := olap.Execute(ctx, stmt)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if q.DryRun {
+		q.Result = &runtimev1.QueryResponse{}
+		return nil
+	}
+	defer rows.Close()
+
+	res := &runtimev1.QueryResponse{Meta: rows.Schema}
+	if q.ResultFormat == runtimev1.ResultFormat_RESULT_FORMAT_ARROW {
+		res.ArrowData, err = arrowutil.EncodeRows(rows.Schema, rows)
+	} else {
+		res.Data, err = rowsToData(rows)
+	}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	res.Truncated = rows.Truncated()
+
+	q.Result = res
+	return nil
+}
+
+// ResolveStatement returns the statement for the query rewritten against the metrics view's model.
+// It applies the metrics view's security policy, so the result is safe to run outside of Resolve (e.g. for exports).
+func (q *MetricsViewSQL) ResolveStatement(ctx context.Context, rt *runtime.Runtime, instanceID string) (*drivers.Statement, error) {
+	olap, err := rt.OLAP(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkQueryKind(olap, drivers.QueryKindMetricsViewSQL); err != nil {
+		return nil, err
+	}
+
+	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := parseMetricsViewSQL(q.SQL)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported metrics view query: %s", err.Error())
+	}
+
+	policy, err := resolveSecurityPolicy(mv, q.Attributes, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return metricsViewStatement(policy, func(policy *securitypolicy.Policy) (string, []any, error) {
+		sql, err := q.rewrite(mv, policy, stmt)
+		return sql, q.Args, err
+	})
+}
+
+// rewrite rewrites the parsed query into a query against the metrics view's model
+func (q *MetricsViewSQL) rewrite(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, stmt *metricsViewSQLStatement) (string, error) {
+	var selectCols, groupCols []string
+	addDimension := func(d *runtimev1.MetricsView_Dimension, alias string) error {
+		if !policy.CanAccessDimension(d.Name) {
+			return status.Errorf(codes.PermissionDenied, "access to dimension '%s' is not allowed", d.Name)
+		}
+		selectCols = append(selectCols, fmt.Sprintf("%s AS %s", safeName(d.Name), safeName(alias)))
+		groupCols = append(groupCols, fmt.Sprintf("%d", len(selectCols)))
+		return nil
+	}
+	addMeasure := func(m *runtimev1.MetricsView_Measure, alias string) error {
+		if !policy.CanAccessMeasure(m.Name) {
+			return status.Errorf(codes.PermissionDenied, "access to measure '%s' is not allowed", m.Name)
+		}
+		selectCols = append(selectCols, fmt.Sprintf("%s AS %s", m.Expression, safeName(alias)))
+		return nil
+	}
+
+	for _, item := range stmt.items {
+		if item.star {
+			// Expand to all the dimensions and measures the caller can access
+			for _, d := range mv.Dimensions {
+				if policy.CanAccessDimension(d.Name) {
+					_ = addDimension(d, d.Name)
+				}
+			}
+			for _, m := range mv.Measures {
+				if policy.CanAccessMeasure(m.Name) {
+					_ = addMeasure(m, m.Name)
+				}
+			}
+			continue
+		}
+
+		d, m := lookupMetricsViewField(mv, item.name, item.quoted)
+		var err error
+		switch {
+		case d != nil:
+			err = addDimension(d, item.alias)
+		case m != nil:
+			err = addMeasure(m, item.alias)
+		default:
+			err = status.Errorf(codes.InvalidArgument, "metrics view '%s' does not have a dimension or measure named '%s'", mv.Name, item.name)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(selectCols) == 0 {
+		return "", status.Error(codes.InvalidArgument, "no dimensions or measures selected")
+	}
+
+	// The other clauses are passed through (apart from expanding measures), so with a policy in place, we check they only reference
+	// dimensions, measures and aliases the caller can access (and don't query other tables)
+	if mv.Security != nil {
+		if stmt.subquery {
+			return "", status.Error(codes.PermissionDenied, "subqueries are not allowed against metrics views with a security policy")
+		}
+		for _, id := range stmt.identifiers {
+			if err := checkPassthroughIdentifier(mv, policy, stmt, id); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// Measures can't be filtered before aggregation, but are expanded to their expressions in HAVING and ORDER BY
+	for _, id := range stmt.where.identifiers {
+		if referencedMeasure(mv, stmt, id) != nil {
+			return "", status.Errorf(codes.InvalidArgument, "measure '%s' can't be used in WHERE, use HAVING to filter on measures", id.value)
+		}
+	}
+	having, err := expandMeasures(mv, policy, stmt, stmt.having)
+	if err != nil {
+		return "", err
+	}
+	orderBy, err := expandMeasures(mv, policy, stmt, stmt.orderBy)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(selectCols, ", "), metricsViewFrom(mv, policy))
+	if stmt.where.raw != "" {
+		fmt.Fprintf(&b, " WHERE %s", stmt.where.raw)
+	}
+	if len(groupCols) > 0 {
+		// If only dimensions are selected, this returns their distinct values
+		fmt.Fprintf(&b, " GROUP BY %s", strings.Join(groupCols, ", "))
+	}
+	if having != "" {
+		fmt.Fprintf(&b, " HAVING %s", having)
+	}
+	if orderBy != "" {
+		fmt.Fprintf(&b, " ORDER BY %s", orderBy)
+	}
+	if stmt.limit != "" {
+		fmt.Fprintf(&b, " LIMIT %s", stmt.limit)
+	}
+	if stmt.offset != "" {
+		fmt.Fprintf(&b, " OFFSET %s", stmt.offset)
+	}
+
+	return b.String(), nil
+}
+
+// referencedMeasure returns the measure referenced by an identifier in a clause, or nil if it doesn't reference a measure.
+// Aliases of selected measures also reference them, and other aliases take precedence over dimension and measure names.
+func referencedMeasure(mv *runtimev1.MetricsView, stmt *metricsViewSQLStatement, id sqlIdentifier) *runtimev1.MetricsView_Measure {
+	if id.call || id.qualified {
+		return nil
+	}
+	if item, ok := selectedItem(stmt, id); ok {
+		_, m := lookupMetricsViewField(mv, item.name, item.quoted)
+		return m
+	}
+	_, m := lookupMetricsViewField(mv, id.value, id.kind == sqlTokenQuotedIdent)
+	return m
+}
+
+// expandMeasures returns the clause with references to measures replaced by their expressions.
+// References to the aliases of selected items are kept, since the database resolves them to the output columns.
+func expandMeasures(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, stmt *metricsViewSQLStatement, c sqlClause) (string, error) {
+	var b strings.Builder
+	pos := c.start
+	for _, id := range c.identifiers {
+		if _, ok := selectedItem(stmt, id); ok {
+			continue
+		}
+		m := referencedMeasure(mv, stmt, id)
+		if m == nil {
+			continue
+		}
+		if !policy.CanAccessMeasure(m.Name) {
+			return "", status.Errorf(codes.PermissionDenied, "access to measure '%s' is not allowed", m.Name)
+		}
+		b.WriteString(c.raw[pos-c.start : id.start-c.start])
+		fmt.Fprintf(&b, "(%s)", m.Expression)
+		pos = id.end
+	}
+	b.WriteString(c.raw[pos-c.start:])
+	return b.String(), nil
+}
+
+// selectedItem returns the item in the SELECT clause whose alias is referenced by the identifier
+func selectedItem(stmt *metricsViewSQLStatement, id sqlIdentifier) (metricsViewSelectItem, bool) {
+	quoted := id.kind == sqlTokenQuotedIdent
+	for _, item := range stmt.items {
+		if !item.star && (item.alias == id.value || (!quoted && strings.EqualFold(item.alias, id.value))) {
+			return item, true
+		}
+	}
+	return metricsViewSelectItem{}, false
+}
+
+// passthroughKeywords are the keywords allowed in the clauses after FROM of queries against metrics views with a security policy.
+// It includes the names of types used in casts and typed literals.
+var passthroughKeywords = toSet(
+	"AND", "OR", "NOT", "IN", "IS", "NULL", "LIKE", "ILIKE", "BETWEEN", "TRUE", "FALSE", "ASC", "DESC", "NULLS", "FIRST", "LAST",
+	"CASE", "WHEN", "THEN", "ELSE", "END", "AS", "INTERVAL", "DATE", "TIME", "TIMESTAMP", "VARCHAR", "INTEGER", "BIGINT", "DOUBLE",
+	"DAY", "DAYS", "MONTH", "MONTHS", "YEAR", "YEARS", "HOUR", "HOURS", "MINUTE", "MINUTES", "SECOND", "SECONDS", "WEEK", "WEEKS",
+)
+
+// passthroughFunctions are the functions allowed in the clauses after FROM of queries against metrics views with a security policy.
+// Their arguments are checked like any other identifier, so they can't read restricted columns.
+var passthroughFunctions = toSet(
+	"CAST", "COALESCE", "LOWER", "UPPER", "LENGTH", "TRIM", "ABS", "ROUND", "FLOOR", "CEIL",
+	"DATE_TRUNC", "DATE_PART", "EXTRACT", "NOW", "CURRENT_DATE", "COUNT", "SUM", "MIN", "MAX", "AVG",
+)
+
+func toSet(vals ...string) map[string]bool {
+	res := make(map[string]bool, len(vals))
+	for _, v := range vals {
+		res[v] = true
+	}
+	return res
+}
+
+// checkPassthroughIdentifier returns a PermissionDenied error if an identifier in a passed-through clause isn't an allowed
+// keyword or function, an accessible dimension or measure, or the alias of a selected dimension or measure.
+// This prevents callers from probing restricted measures or model columns that the metrics view doesn't expose.
+func checkPassthroughIdentifier(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, stmt *metricsViewSQLStatement, id sqlIdentifier) error {
+	quoted := id.kind == sqlTokenQuotedIdent
+	if !quoted {
+		kw := strings.ToUpper(id.value)
+		if id.call && passthroughFunctions[kw] {
+			return nil
+		}
+		if !id.call && passthroughKeywords[kw] {
+			return nil
+		}
+	}
+
+	if !id.call {
+		d, m := lookupMetricsViewField(mv, id.value, quoted)
+		if d != nil && policy.CanAccessDimension(d.Name) {
+			return nil
+		}
+		if m != nil && policy.CanAccessMeasure(m.Name) {
+			return nil
+		}
+		// The alias may shadow a restricted field, but then the SELECT clause has already been rejected
+		if _, ok := selectedItem(stmt, id); ok {
+			return nil
+		}
+	}
+
+	return status.Errorf(codes.PermissionDenied, "'%s' is not a dimension or measure you can access in metrics view '%s'", id.text, mv.Name)
+}
+
+// lookupMetricsViewField finds the dimension or measure referenced by name in a query.
+// Measures can be referenced by name or label. Unquoted names are matched case insensitively.
+func lookupMetricsViewField(mv *runtimev1.MetricsView, name string, quoted bool) (*runtimev1.MetricsView_Dimension, *runtimev1.MetricsView_Measure) {
+	eq := func(a, b string) bool {
+		if quoted {
+			return a == b
+		}
+		return strings.EqualFold(a, b)
+	}
+
+	for _, d := range mv.Dimensions {
+		if eq(d.Name, name) {
+			return d, nil
+		}
+	}
+	for _, m := range mv.Measures {
+		if eq(m.Name, name) || eq(m.Label, name) {
+			return nil, m
+		}
+	}
+	return nil, nil
+}
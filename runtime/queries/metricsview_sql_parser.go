@@ -0,0 +1,349 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// This file contains a minimal SQL parser for queries against metrics views.
+// It only understands statements of the form:
+//
+//	SELECT <dimension or measure> [[AS] alias], ... FROM <metrics view>
+//	[WHERE ...] [GROUP BY ...] [HAVING ...] [ORDER BY ...] [LIMIT ...] [OFFSET ...]
+//
+// The clauses after FROM are kept as raw SQL (except GROUP BY, which is implied by the selected dimensions).
+
+type sqlTokenKind int
+
+const (
+	sqlTokenIdent sqlTokenKind = iota
+	sqlTokenQuotedIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenSymbol
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	// text is the token as written in the SQL
+	text string
+	// value is the unquoted name for identifiers
+	value string
+	// start and end are byte offsets of the token in the SQL
+	start int
+	end   int
+}
+
+func (t sqlToken) isKeyword(kw string) bool {
+	return t.kind == sqlTokenIdent && strings.EqualFold(t.text, kw)
+}
+
+func tokenizeSQL(sql string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	i := 0
+	for i < len(sql) {
+		c := rune(sql[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for {
+				k := strings.IndexRune(sql[j:], c)
+				if k < 0 {
+					return nil, fmt.Errorf("unterminated quote at position %d", i)
+				}
+				j += k + 1
+				// Two quotes in a row is an escaped quote
+				if j < len(sql) && rune(sql[j]) == c {
+					j++
+					continue
+				}
+				break
+			}
+			text := sql[i:j]
+			tok := sqlToken{kind: sqlTokenString, text: text, start: i, end: j}
+			if c == '"' {
+				tok.kind = sqlTokenQuotedIdent
+				tok.value = strings.ReplaceAll(text[1:len(text)-1], `""`, `"`)
+			}
+			tokens = append(tokens, tok)
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(sql) && (sql[j] == '_' || sql[j] == '$' || unicode.IsLetter(rune(sql[j])) || unicode.IsDigit(rune(sql[j]))) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenIdent, text: sql[i:j], value: sql[i:j], start: i, end: j})
+			i = j
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(sql) && (unicode.IsDigit(rune(sql[j])) || sql[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlTokenNumber, text: sql[i:j], start: i, end: j})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{kind: sqlTokenSymbol, text: sql[i : i+1], start: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// metricsViewSelectItem is a dimension or measure in the SELECT clause of a metrics view query
+type metricsViewSelectItem struct {
+	// name is the referenced dimension or measure
+	name string
+	// quoted is true if name was a quoted identifier (and should be matched case sensitively)
+	quoted bool
+	// alias is the output name
+	alias string
+	// star is true for "SELECT *"
+	star bool
+}
+
+// metricsViewSQLStatement is a parsed query against a metrics view
+type metricsViewSQLStatement struct {
+	items []metricsViewSelectItem
+	from  string
+	// where, having and orderBy are the clauses that may reference dimensions and measures
+	where   sqlClause
+	having  sqlClause
+	orderBy sqlClause
+	// limit and offset contain the raw SQL of each clause (without the keywords)
+	limit  string
+	offset string
+	// identifiers contains the identifiers referenced in the clauses after FROM
+	identifiers []sqlIdentifier
+	// subquery is true if the clauses after FROM contain a nested SELECT
+	subquery bool
+}
+
+// sqlClause is the raw SQL of a clause (without the keyword) and the identifiers it references
+type sqlClause struct {
+	raw string
+	// start is the byte offset of raw in the query
+	start       int
+	identifiers []sqlIdentifier
+}
+
+// sqlIdentifier is an identifier referenced in the clauses after FROM
+type sqlIdentifier struct {
+	sqlToken
+	// call is true if the identifier is followed by an opening parenthesis (e.g. a function name)
+	call bool
+	// qualified is true if the identifier follows a dot (e.g. a struct field)
+	qualified bool
+}
+
+// parseMetricsViewSQL parses a query against a metrics view.
+// It returns an error if the SQL isn't a statement of the supported form.
+func parseMetricsViewSQL(sql string) (*metricsViewSQLStatement, error) {
+	tokens, err := tokenizeSQL(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drop a trailing semicolon
+	if len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	if len(tokens) == 0 || !tokens[0].isKeyword("SELECT") {
+		return nil, fmt.Errorf("expected SELECT")
+	}
+
+	// Split the statement into clauses on top-level keywords
+	type clause struct {
+		keyword string
+		tokens  []sqlToken
+	}
+	var clauses []clause
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		}
+
+		if depth == 0 && t.kind == sqlTokenIdent {
+			kw := strings.ToUpper(t.text)
+			if (kw == "GROUP" || kw == "ORDER") && i+1 < len(tokens) && tokens[i+1].isKeyword("BY") {
+				clauses = append(clauses, clause{keyword: kw + " BY"})
+				i++
+				continue
+			}
+			switch kw {
+			case "SELECT", "FROM", "WHERE", "HAVING", "LIMIT", "OFFSET":
+				if kw == "SELECT" && i != 0 {
+					return nil, fmt.Errorf("unsupported SELECT at position %d", t.start)
+				}
+				clauses = append(clauses, clause{keyword: kw})
+				continue
+			case "JOIN", "UNION", "INTERSECT", "EXCEPT", "WINDOW", "QUALIFY":
+				return nil, fmt.Errorf("unsupported %s", kw)
+			}
+		}
+
+		clauses[len(clauses)-1].tokens = append(clauses[len(clauses)-1].tokens, t)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+
+	stmt := &metricsViewSQLStatement{}
+	seen := make(map[string]bool)
+	for i, c := range clauses {
+		if seen[c.keyword] {
+			return nil, fmt.Errorf("duplicate %s", c.keyword)
+		}
+		seen[c.keyword] = true
+
+		if i == 1 && c.keyword != "FROM" {
+			return nil, fmt.Errorf("expected FROM")
+		}
+
+		if len(c.tokens) == 0 {
+			return nil, fmt.Errorf("empty %s", c.keyword)
+		}
+
+		raw := sql[c.tokens[0].start:c.tokens[len(c.tokens)-1].end]
+		clause := sqlClause{raw: raw, start: c.tokens[0].start}
+		if c.keyword != "SELECT" && c.keyword != "FROM" {
+			for j, t := range c.tokens {
+				if t.kind == sqlTokenIdent || t.kind == sqlTokenQuotedIdent {
+					id := sqlIdentifier{sqlToken: t}
+					if j+1 < len(c.tokens) {
+						id.call = c.tokens[j+1].text == "("
+					}
+					if j > 0 {
+						id.qualified = c.tokens[j-1].text == "."
+					}
+					stmt.identifiers = append(stmt.identifiers, id)
+					clause.identifiers = append(clause.identifiers, id)
+				}
+				if t.isKeyword("SELECT") {
+					stmt.subquery = true
+				}
+			}
+		}
+
+		switch c.keyword {
+		case "SELECT":
+			stmt.items, err = parseSelectItems(c.tokens)
+			if err != nil {
+				return nil, err
+			}
+		case "FROM":
+			if len(c.tokens) != 1 || (c.tokens[0].kind != sqlTokenIdent && c.tokens[0].kind != sqlTokenQuotedIdent) {
+				return nil, fmt.Errorf("expected a single metrics view in FROM")
+			}
+			stmt.from = c.tokens[0].value
+		case "WHERE":
+			stmt.where = clause
+		case "GROUP BY":
+			// Grouping is implied by the selected dimensions
+		case "HAVING":
+			stmt.having = clause
+		case "ORDER BY":
+			stmt.orderBy = clause
+		case "LIMIT":
+			stmt.limit = raw
+		case "OFFSET":
+			stmt.offset = raw
+		}
+	}
+
+	if stmt.from == "" {
+		return nil, fmt.Errorf("expected FROM")
+	}
+
+	return stmt, nil
+}
+
+func parseSelectItems(tokens []sqlToken) ([]metricsViewSelectItem, error) {
+	var items []metricsViewSelectItem
+	var cur []sqlToken
+	flush := func() error {
+		item, err := parseSelectItem(cur)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+		cur = nil
+		return nil
+	}
+
+	for _, t := range tokens {
+		if t.text == "," {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		cur = append(cur, t)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func parseSelectItem(tokens []sqlToken) (metricsViewSelectItem, error) {
+	if len(tokens) == 1 && tokens[0].text == "*" {
+		return metricsViewSelectItem{star: true}, nil
+	}
+
+	isName := func(t sqlToken) bool {
+		return t.kind == sqlTokenIdent || t.kind == sqlTokenQuotedIdent
+	}
+
+	if len(tokens) == 0 || !isName(tokens[0]) {
+		return metricsViewSelectItem{}, fmt.Errorf("only dimensions and measures can be selected")
+	}
+
+	item := metricsViewSelectItem{
+		name:   tokens[0].value,
+		quoted: tokens[0].kind == sqlTokenQuotedIdent,
+		alias:  tokens[0].value,
+	}
+
+	rest := tokens[1:]
+	if len(rest) > 0 && rest[0].isKeyword("AS") {
+		rest = rest[1:]
+		if len(rest) == 0 {
+			return metricsViewSelectItem{}, fmt.Errorf("expected alias after AS")
+		}
+	}
+	switch {
+	case len(rest) == 0:
+	case len(rest) == 1 && isName(rest[0]):
+		item.alias = rest[0].value
+	default:
+		return metricsViewSelectItem{}, fmt.Errorf("only dimensions and measures can be selected")
+	}
+
+	return item, nil
+}
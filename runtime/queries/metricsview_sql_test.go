@@ -0,0 +1,206 @@
+package queries
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rilldata/rill/runtime/testruntime"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetricsViewNameFromSQL(t *testing.T) {
+	cases := []struct {
+		sql  string
+		name string
+		ok   bool
+	}{
+		{"SELECT domain FROM ad_bids_metrics", "ad_bids_metrics", true},
+		{`select "domain", "Total volume" as vol from "ad_bids_metrics" where domain = 'from' order by 2 desc limit 10;`, "ad_bids_metrics", true},
+		{"SELECT * FROM ad_bids_metrics -- comment", "ad_bids_metrics", true},
+		{"SELECT domain, count(*) FROM ad_bids_metrics", "", false},
+		{"SELECT domain FROM ad_bids_metrics JOIN other ON true", "", false},
+		{"SELECT domain FROM (SELECT * FROM ad_bids)", "", false},
+		{"SELECT domain FROM ad_bids_metrics UNION ALL SELECT domain FROM ad_bids", "", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "", false},
+		{"SELECT 1", "", false},
+	}
+	for _, c := range cases {
+		name, ok := MetricsViewNameFromSQL(c.sql)
+		require.Equal(t, c.ok, ok, c.sql)
+		require.Equal(t, c.name, name, c.sql)
+	}
+}
+
+func TestMetricsViewSQL(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	q := &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics",
+		SQL:             `SELECT domain, "Number of bids" AS bids, measure_2 FROM ad_bids_metrics WHERE domain LIKE '%.com' ORDER BY domain`,
+	}
+	err := q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(q.Result.Meta.Fields))
+	require.Equal(t, "bids", q.Result.Meta.Fields[1].Name)
+	require.Equal(t, 2, len(q.Result.Data))
+	require.Equal(t, "msn.com", q.Result.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 1.0, q.Result.Data[0].Fields["bids"].GetNumberValue())
+	require.Equal(t, 2.0, q.Result.Data[0].Fields["measure_2"].GetNumberValue())
+
+	// Without dimensions, measures are aggregated over all rows
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics",
+		SQL:             `SELECT "Number of bids" FROM ad_bids_metrics WHERE domain = ?`,
+		Args:            []any{"yahoo.com"},
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Data))
+	require.Equal(t, 1.0, q.Result.Data[0].Fields["Number of bids"].GetNumberValue())
+
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics",
+		SQL:             `SELECT bid_price FROM ad_bids_metrics`,
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetricsViewSQL_MeasureClauses(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	// Measures are expanded in HAVING and ORDER BY, whether they're selected or not
+	q := &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics",
+		SQL:             `SELECT domain FROM ad_bids_metrics HAVING "Number of bids" > 0 AND measure_2 >= 0 ORDER BY "Total volume" DESC, domain`,
+	}
+	stmt, err := q.ResolveStatement(context.Background(), rt, instanceID)
+	require.NoError(t, err)
+	require.Contains(t, stmt.Query, "HAVING (count(*)) > 0 AND (sum(impressions)) >= 0 ORDER BY (sum(volume)) DESC, domain")
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(q.Result.Data))
+
+	// Aliases of selected items are kept
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics",
+		SQL:             `SELECT domain, "Number of bids" AS measure_1 FROM ad_bids_metrics ORDER BY measure_1 DESC`,
+	}
+	stmt, err = q.ResolveStatement(context.Background(), rt, instanceID)
+	require.NoError(t, err)
+	require.Contains(t, stmt.Query, "ORDER BY measure_1 DESC")
+
+	// Measures can't be used in WHERE
+	for _, sql := range []string{
+		`SELECT domain FROM ad_bids_metrics WHERE "Number of bids" > 1`,
+		`SELECT domain, measure_0 AS n FROM ad_bids_metrics WHERE n > 1`,
+	} {
+		q = &MetricsViewSQL{MetricsViewName: "ad_bids_metrics", SQL: sql}
+		_, err = q.ResolveStatement(context.Background(), rt, instanceID)
+		require.Equal(t, codes.InvalidArgument, status.Code(err), sql)
+		require.Contains(t, err.Error(), "use HAVING", sql)
+	}
+}
+
+func TestMetricsViewSQL_Security(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	q := &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics_secure",
+		SQL:             `SELECT * FROM ad_bids_metrics_secure`,
+		Attributes:      map[string]any{"domain": "msn.com"},
+	}
+	err := q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(q.Result.Meta.Fields))
+	require.Equal(t, 1, len(q.Result.Data))
+	require.Equal(t, "msn.com", q.Result.Data[0].Fields["domain"].GetStringValue())
+
+	// The logged SQL contains the row filter's template instead of the caller's attributes
+	stmt, err := q.ResolveStatement(context.Background(), rt, instanceID)
+	require.NoError(t, err)
+	require.Contains(t, stmt.Query, "domain = 'msn.com'")
+	require.Contains(t, stmt.LoggedQuery, "domain = {{ .user.domain | sqlString }}")
+	require.NotContains(t, stmt.LoggedQuery, "msn.com")
+
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics_secure",
+		SQL:             `SELECT domain FROM ad_bids_metrics_secure WHERE publisher IS NULL`,
+		Attributes:      map[string]any{"domain": "msn.com"},
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics_secure",
+		SQL:             `SELECT domain FROM ad_bids_metrics_secure WHERE domain IN (SELECT domain FROM ad_bids)`,
+		Attributes:      map[string]any{"domain": "msn.com"},
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Clauses may only reference accessible dimensions, measures and aliases
+	denied := []string{
+		`SELECT domain FROM ad_bids_metrics_secure HAVING sum(volume) > 1000`,
+		`SELECT domain FROM ad_bids_metrics_secure WHERE bid_price > 1`,
+		`SELECT domain FROM ad_bids_metrics_secure HAVING "Total volume" > 1000`,
+		`SELECT domain FROM ad_bids_metrics_secure ORDER BY read_csv_auto('data.csv')`,
+	}
+	for _, sql := range denied {
+		q = &MetricsViewSQL{
+			MetricsViewName: "ad_bids_metrics_secure",
+			SQL:             sql,
+			Attributes:      map[string]any{"domain": "msn.com"},
+		}
+		err = q.Resolve(context.Background(), rt, instanceID, 0)
+		require.Equal(t, codes.PermissionDenied, status.Code(err), sql)
+	}
+
+	q = &MetricsViewSQL{
+		MetricsViewName: "ad_bids_metrics_secure",
+		SQL:             `SELECT domain, "Number of bids" AS n FROM ad_bids_metrics_secure WHERE lower(domain) LIKE '%.com' AND domain IS NOT NULL HAVING n > 0 ORDER BY domain DESC NULLS LAST`,
+		Attributes:      map[string]any{"domain": "msn.com"},
+	}
+	err = q.Resolve(context.Background(), rt, instanceID, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(q.Result.Data))
+}
+
+func TestMetricsViewSQL_SecurityKey(t *testing.T) {
+	rt, instanceID := testruntime.NewInstanceForProject(t, "ad_bids_2rows")
+
+	key := func(attrs map[string]any) string {
+		q := &MetricsViewSQL{
+			MetricsViewName: "ad_bids_metrics_secure",
+			SQL:             `SELECT domain FROM ad_bids_metrics_secure`,
+			Attributes:      attrs,
+		}
+		require.NoError(t, q.Prepare(context.Background(), rt, instanceID))
+		return q.Key()
+	}
+
+	// Claims that don't affect the policy don't affect the key, and the claims aren't part of the key
+	a := key(map[string]any{"domain": "msn.com", "exp": 1, "jti": "a"})
+	require.Equal(t, a, key(map[string]any{"domain": "msn.com", "exp": 2, "jti": "b"}))
+	require.NotContains(t, a, "jti")
+
+	// Callers with different row filters or access rules have different keys
+	require.NotEqual(t, a, key(map[string]any{"domain": "yahoo.com"}))
+	require.NotEqual(t, a, key(map[string]any{"domain": "msn.com", "role": "admin"}))
+}
+
+func BenchmarkMetricsViewSQL(b *testing.B) {
+	rt, instanceID := testruntime.NewInstanceForProject(b, "ad_bids")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q := &MetricsViewSQL{
+			MetricsViewName: "ad_bids_metrics",
+			SQL:             `SELECT domain, measure_0 FROM ad_bids_metrics ORDER BY 2 DESC LIMIT 10`,
+		}
+		err := q.Resolve(context.Background(), rt, instanceID, 0)
+		require.NoError(b, err)
+		require.NotEmpty(b, q.Result)
+	}
+}
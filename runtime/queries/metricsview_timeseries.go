@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -21,27 +24,49 @@ type MetricsViewTimeSeries struct {
 	Offset          int64                        `json:"offset,omitempty"`
 	Sort            []*runtimev1.MetricsViewSort `json:"sort,omitempty"`
 	Filter          *runtimev1.MetricsViewFilter `json:"filter,omitempty"`
-	TimeGranularity string                       `json:"time_granularity,omitempty"`
+	// Attributes are used to resolve the caller\'s security policy. The resolved policy is part of the cache key instead.
+	Attributes      map[string]any         `json:"-"`
+	TimeGranularity string                 `json:"time_granularity,omitempty"`
+	TimeZone        string                 `json:"time_zone,omitempty"`
+	ResultFormat    runtimev1.ResultFormat `json:"result_format,omitempty"`
 
 	Result *runtimev1.MetricsViewTimeSeriesResponse `json:"-"`
+
+	policyKey string
 }
 
-var _ runtime.Query = &MetricsViewTimeSeries{}
+var (
+	_ runtime.PersistentQuery = &MetricsViewTimeSeries{}
+	_ runtime.PreparedQuery   = &MetricsViewTimeSeries{}
+)
 
 func (q *MetricsViewTimeSeries) Key() string {
 	r, err := json.Marshal(q)
 	if err != nil {
 		panic(err)
 	}
-	return fmt.Sprintf("MetricsViewTimeSeries:%s", string(r))
+	return fmt.Sprintf("MetricsViewTimeSeries:%s:%s", string(r), q.policyKey)
+}
+
+// Prepare resolves the caller's security policy, which is part of the cache key (see securityPolicyKey)
+func (q *MetricsViewTimeSeries) Prepare(ctx context.Context, rt *runtime.Runtime, instanceID string) error {
+	key, err := securityPolicyKey(ctx, rt, instanceID, q.MetricsViewName, q.Attributes)
+	if err != nil {
+		return err
+	}
+	q.policyKey = key
+	return nil
 }
 
 func (q *MetricsViewTimeSeries) Deps() []string {
 	return []string{q.MetricsViewName}
 }
 
-func (q *MetricsViewTimeSeries) MarshalResult() any {
-	return q.Result
+func (q *MetricsViewTimeSeries) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *MetricsViewTimeSeries) UnmarshalResult(v any) error {
@@ -53,14 +78,27 @@ func (q *MetricsViewTimeSeries) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *MetricsViewTimeSeries) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *MetricsViewTimeSeries) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.MetricsViewTimeSeriesResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *MetricsViewTimeSeries) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindMetricsView); err != nil {
+		return err
 	}
 
 	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
@@ -72,29 +110,124 @@ func (q *MetricsViewTimeSeries) Resolve(ctx context.Context, rt *runtime.Runtime
 		return fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
 	}
 
+	policy, err := resolveSecurityPolicy(mv, q.Attributes, nil, q.MeasureNames, q.Filter)
+	if err != nil {
+		return err
+	}
+
+	tz, err := q.resolveTimeZone(ctx, rt, instanceID, priority, mv, olap.Dialect())
+	if err != nil {
+		return err
+	}
+	tz.calendar = calendar{
+		firstDayOfWeek:   mv.FirstDayOfWeek,
+		firstMonthOfYear: mv.FirstMonthOfYear,
+	}
+
 	// Build query
-	sql, args, err := q.buildMetricsTimeSeriesSQL(mv)
+	stmt, err := metricsViewStatement(policy, func(policy *securitypolicy.Policy) (string, []any, error) {
+		return q.buildMetricsTimeSeriesSQL(mv, tz, policy, olap.Dialect())
+	})
 	if err != nil {
 		return fmt.Errorf("error building query: %w", err)
 	}
 
 	// Execute
-	meta, data, err := metricsQuery(ctx, olap, priority, sql, args)
+	arrow := q.ResultFormat == runtimev1.ResultFormat_RESULT_FORMAT_ARROW
+	res, err := metricsQuery(ctx, olap, priority, stmt, arrow)
 	if err != nil {
 		return err
 	}
 
+	// Return timestamps with the time zone's offset.
+	// (Arrow timestamps are time zone independent, so they're returned as is.)
+	if !tz.isUTC() {
+		for _, row := range res.Data {
+			v, ok := row.Fields[mv.TimeDimension]
+			if !ok || v.GetStringValue() == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, v.GetStringValue())
+			if err != nil {
+				return err
+			}
+			row.Fields[mv.TimeDimension] = structpb.NewStringValue(t.In(tz.loc).Format(time.RFC3339Nano))
+		}
+	}
+
 	q.Result = &runtimev1.MetricsViewTimeSeriesResponse{
-		Meta: meta,
-		Data: data,
+		Meta:      res.Meta,
+		Data:      res.Data,
+		ArrowData: res.ArrowData,
 	}
 
 	return nil
 }
 
-func (q *MetricsViewTimeSeries) buildMetricsTimeSeriesSQL(mv *runtimev1.MetricsView) (string, []any, error) {
+// resolveTimeZone resolves the request's time zone, falling back to the metrics view's default time zone.
+func (q *MetricsViewTimeSeries) resolveTimeZone(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int, mv *runtimev1.MetricsView, dialect drivers.Dialect) (*timeZone, error) {
+	name := q.TimeZone
+	if name == "" {
+		name = mv.TimeZone
+	}
+	if name == "" {
+		return newTimeZone("", time.Time{}, time.Time{})
+	}
+
+	// Druid, ClickHouse and Postgres resolve the DST transitions themselves, so we only need them to tell if the zone is UTC
+	if dialect != drivers.DialectDuckDB {
+		now := time.Now()
+		return newTimeZone(name, now, now)
+	}
+
+	// The DST transitions to resolve depend on the time range, so we default to the range of the data
+	var start, end time.Time
+	if q.TimeStart == nil || q.TimeEnd == nil {
+		ctr := &ColumnTimeRange{
+			TableName:  mv.Model,
+			ColumnName: mv.TimeDimension,
+		}
+		err := rt.Query(ctx, instanceID, ctr, priority)
+		if err != nil {
+			return nil, err
+		}
+		start = ctr.Result.Min.AsTime()
+		end = ctr.Result.Max.AsTime()
+	}
+	if q.TimeStart != nil {
+		start = q.TimeStart.AsTime()
+	}
+	if q.TimeEnd != nil {
+		end = q.TimeEnd.AsTime()
+	}
+
+	return newTimeZone(name, start, end)
+}
+
+func (q *MetricsViewTimeSeries) buildMetricsTimeSeriesSQL(mv *runtimev1.MetricsView, tz *timeZone, policy *securitypolicy.Policy, dialect drivers.Dialect) (string, []any, error) {
 	timestampColumnName := safeName(mv.TimeDimension)
-	timeCol := fmt.Sprintf("DATE_TRUNC('%s', %s) AS %s", q.TimeGranularity, timestampColumnName, timestampColumnName)
+	truncateExpr := tz.truncateExpr(q.TimeGranularity, timestampColumnName)
+	switch dialect {
+	case drivers.DialectDruid:
+		var err error
+		truncateExpr, err = druidTimeFloorExpr(q.TimeGranularity, timestampColumnName, tz.loc, tz.calendar)
+		if err != nil {
+			return "", nil, err
+		}
+	case drivers.DialectClickHouse:
+		var err error
+		truncateExpr, err = clickHouseTimeFloorExpr(q.TimeGranularity, timestampColumnName, tz.loc, tz.calendar)
+		if err != nil {
+			return "", nil, err
+		}
+	case drivers.DialectPostgres:
+		var err error
+		truncateExpr, err = postgresTimeFloorExpr(q.TimeGranularity, timestampColumnName, tz.loc, tz.calendar)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	timeCol := fmt.Sprintf("%s AS %s", truncateExpr, timestampColumnName)
 	selectCols := []string{timeCol}
 	for _, n := range q.MeasureNames {
 		found := false
@@ -125,7 +258,7 @@ func (q *MetricsViewTimeSeries) buildMetricsTimeSeriesSQL(mv *runtimev1.MetricsV
 	}
 
 	if q.Filter != nil {
-		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter)
+		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter, dialect)
 		if err != nil {
 			return "", nil, err
 		}
@@ -136,7 +269,7 @@ func (q *MetricsViewTimeSeries) buildMetricsTimeSeriesSQL(mv *runtimev1.MetricsV
 	sql := fmt.Sprintf(
 		"SELECT %s FROM %s WHERE %s GROUP BY 1 ORDER BY %s LIMIT 1000",
 		strings.Join(selectCols, ", "),
-		mv.Model,
+		metricsViewFrom(mv, policy),
 		whereClause,
 		timestampColumnName,
 	)
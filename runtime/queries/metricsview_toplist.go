@@ -9,9 +9,19 @@ import (
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+const (
+	// otherFieldName is used internally to tell the "Other" row apart from the top rows
+	otherFieldName = "__rill_other"
+	// percentFieldSuffix is appended to measure names for their percent of total
+	percentFieldSuffix = "__percent_of_total"
+)
+
 type MetricsViewToplist struct {
 	MetricsViewName string                       `json:"metrics_view_name,omitempty"`
 	DimensionName   string                       `json:"dimension_name,omitempty"`
@@ -22,26 +32,49 @@ type MetricsViewToplist struct {
 	Offset          int64                        `json:"offset,omitempty"`
 	Sort            []*runtimev1.MetricsViewSort `json:"sort,omitempty"`
 	Filter          *runtimev1.MetricsViewFilter `json:"filter,omitempty"`
+	// Attributes are used to resolve the caller\'s security policy. The resolved policy is part of the cache key instead.
+	Attributes            map[string]any         `json:"-"`
+	IncludeOther          bool                   `json:"include_other,omitempty"`
+	IncludePercentOfTotal bool                   `json:"include_percent_of_total,omitempty"`
+	ResultFormat          runtimev1.ResultFormat `json:"result_format,omitempty"`
 
 	Result *runtimev1.MetricsViewToplistResponse `json:"-"`
+
+	policyKey string
 }
 
-var _ runtime.Query = &MetricsViewToplist{}
+var (
+	_ runtime.PersistentQuery = &MetricsViewToplist{}
+	_ runtime.PreparedQuery   = &MetricsViewToplist{}
+)
 
 func (q *MetricsViewToplist) Key() string {
 	r, err := json.Marshal(q)
 	if err != nil {
 		panic(err)
 	}
-	return fmt.Sprintf("MetricsViewToplist:%s", string(r))
+	return fmt.Sprintf("MetricsViewToplist:%s:%s", string(r), q.policyKey)
+}
+
+// Prepare resolves the caller's security policy, which is part of the cache key (see securityPolicyKey)
+func (q *MetricsViewToplist) Prepare(ctx context.Context, rt *runtime.Runtime, instanceID string) error {
+	key, err := securityPolicyKey(ctx, rt, instanceID, q.MetricsViewName, q.Attributes)
+	if err != nil {
+		return err
+	}
+	q.policyKey = key
+	return nil
 }
 
 func (q *MetricsViewToplist) Deps() []string {
 	return []string{q.MetricsViewName}
 }
 
-func (q *MetricsViewToplist) MarshalResult() any {
-	return q.Result
+func (q *MetricsViewToplist) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *MetricsViewToplist) UnmarshalResult(v any) error {
@@ -53,46 +86,125 @@ func (q *MetricsViewToplist) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *MetricsViewToplist) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *MetricsViewToplist) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.MetricsViewToplistResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *MetricsViewToplist) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	stmt, err := q.ResolveStatement(ctx, rt, instanceID)
+	if err != nil {
+		return err
 	}
 
-	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
+	// Execute
+	// If there's an "Other" row, the rows are post-processed before they're encoded (see below)
+	other := q.IncludeOther || q.IncludePercentOfTotal
+	arrow := q.ResultFormat == runtimev1.ResultFormat_RESULT_FORMAT_ARROW
+	res, err := metricsQuery(ctx, olap, priority, stmt, arrow && !other)
 	if err != nil {
 		return err
 	}
 
-	if mv.TimeDimension == "" && (q.TimeStart != nil || q.TimeEnd != nil) {
-		return fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
+	q.Result = &runtimev1.MetricsViewToplistResponse{
+		Meta:      res.Meta,
+		Data:      res.Data,
+		ArrowData: res.ArrowData,
 	}
 
-	// Build query
-	sql, args, err := q.buildMetricsTopListSQL(mv)
+	// Move the "Other" row out of the data
+	if other {
+		schema := &runtimev1.StructType{}
+		for _, f := range res.Schema.Fields {
+			if f.Name != otherFieldName {
+				schema.Fields = append(schema.Fields, f)
+			}
+		}
+		q.Result.Meta = structTypeToMetricsViewColumn(schema)
+		q.Result.Data = nil
+		for _, row := range res.Data {
+			isOther := row.Fields[otherFieldName].GetBoolValue()
+			delete(row.Fields, otherFieldName)
+			if isOther {
+				delete(row.Fields, q.DimensionName)
+				q.Result.Other = row
+			} else {
+				q.Result.Data = append(q.Result.Data, row)
+			}
+		}
+
+		if arrow {
+			q.Result.ArrowData, err = metricsViewArrowData(schema, q.Result.Data)
+			if err != nil {
+				return err
+			}
+			q.Result.Data = nil
+		}
+	}
+
+	return nil
+}
+
+// ResolveStatement returns the statement for the toplist after checking them against the metrics view's security policy.
+// If IncludeOther is set, the result contains a "__rill_other" column that's true for the "Other" row.
+func (q *MetricsViewToplist) ResolveStatement(ctx context.Context, rt *runtime.Runtime, instanceID string) (*drivers.Statement, error) {
+	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
-		return fmt.Errorf("error building query: %w", err)
+		return nil, err
 	}
 
-	// Execute
-	meta, data, err := metricsQuery(ctx, olap, priority, sql, args)
+	if err := checkQueryKind(olap, drivers.QueryKindMetricsView); err != nil {
+		return nil, err
+	}
+
+	if q.IncludeOther || q.IncludePercentOfTotal {
+		if err := checkQueryKind(olap, drivers.QueryKindMetricsViewToplistOther); err != nil {
+			return nil, err
+		}
+	}
+
+	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	q.Result = &runtimev1.MetricsViewToplistResponse{
-		Meta: meta,
-		Data: data,
+	if mv.TimeDimension == "" && (q.TimeStart != nil || q.TimeEnd != nil) {
+		return nil, fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
 	}
 
-	return nil
+	policy, err := resolveSecurityPolicy(mv, q.Attributes, []string{q.DimensionName}, q.MeasureNames, q.Filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range q.Sort {
+		if !policy.CanAccessField(s.Name) {
+			return nil, status.Errorf(codes.PermissionDenied, "access to '%s' is not allowed", s.Name)
+		}
+	}
+
+	stmt, err := metricsViewStatement(policy, func(policy *securitypolicy.Policy) (string, []any, error) {
+		return q.buildMetricsTopListSQL(mv, policy, olap.Dialect())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+	return stmt, nil
 }
 
-func (q *MetricsViewToplist) buildMetricsTopListSQL(mv *runtimev1.MetricsView) (string, []any, error) {
+func (q *MetricsViewToplist) buildMetricsTopListSQL(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, dialect drivers.Dialect) (string, []any, error) {
 	dimName := safeName(q.DimensionName)
 	selectCols := []string{dimName}
 	for _, n := range q.MeasureNames {
@@ -124,7 +236,7 @@ func (q *MetricsViewToplist) buildMetricsTopListSQL(mv *runtimev1.MetricsView) (
 	}
 
 	if q.Filter != nil {
-		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter)
+		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter, dialect)
 		if err != nil {
 			return "", nil, err
 		}
@@ -132,27 +244,90 @@ func (q *MetricsViewToplist) buildMetricsTopListSQL(mv *runtimev1.MetricsView) (
 		args = append(args, clauseArgs...)
 	}
 
-	orderClause := "true"
+	// DuckDB accepts "true" as a no-op sort key, so it's used to keep the clause valid without sorts
+	var orderCols []string
+	if dialect == drivers.DialectDuckDB {
+		orderCols = append(orderCols, "true")
+	}
 	for _, s := range q.Sort {
-		orderClause += ", "
-		orderClause += safeName(s.Name)
-		if !s.Ascending {
-			orderClause += " DESC"
-		}
-		orderClause += " NULLS LAST"
+		orderCols = append(orderCols, orderByExpr(dialect, s.Name, s.Ascending))
 	}
+	orderClause := strings.Join(orderCols, ", ")
 
 	if q.Limit == 0 {
 		q.Limit = 100
 	}
 
-	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s GROUP BY %s ORDER BY %s LIMIT %d",
-		strings.Join(selectCols, ", "),
-		mv.Model,
-		whereClause,
-		dimName,
+	if !q.IncludeOther && !q.IncludePercentOfTotal {
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s GROUP BY %s",
+			strings.Join(selectCols, ", "),
+			metricsViewFrom(mv, policy),
+			whereClause,
+			dimName,
+		)
+		if orderClause != "" {
+			sql += " ORDER BY " + orderClause
+		}
+		sql += fmt.Sprintf(" LIMIT %d", q.Limit)
+		return sql, args, nil
+	}
+
+	// The query below relies on DuckDB syntax (and correlated subqueries that Druid and ClickHouse don't support)
+	if dialect != drivers.DialectDuckDB {
+		return "", nil, fmt.Errorf("include_other and include_percent_of_total are not available for dialect '%s'", dialect)
+	}
+
+	// The top rows, the "Other" row and the totals are computed in a single query over the filtered rows.
+	// The "Other" row aggregates the underlying rows (instead of the top rows), so it's correct for non-additive measures.
+	measureCols := selectCols[1:]
+	ctes := []string{
+		fmt.Sprintf("filtered AS (SELECT * FROM %s WHERE %s)", metricsViewFrom(mv, policy), whereClause),
+		fmt.Sprintf("top AS (SELECT %s FROM filtered GROUP BY %s ORDER BY %s LIMIT %d)", strings.Join(selectCols, ", "), dimName, orderClause, q.Limit),
+	}
+
+	var topCols, otherCols []string
+	if q.IncludeOther {
+		topCols = append(topCols, fmt.Sprintf("false AS %s", safeName(otherFieldName)))
+		otherCols = append(otherCols, "true")
+	}
+	topCols = append(topCols, fmt.Sprintf("top.%s", dimName))
+	otherCols = append(otherCols, "NULL")
+	for _, n := range q.MeasureNames {
+		topCols = append(topCols, fmt.Sprintf("top.%s", safeName(n)))
+		otherCols = append(otherCols, fmt.Sprintf("other.%s", safeName(n)))
+	}
+
+	topFrom := "top"
+	otherFrom := "other"
+	if q.IncludePercentOfTotal {
+		ctes = append(ctes, fmt.Sprintf("total AS (SELECT %s FROM filtered)", strings.Join(measureCols, ", ")))
+		for _, n := range q.MeasureNames {
+			topCols = append(topCols, fmt.Sprintf("top.%s::DOUBLE / NULLIF(total.%s, 0) AS %s", safeName(n), safeName(n), safeName(n+percentFieldSuffix)))
+			otherCols = append(otherCols, fmt.Sprintf("other.%s::DOUBLE / NULLIF(total.%s, 0)", safeName(n), safeName(n)))
+		}
+		topFrom += " CROSS JOIN total"
+		otherFrom += " CROSS JOIN total"
+	}
+
+	sql := fmt.Sprintf("SELECT %s FROM %s", strings.Join(topCols, ", "), topFrom)
+	if q.IncludeOther {
+		ctes = append(ctes, fmt.Sprintf(
+			"other AS (SELECT %s, count(*) AS __rill_count FROM filtered WHERE NOT EXISTS (SELECT 1 FROM top WHERE top.%s IS NOT DISTINCT FROM filtered.%s))",
+			strings.Join(measureCols, ", "),
+			dimName,
+			dimName,
+		))
+		sql += fmt.Sprintf(" UNION ALL SELECT %s FROM %s WHERE other.__rill_count > 0", strings.Join(otherCols, ", "), otherFrom)
+	}
+
+	if q.IncludeOther {
+		// Sorts the "Other" row last
+		orderClause = fmt.Sprintf("%s, %s", safeName(otherFieldName), orderClause)
+	}
+	sql = fmt.Sprintf("WITH %s SELECT * FROM (%s) ORDER BY %s",
+		strings.Join(ctes, ", "),
+		sql,
 		orderClause,
-		q.Limit,
 	)
 
 	return sql, args, nil
@@ -9,6 +9,7 @@ import (
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
@@ -18,26 +19,47 @@ type MetricsViewTotals struct {
 	TimeStart       *timestamppb.Timestamp       `json:"time_start,omitempty"`
 	TimeEnd         *timestamppb.Timestamp       `json:"time_end,omitempty"`
 	Filter          *runtimev1.MetricsViewFilter `json:"filter,omitempty"`
+	ResultFormat    runtimev1.ResultFormat       `json:"result_format,omitempty"`
+	// Attributes are used to resolve the caller\'s security policy. The resolved policy is part of the cache key instead.
+	Attributes map[string]any `json:"-"`
 
 	Result *runtimev1.MetricsViewTotalsResponse `json:"-"`
+
+	policyKey string
 }
 
-var _ runtime.Query = &MetricsViewTotals{}
+var (
+	_ runtime.PersistentQuery = &MetricsViewTotals{}
+	_ runtime.PreparedQuery   = &MetricsViewTotals{}
+)
 
 func (q *MetricsViewTotals) Key() string {
 	r, err := json.Marshal(q)
 	if err != nil {
 		panic(err)
 	}
-	return fmt.Sprintf("MetricsViewTotals:%s", string(r))
+	return fmt.Sprintf("MetricsViewTotals:%s:%s", string(r), q.policyKey)
+}
+
+// Prepare resolves the caller's security policy, which is part of the cache key (see securityPolicyKey)
+func (q *MetricsViewTotals) Prepare(ctx context.Context, rt *runtime.Runtime, instanceID string) error {
+	key, err := securityPolicyKey(ctx, rt, instanceID, q.MetricsViewName, q.Attributes)
+	if err != nil {
+		return err
+	}
+	q.policyKey = key
+	return nil
 }
 
 func (q *MetricsViewTotals) Deps() []string {
 	return []string{q.MetricsViewName}
 }
 
-func (q *MetricsViewTotals) MarshalResult() any {
-	return q.Result
+func (q *MetricsViewTotals) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *MetricsViewTotals) UnmarshalResult(v any) error {
@@ -49,48 +71,85 @@ func (q *MetricsViewTotals) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *MetricsViewTotals) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *MetricsViewTotals) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.MetricsViewTotalsResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *MetricsViewTotals) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	stmt, err := q.ResolveStatement(ctx, rt, instanceID)
+	if err != nil {
+		return err
 	}
 
-	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
+	res, err := metricsQuery(ctx, olap, priority, stmt, q.ResultFormat == runtimev1.ResultFormat_RESULT_FORMAT_ARROW)
 	if err != nil {
 		return err
 	}
 
-	if mv.TimeDimension == "" && (q.TimeStart != nil || q.TimeEnd != nil) {
-		return fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
+	if res.NumRows == 0 {
+		return fmt.Errorf("no rows received from totals query")
+	}
+
+	q.Result = &runtimev1.MetricsViewTotalsResponse{
+		Meta:      res.Meta,
+		ArrowData: res.ArrowData,
+	}
+	if res.Data != nil {
+		q.Result.Data = res.Data[0]
 	}
 
-	ql, args, err := q.buildMetricsTotalsSQL(mv)
+	return nil
+}
+
+// ResolveStatement returns the statement for the totals after checking them against the metrics view's security policy.
+func (q *MetricsViewTotals) ResolveStatement(ctx context.Context, rt *runtime.Runtime, instanceID string) (*drivers.Statement, error) {
+	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
-		return fmt.Errorf("error building query: %w", err)
+		return nil, err
 	}
 
-	meta, data, err := metricsQuery(ctx, olap, priority, ql, args)
+	if err := checkQueryKind(olap, drivers.QueryKindMetricsView); err != nil {
+		return nil, err
+	}
+
+	mv, err := lookupMetricsView(ctx, rt, instanceID, q.MetricsViewName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(data) == 0 {
-		return fmt.Errorf("no rows received from totals query")
+	if mv.TimeDimension == "" && (q.TimeStart != nil || q.TimeEnd != nil) {
+		return nil, fmt.Errorf("metrics view '%s' does not have a time dimension", q.MetricsViewName)
 	}
 
-	q.Result = &runtimev1.MetricsViewTotalsResponse{
-		Meta: meta,
-		Data: data[0],
+	policy, err := resolveSecurityPolicy(mv, q.Attributes, nil, q.MeasureNames, q.Filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	stmt, err := metricsViewStatement(policy, func(policy *securitypolicy.Policy) (string, []any, error) {
+		return q.buildMetricsTotalsSQL(mv, policy, olap.Dialect())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error building query: %w", err)
+	}
+	return stmt, nil
 }
 
-func (q *MetricsViewTotals) buildMetricsTotalsSQL(mv *runtimev1.MetricsView) (string, []any, error) {
+func (q *MetricsViewTotals) buildMetricsTotalsSQL(mv *runtimev1.MetricsView, policy *securitypolicy.Policy, dialect drivers.Dialect) (string, []any, error) {
 	selectCols := []string{}
 	for _, n := range q.MeasureNames {
 		found := false
@@ -121,7 +180,7 @@ func (q *MetricsViewTotals) buildMetricsTotalsSQL(mv *runtimev1.MetricsView) (st
 	}
 
 	if q.Filter != nil {
-		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter)
+		clause, clauseArgs, err := buildFilterClauseForMetricsViewFilter(q.Filter, dialect)
 		if err != nil {
 			return "", nil, err
 		}
@@ -132,7 +191,7 @@ func (q *MetricsViewTotals) buildMetricsTotalsSQL(mv *runtimev1.MetricsView) (st
 	sql := fmt.Sprintf(
 		"SELECT %s FROM %s WHERE %s",
 		strings.Join(selectCols, ", "),
-		mv.Model,
+		metricsViewFrom(mv, policy),
 		whereClause,
 	)
 	return sql, args, nil
@@ -0,0 +1,135 @@
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// sizeOf estimates the memory used by a query result for the purpose of bounding the query cache.
+// It walks the exported fields of v with Go reflection. We don't use proto.Size or protoreflect because
+// they mutate the internal state of the messages (which breaks equality checks on cached results).
+func sizeOf(v any) int64 {
+	return sizeOfValue(reflect.ValueOf(v))
+}
+
+func sizeOfValue(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 8
+		}
+		return 8 + sizeOfValue(v.Elem())
+	case reflect.String:
+		return int64(16 + v.Len())
+	case reflect.Slice, reflect.Array:
+		n := int64(24)
+		for i := 0; i < v.Len(); i++ {
+			n += sizeOfValue(v.Index(i))
+		}
+		return n
+	case reflect.Map:
+		n := int64(48)
+		iter := v.MapRange()
+		for iter.Next() {
+			n += sizeOfValue(iter.Key()) + sizeOfValue(iter.Value())
+		}
+		return n
+	case reflect.Struct:
+		var n int64
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			// Skips unexported fields, such as the internal state of proto messages
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			n += sizeOfValue(v.Field(i))
+		}
+		return n
+	default:
+		return int64(v.Type().Size())
+	}
+}
+
+// marshalProtoResult serializes a proto message result for the persistent query cache.
+// Nil results are encoded as an empty slice, other results are prefixed with a byte to distinguish empty messages.
+func marshalProtoResult(m proto.Message) ([]byte, error) {
+	if m == nil || reflect.ValueOf(m).IsNil() {
+		return []byte{}, nil
+	}
+	return proto.MarshalOptions{}.MarshalAppend([]byte{1}, m)
+}
+
+// unmarshalProtoResult deserializes a result serialized with marshalProtoResult
+func unmarshalProtoResult[T any, PT interface {
+	*T
+	proto.Message
+}](data []byte) (PT, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	m := PT(new(T))
+	err := proto.Unmarshal(data[1:], m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// marshalProtoResults serializes a slice of proto messages for the persistent query cache.
+// Each message is length-prefixed.
+func marshalProtoResults[T proto.Message](ms []T) ([]byte, error) {
+	if ms == nil {
+		return []byte{}, nil
+	}
+	b := []byte{1}
+	for _, m := range ms {
+		data, err := proto.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendBytes(b, data)
+	}
+	return b, nil
+}
+
+// unmarshalProtoResults deserializes a result serialized with marshalProtoResults
+func unmarshalProtoResults[T any, PT interface {
+	*T
+	proto.Message
+}](data []byte) ([]PT, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	res := []PT{}
+	data = data[1:]
+	for len(data) > 0 {
+		v, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid result: %w", protowire.ParseError(n))
+		}
+		m := PT(new(T))
+		err := proto.Unmarshal(v, m)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+		data = data[n:]
+	}
+	return res, nil
+}
+
+// marshalScalarResult serializes a numeric or enum result for the persistent query cache
+func marshalScalarResult(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// unmarshalScalarResult deserializes a result serialized with marshalScalarResult into v
+func unmarshalScalarResult(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
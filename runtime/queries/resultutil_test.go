@@ -0,0 +1,54 @@
+package queries
+
+import (
+	"testing"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarshalProtoResult(t *testing.T) {
+	data, err := marshalProtoResult((*runtimev1.TopK)(nil))
+	require.NoError(t, err)
+	res, err := unmarshalProtoResult[runtimev1.TopK](data)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	data, err = marshalProtoResult(&runtimev1.TopK{})
+	require.NoError(t, err)
+	res, err = unmarshalProtoResult[runtimev1.TopK](data)
+	require.NoError(t, err)
+	require.NotNil(t, res)
+
+	topK := &runtimev1.TopK{Entries: []*runtimev1.TopK_Entry{{Count: 10}}}
+	data, err = marshalProtoResult(topK)
+	require.NoError(t, err)
+	res, err = unmarshalProtoResult[runtimev1.TopK](data)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(topK, res))
+}
+
+func TestMarshalProtoResults(t *testing.T) {
+	data, err := marshalProtoResults([]*runtimev1.NumericOutliers_Outlier(nil))
+	require.NoError(t, err)
+	res, err := unmarshalProtoResults[runtimev1.NumericOutliers_Outlier](data)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	outliers := []*runtimev1.NumericOutliers_Outlier{{Bucket: 1, Low: 1}, {}, {Bucket: 2, Count: 5}}
+	data, err = marshalProtoResults(outliers)
+	require.NoError(t, err)
+	res, err = unmarshalProtoResults[runtimev1.NumericOutliers_Outlier](data)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	for i := range outliers {
+		require.True(t, proto.Equal(outliers[i], res[i]))
+	}
+}
+
+func TestSizeOf(t *testing.T) {
+	require.Equal(t, int64(8), sizeOf(float64(1)))
+	require.Equal(t, int64(0), sizeOf(nil))
+	require.Greater(t, sizeOf(&runtimev1.TopK{Entries: []*runtimev1.TopK_Entry{{Count: 10}}}), sizeOf(&runtimev1.TopK{}))
+}
@@ -25,6 +25,30 @@ func safeName(name string) string {
 	return quoteName(escapeDoubleQuotes(name))
 }
 
+// isNumericProfilingSupported returns false if the column's type can't be profiled with numeric aggregates
+// (for example lists, structs and maps), in which case the numeric profiling queries return an empty result.
+// If the column's type can't be looked up, it returns true and leaves it to the profiling query to fail.
+func isNumericProfilingSupported(ctx context.Context, olap drivers.OLAPStore, tableName, columnName string) bool {
+	table, err := olap.InformationSchema().Lookup(ctx, tableName)
+	if err != nil {
+		return true
+	}
+
+	for _, f := range table.Schema.Fields {
+		if f.Name != columnName {
+			continue
+		}
+		switch f.Type.Code {
+		case runtimev1.Type_CODE_ARRAY, runtimev1.Type_CODE_STRUCT, runtimev1.Type_CODE_MAP, runtimev1.Type_CODE_INTERVAL, runtimev1.Type_CODE_BYTES:
+			return false
+		default:
+			return true
+		}
+	}
+
+	return true
+}
+
 func dropTempTable(olap drivers.OLAPStore, priority int, tableName string) {
 	rs, er := olap.Execute(context.Background(), &drivers.Statement{
 		Query:    `DROP TABLE "` + tableName + `"`,
@@ -13,7 +13,7 @@ type TableCardinality struct {
 	Result    int64
 }
 
-var _ runtime.Query = &TableCardinality{}
+var _ runtime.PersistentQuery = &TableCardinality{}
 
 func (q *TableCardinality) Key() string {
 	return fmt.Sprintf("TableCardinality:%s", q.TableName)
@@ -23,8 +23,11 @@ func (q *TableCardinality) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *TableCardinality) MarshalResult() any {
-	return q.Result
+func (q *TableCardinality) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *TableCardinality) UnmarshalResult(v any) error {
@@ -36,6 +39,14 @@ func (q *TableCardinality) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *TableCardinality) MarshalResultBytes() ([]byte, error) {
+	return marshalScalarResult(q.Result)
+}
+
+func (q *TableCardinality) UnmarshalResultBytes(data []byte) error {
+	return unmarshalScalarResult(data, &q.Result)
+}
+
 func (q *TableCardinality) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	countSQL := fmt.Sprintf("SELECT count(*) AS count FROM %s",
 		safeName(q.TableName),
@@ -46,8 +57,8 @@ func (q *TableCardinality) Resolve(ctx context.Context, rt *runtime.Runtime, ins
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	if err := checkQueryKind(olap, drivers.QueryKindColumnProfile); err != nil {
+		return err
 	}
 
 	rows, err := olap.Execute(ctx, &drivers.Statement{
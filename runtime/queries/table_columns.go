@@ -3,6 +3,7 @@ package queries
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
@@ -14,7 +15,7 @@ type TableColumns struct {
 	Result    []*runtimev1.ProfileColumn
 }
 
-var _ runtime.Query = &TableColumns{}
+var _ runtime.PersistentQuery = &TableColumns{}
 
 func (q *TableColumns) Key() string {
 	return fmt.Sprintf("TableColumns:%s", q.TableName)
@@ -24,8 +25,11 @@ func (q *TableColumns) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *TableColumns) MarshalResult() any {
-	return q.Result
+func (q *TableColumns) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *TableColumns) UnmarshalResult(v any) error {
@@ -37,14 +41,28 @@ func (q *TableColumns) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *TableColumns) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResults(q.Result)
+}
+
+func (q *TableColumns) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResults[runtimev1.ProfileColumn](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *TableColumns) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	olap, err := rt.OLAP(ctx, instanceID)
 	if err != nil {
 		return err
 	}
 
-	if olap.Dialect() != drivers.DialectDuckDB {
-		return fmt.Errorf("not available for dialect '%s'", olap.Dialect())
+	// Fall back to the information schema for OLAP stores that don't support the query
+	if !olap.Capabilities().Supports(drivers.QueryKindTableColumns) {
+		return q.resolveFromInformationSchema(ctx, olap)
 	}
 
 	temporaryTableName := tempName("profile_columns_")
@@ -61,7 +79,7 @@ func (q *TableColumns) Resolve(ctx context.Context, rt *runtime.Runtime, instanc
 
 	rows, err = olap.Execute(ctx, &drivers.Statement{
 		Query: fmt.Sprintf(`select column_name as name, data_type as type from information_schema.columns 
-		where table_name = '%s' and table_schema = 'temp'`, temporaryTableName),
+		where table_name = '%s' and table_catalog = 'temp'`, temporaryTableName),
 		Priority: priority,
 	})
 	if err != nil {
@@ -105,3 +123,23 @@ func (q *TableColumns) Resolve(ctx context.Context, rt *runtime.Runtime, instanc
 	q.Result = pcs[0:i]
 	return nil
 }
+
+// resolveFromInformationSchema lists the table's columns from the OLAP store's information schema.
+// The column types are the runtime's type codes (such as "STRING") instead of the store's type names.
+func (q *TableColumns) resolveFromInformationSchema(ctx context.Context, olap drivers.OLAPStore) error {
+	table, err := olap.InformationSchema().Lookup(ctx, q.TableName)
+	if err != nil {
+		return err
+	}
+
+	pcs := make([]*runtimev1.ProfileColumn, len(table.Schema.Fields))
+	for i, f := range table.Schema.Fields {
+		pcs[i] = &runtimev1.ProfileColumn{
+			Name: f.Name,
+			Type: strings.TrimPrefix(f.Type.Code.String(), "CODE_"),
+		}
+	}
+
+	q.Result = pcs
+	return nil
+}
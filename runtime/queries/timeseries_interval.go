@@ -14,7 +14,7 @@ type RollupInterval struct {
 	Result     *runtimev1.EstimateRollupIntervalResponse
 }
 
-var _ runtime.Query = &RollupInterval{}
+var _ runtime.PersistentQuery = &RollupInterval{}
 
 func (q *RollupInterval) Key() string {
 	return fmt.Sprintf("RollupInterval:%s:%s", q.TableName, q.ColumnName)
@@ -24,8 +24,11 @@ func (q *RollupInterval) Deps() []string {
 	return []string{q.TableName}
 }
 
-func (q *RollupInterval) MarshalResult() any {
-	return q.Result
+func (q *RollupInterval) MarshalResult() *runtime.QueryResult {
+	return &runtime.QueryResult{
+		Value: q.Result,
+		Bytes: sizeOf(q.Result),
+	}
 }
 
 func (q *RollupInterval) UnmarshalResult(v any) error {
@@ -37,6 +40,19 @@ func (q *RollupInterval) UnmarshalResult(v any) error {
 	return nil
 }
 
+func (q *RollupInterval) MarshalResultBytes() ([]byte, error) {
+	return marshalProtoResult(q.Result)
+}
+
+func (q *RollupInterval) UnmarshalResultBytes(data []byte) error {
+	res, err := unmarshalProtoResult[runtimev1.EstimateRollupIntervalResponse](data)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
 func (q *RollupInterval) Resolve(ctx context.Context, rt *runtime.Runtime, instanceID string, priority int) error {
 	ctr := &ColumnTimeRange{
 		TableName:  q.TableName,
@@ -0,0 +1,185 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Embed the IANA time zone database so time zones resolve on hosts without zoneinfo
+	_ "time/tzdata"
+)
+
+const (
+	IsoFormatWithOffset string = "2006-01-02T15:04:05.000Z07:00"
+	sqlTimestampFormat  string = "2006-01-02 15:04:05"
+)
+
+// timeZone converts time series buckets to a local time zone.
+// DuckDB is not built with ICU, so it doesn't know about time zones. Instead, we resolve the UTC offsets
+// (including DST transitions) in Go for the time range being queried and inline them in the SQL.
+type timeZone struct {
+	loc         *time.Location
+	transitions []zoneTransition
+	calendar    calendar
+}
+
+// calendar configures how weeks, quarters and years are aligned when truncating timestamps.
+// The zero value uses ISO weeks and calendar years.
+type calendar struct {
+	// firstDayOfWeek is from 1 (Monday) to 7 (Sunday)
+	firstDayOfWeek uint32
+	// firstMonthOfYear is from 1 (January) to 12 (December)
+	firstMonthOfYear uint32
+}
+
+// zoneTransition represents a UTC offset that applies until the next transition.
+type zoneTransition struct {
+	start  time.Time
+	offset int
+}
+
+// newTimeZone resolves the IANA time zone name for queries between start and end.
+// An empty name resolves to UTC.
+func newTimeZone(name string, start, end time.Time) (*timeZone, error) {
+	if name == "" {
+		name = "UTC"
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+
+	// Buckets can start up to a year before start (when truncating to years),
+	// so we also need the offsets for the preceding year.
+	start = start.AddDate(-1, 0, 0)
+	end = end.AddDate(1, 0, 0)
+
+	var transitions []zoneTransition
+	t := start
+	for {
+		_, offset := t.In(loc).Zone()
+		transitions = append(transitions, zoneTransition{start: t, offset: offset})
+		_, next := t.In(loc).ZoneBounds()
+		if next.IsZero() || !next.Before(end) {
+			break
+		}
+		t = next
+	}
+
+	return &timeZone{loc: loc, transitions: transitions}, nil
+}
+
+func (tz *timeZone) isUTC() bool {
+	return len(tz.transitions) == 1 && tz.transitions[0].offset == 0
+}
+
+// format formats t as an ISO timestamp with the zone's UTC offset.
+func (tz *timeZone) format(t time.Time) string {
+	if tz.isUTC() {
+		return t.UTC().Format(IsoFormat)
+	}
+	return t.In(tz.loc).Format(IsoFormatWithOffset)
+}
+
+// truncateExpr returns a SQL expression that truncates the UTC timestamp expression expr to the start of
+// its grain-sized bucket in the time zone. The result is a UTC timestamp.
+func (tz *timeZone) truncateExpr(grain, expr string) string {
+	if tz.isUTC() {
+		return tz.calendar.dateTruncExpr(grain, expr)
+	}
+
+	// Buckets smaller than a day don't cross DST transitions, so we shift back by the row's own offset.
+	// This also keeps the two local hours that repeat when the clocks fall back apart.
+	offset := tz.offsetExpr(expr, false)
+	local := fmt.Sprintf("%s::TIMESTAMP", tz.calendar.dateTruncExpr(grain, fmt.Sprintf("%s + INTERVAL (%s) SECOND", expr, offset)))
+	if !isCalendarGrain(grain) {
+		return fmt.Sprintf("(%s - INTERVAL (%s) SECOND)", local, offset)
+	}
+
+	// Larger buckets may cross DST transitions, so we resolve the offset at the start of the local bucket.
+	return tz.localToUTCExpr(local)
+}
+
+// seriesSQL returns a SELECT statement that generates the start of every grain-sized bucket between start and end
+// in the time zone. The buckets are UTC timestamps in a column named alias.
+func (tz *timeZone) seriesSQL(grain string, start, end time.Time, alias string) string {
+	if tz.isUTC() || !isCalendarGrain(grain) {
+		return fmt.Sprintf(
+			"SELECT generate_series as %s FROM generate_series(%s, %s, interval '1 %s')",
+			alias,
+			tz.truncateExpr(grain, fmt.Sprintf("TIMESTAMP '%s'", start.UTC().Format(IsoFormat))),
+			tz.truncateExpr(grain, fmt.Sprintf("TIMESTAMP '%s'", end.UTC().Format(IsoFormat))),
+			grain,
+		)
+	}
+
+	// Generate the series on local wall-clock time so that every bucket has the same local length
+	return fmt.Sprintf(
+		"SELECT %s as %s FROM generate_series(%s, %s, interval '1 %s')",
+		tz.localToUTCExpr("generate_series"),
+		alias,
+		tz.calendar.dateTruncExpr(grain, fmt.Sprintf("TIMESTAMP '%s'", start.In(tz.loc).Format(sqlTimestampFormat))),
+		tz.calendar.dateTruncExpr(grain, fmt.Sprintf("TIMESTAMP '%s'", end.In(tz.loc).Format(sqlTimestampFormat))),
+		grain,
+	)
+}
+
+// localToUTCExpr returns a SQL expression that converts the local wall-clock timestamp expression expr to UTC.
+func (tz *timeZone) localToUTCExpr(expr string) string {
+	return fmt.Sprintf("(%s - INTERVAL (%s) SECOND)", expr, tz.offsetExpr(expr, true))
+}
+
+// offsetExpr returns a SQL expression for the zone's UTC offset in seconds at the timestamp expression expr.
+// If local is true, expr is a wall-clock timestamp in the zone, otherwise it's a UTC timestamp.
+func (tz *timeZone) offsetExpr(expr string, local bool) string {
+	if len(tz.transitions) == 1 {
+		return fmt.Sprintf("%d", tz.transitions[0].offset)
+	}
+
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i := 1; i < len(tz.transitions); i++ {
+		prev := tz.transitions[i-1]
+		threshold := tz.transitions[i].start
+		if local {
+			// The wall-clock time at which the transition happens (in terms of the previous offset)
+			threshold = threshold.Add(time.Duration(prev.offset) * time.Second)
+		}
+		fmt.Fprintf(&b, " WHEN %s < TIMESTAMP '%s' THEN %d", expr, threshold.UTC().Format(sqlTimestampFormat), prev.offset)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", tz.transitions[len(tz.transitions)-1].offset)
+	return b.String()
+}
+
+// dateTruncExpr returns a date_trunc SQL expression for expr, aligning weeks to firstDayOfWeek
+// and quarters and years to firstMonthOfYear.
+func (c calendar) dateTruncExpr(grain, expr string) string {
+	var shift string
+	switch strings.ToUpper(grain) {
+	case "WEEK":
+		if c.firstDayOfWeek > 1 {
+			shift = fmt.Sprintf("INTERVAL (%d) DAY", c.firstDayOfWeek-1)
+		}
+	case "QUARTER", "YEAR":
+		if c.firstMonthOfYear > 1 {
+			shift = fmt.Sprintf("INTERVAL (%d) MONTH", c.firstMonthOfYear-1)
+		}
+	}
+
+	if shift == "" {
+		return fmt.Sprintf("date_trunc('%s', %s)", grain, expr)
+	}
+
+	// Shift the timestamp to the standard calendar, truncate, and shift the bucket back
+	return fmt.Sprintf("(date_trunc('%s', %s - %s) + %s)", grain, expr, shift, shift)
+}
+
+// isCalendarGrain returns true for date_trunc specifiers of a day or larger
+func isCalendarGrain(grain string) bool {
+	switch strings.ToUpper(grain) {
+	case "DAY", "WEEK", "MONTH", "QUARTER", "YEAR":
+		return true
+	}
+	return false
+}
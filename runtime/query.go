@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"go.uber.org/zap"
 )
 
 type Query interface {
@@ -12,9 +15,8 @@ type Query interface {
 	// Deps should return the source and model names that the query targets.
 	// It's used to invalidate cached queries when the underlying data changes.
 	Deps() []string
-	// MarshalResult should return the query result for caching.
-	// TODO: Also return estimated cost in bytes.
-	MarshalResult() any
+	// MarshalResult should return the query result and its estimated size in bytes for caching.
+	MarshalResult() *QueryResult
 	// UnmarshalResult should populate a query with a cached result
 	UnmarshalResult(v any) error
 	// Resolve should execute the query against the instance's infra.
@@ -22,27 +24,88 @@ type Query interface {
 	Resolve(ctx context.Context, rt *Runtime, instanceID string, priority int) error
 }
 
+// PreparedQuery is implemented by queries whose cache key depends on state that must be resolved first,
+// such as the caller's security policy. Prepare is called before Key.
+type PreparedQuery interface {
+	Query
+	// Prepare should resolve the state that the query's key depends on
+	Prepare(ctx context.Context, rt *Runtime, instanceID string) error
+}
+
+// QueryResult is a query result and its estimated size in bytes.
+// The size is used to bound the memory used by the query cache.
+type QueryResult struct {
+	Value any
+	Bytes int64
+}
+
+// QueryWithTTL is implemented by queries whose cached results should expire after a duration.
+// Results of other queries expire after Options.QueryCacheTTL (if set).
+type QueryWithTTL interface {
+	Query
+	TTL() time.Duration
+}
+
+// PersistentQuery is implemented by queries whose results can be stored in the persistent query cache
+// (see Options.QueryCacheDir).
+type PersistentQuery interface {
+	Query
+	// MarshalResultBytes should serialize the query result
+	MarshalResultBytes() ([]byte, error)
+	// UnmarshalResultBytes should populate a query with a result serialized by MarshalResultBytes
+	UnmarshalResultBytes(data []byte) error
+}
+
 type queryCacheKey struct {
 	instanceID    string
 	queryKey      string
 	dependencyKey string
 }
 
+// Query resolves a query (or returns its cached result) and records it in the query history.
 func (r *Runtime) Query(ctx context.Context, instanceID string, query Query, priority int) error {
+	rec := &queryRecorder{}
+	start := time.Now()
+	cacheHit, err := r.query(context.WithValue(ctx, queryRecorderKey{}, rec), instanceID, query, priority)
+
+	e := &QueryLogEntry{
+		InstanceID: instanceID,
+		Type:       queryType(query),
+		Caller:     callerFromContext(ctx),
+		StartedOn:  start,
+		Duration:   time.Since(start),
+		CacheHit:   cacheHit,
+	}
+	var omitError bool
+	e.SQL, e.Rows, omitError = rec.result()
+	if err != nil {
+		e.Error = err.Error()
+		if omitError {
+			e.Error = omittedQueryError
+		}
+	}
+	r.queryHistory.record(e)
+
+	return err
+}
+
+// query resolves a query using the query caches. It returns true if the result was found in a cache.
+func (r *Runtime) query(ctx context.Context, instanceID string, query Query, priority int) (bool, error) {
+	if pq, ok := query.(PreparedQuery); ok {
+		if err := pq.Prepare(ctx, r, instanceID); err != nil {
+			return false, err
+		}
+	}
+
 	// if key is empty, skip caching
 	if query.Key() == "" {
-		return query.Resolve(ctx, r, instanceID, priority)
+		return false, query.Resolve(ctx, r, instanceID, priority)
 	}
 	deps := query.Deps()
-	depKeys := make([]string, len(deps))
-	for i, dep := range deps {
-		entry, err := r.GetCatalogEntry(ctx, instanceID, dep)
-		if err != nil {
-			return fmt.Errorf("query dependency %q not found", dep)
-		}
-		depKeys[i] = entry.Name + ":" + entry.UpdatedOn.String()
+	depKey, err := r.dependencyKey(ctx, instanceID, deps)
+	if err != nil {
+		return false, err
 	}
-	depKey := strings.Join(depKeys, ";")
 	key := queryCacheKey{
 		instanceID:    instanceID,
 		queryKey:      query.Key(),
@@ -50,12 +113,100 @@ func (r *Runtime) Query(ctx context.Context, instanceID string, query Query, pri
 	}
 	val, ok := r.queryCache.get(key)
 	if ok {
-		return query.UnmarshalResult(val)
+		return true, query.UnmarshalResult(val)
 	}
-	err := query.Resolve(ctx, r, instanceID, priority)
+
+	var ttl time.Duration
+	if q, ok := query.(QueryWithTTL); ok {
+		ttl = q.TTL()
+	}
+
+	// Check the persistent cache and populate the in-memory cache on hits
+	pq, persistent := query.(PersistentQuery)
+	persistent = persistent && r.persistentQueryCache != nil
+	if persistent {
+		data, ok := r.persistentQueryCache.get(key)
+		if ok {
+			err := pq.UnmarshalResultBytes(data)
+			if err == nil {
+				res := query.MarshalResult()
+				r.queryCache.add(key, deps, res.Value, res.Bytes, ttl)
+				return true, nil
+			}
+			r.logger.Warn("could not unmarshal persisted query result", zap.String("instance_id", instanceID), zap.Error(err))
+		}
+	}
+
+	err = query.Resolve(ctx, r, instanceID, priority)
+	if err != nil {
+		return false, err
+	}
+	res := query.MarshalResult()
+	r.queryCache.add(key, deps, res.Value, res.Bytes, ttl)
+
+	if persistent {
+		if ttl == 0 {
+			ttl = r.opts.QueryCacheTTL
+		}
+		data, err := pq.MarshalResultBytes()
+		if err == nil {
+			err = r.persistentQueryCache.add(key, deps, data, ttl)
+		}
+		if err != nil {
+			r.logger.Warn("could not persist query result", zap.String("instance_id", instanceID), zap.Error(err))
+		}
+	}
+
+	return false, nil
+}
+
+// dependencyKey returns a key that identifies the current versions of a query's dependencies
+func (r *Runtime) dependencyKey(ctx context.Context, instanceID string, deps []string) (string, error) {
+	depKeys := make([]string, len(deps))
+	for i, dep := range deps {
+		entry, err := r.GetCatalogEntry(ctx, instanceID, dep)
+		if err != nil {
+			return "", fmt.Errorf("query dependency %q not found", dep)
+		}
+		depKeys[i] = entry.Name + ":" + entry.UpdatedOn.String()
+	}
+	return strings.Join(depKeys, ";"), nil
+}
+
+// sweepQueryCache removes the persisted results of queries whose dependencies have changed or been removed.
+// It's called after the catalog has been updated. (Such results can't be served anymore, but would otherwise stay on disk.)
+func (r *Runtime) sweepQueryCache(ctx context.Context, instanceID string) {
+	if r.persistentQueryCache == nil {
+		return
+	}
+
+	n, err := r.persistentQueryCache.sweep(instanceID, func(deps []string) (string, error) {
+		return r.dependencyKey(ctx, instanceID, deps)
+	})
 	if err != nil {
-		return err
+		r.logger.Warn("could not sweep persisted query results", zap.String("instance_id", instanceID), zap.Error(err))
+	} else if n > 0 {
+		r.logger.Debug("swept persisted query results", zap.String("instance_id", instanceID), zap.Int("removed", n))
+	}
+}
+
+// FlushQueryCache removes cached query results for an instance (from memory and from the persistent cache, if enabled).
+// If dependency is not empty, it only removes results of queries that target the named source, model or metrics view.
+// It returns the number of removed results.
+func (r *Runtime) FlushQueryCache(instanceID, dependency string) (int, error) {
+	keys := r.queryCache.flush(instanceID, dependency)
+	if r.persistentQueryCache == nil {
+		return len(keys), nil
+	}
+
+	// Results are usually in both caches, so we count distinct results
+	removed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		removed[r.persistentQueryCache.path(key)] = true
+	}
+	paths, err := r.persistentQueryCache.flush(instanceID, dependency)
+	for _, path := range paths {
+		removed[path] = true
 	}
-	r.queryCache.add(key, query.MarshalResult())
-	return nil
+	return len(removed), err
 }
@@ -0,0 +1,419 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rilldata/rill/runtime/drivers"
+	"go.uber.org/zap"
+)
+
+// defaultQueryHistorySize is the number of queries kept in the history if Options.QueryHistorySize is not set
+const defaultQueryHistorySize = 1000
+
+// defaultQuerySlowThreshold is the duration above which queries are flagged as slow if Options.QuerySlowThreshold is not set
+const defaultQuerySlowThreshold = 5 * time.Second
+
+// QueryTypeRaw is the type of statements that are executed directly against the OLAP store (not through Runtime.Query)
+const QueryTypeRaw = "raw"
+
+// omittedQueryError replaces the errors of statements with a LoggedQuery, since errors may quote the executed SQL
+const omittedQueryError = "query failed (the error is omitted since it may disclose values that are not logged)"
+
+type callerKey struct{}
+
+// WithCaller returns a context for requests made by the given caller (e.g. the subject of an access token).
+// Queries executed with the context are attributed to the caller in the query history and in RunningQueries.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// callerFromContext returns the caller set with WithCaller (or an empty string for anonymous callers)
+func callerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// QueryLogEntry describes a query executed by the runtime
+type QueryLogEntry struct {
+	ID         uint64 `json:"id"`
+	InstanceID string `json:"instance_id"`
+	// Type is QueryTypeRaw or the prefix of the Runtime.Query key (e.g. "MetricsViewToplist")
+	Type string `json:"type"`
+	// Caller is the caller the query was executed for (see WithCaller)
+	Caller    string    `json:"caller,omitempty"`
+	SQL       string    `json:"sql"`
+	StartedOn time.Time `json:"started_on"`
+	// Duration includes the time spent reading the result
+	Duration time.Duration `json:"duration"`
+	Rows     int64         `json:"rows"`
+	CacheHit bool          `json:"cache_hit"`
+	Error    string        `json:"error,omitempty"`
+	// Slow is true if the duration exceeded Options.QuerySlowThreshold
+	Slow bool `json:"slow"`
+}
+
+// QueryHistoryOptions filters the entries returned by QueryHistory
+type QueryHistoryOptions struct {
+	// Type only returns queries of the given type (if set)
+	Type string
+	// Caller only returns queries executed for the given caller (if set)
+	Caller string
+	// SlowOnly only returns queries flagged as slow
+	SlowOnly bool
+	// Limit is the max number of entries to return (0 means no limit)
+	Limit int
+}
+
+// QueryHistory returns the most recently executed queries for an instance, newest first.
+func (r *Runtime) QueryHistory(instanceID string, opts *QueryHistoryOptions) []*QueryLogEntry {
+	return r.queryHistory.list(instanceID, opts)
+}
+
+// queryHistory keeps the most recent queries in a ring buffer.
+// If a path is set, entries are also appended to a file as JSON lines and loaded from it on startup.
+// The file is rewritten with only the buffered entries when it has grown to twice the size of the buffer.
+type queryHistory struct {
+	mu            sync.Mutex
+	entries       []*QueryLogEntry
+	next          int
+	count         int
+	seq           uint64
+	slowThreshold time.Duration
+	logger        *zap.Logger
+	path          string
+	file          *os.File
+	fileEntries   int
+}
+
+func newQueryHistory(size int, slowThreshold time.Duration, path string, logger *zap.Logger) (*queryHistory, error) {
+	if size <= 0 {
+		size = defaultQueryHistorySize
+	}
+	if slowThreshold == 0 {
+		slowThreshold = defaultQuerySlowThreshold
+	}
+
+	h := &queryHistory{
+		entries:       make([]*QueryLogEntry, size),
+		slowThreshold: slowThreshold,
+		logger:        logger,
+		path:          path,
+	}
+
+	if path != "" {
+		err := h.load()
+		if err != nil {
+			return nil, fmt.Errorf("could not load query history: %w", err)
+		}
+		err = h.compact()
+		if err != nil {
+			return nil, fmt.Errorf("could not write query history: %w", err)
+		}
+	}
+
+	return h, nil
+}
+
+func (h *queryHistory) record(e *QueryLogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	e.ID = h.seq
+	e.Slow = e.Duration >= h.slowThreshold
+	h.push(e)
+
+	if e.Slow {
+		h.logger.Warn("slow query",
+			zap.String("instance_id", e.InstanceID),
+			zap.String("type", e.Type),
+			zap.Duration("duration", e.Duration),
+			zap.Int64("rows", e.Rows),
+			zap.String("sql", e.SQL),
+		)
+	}
+
+	if h.file == nil {
+		return
+	}
+
+	err := h.append(e)
+	if err == nil && h.fileEntries >= 2*len(h.entries) {
+		err = h.compact()
+	}
+	if err != nil {
+		h.logger.Warn("could not persist query history", zap.Error(err))
+	}
+}
+
+func (h *queryHistory) list(instanceID string, opts *QueryHistoryOptions) []*QueryLogEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var res []*QueryLogEntry
+	for i := 0; i < h.count; i++ {
+		e := h.entries[(h.next-1-i+len(h.entries))%len(h.entries)]
+		if e.InstanceID != instanceID {
+			continue
+		}
+		if opts.Type != "" && e.Type != opts.Type {
+			continue
+		}
+		if opts.Caller != "" && e.Caller != opts.Caller {
+			continue
+		}
+		if opts.SlowOnly && !e.Slow {
+			continue
+		}
+		snapshot := *e
+		res = append(res, &snapshot)
+		if opts.Limit > 0 && len(res) == opts.Limit {
+			break
+		}
+	}
+	return res
+}
+
+// deleteInstance removes all entries for an instance
+func (h *queryHistory) deleteInstance(instanceID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	kept := h.ordered()
+	h.next = 0
+	h.count = 0
+	for _, e := range kept {
+		if e.InstanceID != instanceID {
+			h.push(e)
+		}
+	}
+
+	if h.file != nil {
+		err := h.compact()
+		if err != nil {
+			h.logger.Warn("could not persist query history", zap.Error(err))
+		}
+	}
+}
+
+// push adds an entry to the ring buffer. It must be called while holding the lock.
+func (h *queryHistory) push(e *QueryLogEntry) {
+	h.entries[h.next] = e
+	h.next = (h.next + 1) % len(h.entries)
+	if h.count < len(h.entries) {
+		h.count++
+	}
+}
+
+// ordered returns the buffered entries, oldest first. It must be called while holding the lock.
+func (h *queryHistory) ordered() []*QueryLogEntry {
+	res := make([]*QueryLogEntry, 0, h.count)
+	for i := h.count; i > 0; i-- {
+		res = append(res, h.entries[(h.next-i+len(h.entries))%len(h.entries)])
+	}
+	return res
+}
+
+// load reads entries from the history file (if it exists)
+func (h *queryHistory) load() error {
+	f, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		e := &QueryLogEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			// Skip lines that were partially written
+			continue
+		}
+		h.push(e)
+		if e.ID > h.seq {
+			h.seq = e.ID
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the history file with the buffered entries. It must be called while holding the lock.
+func (h *queryHistory) compact() error {
+	if h.file != nil {
+		h.file.Close()
+		h.file = nil
+	}
+
+	tmp := h.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	entries := h.ordered()
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, h.path); err != nil {
+		return err
+	}
+
+	h.file, err = os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	h.fileEntries = len(entries)
+	return nil
+}
+
+// append writes an entry to the history file. It must be called while holding the lock.
+func (h *queryHistory) append(e *QueryLogEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = h.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	h.fileEntries++
+	return nil
+}
+
+// queryType returns the type of a query for the query history
+func queryType(q Query) string {
+	if key := q.Key(); key != "" {
+		if i := strings.IndexByte(key, ':'); i >= 0 {
+			return key[:i]
+		}
+		return key
+	}
+	name := fmt.Sprintf("%T", q)
+	return name[strings.LastIndexByte(name, '.')+1:]
+}
+
+type queryRecorderKey struct{}
+
+// queryRecorder collects the statements executed while resolving a query passed to Runtime.Query
+type queryRecorder struct {
+	mu   sync.Mutex
+	sql  []string
+	rows int64
+	// omitError is set if a statement with a LoggedQuery failed (see omittedQueryError)
+	omitError bool
+}
+
+func queryRecorderFromContext(ctx context.Context) *queryRecorder {
+	rec, _ := ctx.Value(queryRecorderKey{}).(*queryRecorder)
+	return rec
+}
+
+func (r *queryRecorder) addSQL(sql string) {
+	r.mu.Lock()
+	r.sql = append(r.sql, strings.TrimSpace(sql))
+	r.mu.Unlock()
+}
+
+func (r *queryRecorder) setOmitError() {
+	r.mu.Lock()
+	r.omitError = true
+	r.mu.Unlock()
+}
+
+func (r *queryRecorder) addRows(n int64) {
+	r.mu.Lock()
+	r.rows += n
+	r.mu.Unlock()
+}
+
+func (r *queryRecorder) result() (string, int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.sql, ";\n"), r.rows, r.omitError
+}
+
+// loggedOLAPStore records statements executed against an OLAP store in the query history.
+// Statements executed while resolving a Runtime.Query are added to the query's entry instead of getting their own.
+type loggedOLAPStore struct {
+	drivers.OLAPStore
+	instanceID string
+	history    *queryHistory
+}
+
+func (o *loggedOLAPStore) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
+	start := time.Now()
+	ctx = drivers.WithQueueKey(ctx, queueKey(o.instanceID, callerFromContext(ctx)))
+	rec := queryRecorderFromContext(ctx)
+	if rec != nil {
+		rec.addSQL(stmt.Logged())
+	}
+
+	res, err := o.OLAPStore.Execute(ctx, stmt)
+	if err != nil || res == nil {
+		if rec == nil {
+			e := o.entry(ctx, stmt, start)
+			if err != nil {
+				e.Error = err.Error()
+				if stmt.LoggedQuery != "" {
+					e.Error = omittedQueryError
+				}
+			}
+			o.history.record(e)
+		} else if err != nil && stmt.LoggedQuery != "" {
+			rec.setOmitError()
+		}
+		return res, err
+	}
+
+	res.AddCleanupFunc(func() {
+		if rec != nil {
+			rec.addRows(res.RowsRead())
+			return
+		}
+		e := o.entry(ctx, stmt, start)
+		e.Rows = res.RowsRead()
+		o.history.record(e)
+	})
+	return res, nil
+}
+
+// queueKey returns the fair queuing key for statements, which shares the OLAP store's queue fairly between the instance's callers
+func queueKey(instanceID, caller string) string {
+	if caller == "" {
+		return instanceID
+	}
+	return instanceID + "/" + caller
+}
+
+func (o *loggedOLAPStore) entry(ctx context.Context, stmt *drivers.Statement, start time.Time) *QueryLogEntry {
+	return &QueryLogEntry{
+		InstanceID: o.instanceID,
+		Type:       QueryTypeRaw,
+		Caller:     callerFromContext(ctx),
+		SQL:        strings.TrimSpace(stmt.Logged()),
+		StartedOn:  start,
+		Duration:   time.Since(start),
+	}
+}
@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestQueryHistory(t *testing.T) {
+	h, err := newQueryHistory(3, time.Second, "", zap.NewNop())
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		h.record(&QueryLogEntry{InstanceID: "a", Type: QueryTypeRaw, Duration: time.Duration(i) * time.Second})
+	}
+	h.record(&QueryLogEntry{InstanceID: "b", Type: "TableColumns"})
+
+	// The buffer only keeps the last 3 entries
+	entries := h.list("a", &QueryHistoryOptions{})
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(4), entries[0].ID)
+	require.Equal(t, uint64(3), entries[1].ID)
+	require.True(t, entries[0].Slow)
+	require.True(t, entries[1].Slow)
+
+	require.Len(t, h.list("a", &QueryHistoryOptions{Limit: 1}), 1)
+	require.Len(t, h.list("b", &QueryHistoryOptions{Type: "TableColumns"}), 1)
+	require.Len(t, h.list("b", &QueryHistoryOptions{Type: QueryTypeRaw}), 0)
+	require.Len(t, h.list("b", &QueryHistoryOptions{SlowOnly: true}), 0)
+
+	h.deleteInstance("a")
+	require.Len(t, h.list("a", &QueryHistoryOptions{}), 0)
+	require.Len(t, h.list("b", &QueryHistoryOptions{}), 1)
+}
+
+func TestQueryHistoryPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	h, err := newQueryHistory(2, time.Second, path, zap.NewNop())
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		h.record(&QueryLogEntry{InstanceID: "a", Type: QueryTypeRaw, SQL: "SELECT 1"})
+	}
+	// The file was compacted after reaching twice the size of the buffer
+	require.Equal(t, 3, h.fileEntries)
+
+	h, err = newQueryHistory(2, time.Second, path, zap.NewNop())
+	require.NoError(t, err)
+	entries := h.list("a", &QueryHistoryOptions{})
+	require.Len(t, entries, 2)
+	require.Equal(t, uint64(5), entries[0].ID)
+	require.Equal(t, "SELECT 1", entries[0].SQL)
+
+	// IDs continue from the persisted entries
+	h.record(&QueryLogEntry{InstanceID: "a", Type: QueryTypeRaw})
+	require.Equal(t, uint64(6), h.list("a", &QueryHistoryOptions{Limit: 1})[0].ID)
+}
+
+func TestQueryHistoryLoggedQuery(t *testing.T) {
+	h, err := newQueryHistory(10, time.Second, "", zap.NewNop())
+	require.NoError(t, err)
+	olap := &loggedOLAPStore{OLAPStore: failingOLAPStore{}, instanceID: "a", history: h}
+
+	_, err = olap.Execute(context.Background(), &drivers.Statement{Query: "SELECT * FROM t WHERE domain = 'msn.com'"})
+	require.Error(t, err)
+	_, err = olap.Execute(context.Background(), &drivers.Statement{
+		Query:       "SELECT * FROM t WHERE domain = 'msn.com'",
+		LoggedQuery: "SELECT * FROM t WHERE domain = {{ .user.domain | sqlString }}",
+	})
+	require.Error(t, err)
+
+	entries := h.list("a", &QueryHistoryOptions{})
+	require.Len(t, entries, 2)
+	require.Equal(t, "SELECT * FROM t WHERE domain = {{ .user.domain | sqlString }}", entries[0].SQL)
+	require.Equal(t, omittedQueryError, entries[0].Error)
+	require.Equal(t, "SELECT * FROM t WHERE domain = 'msn.com'", entries[1].SQL)
+	require.Contains(t, entries[1].Error, "msn.com")
+}
+
+// failingOLAPStore is an OLAP store whose statements fail with an error that quotes the SQL
+type failingOLAPStore struct {
+	drivers.OLAPStore
+}
+
+func (failingOLAPStore) Execute(ctx context.Context, stmt *drivers.Statement) (*drivers.Result, error) {
+	return nil, fmt.Errorf("could not execute %q", stmt.Query)
+}
@@ -18,7 +18,7 @@ func (r *Runtime) FindInstance(ctx context.Context, instanceID string) (*drivers
 
 func (r *Runtime) CreateInstance(ctx context.Context, inst *drivers.Instance) error {
 	// Check OLAP connection
-	olap, err := drivers.Open(inst.OLAPDriver, inst.OLAPDSN)
+	olap, err := drivers.Open(inst.OLAPDriver, olapDSN(inst))
 	if err != nil {
 		return err
 	}
@@ -70,5 +70,14 @@ func (r *Runtime) DeleteInstance(ctx context.Context, instanceID string) error {
 		return err
 	}
 
+	_, err = r.FlushQueryCache(instanceID, "")
+	if err != nil {
+		return err
+	}
+
+	r.exports.deleteInstance(instanceID)
+	r.queryHistory.deleteInstance(instanceID)
+	r.runningQueries.deleteInstance(instanceID)
+
 	return nil
 }
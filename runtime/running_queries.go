@@ -0,0 +1,222 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rilldata/rill/runtime/drivers"
+)
+
+// ErrQueryIDInUse is returned by TrackQuery if a query with the same ID is already running
+var ErrQueryIDInUse = errors.New("query ID is already in use")
+
+// RunningQuery describes a query that is running or waiting to run
+type RunningQuery struct {
+	// QueryID is empty for statements that were not executed with a context returned by TrackQuery
+	QueryID string
+	// Caller is the caller the query was executed for (see WithCaller). It's empty for internal statements.
+	Caller   string
+	SQL      string
+	Priority int
+	// Queued is true if the statement is waiting in the OLAP store's queue
+	Queued bool
+	// QueuePosition is the position in the OLAP store's queue, starting at 1 (0 if not queued)
+	QueuePosition int
+	StartedOn     time.Time
+}
+
+// TrackQuery registers a query, so it's listed by RunningQueries and can be cancelled with CancelQuery.
+// If queryID is empty, a random ID is generated. The query must be executed with the returned context,
+// and done must be called when the query has finished (including reading its result).
+func (r *Runtime) TrackQuery(ctx context.Context, instanceID, queryID, sql string, priority int) (context.Context, string, func(), error) {
+	if queryID == "" {
+		queryID = uuid.NewString()
+	}
+
+	ctx, cancel := context.WithCancel(drivers.WithQueryID(ctx, queryID))
+	q := &trackedQuery{
+		instanceID: instanceID,
+		id:         queryID,
+		caller:     callerFromContext(ctx),
+		sql:        sql,
+		priority:   priority,
+		startedOn:  time.Now(),
+		cancel:     cancel,
+	}
+
+	if !r.runningQueries.add(q) {
+		cancel()
+		return nil, "", nil, ErrQueryIDInUse
+	}
+
+	done := func() {
+		r.runningQueries.remove(q)
+		cancel()
+	}
+	return ctx, queryID, done, nil
+}
+
+// CancelQuery cancels a query registered with TrackQuery. If caller is not empty, only the caller's queries
+// can be cancelled (others are reported as not found).
+//
+// Queued statements of the query are removed from the queue, and executing statements are interrupted when the
+// OLAP driver supports it. Otherwise they finish in the background while the query returns a cancellation error,
+// in which case CancelQuery returns true.
+func (r *Runtime) CancelQuery(ctx context.Context, instanceID, queryID, caller string) (bool, error) {
+	q, ok := r.runningQueries.get(instanceID, queryID)
+	if !ok || (caller != "" && q.caller != caller) {
+		return false, drivers.ErrNotFound
+	}
+	q.cancel()
+
+	// Statements that were executing when the query was cancelled are still listed by the queue until they finish
+	olap, err := r.olap(ctx, instanceID)
+	if err != nil {
+		return false, err
+	}
+	if queue, ok := olap.(drivers.StatementQueue); ok {
+		for _, stmt := range queue.QueuedStatements() {
+			if stmt.QueryID == queryID && stmt.Position == 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// RunningQueries returns the statements in the OLAP store's queue followed by other queries registered with TrackQuery.
+func (r *Runtime) RunningQueries(ctx context.Context, instanceID string) ([]*RunningQuery, error) {
+	olap, err := r.olap(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := r.runningQueries.list(instanceID)
+	byID := make(map[string]*trackedQuery, len(tracked))
+	for _, q := range tracked {
+		byID[q.id] = q
+	}
+
+	var res []*RunningQuery
+	listed := make(map[string]bool)
+	if queue, ok := olap.(drivers.StatementQueue); ok {
+		for _, stmt := range queue.QueuedStatements() {
+			rq := &RunningQuery{
+				QueryID:       stmt.QueryID,
+				SQL:           stmt.Query,
+				Priority:      stmt.Priority,
+				Queued:        stmt.Position > 0,
+				QueuePosition: stmt.Position,
+				StartedOn:     stmt.EnqueuedOn,
+			}
+			if q, ok := byID[stmt.QueryID]; ok {
+				rq.Caller = q.caller
+				rq.StartedOn = q.startedOn
+				listed[q.id] = true
+			}
+			res = append(res, rq)
+		}
+	}
+
+	for _, q := range tracked {
+		if listed[q.id] {
+			continue
+		}
+		res = append(res, &RunningQuery{
+			QueryID:   q.id,
+			Caller:    q.caller,
+			SQL:       q.sql,
+			Priority:  q.priority,
+			StartedOn: q.startedOn,
+		})
+	}
+
+	return res, nil
+}
+
+// runningQueries tracks queries registered with TrackQuery
+type runningQueries struct {
+	mu      sync.Mutex
+	queries map[string]map[string]*trackedQuery
+}
+
+type trackedQuery struct {
+	instanceID string
+	id         string
+	caller     string
+	sql        string
+	priority   int
+	startedOn  time.Time
+	cancel     context.CancelFunc
+}
+
+func newRunningQueries() *runningQueries {
+	return &runningQueries{queries: make(map[string]map[string]*trackedQuery)}
+}
+
+func (r *runningQueries) add(q *trackedQuery) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m, ok := r.queries[q.instanceID]
+	if !ok {
+		m = make(map[string]*trackedQuery)
+		r.queries[q.instanceID] = m
+	}
+	if _, ok := m[q.id]; ok {
+		return false
+	}
+	m[q.id] = q
+	return true
+}
+
+func (r *runningQueries) remove(q *trackedQuery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.queries[q.instanceID]
+	if m[q.id] == q {
+		delete(m, q.id)
+	}
+	if len(m) == 0 {
+		delete(r.queries, q.instanceID)
+	}
+}
+
+func (r *runningQueries) get(instanceID, queryID string) (*trackedQuery, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	q, ok := r.queries[instanceID][queryID]
+	return q, ok
+}
+
+// list returns the queries for an instance, oldest first
+func (r *runningQueries) list(instanceID string) []*trackedQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]*trackedQuery, 0, len(r.queries[instanceID]))
+	for _, q := range r.queries[instanceID] {
+		res = append(res, q)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].startedOn.Before(res[j].startedOn)
+	})
+	return res
+}
+
+// deleteInstance cancels all queries for an instance
+func (r *runningQueries) deleteInstance(instanceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, q := range r.queries[instanceID] {
+		q.cancel()
+	}
+	delete(r.queries, instanceID)
+}
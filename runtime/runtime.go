@@ -3,6 +3,7 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/rilldata/rill/runtime/drivers"
 	"go.uber.org/zap"
@@ -12,7 +13,26 @@ type Options struct {
 	ConnectionCacheSize int
 	MetastoreDriver     string
 	MetastoreDSN        string
-	QueryCacheSize      int
+	QueryCacheSizeBytes int64
+	QueryCacheTTL       time.Duration
+	// QueryCacheDir is a directory for persisting cached query results across restarts.
+	// If empty, query results are only cached in memory.
+	QueryCacheDir string
+	// QueryCacheDirSizeBytes is the maximum size of the results persisted in QueryCacheDir.
+	// The least recently used results are removed when it's exceeded. If 0, the size is not bounded.
+	QueryCacheDirSizeBytes int64
+	// ExportDir is a directory for files written by export jobs.
+	// If empty, a temporary directory is created on the first export.
+	ExportDir string
+	// ExportTTL is how long exported files are kept after the export has completed (defaults to an hour)
+	ExportTTL time.Duration
+	// QueryHistorySize is the number of recently executed queries kept in the query history (defaults to 1000)
+	QueryHistorySize int
+	// QueryHistoryPath is a file for persisting the query history across restarts.
+	// If empty, the query history is only kept in memory.
+	QueryHistoryPath string
+	// QuerySlowThreshold is the duration above which queries are flagged as slow and logged (defaults to 5 seconds)
+	QuerySlowThreshold time.Duration
 }
 
 type Runtime struct {
@@ -22,6 +42,11 @@ type Runtime struct {
 	connCache    *connectionCache
 	catalogCache *catalogCache
 	queryCache   *queryCache
+	// persistentQueryCache is nil if Options.QueryCacheDir is not set
+	persistentQueryCache *persistentQueryCache
+	exports              *exportJobs
+	queryHistory         *queryHistory
+	runningQueries       *runningQueries
 }
 
 func New(opts *Options, logger *zap.Logger) (*Runtime, error) {
@@ -41,12 +66,33 @@ func New(opts *Options, logger *zap.Logger) (*Runtime, error) {
 		return nil, fmt.Errorf("server metastore must be a valid registry")
 	}
 
+	var persistentCache *persistentQueryCache
+	if opts.QueryCacheDir != "" {
+		persistentCache, err = newPersistentQueryCache(opts.QueryCacheDir, opts.QueryCacheDirSizeBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	history, err := newQueryHistory(opts.QueryHistorySize, opts.QuerySlowThreshold, opts.QueryHistoryPath, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Runtime{
-		opts:         opts,
-		metastore:    metastore,
-		logger:       logger,
-		connCache:    newConnectionCache(opts.ConnectionCacheSize),
-		catalogCache: newCatalogCache(),
-		queryCache:   newQueryCache(opts.QueryCacheSize),
+		opts:                 opts,
+		metastore:            metastore,
+		logger:               logger,
+		connCache:            newConnectionCache(opts.ConnectionCacheSize),
+		catalogCache:         newCatalogCache(),
+		queryCache:           newQueryCache(opts.QueryCacheSizeBytes, opts.QueryCacheTTL),
+		persistentQueryCache: persistentCache,
+		exports:              newExportJobs(opts.ExportDir, opts.ExportTTL, logger),
+		queryHistory:         history,
+		runningQueries:       newRunningQueries(),
 	}, nil
 }
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	gateway "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
+	"github.com/rilldata/rill/runtime/drivers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type attributesKey struct{}
+
+// authenticate is an auth.AuthFunc that parses the caller's attributes from a JWT passed as a bearer token.
+// The attributes are used to resolve the security policies of metrics views.
+// Requests without a token are anonymous, i.e. they have no attributes.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	if s.opts.AuthSecret == "" {
+		return ctx, nil
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("authorization")) == 0 {
+		return ctx, nil
+	}
+
+	tokenStr, err := auth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withToken(ctx, tokenStr)
+}
+
+// authenticateHTTP wraps a REST-only handler (see HTTPHandler) with the same authentication as authenticate,
+// since handlers mounted with HandlePath bypass the gRPC interceptors.
+func (s *Server) authenticateHTTP(h gateway.HandlerFunc) gateway.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		header := req.Header.Get("Authorization")
+		if s.opts.AuthSecret == "" || header == "" {
+			h(w, req, pathParams)
+			return
+		}
+
+		scheme, tokenStr, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "bearer") {
+			http.Error(w, "bad authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, err := s.withToken(req.Context(), tokenStr)
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusUnauthorized)
+			return
+		}
+
+		h(w, req.WithContext(ctx), pathParams)
+	}
+}
+
+// withToken verifies a JWT and adds its claims as the caller's attributes to the context
+func (s *Server) withToken(ctx context.Context, tokenStr string) (context.Context, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.opts.AuthSecret), nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %s", err.Error())
+	}
+
+	ctx = context.WithValue(ctx, attributesKey{}, map[string]any(claims))
+	if sub, ok := claims["sub"].(string); ok {
+		ctx = runtime.WithCaller(ctx, sub)
+	}
+	return ctx, nil
+}
+
+// caller returns the subject of the authenticated caller's token and whether the caller is an admin.
+// Admins have an "admin" claim set to true. If authentication is disabled, all callers are admins.
+// Anonymous callers and callers without a subject have an empty subject.
+func (s *Server) caller(ctx context.Context) (string, bool) {
+	if s.opts.AuthSecret == "" {
+		return "", true
+	}
+	attrs := attributesFromContext(ctx)
+	sub, _ := attrs["sub"].(string)
+	admin, _ := attrs["admin"].(bool)
+	return sub, admin
+}
+
+// attributesFromContext returns the attributes of the authenticated caller, or nil for anonymous callers
+func attributesFromContext(ctx context.Context) map[string]any {
+	attrs, _ := ctx.Value(attributesKey{}).(map[string]any)
+	return attrs
+}
+
+// sqlIdentifierRegex matches the (quoted or unquoted) identifiers in a SQL query
+var sqlIdentifierRegex = regexp.MustCompile(`"((?:[^"]|"")+)"|[A-Za-z_][A-Za-z0-9_$]*`)
+
+// checkModelAccess returns a PermissionDenied error if a caller who isn't an admin queries the model of a metrics view
+// with a security policy directly, since that would bypass the policy's row filter and access rules.
+// Identifiers are matched conservatively, i.e. any mention of a secured model's name in the SQL is rejected.
+func (s *Server) checkModelAccess(ctx context.Context, instanceID, sql string) error {
+	if _, admin := s.caller(ctx); admin {
+		return nil
+	}
+
+	mvs, err := s.runtime.ListCatalogEntries(ctx, instanceID, drivers.ObjectTypeMetricsView)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	secured := make(map[string]string)
+	for _, e := range mvs {
+		mv, ok := e.Object.(*runtimev1.MetricsView)
+		if ok && mv.Security != nil && mv.Model != "" {
+			secured[strings.ToLower(mv.Model)] = mv.Name
+		}
+	}
+	if len(secured) == 0 {
+		return nil
+	}
+
+	for _, m := range sqlIdentifierRegex.FindAllStringSubmatch(sql, -1) {
+		name := m[0]
+		if m[1] != "" {
+			name = strings.ReplaceAll(m[1], `""`, `"`)
+		}
+		if mv, ok := secured[strings.ToLower(name)]; ok {
+			return status.Errorf(codes.PermissionDenied, "model %q is protected by the security policy of metrics view %q, query the metrics view instead", name, mv)
+		}
+	}
+	return nil
+}
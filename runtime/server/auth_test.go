@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Authenticate(t *testing.T) {
+	server, err := NewServer(&Options{AuthSecret: "secret"}, nil, nil)
+	require.NoError(t, err)
+
+	// Anonymous
+	ctx, err := server.authenticate(context.Background())
+	require.NoError(t, err)
+	require.Nil(t, attributesFromContext(ctx))
+
+	// Valid token
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"domain": "msn.com"}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	ctx, err = server.authenticate(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "msn.com", attributesFromContext(ctx)["domain"])
+
+	// Token signed with another secret
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"domain": "msn.com"}).SignedString([]byte("other"))
+	require.NoError(t, err)
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	_, err = server.authenticate(ctx)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_CheckModelAccess(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+	server.opts.AuthSecret = "secret"
+	alice := authContext(t, server, jwt.MapClaims{"sub": "alice", "domain": "msn.com"})
+	admin := authContext(t, server, jwt.MapClaims{"sub": "carol", "admin": true})
+
+	// The model of a secured metrics view can't be queried directly
+	for _, sql := range []string{`SELECT * FROM ad_bids`, `select count(*) from "AD_BIDS"`, `WITH t AS (SELECT 1) SELECT * FROM t, main.ad_bids`} {
+		_, err := server.Query(alice, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: sql})
+		require.Equal(t, codes.PermissionDenied, status.Code(err), sql)
+	}
+	_, err := server.ExplainQuery(alice, &runtimev1.ExplainQueryRequest{InstanceId: instanceId, Source: &runtimev1.ExplainQueryRequest_Sql{Sql: "SELECT * FROM ad_bids"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.CreateExport(alice, &runtimev1.CreateExportRequest{
+		InstanceId: instanceId,
+		Format:     runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		Source:     &runtimev1.CreateExportRequest_TableName{TableName: "ad_bids"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Other tables and the metrics view itself can be queried
+	_, err = server.Query(alice, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT * FROM ad_bids_source"})
+	require.NoError(t, err)
+	res, err := server.Query(alice, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT domain FROM ad_bids_metrics_secure"})
+	require.NoError(t, err)
+	for _, row := range res.Data {
+		require.Equal(t, "msn.com", row.Fields["domain"].GetStringValue())
+	}
+
+	// Admins can query the model
+	_, err = server.Query(admin, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT * FROM ad_bids"})
+	require.NoError(t, err)
+}
+
+func TestServer_AuthenticateHTTP(t *testing.T) {
+	server, err := NewServer(&Options{AuthSecret: "secret"}, nil, nil)
+	require.NoError(t, err)
+
+	var attrs map[string]any
+	handler := server.authenticateHTTP(func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		attrs = attributesFromContext(req.Context())
+	})
+
+	// Anonymous
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/", nil), nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Nil(t, attrs)
+
+	// Valid token
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"domain": "msn.com"}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	handler(rec, req, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "msn.com", attrs["domain"])
+
+	// Token signed with another secret
+	attrs = nil
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"domain": "msn.com"}).SignedString([]byte("other"))
+	require.NoError(t, err)
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	handler(rec, req, nil)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Nil(t, attrs)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/queries"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ExplainQuery implements RuntimeService.
+func (s *Server) ExplainQuery(ctx context.Context, req *runtimev1.ExplainQueryRequest) (*runtimev1.ExplainQueryResponse, error) {
+	inst, err := s.runtime.FindInstance(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The resolved SQL and the plan contain the metrics view's row filter, which is only disclosed to admins
+	if name := explainMetricsViewName(req); name != "" {
+		if _, admin := s.caller(ctx); !admin && s.isSecuredMetricsView(ctx, req.InstanceId, name) {
+			return nil, status.Errorf(codes.PermissionDenied, "explaining queries against metrics view '%s' requires admin access, since it has a security policy", name)
+		}
+	}
+
+	stmt, err := s.explainStatement(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Analyzing executes the query, so the instance's query timeout applies
+	stmt.ExecutionTimeout, _ = queryLimits(inst, 0, 0)
+
+	olap, err := s.runtime.OLAP(ctx, req.InstanceId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	plan, err := olap.Explain(ctx, stmt, req.Analyze)
+	if err != nil {
+		switch {
+		case errors.Is(err, drivers.ErrExplainAnalyzeUnsupported):
+			return nil, status.Errorf(codes.Unimplemented, "analyze is not supported for dialect '%s'", olap.Dialect())
+		case errors.Is(err, context.DeadlineExceeded):
+			return nil, status.Error(codes.DeadlineExceeded, "query timed out")
+		default:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	return &runtimev1.ExplainQueryResponse{
+		Plan:       plan.Plan,
+		Profile:    planNodeToPB(plan.Profile),
+		RawProfile: plan.RawProfile,
+		Sql:        stmt.Query,
+	}, nil
+}
+
+// explainStatement resolves the SQL for the source of an explain request
+func (s *Server) explainStatement(ctx context.Context, req *runtimev1.ExplainQueryRequest) (*drivers.Statement, error) {
+	args := make([]any, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = arg.AsInterface()
+	}
+
+	var stmt *drivers.Statement
+	var err error
+	switch src := req.Source.(type) {
+	case *runtimev1.ExplainQueryRequest_Sql:
+		stmt = &drivers.Statement{Query: src.Sql, Args: args}
+		// Queries against metrics views are rewritten to queries against their models
+		if name, ok := queries.MetricsViewNameFromSQL(src.Sql); ok && s.isMetricsView(ctx, req.InstanceId, name) {
+			q := &queries.MetricsViewSQL{
+				MetricsViewName: name,
+				SQL:             src.Sql,
+				Args:            args,
+				Attributes:      attributesFromContext(ctx),
+			}
+			stmt, err = q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+			if err != nil {
+				return nil, err
+			}
+		} else if err := s.checkModelAccess(ctx, req.InstanceId, src.Sql); err != nil {
+			return nil, err
+		}
+	case *runtimev1.ExplainQueryRequest_MetricsViewToplist:
+		tr := src.MetricsViewToplist
+		q := &queries.MetricsViewToplist{
+			MetricsViewName:       tr.MetricsViewName,
+			DimensionName:         tr.DimensionName,
+			MeasureNames:          tr.MeasureNames,
+			TimeStart:             tr.TimeStart,
+			TimeEnd:               tr.TimeEnd,
+			Limit:                 tr.Limit,
+			Offset:                tr.Offset,
+			Sort:                  tr.Sort,
+			Filter:                tr.Filter,
+			Attributes:            attributesFromContext(ctx),
+			IncludeOther:          tr.IncludeOther,
+			IncludePercentOfTotal: tr.IncludePercentOfTotal,
+		}
+		stmt, err = q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+		if err != nil {
+			return nil, err
+		}
+	case *runtimev1.ExplainQueryRequest_MetricsViewTotals:
+		tr := src.MetricsViewTotals
+		q := &queries.MetricsViewTotals{
+			MetricsViewName: tr.MetricsViewName,
+			MeasureNames:    tr.MeasureNames,
+			TimeStart:       tr.TimeStart,
+			TimeEnd:         tr.TimeEnd,
+			Filter:          tr.Filter,
+			Attributes:      attributesFromContext(ctx),
+		}
+		stmt, err = q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, status.Error(codes.InvalidArgument, "missing query to explain")
+	}
+
+	stmt.Priority = int(req.Priority)
+	return stmt, nil
+}
+
+// explainMetricsViewName returns the name of the metrics view targeted by an explain request, if any
+func explainMetricsViewName(req *runtimev1.ExplainQueryRequest) string {
+	switch src := req.Source.(type) {
+	case *runtimev1.ExplainQueryRequest_Sql:
+		name, _ := queries.MetricsViewNameFromSQL(src.Sql)
+		return name
+	case *runtimev1.ExplainQueryRequest_MetricsViewToplist:
+		return src.MetricsViewToplist.MetricsViewName
+	case *runtimev1.ExplainQueryRequest_MetricsViewTotals:
+		return src.MetricsViewTotals.MetricsViewName
+	default:
+		return ""
+	}
+}
+
+func planNodeToPB(n *drivers.PlanNode) *runtimev1.QueryPlanNode {
+	if n == nil {
+		return nil
+	}
+	res := &runtimev1.QueryPlanNode{
+		Name:     n.Name,
+		Details:  n.Details,
+		Duration: durationpb.New(n.Duration),
+		Rows:     n.Rows,
+	}
+	for _, c := range n.Children {
+		res.Children = append(res.Children, planNodeToPB(c))
+	}
+	return res
+}
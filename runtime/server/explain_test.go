@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestServer_ExplainQuery(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	ctx := context.Background()
+
+	res, err := server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{
+		InstanceId: instanceId,
+		Source:     &runtimev1.ExplainQueryRequest_Sql{Sql: "SELECT a, count(*) FROM test WHERE a > ? GROUP BY a"},
+		Args:       []*structpb.Value{structpb.NewNumberValue(0)},
+	})
+	require.NoError(t, err)
+	require.Contains(t, res.Plan, "GROUP_BY")
+	require.Nil(t, res.Profile)
+
+	res, err = server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{
+		InstanceId: instanceId,
+		Source:     &runtimev1.ExplainQueryRequest_Sql{Sql: "SELECT a, count(*) FROM test WHERE a > ? GROUP BY a"},
+		Args:       []*structpb.Value{structpb.NewNumberValue(0)},
+		Analyze:    true,
+	})
+	require.NoError(t, err)
+	require.Contains(t, res.Plan, "GROUP_BY")
+	require.NotEmpty(t, res.RawProfile)
+	require.Contains(t, res.Profile.Name, "GROUP_BY")
+	require.Equal(t, int64(1), res.Profile.Rows)
+	require.NotEmpty(t, res.Profile.Children)
+
+	// Profiling is disabled again after analyzing
+	qres, err := server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT current_setting('enable_profiling') AS p"})
+	require.NoError(t, err)
+	require.NotEqual(t, "json", qres.Data[0].Fields["p"].GetStringValue())
+
+	_, err = server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{InstanceId: instanceId})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_ExplainQuery_MetricsView(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+	ctx := context.Background()
+
+	res, err := server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{
+		InstanceId: instanceId,
+		Source: &runtimev1.ExplainQueryRequest_MetricsViewToplist{MetricsViewToplist: &runtimev1.MetricsViewToplistRequest{
+			MetricsViewName: "ad_bids_metrics",
+			DimensionName:   "domain",
+			MeasureNames:    []string{"measure_2"},
+		}},
+		Analyze: true,
+	})
+	require.NoError(t, err)
+	require.Contains(t, res.Sql, "ad_bids")
+	require.NotEmpty(t, res.Plan)
+	require.NotNil(t, res.Profile)
+
+	res, err = server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{
+		InstanceId: instanceId,
+		Source: &runtimev1.ExplainQueryRequest_MetricsViewTotals{MetricsViewTotals: &runtimev1.MetricsViewTotalsRequest{
+			MetricsViewName: "ad_bids_metrics",
+			MeasureNames:    []string{"measure_0"},
+		}},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.Plan)
+
+	res, err = server.ExplainQuery(ctx, &runtimev1.ExplainQueryRequest{
+		InstanceId: instanceId,
+		Source:     &runtimev1.ExplainQueryRequest_Sql{Sql: "SELECT domain, measure_2 FROM ad_bids_metrics"},
+	})
+	require.NoError(t, err)
+	require.NotContains(t, res.Sql, "ad_bids_metrics")
+	require.NotEmpty(t, res.Plan)
+}
+
+func TestServer_ExplainQuery_Security(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+	server.opts.AuthSecret = "secret"
+	alice := authContext(t, server, jwt.MapClaims{"sub": "alice", "domain": "msn.com"})
+	admin := authContext(t, server, jwt.MapClaims{"sub": "carol", "admin": true, "domain": "msn.com"})
+
+	reqs := []*runtimev1.ExplainQueryRequest{
+		{InstanceId: instanceId, Source: &runtimev1.ExplainQueryRequest_Sql{Sql: "SELECT domain FROM ad_bids_metrics_secure"}},
+		{InstanceId: instanceId, Source: &runtimev1.ExplainQueryRequest_MetricsViewTotals{MetricsViewTotals: &runtimev1.MetricsViewTotalsRequest{
+			MetricsViewName: "ad_bids_metrics_secure",
+			MeasureNames:    []string{"measure_0"},
+		}}},
+	}
+	for _, req := range reqs {
+		// The resolved SQL contains the row filter, so it's only returned to admins
+		_, err := server.ExplainQuery(alice, req)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		res, err := server.ExplainQuery(admin, req)
+		require.NoError(t, err)
+		require.Contains(t, res.Sql, "msn.com")
+	}
+}
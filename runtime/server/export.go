@@ -1,29 +1,222 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path"
+	"net/url"
+	"strings"
 
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/exportutil"
+	"github.com/rilldata/rill/runtime/queries"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// downloadExportChunkSize is the max number of bytes in each DownloadExport message
+const downloadExportChunkSize = 1 << 20
+
+// CreateExport implements RuntimeService.
+func (s *Server) CreateExport(ctx context.Context, req *runtimev1.CreateExportRequest) (*runtimev1.CreateExportResponse, error) {
+	if req.Format == runtimev1.ExportFormat_EXPORT_FORMAT_UNSPECIFIED {
+		return nil, status.Error(codes.InvalidArgument, "format is required")
+	}
+
+	// Exports are only available to their creator, so they must be created by an identifiable caller
+	if caller, admin := s.caller(ctx); !admin && caller == "" {
+		return nil, status.Error(codes.PermissionDenied, "exports are only available to authenticated callers")
+	}
+
+	opts, err := s.exportOptions(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := s.runtime.CreateExport(ctx, req.InstanceId, opts)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &runtimev1.CreateExportResponse{Export: exportToPB(e)}, nil
+}
+
+// exportOptions resolves the SQL for the source of an export request
+func (s *Server) exportOptions(ctx context.Context, req *runtimev1.CreateExportRequest) (*runtime.ExportOptions, error) {
+	args := make([]any, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = arg.AsInterface()
+	}
+
+	opts := &runtime.ExportOptions{
+		Format:   req.Format,
+		Args:     args,
+		Priority: int(req.Priority),
+	}
+
+	switch src := req.Source.(type) {
+	case *runtimev1.CreateExportRequest_TableName:
+		if s.isMetricsView(ctx, req.InstanceId, src.TableName) {
+			return nil, status.Error(codes.InvalidArgument, "use sql or metrics_view_toplist to export a metrics view")
+		}
+		if err := s.checkModelAccess(ctx, req.InstanceId, safeSQLName(src.TableName)); err != nil {
+			return nil, err
+		}
+		opts.SQL = fmt.Sprintf("SELECT * FROM %s", safeSQLName(src.TableName))
+		opts.FileName = src.TableName
+	case *runtimev1.CreateExportRequest_Sql:
+		opts.SQL = src.Sql
+		opts.FileName = "query"
+		// Queries against metrics views are rewritten to queries against their models
+		if name, ok := queries.MetricsViewNameFromSQL(src.Sql); ok && s.isMetricsView(ctx, req.InstanceId, name) {
+			q := &queries.MetricsViewSQL{
+				MetricsViewName: name,
+				SQL:             src.Sql,
+				Args:            args,
+				Attributes:      attributesFromContext(ctx),
+			}
+			stmt, err := q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+			if err != nil {
+				return nil, err
+			}
+			opts.SQL = stmt.Query
+			opts.LoggedSQL = stmt.LoggedQuery
+			opts.FileName = name
+		} else if err := s.checkModelAccess(ctx, req.InstanceId, src.Sql); err != nil {
+			return nil, err
+		}
+	case *runtimev1.CreateExportRequest_MetricsViewToplist:
+		tr := src.MetricsViewToplist
+		q := &queries.MetricsViewToplist{
+			MetricsViewName:       tr.MetricsViewName,
+			DimensionName:         tr.DimensionName,
+			MeasureNames:          tr.MeasureNames,
+			TimeStart:             tr.TimeStart,
+			TimeEnd:               tr.TimeEnd,
+			Limit:                 tr.Limit,
+			Offset:                tr.Offset,
+			Sort:                  tr.Sort,
+			Filter:                tr.Filter,
+			Attributes:            attributesFromContext(ctx),
+			IncludePercentOfTotal: tr.IncludePercentOfTotal,
+		}
+		if tr.IncludeOther {
+			return nil, status.Error(codes.InvalidArgument, "include_other is not supported for exports")
+		}
+		stmt, err := q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+		if err != nil {
+			return nil, err
+		}
+		opts.SQL = stmt.Query
+		opts.Args = stmt.Args
+		opts.LoggedSQL = stmt.LoggedQuery
+		opts.FileName = fmt.Sprintf("%s_%s", tr.MetricsViewName, tr.DimensionName)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "missing export source")
+	}
+
+	return opts, nil
+}
+
+// GetExport implements RuntimeService.
+func (s *Server) GetExport(ctx context.Context, req *runtimev1.GetExportRequest) (*runtimev1.GetExportResponse, error) {
+	e, err := s.runtime.GetExport(req.InstanceId, req.ExportId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "export not found")
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !s.canAccessExport(ctx, e) {
+		return nil, status.Error(codes.NotFound, "export not found")
+	}
+	return &runtimev1.GetExportResponse{Export: exportToPB(e)}, nil
+}
+
+// DownloadExport implements RuntimeService.
+func (s *Server) DownloadExport(req *runtimev1.DownloadExportRequest, srv runtimev1.RuntimeService_DownloadExportServer) error {
+	f, e, err := s.runtime.OpenExport(req.InstanceId, req.ExportId)
+	if err != nil {
+		return exportErrorToStatus(err)
+	}
+	defer f.Close()
+
+	if !s.canAccessExport(srv.Context(), e) {
+		return status.Error(codes.NotFound, "export not found")
+	}
+
+	buf := make([]byte, downloadExportChunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if err := srv.Send(&runtimev1.DownloadExportResponse{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+}
+
+// DownloadExportHTTP triggers a HTTP download of the file of a completed export job.
+// It's mounted as a REST API only. Over gRPC, use DownloadExport.
+func (s *Server) DownloadExportHTTP(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+	f, e, err := s.runtime.OpenExport(pathParams["instance_id"], pathParams["export_id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, drivers.ErrNotFound):
+			http.Error(w, "export not found", http.StatusNotFound)
+		case errors.Is(err, runtime.ErrExportNotCompleted):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer f.Close()
+
+	if !s.canAccessExport(req.Context(), e) {
+		http.Error(w, "export not found", http.StatusNotFound)
+		return
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition(e.FileName))
+	w.Header().Set("Content-Type", exportutil.ContentType(e.Format))
+	http.ServeContent(w, req, e.FileName, info.ModTime(), f)
+}
+
 // ExportTable exports a table or view as a flat file and triggers a HTTP download of it.
 // It's mounted as a REST API only, and is not available over gRPC.
-//
-// TODO: This is a temporary hack that only supports DuckDB.
-// We should add a generic workflow for data export that also supports possibly very large tables.
+// The result is streamed to the client; for large tables, prefer the async CreateExport API.
 func (s *Server) ExportTable(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
-	var exportString string
+	var format runtimev1.ExportFormat
 	switch pathParams["format"] {
 	case "csv":
-		exportString = "FORMAT CSV, HEADER"
+		format = runtimev1.ExportFormat_EXPORT_FORMAT_CSV
 	case "parquet":
-		exportString = "FORMAT PARQUET"
+		format = runtimev1.ExportFormat_EXPORT_FORMAT_PARQUET
+	case "json":
+		format = runtimev1.ExportFormat_EXPORT_FORMAT_JSON
+	case "xlsx":
+		format = runtimev1.ExportFormat_EXPORT_FORMAT_XLSX
 	default:
-		http.Error(w, fmt.Sprintf("unknown format: %s", pathParams), http.StatusBadRequest)
+		http.Error(w, fmt.Sprintf("unknown format: %s", pathParams["format"]), http.StatusBadRequest)
+		return
 	}
 
 	if pathParams["instance_id"] == "" || pathParams["table_name"] == "" {
@@ -31,39 +224,114 @@ func (s *Server) ExportTable(w http.ResponseWriter, req *http.Request, pathParam
 		return
 	}
 
-	fileName := fmt.Sprintf("%s.%s", pathParams["table_name"], pathParams["format"])
-	filePath := path.Join(os.TempDir(), fileName)
-	defer os.Remove(filePath)
-
-	// select * from the table and write to the temp file (DuckDB only)
-	olap, err := s.runtime.OLAP(req.Context(), pathParams["instance_id"])
+	err := s.checkModelAccess(req.Context(), pathParams["instance_id"], safeSQLName(pathParams["table_name"]))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		code := http.StatusBadRequest
+		if status.Code(err) == codes.PermissionDenied {
+			code = http.StatusForbidden
+		}
+		http.Error(w, status.Convert(err).Message(), code)
 		return
 	}
-	_, err = olap.Execute(req.Context(), &drivers.Statement{
-		Query:    fmt.Sprintf("COPY (SELECT * FROM %s) TO '%s' (%s)", pathParams["table_name"], filePath, exportString),
-		DryRun:   false,
-		Priority: 0,
-	})
+
+	// Buffer the start of the output, so errors that occur before any rows are written can still be returned as HTTP errors
+	bw := &bufferedResponseWriter{w: w, limit: 1 << 16}
+	fileName := fmt.Sprintf("%s.%s", pathParams["table_name"], exportutil.FileExtension(format))
+	bw.header = func() {
+		w.Header().Set("Content-Disposition", contentDisposition(fileName))
+		w.Header().Set("Content-Type", exportutil.ContentType(format))
+	}
+
+	_, err = s.runtime.Export(req.Context(), pathParams["instance_id"], &runtime.ExportOptions{
+		Format: format,
+		SQL:    fmt.Sprintf("SELECT * FROM %s", safeSQLName(pathParams["table_name"])),
+	}, bw)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if !bw.flushed {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		// If data has already been sent, we can only abort the response
 		return
 	}
 
-	// set the header to trigger download
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
-	w.Header().Set("Content-Type", req.Header.Get("Content-Type"))
+	_ = bw.flush()
+}
 
-	// read and stream the file
-	file, err := os.Open(filePath)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+// bufferedResponseWriter buffers writes up to limit bytes before sending headers and data to the client
+type bufferedResponseWriter struct {
+	w       http.ResponseWriter
+	header  func()
+	limit   int
+	buf     []byte
+	flushed bool
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	if b.flushed {
+		return b.w.Write(p)
 	}
-	_, err = io.Copy(w, file)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	b.buf = append(b.buf, p...)
+	if len(b.buf) >= b.limit {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (b *bufferedResponseWriter) flush() error {
+	if b.flushed {
+		return nil
+	}
+	b.flushed = true
+	b.header()
+	_, err := b.w.Write(b.buf)
+	b.buf = nil
+	return err
+}
+
+// canAccessExport returns true if the caller created the export or is an admin.
+// Other callers get a not found error, so they can't probe for the IDs of other callers' exports.
+func (s *Server) canAccessExport(ctx context.Context, e *runtime.Export) bool {
+	caller, admin := s.caller(ctx)
+	return admin || (caller != "" && caller == e.Creator)
+}
+
+func exportToPB(e *runtime.Export) *runtimev1.Export {
+	res := &runtimev1.Export{
+		Id:         e.ID,
+		InstanceId: e.InstanceID,
+		Format:     e.Format,
+		Status:     e.Status,
+		Error:      e.Error,
+		FileName:   e.FileName,
+		Rows:       e.Rows,
+		SizeBytes:  e.SizeBytes,
+		CreatedOn:  timestamppb.New(e.CreatedOn),
 	}
+	if !e.CompletedOn.IsZero() {
+		res.CompletedOn = timestamppb.New(e.CompletedOn)
+	}
+	return res
+}
+
+func exportErrorToStatus(err error) error {
+	switch {
+	case errors.Is(err, drivers.ErrNotFound):
+		return status.Error(codes.NotFound, "export not found")
+	case errors.Is(err, runtime.ErrExportNotCompleted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// contentDisposition returns a Content-Disposition header value that triggers a download of the named file
+func contentDisposition(fileName string) string {
+	return fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", fileName, url.PathEscape(fileName))
+}
+
+// safeSQLName quotes a table name for use in a SQL query
+func safeSQLName(name string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(name, "\"", "\"\""))
 }
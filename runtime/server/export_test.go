@@ -0,0 +1,165 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_CreateExport(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	ctx := context.Background()
+
+	res, err := server.CreateExport(ctx, &runtimev1.CreateExportRequest{
+		InstanceId: instanceId,
+		Format:     runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		Source:     &runtimev1.CreateExportRequest_TableName{TableName: "test"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "test.csv", res.Export.FileName)
+
+	e := waitForExportPB(ctx, t, server, instanceId, res.Export.Id)
+	require.Equal(t, runtimev1.Export_STATUS_COMPLETED, e.Status, e.Error)
+	require.Equal(t, int64(1), e.Rows)
+	require.NotNil(t, e.CompletedOn)
+
+	srv := &fakeDownloadExportServer{ctx: ctx}
+	err = server.DownloadExport(&runtimev1.DownloadExportRequest{InstanceId: instanceId, ExportId: e.Id}, srv)
+	require.NoError(t, err)
+	require.Equal(t, "a,\"b\"\"b\"\n1,10\n", srv.buf.String())
+
+	_, err = server.GetExport(ctx, &runtimev1.GetExportRequest{InstanceId: instanceId, ExportId: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.CreateExport(ctx, &runtimev1.CreateExportRequest{
+		InstanceId: instanceId,
+		Format:     runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_CreateExport_Creator(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	server.opts.AuthSecret = "secret"
+	alice := authContext(t, server, jwt.MapClaims{"sub": "alice"})
+	bob := authContext(t, server, jwt.MapClaims{"sub": "bob"})
+	admin := authContext(t, server, jwt.MapClaims{"sub": "carol", "admin": true})
+
+	req := &runtimev1.CreateExportRequest{
+		InstanceId: instanceId,
+		Format:     runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		Source:     &runtimev1.CreateExportRequest_TableName{TableName: "test"},
+	}
+	_, err := server.CreateExport(context.Background(), req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	res, err := server.CreateExport(alice, req)
+	require.NoError(t, err)
+	e := waitForExportPB(alice, t, server, instanceId, res.Export.Id)
+	require.Equal(t, runtimev1.Export_STATUS_COMPLETED, e.Status, e.Error)
+
+	// Only the creator and admins can access the export
+	for _, ctx := range []context.Context{alice, admin} {
+		_, err = server.GetExport(ctx, &runtimev1.GetExportRequest{InstanceId: instanceId, ExportId: e.Id})
+		require.NoError(t, err)
+		err = server.DownloadExport(&runtimev1.DownloadExportRequest{InstanceId: instanceId, ExportId: e.Id}, &fakeDownloadExportServer{ctx: ctx})
+		require.NoError(t, err)
+	}
+	for _, ctx := range []context.Context{bob, context.Background()} {
+		_, err = server.GetExport(ctx, &runtimev1.GetExportRequest{InstanceId: instanceId, ExportId: e.Id})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		err = server.DownloadExport(&runtimev1.DownloadExportRequest{InstanceId: instanceId, ExportId: e.Id}, &fakeDownloadExportServer{ctx: ctx})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	}
+
+	rec := httptest.NewRecorder()
+	server.DownloadExportHTTP(rec, httptest.NewRequest("GET", "/", nil).WithContext(bob), map[string]string{"instance_id": instanceId, "export_id": e.Id})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	rec = httptest.NewRecorder()
+	server.DownloadExportHTTP(rec, httptest.NewRequest("GET", "/", nil).WithContext(alice), map[string]string{"instance_id": instanceId, "export_id": e.Id})
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestServer_CreateExport_MetricsViewToplist(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+	ctx := context.Background()
+
+	res, err := server.CreateExport(ctx, &runtimev1.CreateExportRequest{
+		InstanceId: instanceId,
+		Format:     runtimev1.ExportFormat_EXPORT_FORMAT_CSV,
+		Source: &runtimev1.CreateExportRequest_MetricsViewToplist{MetricsViewToplist: &runtimev1.MetricsViewToplistRequest{
+			MetricsViewName: "ad_bids_metrics",
+			DimensionName:   "domain",
+			MeasureNames:    []string{"measure_2"},
+			Sort:            []*runtimev1.MetricsViewSort{{Name: "measure_2"}},
+		}},
+	})
+	require.NoError(t, err)
+
+	e := waitForExportPB(ctx, t, server, instanceId, res.Export.Id)
+	require.Equal(t, runtimev1.Export_STATUS_COMPLETED, e.Status, e.Error)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	server.DownloadExportHTTP(rec, req, map[string]string{"instance_id": instanceId, "export_id": e.Id})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Header().Get("Content-Disposition"), "ad_bids_metrics_domain.csv")
+	require.Equal(t, "domain,measure_2\nmsn.com,2\nyahoo.com,1\n", rec.Body.String())
+}
+
+func TestServer_ExportTable(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	server.ExportTable(rec, req, map[string]string{"instance_id": instanceId, "table_name": "test", "format": "json"})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "[\n{\"a\":1,\"b\\\"b\":10}\n]\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	server.ExportTable(rec, req, map[string]string{"instance_id": instanceId, "table_name": "test", "format": "xml"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	server.ExportTable(rec, req, map[string]string{"instance_id": instanceId, "table_name": "missing", "format": "csv"})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func waitForExportPB(ctx context.Context, t *testing.T, server *Server, instanceId, exportId string) *runtimev1.Export {
+	for i := 0; i < 100; i++ {
+		res, err := server.GetExport(ctx, &runtimev1.GetExportRequest{InstanceId: instanceId, ExportId: exportId})
+		require.NoError(t, err)
+		if res.Export.Status == runtimev1.Export_STATUS_COMPLETED || res.Export.Status == runtimev1.Export_STATUS_FAILED {
+			return res.Export
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("export did not complete")
+	return nil
+}
+
+type fakeDownloadExportServer struct {
+	grpc.ServerStream
+	ctx context.Context
+	buf bytes.Buffer
+}
+
+func (s *fakeDownloadExportServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeDownloadExportServer) Send(msg *runtimev1.DownloadExportResponse) error {
+	s.buf.Write(msg.Data)
+	return nil
+}
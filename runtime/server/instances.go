@@ -40,15 +40,39 @@ func (s *Server) GetInstance(ctx context.Context, req *runtimev1.GetInstanceRequ
 	}, nil
 }
 
+// GetInstanceCapabilities implements RuntimeService.
+func (s *Server) GetInstanceCapabilities(ctx context.Context, req *runtimev1.GetInstanceCapabilitiesRequest) (*runtimev1.GetInstanceCapabilitiesResponse, error) {
+	_, err := s.runtime.FindInstance(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	olap, err := s.runtime.OLAP(ctx, req.InstanceId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &runtimev1.GetInstanceCapabilitiesResponse{
+		Olap: capabilitiesToPB(olap.Dialect(), olap.Capabilities()),
+	}, nil
+}
+
 // CreateInstance implements RuntimeService.
 func (s *Server) CreateInstance(ctx context.Context, req *runtimev1.CreateInstanceRequest) (*runtimev1.CreateInstanceResponse, error) {
 	inst := &drivers.Instance{
-		ID:           req.InstanceId,
-		OLAPDriver:   req.OlapDriver,
-		OLAPDSN:      req.OlapDsn,
-		RepoDriver:   req.RepoDriver,
-		RepoDSN:      req.RepoDsn,
-		EmbedCatalog: req.EmbedCatalog,
+		ID:                   req.InstanceId,
+		OLAPDriver:           req.OlapDriver,
+		OLAPDSN:              req.OlapDsn,
+		RepoDriver:           req.RepoDriver,
+		RepoDSN:              req.RepoDsn,
+		EmbedCatalog:         req.EmbedCatalog,
+		QueryTimeoutSeconds:  req.QueryTimeoutSeconds,
+		QueryMaxRows:         req.QueryMaxRows,
+		OLAPMemoryLimitBytes: req.OlapMemoryLimitBytes,
+		OLAPThreads:          req.OlapThreads,
 	}
 
 	err := s.runtime.CreateInstance(ctx, inst)
@@ -73,11 +97,57 @@ func (s *Server) DeleteInstance(ctx context.Context, req *runtimev1.DeleteInstan
 
 func instanceToPB(inst *drivers.Instance) *runtimev1.Instance {
 	return &runtimev1.Instance{
-		InstanceId:   inst.ID,
-		OlapDriver:   inst.OLAPDriver,
-		OlapDsn:      inst.OLAPDSN,
-		RepoDriver:   inst.RepoDriver,
-		RepoDsn:      inst.RepoDSN,
-		EmbedCatalog: inst.EmbedCatalog,
+		InstanceId:           inst.ID,
+		OlapDriver:           inst.OLAPDriver,
+		OlapDsn:              inst.OLAPDSN,
+		RepoDriver:           inst.RepoDriver,
+		RepoDsn:              inst.RepoDSN,
+		EmbedCatalog:         inst.EmbedCatalog,
+		QueryTimeoutSeconds:  inst.QueryTimeoutSeconds,
+		QueryMaxRows:         inst.QueryMaxRows,
+		OlapMemoryLimitBytes: inst.OLAPMemoryLimitBytes,
+		OlapThreads:          inst.OLAPThreads,
+	}
+}
+
+func capabilitiesToPB(dialect drivers.Dialect, caps drivers.Capabilities) *runtimev1.OLAPCapabilities {
+	kinds := make([]runtimev1.OLAPCapabilities_QueryKind, len(caps.QueryKinds))
+	for i, k := range caps.QueryKinds {
+		kinds[i] = queryKindToPB(k)
+	}
+
+	return &runtimev1.OLAPCapabilities{
+		Dialect:             dialect.String(),
+		QueryKinds:          kinds,
+		Connectors:          caps.Connectors,
+		ApproxQuantile:      caps.ApproxQuantile,
+		ApproxCountDistinct: caps.ApproxCountDistinct,
+		Sampling:            caps.Sampling,
+		MaxConcurrency:      int32(caps.MaxConcurrency),
+	}
+}
+
+func queryKindToPB(k drivers.QueryKind) runtimev1.OLAPCapabilities_QueryKind {
+	switch k {
+	case drivers.QueryKindMetricsView:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_METRICS_VIEW
+	case drivers.QueryKindMetricsViewToplistOther:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_METRICS_VIEW_TOPLIST_OTHER
+	case drivers.QueryKindMetricsViewSQL:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_METRICS_VIEW_SQL
+	case drivers.QueryKindColumnProfile:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_COLUMN_PROFILE
+	case drivers.QueryKindDescriptiveStatistics:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_DESCRIPTIVE_STATISTICS
+	case drivers.QueryKindRugHistogram:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_RUG_HISTOGRAM
+	case drivers.QueryKindTimeGrain:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_TIME_GRAIN
+	case drivers.QueryKindColumnTimeseries:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_COLUMN_TIMESERIES
+	case drivers.QueryKindTableColumns:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_TABLE_COLUMNS
+	default:
+		return runtimev1.OLAPCapabilities_QUERY_KIND_UNSPECIFIED
 	}
 }
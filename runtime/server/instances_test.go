@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_GetInstanceCapabilities(t *testing.T) {
+	server, instanceId := getTestServer(t)
+
+	res, err := server.GetInstanceCapabilities(context.Background(), &runtimev1.GetInstanceCapabilitiesRequest{InstanceId: instanceId})
+	require.NoError(t, err)
+	require.Equal(t, "duckdb", res.Olap.Dialect)
+	require.Contains(t, res.Olap.QueryKinds, runtimev1.OLAPCapabilities_QUERY_KIND_METRICS_VIEW_SQL)
+	require.Contains(t, res.Olap.QueryKinds, runtimev1.OLAPCapabilities_QUERY_KIND_DESCRIPTIVE_STATISTICS)
+	require.Contains(t, res.Olap.Connectors, "local_file")
+	require.True(t, res.Olap.ApproxQuantile)
+	require.True(t, res.Olap.Sampling)
+	require.Equal(t, int32(1), res.Olap.MaxConcurrency)
+
+	_, err = server.GetInstanceCapabilities(context.Background(), &runtimev1.GetInstanceCapabilitiesRequest{InstanceId: "bad"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -69,6 +69,17 @@ func ToValue(v any) (*structpb.Value, error) {
 			return nil, err
 		}
 		return structpb.NewStructValue(v2), nil
+	case duckdb.Map:
+		// JSON objects only have string keys, so we format the keys
+		m := make(map[string]any, len(v))
+		for k, v := range v {
+			m[fmt.Sprint(k)] = v
+		}
+		v2, err := ToStruct(m)
+		if err != nil {
+			return nil, err
+		}
+		return structpb.NewStructValue(v2), nil
 	default:
 		// Default handling for basic types (ints, string, etc.)
 		return structpb.NewValue(v)
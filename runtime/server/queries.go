@@ -2,18 +2,22 @@ package server
 
 import (
 	"context"
-	"fmt"
-	"math"
-	"math/big"
+	"errors"
 	"time"
-	"unicode/utf8"
 
-	"github.com/marcboeker/go-duckdb"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/arrowutil"
+	"github.com/rilldata/rill/runtime/queries"
+	"github.com/rilldata/rill/runtime/server/pbutil"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Query implements RuntimeService.
@@ -23,13 +27,70 @@ func (s *Server) Query(ctx context.Context, req *runtimev1.QueryRequest) (*runti
 		args[i] = arg.AsInterface()
 	}
 
+	inst, err := s.runtime.FindInstance(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	timeout, maxRows := queryLimits(inst, req.TimeoutSeconds, req.MaxRows)
+
+	ctx, queryID, done, err := s.trackQuery(ctx, req.InstanceId, req.QueryId, req.Sql, req.Priority)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// Queries against metrics views are rewritten to queries against their models
+	if name, ok := queries.MetricsViewNameFromSQL(req.Sql); ok && s.isMetricsView(ctx, req.InstanceId, name) {
+		q := &queries.MetricsViewSQL{
+			MetricsViewName: name,
+			SQL:             req.Sql,
+			Args:            args,
+			DryRun:          req.DryRun,
+			MaxRows:         maxRows,
+			ResultFormat:    req.ResultFormat,
+			Timeout:         timeout,
+			Attributes:      attributesFromContext(ctx),
+		}
+		err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil, status.Error(codes.Canceled, "query cancelled")
+			}
+			return nil, queryErrorToStatus(err)
+		}
+		// The result is cached, so it's copied before setting the query ID
+		return &runtimev1.QueryResponse{
+			Meta:      q.Result.Meta,
+			Data:      q.Result.Data,
+			Truncated: q.Result.Truncated,
+			ArrowData: q.Result.ArrowData,
+			QueryId:   queryID,
+		}, nil
+	}
+
+	err = s.checkModelAccess(ctx, req.InstanceId, req.Sql)
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := s.query(ctx, req.InstanceId, &drivers.Statement{
-		Query:    req.Sql,
-		Args:     args,
-		DryRun:   req.DryRun,
-		Priority: int(req.Priority),
+		Query:            req.Sql,
+		Args:             args,
+		DryRun:           req.DryRun,
+		Priority:         int(req.Priority),
+		ExecutionTimeout: timeout,
+		MaxRows:          maxRows,
 	})
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, status.Error(codes.Canceled, "query cancelled")
+		}
 		// TODO: Parse error to determine error code
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
@@ -37,151 +98,375 @@ func (s *Server) Query(ctx context.Context, req *runtimev1.QueryRequest) (*runti
 	if req.DryRun {
 		// TODO: Return a meta object for dry-run queries
 		// NOTE: Currently, instance.Query return nil rows for successful dry-run queries
-		return &runtimev1.QueryResponse{}, nil
+		return &runtimev1.QueryResponse{QueryId: queryID}, nil
 	}
 
 	defer res.Close()
 
-	data, err := rowsToData(res)
+	resp := &runtimev1.QueryResponse{Meta: res.Schema, QueryId: queryID}
+	if req.ResultFormat == runtimev1.ResultFormat_RESULT_FORMAT_ARROW {
+		resp.ArrowData, err = arrowutil.EncodeRows(res.Schema, res)
+	} else {
+		resp.Data, err = rowsToData(res)
+	}
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, status.Error(codes.Canceled, "query cancelled")
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-
-	resp := &runtimev1.QueryResponse{
-		Meta: res.Schema,
-		Data: data,
-	}
+	resp.Truncated = res.Truncated()
 
 	return resp, nil
 }
 
-func (s *Server) query(ctx context.Context, instanceID string, stmt *drivers.Statement) (*drivers.Result, error) {
-	olap, err := s.runtime.OLAP(ctx, instanceID)
+// defaultQueryStreamBatchSize is the number of rows in each QueryStream message if not set in the request
+const defaultQueryStreamBatchSize = 1000
+
+// QueryStream implements RuntimeService.
+func (s *Server) QueryStream(req *runtimev1.QueryStreamRequest, srv runtimev1.RuntimeService_QueryStreamServer) error {
+	ctx := srv.Context()
+
+	args := make([]any, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = arg.AsInterface()
+	}
+
+	inst, err := s.runtime.FindInstance(ctx, req.InstanceId)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, drivers.ErrNotFound) {
+			return status.Error(codes.InvalidArgument, "instance not found")
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
 	}
+	timeout, maxRows := queryLimits(inst, req.TimeoutSeconds, req.MaxRows)
 
-	return olap.Execute(ctx, stmt)
-}
+	ctx, queryID, done, err := s.trackQuery(ctx, req.InstanceId, req.QueryId, req.Sql, req.Priority)
+	if err != nil {
+		return err
+	}
+	defer done()
 
-func rowsToData(rows *drivers.Result) ([]*structpb.Struct, error) {
-	var data []*structpb.Struct
-	for rows.Next() {
+	batchSize := int(req.BatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultQueryStreamBatchSize
+	}
+
+	// Queries against metrics views are rewritten against the metrics view's model and streamed like other queries.
+	// (Unlike the Query RPC, their results are not cached.)
+	stmt := &drivers.Statement{Query: req.Sql, Args: args}
+	if name, ok := queries.MetricsViewNameFromSQL(req.Sql); ok && s.isMetricsView(ctx, req.InstanceId, name) {
+		q := &queries.MetricsViewSQL{
+			MetricsViewName: name,
+			SQL:             req.Sql,
+			Args:            args,
+			Attributes:      attributesFromContext(ctx),
+		}
+		stmt, err = q.ResolveStatement(ctx, s.runtime, req.InstanceId)
+		if err != nil {
+			return queryErrorToStatus(err)
+		}
+	} else if err := s.checkModelAccess(ctx, req.InstanceId, req.Sql); err != nil {
+		return err
+	}
+	stmt.Priority = int(req.Priority)
+	stmt.ExecutionTimeout = timeout
+	stmt.MaxRows = maxRows
+
+	res, err := s.query(ctx, req.InstanceId, stmt)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		if errors.Is(err, context.Canceled) {
+			return status.Error(codes.Canceled, "query cancelled")
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	defer res.Close()
+
+	// Send rows in batches as they're scanned. The schema and query ID are sent in the first message.
+	meta := res.Schema
+	batch := make([]*structpb.Struct, 0, batchSize)
+	send := func(last bool) error {
+		msg := &runtimev1.QueryStreamResponse{
+			Meta:    meta,
+			Data:    batch,
+			QueryId: queryID,
+		}
+		if last {
+			msg.Truncated = res.Truncated()
+		}
+		meta = nil
+		queryID = ""
+		batch = make([]*structpb.Struct, 0, batchSize)
+		return srv.Send(msg)
+	}
+
+	for res.Next() {
 		rowMap := make(map[string]any)
-		err := rows.MapScan(rowMap)
+		err := res.MapScan(rowMap)
 		if err != nil {
-			return nil, err
+			return status.Error(codes.Internal, err.Error())
 		}
 
-		rowStruct, err := mapToPB(rowMap)
+		rowStruct, err := pbutil.ToStruct(rowMap)
 		if err != nil {
-			return nil, err
+			return status.Error(codes.Internal, err.Error())
 		}
 
-		data = append(data, rowStruct)
+		batch = append(batch, rowStruct)
+		if len(batch) == batchSize {
+			err := send(false)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	err := rows.Err()
+	err = res.Err()
 	if err != nil {
-		return nil, err
+		if errors.Is(err, context.DeadlineExceeded) {
+			return status.Error(codes.DeadlineExceeded, "query timed out")
+		}
+		if errors.Is(err, context.Canceled) {
+			return status.Error(codes.Canceled, "query cancelled")
+		}
+		return status.Error(codes.Internal, err.Error())
 	}
 
-	return data, nil
+	// The last message may be empty, but it's always sent to signal whether the result was truncated
+	return send(true)
 }
 
-// valToPB converts any value to a google.protobuf.Value. It's similar to
-// structpb.NewValue, but adds support for a few extra primitive types.
-func valToPB(v any) (*structpb.Value, error) {
-	switch v := v.(type) {
-	// In addition to the extra supported types, we also override handling for
-	// maps and lists since we need to use valToPB on nested fields.
-	case map[string]interface{}:
-		v2, err := mapToPB(v)
-		if err != nil {
-			return nil, err
+// queryErrorToStatus maps an error returned from running a query to a gRPC status error.
+// Status errors returned by the query (such as permission errors) are passed through.
+func queryErrorToStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, drivers.ErrUnsupportedQuery):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
+// queryLimits returns the timeout and max rows for an ad-hoc query.
+// The request's limits are used if they're stricter than the instance's limits. Zero means no limit.
+func queryLimits(inst *drivers.Instance, timeoutSeconds uint32, maxRows int64) (time.Duration, int64) {
+	if inst.QueryTimeoutSeconds != 0 && (timeoutSeconds == 0 || timeoutSeconds > inst.QueryTimeoutSeconds) {
+		timeoutSeconds = inst.QueryTimeoutSeconds
+	}
+	if inst.QueryMaxRows != 0 && (maxRows <= 0 || maxRows > inst.QueryMaxRows) {
+		maxRows = inst.QueryMaxRows
+	}
+	if maxRows < 0 {
+		maxRows = 0
+	}
+	return time.Duration(timeoutSeconds) * time.Second, maxRows
+}
+
+// queryIDHeader is the response header that contains the ID of a query started with Query or QueryStream
+const queryIDHeader = "x-query-id"
+
+// trackQuery registers a query for CancelQuery and ListRunningQueries.
+// It sends the query ID in a response header before the query runs, so clients can cancel it before it returns.
+func (s *Server) trackQuery(ctx context.Context, instanceID, queryID, sql string, priority int32) (context.Context, string, func(), error) {
+	ctx, queryID, done, err := s.runtime.TrackQuery(ctx, instanceID, queryID, sql, int(priority))
+	if err != nil {
+		if errors.Is(err, runtime.ErrQueryIDInUse) {
+			return nil, "", nil, status.Error(codes.AlreadyExists, err.Error())
 		}
-		return structpb.NewStructValue(v2), nil
-	case []interface{}:
-		v2, err := sliceToPB(v)
-		if err != nil {
-			return nil, err
+		return nil, "", nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// Fails if ctx is not from a gRPC call (e.g. in tests), in which case the ID is only returned in the response
+	_ = grpc.SendHeader(ctx, metadata.Pairs(queryIDHeader, queryID))
+
+	return ctx, queryID, done, nil
+}
+
+// CancelQuery implements RuntimeService.
+func (s *Server) CancelQuery(ctx context.Context, req *runtimev1.CancelQueryRequest) (*runtimev1.CancelQueryResponse, error) {
+	// Callers can only cancel their own queries, unless they're admins
+	caller, admin := s.caller(ctx)
+	if !admin && caller == "" {
+		return nil, status.Error(codes.PermissionDenied, "queries can only be cancelled by authenticated callers")
+	}
+	if admin {
+		caller = ""
+	}
+
+	stillExecuting, err := s.runtime.CancelQuery(ctx, req.InstanceId, req.QueryId, caller)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, "query not found")
 		}
-		return structpb.NewListValue(v2), nil
-	// Handle types not handled by structpb.NewValue
-	case int8:
-		return structpb.NewNumberValue(float64(v)), nil
-	case int16:
-		return structpb.NewNumberValue(float64(v)), nil
-	case uint8:
-		return structpb.NewNumberValue(float64(v)), nil
-	case uint16:
-		return structpb.NewNumberValue(float64(v)), nil
-	case time.Time:
-		s := v.Format(time.RFC3339Nano)
-		return structpb.NewStringValue(s), nil
-	case float32:
-		// Turning NaNs and Infs into nulls until frontend can deal with them as strings
-		// (They don't have a native JSON representation)
-		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
-			return structpb.NewNullValue(), nil
-		}
-		return structpb.NewNumberValue(float64(v)), nil
-	case float64:
-		// Turning NaNs and Infs into nulls until frontend can deal with them as strings
-		// (They don't have a native JSON representation)
-		if math.IsNaN(v) || math.IsInf(v, 0) {
-			return structpb.NewNullValue(), nil
-		}
-		return structpb.NewNumberValue(v), nil
-	case *big.Int:
-		// Evil cast to float until frontend can deal with bigs:
-		v2, _ := new(big.Float).SetInt(v).Float64()
-		return structpb.NewNumberValue(v2), nil
-		// This is what we should do when frontend supports it:
-		// s := v.String()
-		// return structpb.NewStringValue(s), nil
-	case duckdb.Interval:
-		m := map[string]any{"months": v.Months, "days": v.Days, "micros": v.Micros}
-		v2, err := mapToPB(m)
-		if err != nil {
-			return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &runtimev1.CancelQueryResponse{StillExecuting: stillExecuting}, nil
+}
+
+// ListRunningQueries implements RuntimeService.
+func (s *Server) ListRunningQueries(ctx context.Context, req *runtimev1.ListRunningQueriesRequest) (*runtimev1.ListRunningQueriesResponse, error) {
+	// Callers only see their own queries, unless they're admins
+	caller, admin := s.caller(ctx)
+	if !admin && caller == "" {
+		return nil, status.Error(codes.PermissionDenied, "running queries are only listed for authenticated callers")
+	}
+
+	rqs, err := s.runtime.RunningQueries(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
 		}
-		return structpb.NewStructValue(v2), nil
-	default:
-		// Default handling for basic types (ints, string, etc.)
-		return structpb.NewValue(v)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	now := time.Now()
+	res := &runtimev1.ListRunningQueriesResponse{Queries: make([]*runtimev1.RunningQuery, 0, len(rqs))}
+	for _, q := range rqs {
+		if !admin && q.Caller != caller {
+			continue
+		}
+		res.Queries = append(res.Queries, &runtimev1.RunningQuery{
+			QueryId:       q.QueryID,
+			Sql:           q.SQL,
+			Priority:      int32(q.Priority),
+			Queued:        q.Queued,
+			QueuePosition: int32(q.QueuePosition),
+			StartedOn:     timestamppb.New(q.StartedOn),
+			Elapsed:       durationpb.New(now.Sub(q.StartedOn)),
+		})
 	}
+	return res, nil
 }
 
-// mapToPB converts a map to a google.protobuf.Struct. It's similar to
-// structpb.NewStruct(), but it recurses on valToPB instead of structpb.NewValue
-// to add support for more types.
-func mapToPB(v map[string]any) (*structpb.Struct, error) {
-	x := &structpb.Struct{Fields: make(map[string]*structpb.Value, len(v))}
-	for k, v := range v {
-		if !utf8.ValidString(k) {
-			return nil, fmt.Errorf("invalid UTF-8 in string: %q", k)
-		}
-		var err error
-		x.Fields[k], err = valToPB(v)
-		if err != nil {
-			return nil, err
+// defaultQueryHistoryLimit is the number of entries returned by ListQueryHistory if no limit is set
+const defaultQueryHistoryLimit = 100
+
+// ListQueryHistory implements RuntimeService.
+func (s *Server) ListQueryHistory(ctx context.Context, req *runtimev1.ListQueryHistoryRequest) (*runtimev1.ListQueryHistoryResponse, error) {
+	_, err := s.runtime.FindInstance(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Callers only see their own queries, unless they're admins
+	caller, admin := s.caller(ctx)
+	if !admin && caller == "" {
+		return nil, status.Error(codes.PermissionDenied, "the query history is only available to authenticated callers")
+	}
+	if admin {
+		caller = ""
+	}
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultQueryHistoryLimit
+	}
+
+	entries := s.runtime.QueryHistory(req.InstanceId, &runtime.QueryHistoryOptions{
+		Type:     req.QueryType,
+		Caller:   caller,
+		SlowOnly: req.SlowOnly,
+		Limit:    limit,
+	})
+
+	res := &runtimev1.ListQueryHistoryResponse{Entries: make([]*runtimev1.QueryLogEntry, len(entries))}
+	for i, e := range entries {
+		res.Entries[i] = &runtimev1.QueryLogEntry{
+			Id:        e.ID,
+			QueryType: e.Type,
+			Sql:       e.SQL,
+			StartedOn: timestamppb.New(e.StartedOn),
+			Duration:  durationpb.New(e.Duration),
+			Rows:      e.Rows,
+			CacheHit:  e.CacheHit,
+			Error:     e.Error,
+			Slow:      e.Slow,
+		}
+	}
+	return res, nil
+}
+
+// FlushQueryCache implements RuntimeService.
+func (s *Server) FlushQueryCache(ctx context.Context, req *runtimev1.FlushQueryCacheRequest) (*runtimev1.FlushQueryCacheResponse, error) {
+	_, err := s.runtime.FindInstance(ctx, req.InstanceId)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			return nil, status.Error(codes.InvalidArgument, "instance not found")
 		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	n, err := s.runtime.FlushQueryCache(req.InstanceId, req.DependencyName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &runtimev1.FlushQueryCacheResponse{EntriesFlushed: int64(n)}, nil
+}
+
+func (s *Server) isMetricsView(ctx context.Context, instanceID, name string) bool {
+	obj, err := s.runtime.GetCatalogEntry(ctx, instanceID, name)
+	if err != nil {
+		return false
+	}
+	return obj.Type == drivers.ObjectTypeMetricsView
+}
+
+// isSecuredMetricsView returns true if name refers to a metrics view with a security policy
+func (s *Server) isSecuredMetricsView(ctx context.Context, instanceID, name string) bool {
+	obj, err := s.runtime.GetCatalogEntry(ctx, instanceID, name)
+	if err != nil || obj.Type != drivers.ObjectTypeMetricsView {
+		return false
+	}
+	mv, ok := obj.Object.(*runtimev1.MetricsView)
+	return ok && mv.Security != nil
+}
+
+func (s *Server) query(ctx context.Context, instanceID string, stmt *drivers.Statement) (*drivers.Result, error) {
+	olap, err := s.runtime.OLAP(ctx, instanceID)
+	if err != nil {
+		return nil, err
 	}
-	return x, nil
+
+	return olap.Execute(ctx, stmt)
 }
 
-// sliceToPB converts a map to a google.protobuf.List. It's similar to
-// structpb.NewList(), but it recurses on valToPB instead of structpb.NewList
-// to add support for more types.
-func sliceToPB(v []interface{}) (*structpb.ListValue, error) {
-	x := &structpb.ListValue{Values: make([]*structpb.Value, len(v))}
-	for i, v := range v {
-		var err error
-		x.Values[i], err = valToPB(v)
+func rowsToData(rows *drivers.Result) ([]*structpb.Struct, error) {
+	var data []*structpb.Struct
+	for rows.Next() {
+		rowMap := make(map[string]any)
+		err := rows.MapScan(rowMap)
 		if err != nil {
 			return nil, err
 		}
+
+		rowStruct, err := pbutil.ToStruct(rowMap)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, rowStruct)
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return nil, err
 	}
-	return x, nil
+
+	return data, nil
 }
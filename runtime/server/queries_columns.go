@@ -5,8 +5,6 @@ import (
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/queries"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 func (s *Server) GetTopK(ctx context.Context, req *runtimev1.GetTopKRequest) (*runtimev1.GetTopKResponse, error) {
@@ -29,7 +27,7 @@ func (s *Server) GetTopK(ctx context.Context, req *runtimev1.GetTopKRequest) (*r
 
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 
 	return &runtimev1.GetTopKResponse{
@@ -49,7 +47,7 @@ func (s *Server) GetNullCount(ctx context.Context, req *runtimev1.GetNullCountRe
 
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 
 	return &runtimev1.GetNullCountResponse{
@@ -64,7 +62,7 @@ func (s *Server) GetDescriptiveStatistics(ctx context.Context, req *runtimev1.Ge
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 
 	resp := &runtimev1.NumericSummary{
@@ -110,7 +108,7 @@ func (s *Server) EstimateSmallestTimeGrain(ctx context.Context, req *runtimev1.E
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.EstimateSmallestTimeGrainResponse{
 		TimeGrain: q.Result,
@@ -124,7 +122,7 @@ func (s *Server) GetNumericHistogram(ctx context.Context, req *runtimev1.GetNume
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.GetNumericHistogramResponse{
 		NumericSummary: &runtimev1.NumericSummary{
@@ -144,7 +142,7 @@ func (s *Server) GetRugHistogram(ctx context.Context, req *runtimev1.GetRugHisto
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.GetRugHistogramResponse{
 		NumericSummary: &runtimev1.NumericSummary{
@@ -164,7 +162,7 @@ func (s *Server) GetTimeRangeSummary(ctx context.Context, req *runtimev1.GetTime
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, status.Error(codes.Unknown, err.Error())
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.GetTimeRangeSummaryResponse{
 		TimeRangeSummary: q.Result,
@@ -178,7 +176,7 @@ func (s *Server) GetCardinalityOfColumn(ctx context.Context, req *runtimev1.GetC
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.GetCardinalityOfColumnResponse{
 		CategoricalSummary: &runtimev1.CategoricalSummary{
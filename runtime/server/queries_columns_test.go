@@ -334,6 +334,29 @@ func TestServer_GetRugHistogram_3rows_null(t *testing.T) {
 	require.Equal(t, 2, len(outliers))
 }
 
+func TestServer_NumericProfiling_NestedColumns(t *testing.T) {
+	sql := `
+		SELECT [1, 2] AS list, {'a': 1} AS struct, map([1], ['a']) AS map
+		UNION ALL
+		SELECT [3] AS list, {'a': 2} AS struct, map([2], ['b']) AS map
+	`
+	server, instanceId := getColumnTestServerWithModel(t, sql, 2)
+
+	for _, col := range []string{"list", "struct", "map"} {
+		stats, err := server.GetDescriptiveStatistics(context.Background(), &runtimev1.GetDescriptiveStatisticsRequest{InstanceId: instanceId, TableName: "test", ColumnName: col})
+		require.NoError(t, err)
+		require.Nil(t, stats.NumericSummary.GetNumericStatistics())
+
+		hist, err := server.GetNumericHistogram(context.Background(), &runtimev1.GetNumericHistogramRequest{InstanceId: instanceId, TableName: "test", ColumnName: col})
+		require.NoError(t, err)
+		require.Nil(t, hist.NumericSummary.GetNumericHistogramBins().Bins)
+
+		rug, err := server.GetRugHistogram(context.Background(), &runtimev1.GetRugHistogramRequest{InstanceId: instanceId, TableName: "test", ColumnName: col})
+		require.NoError(t, err)
+		require.Nil(t, rug.NumericSummary.GetNumericOutliers().Outliers)
+	}
+}
+
 func TestServer_GetCategoricalHistogram_EmptyModel(t *testing.T) {
 	server, instanceId := getColumnTestServerWithEmptyModel(t)
 
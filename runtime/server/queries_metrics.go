@@ -13,19 +13,23 @@ import (
 // MetricsViewToplist implements RuntimeService.
 func (s *Server) MetricsViewToplist(ctx context.Context, req *runtimev1.MetricsViewToplistRequest) (*runtimev1.MetricsViewToplistResponse, error) {
 	q := &queries.MetricsViewToplist{
-		MetricsViewName: req.MetricsViewName,
-		DimensionName:   req.DimensionName,
-		MeasureNames:    req.MeasureNames,
-		TimeStart:       req.TimeStart,
-		TimeEnd:         req.TimeEnd,
-		Limit:           req.Limit,
-		Offset:          req.Offset,
-		Sort:            req.Sort,
-		Filter:          req.Filter,
+		MetricsViewName:       req.MetricsViewName,
+		DimensionName:         req.DimensionName,
+		MeasureNames:          req.MeasureNames,
+		TimeStart:             req.TimeStart,
+		TimeEnd:               req.TimeEnd,
+		Limit:                 req.Limit,
+		Offset:                req.Offset,
+		Sort:                  req.Sort,
+		Filter:                req.Filter,
+		Attributes:            attributesFromContext(ctx),
+		IncludeOther:          req.IncludeOther,
+		IncludePercentOfTotal: req.IncludePercentOfTotal,
+		ResultFormat:          req.ResultFormat,
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 
 	return q.Result, nil
@@ -33,18 +37,26 @@ func (s *Server) MetricsViewToplist(ctx context.Context, req *runtimev1.MetricsV
 
 // MetricsViewTimeSeries implements RuntimeService.
 func (s *Server) MetricsViewTimeSeries(ctx context.Context, req *runtimev1.MetricsViewTimeSeriesRequest) (*runtimev1.MetricsViewTimeSeriesResponse, error) {
+	err := validateCalendar(req.TimeZone, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	q := &queries.MetricsViewTimeSeries{
 		MetricsViewName: req.MetricsViewName,
 		MeasureNames:    req.MeasureNames,
 		TimeStart:       req.TimeStart,
 		TimeEnd:         req.TimeEnd,
 		TimeGranularity: req.TimeGranularity,
+		TimeZone:        req.TimeZone,
 		Filter:          req.Filter,
+		ResultFormat:    req.ResultFormat,
+		Attributes:      attributesFromContext(ctx),
 	}
 
-	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
+	err = s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 	return q.Result, nil
 }
@@ -57,10 +69,33 @@ func (s *Server) MetricsViewTotals(ctx context.Context, req *runtimev1.MetricsVi
 		TimeStart:       req.TimeStart,
 		TimeEnd:         req.TimeEnd,
 		Filter:          req.Filter,
+		ResultFormat:    req.ResultFormat,
+		Attributes:      attributesFromContext(ctx),
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
+	}
+	return q.Result, nil
+}
+
+// MetricsViewDimensionValues implements RuntimeService.
+func (s *Server) MetricsViewDimensionValues(ctx context.Context, req *runtimev1.MetricsViewDimensionValuesRequest) (*runtimev1.MetricsViewDimensionValuesResponse, error) {
+	q := &queries.MetricsViewDimensionValues{
+		MetricsViewName: req.MetricsViewName,
+		DimensionName:   req.DimensionName,
+		Search:          req.Search,
+		SearchPrefix:    req.SearchPrefix,
+		TimeStart:       req.TimeStart,
+		TimeEnd:         req.TimeEnd,
+		Filter:          req.Filter,
+		Limit:           req.Limit,
+		Offset:          req.Offset,
+		Attributes:      attributesFromContext(ctx),
+	}
+	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
+	if err != nil {
+		return nil, queryErrorToStatus(err)
 	}
 	return q.Result, nil
 }
@@ -1,12 +1,19 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/testruntime"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -32,6 +39,28 @@ func TestServer_MetricsViewTotals(t *testing.T) {
 	require.Equal(t, 2.0, tr.Data.Fields["measure_0"].GetNumberValue())
 }
 
+func TestServer_MetricsViewTotals_Arrow(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewTotals(context.Background(), &runtimev1.MetricsViewTotalsRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics",
+		MeasureNames:    []string{"measure_0"},
+		ResultFormat:    runtimev1.ResultFormat_RESULT_FORMAT_ARROW,
+	})
+	require.NoError(t, err)
+	require.Nil(t, tr.Data)
+
+	r, err := ipc.NewReader(bytes.NewReader(tr.ArrowData))
+	require.NoError(t, err)
+	defer r.Release()
+	require.True(t, r.Next())
+	rec := r.Record()
+	require.Equal(t, int64(1), rec.NumRows())
+	// The count is encoded with the type reported by the driver
+	require.Equal(t, []int64{2}, rec.Column(0).(*array.Int64).Int64Values())
+}
+
 func TestServer_MetricsViewTotals_timestamp_name_with_spaces(t *testing.T) {
 	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
 
@@ -406,6 +435,56 @@ func TestServer_MetricsViewToplist(t *testing.T) {
 	require.Equal(t, 1.0, tr.Data[1].Fields["measure_2"].GetNumberValue())
 }
 
+func TestServer_MetricsViewToplist_OtherAndPercentOfTotal(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewToplist(context.Background(), &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "domain",
+		MeasureNames:    []string{"measure_0", "measure_2"},
+		Limit:           1,
+		Sort: []*runtimev1.MetricsViewSort{
+			{
+				Name:      "domain",
+				Ascending: true,
+			},
+		},
+		IncludeOther:          true,
+		IncludePercentOfTotal: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, len(tr.Meta))
+	require.Equal(t, 1, len(tr.Data))
+
+	require.Equal(t, "msn.com", tr.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 1.0, tr.Data[0].Fields["measure_0"].GetNumberValue())
+	require.Equal(t, 0.5, tr.Data[0].Fields["measure_0__percent_of_total"].GetNumberValue())
+	require.InDelta(t, 2.0/3.0, tr.Data[0].Fields["measure_2__percent_of_total"].GetNumberValue(), 0.0001)
+
+	require.NotNil(t, tr.Other)
+	require.NotContains(t, tr.Other.Fields, "domain")
+	require.Equal(t, 1.0, tr.Other.Fields["measure_0"].GetNumberValue())
+	require.Equal(t, 0.5, tr.Other.Fields["measure_0__percent_of_total"].GetNumberValue())
+	require.InDelta(t, 1.0/3.0, tr.Other.Fields["measure_2__percent_of_total"].GetNumberValue(), 0.0001)
+}
+
+func TestServer_MetricsViewToplist_OtherWithoutRemainder(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewToplist(context.Background(), &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics",
+		DimensionName:   "publisher",
+		MeasureNames:    []string{"measure_0"},
+		IncludeOther:    true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tr.Meta))
+	require.Equal(t, 2, len(tr.Data))
+	require.Nil(t, tr.Other)
+}
+
 func TestServer_MetricsViewToplist_quotes(t *testing.T) {
 	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
 
@@ -663,6 +742,79 @@ func TestServer_MetricsViewTimeSeries(t *testing.T) {
 	require.Equal(t, 1.0, tr.Data[1].Fields["measure_2"].GetNumberValue())
 }
 
+func TestServer_MetricsViewTimeSeries_Arrow(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewTimeSeries(context.Background(), &runtimev1.MetricsViewTimeSeriesRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics",
+		TimeGranularity: "DAY",
+		MeasureNames:    []string{"measure_0", "measure_2"},
+		ResultFormat:    runtimev1.ResultFormat_RESULT_FORMAT_ARROW,
+	})
+	require.NoError(t, err)
+	require.Empty(t, tr.Data)
+	require.Equal(t, 3, len(tr.Meta))
+
+	r, err := ipc.NewReader(bytes.NewReader(tr.ArrowData))
+	require.NoError(t, err)
+	defer r.Release()
+	require.True(t, r.Next())
+	rec := r.Record()
+	require.Equal(t, int64(2), rec.NumRows())
+	require.Equal(t, "timestamp", rec.ColumnName(0))
+	// The time dimension of ad_bids_2rows is a date
+	days := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Unix() / 86400
+	require.Equal(t, arrow.Date32(days), rec.Column(0).(*array.Date32).Value(0))
+	require.Equal(t, "measure_0", rec.ColumnName(1))
+	require.Equal(t, []int64{1, 1}, rec.Column(1).(*array.Int64).Int64Values())
+}
+
+func TestServer_MetricsViewTimeSeries_TimeZone(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewTimeSeries(context.Background(), &runtimev1.MetricsViewTimeSeriesRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics",
+		TimeGranularity: "DAY",
+		TimeZone:        "America/Los_Angeles",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tr.Data))
+	require.Equal(t, "2022-01-01T00:00:00-08:00", tr.Data[0].Fields["timestamp"].GetStringValue())
+	require.Equal(t, 1.0, tr.Data[0].Fields["measure_0"].GetNumberValue())
+	require.Equal(t, "2022-01-02T00:00:00-08:00", tr.Data[1].Fields["timestamp"].GetStringValue())
+	require.Equal(t, 1.0, tr.Data[1].Fields["measure_0"].GetNumberValue())
+}
+
+func TestServer_MetricsViewTimeSeries_DefaultTimeZone(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	tr, err := server.MetricsViewTimeSeries(context.Background(), &runtimev1.MetricsViewTimeSeriesRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_kolkata",
+		TimeGranularity: "DAY",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tr.Data))
+	require.Equal(t, "2022-01-01T00:00:00+05:30", tr.Data[0].Fields["timestamp"].GetStringValue())
+	require.Equal(t, "2022-01-02T00:00:00+05:30", tr.Data[1].Fields["timestamp"].GetStringValue())
+
+	// The request's time zone overrides the metrics view's default
+	tr, err = server.MetricsViewTimeSeries(context.Background(), &runtimev1.MetricsViewTimeSeriesRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_kolkata",
+		TimeGranularity: "DAY",
+		TimeZone:        "UTC",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tr.Data))
+	require.Equal(t, "2022-01-01T00:00:00Z", tr.Data[0].Fields["timestamp"].GetStringValue())
+}
+
 func TestServer_MetricsViewTimeSeries_complete_source_sanity_test(t *testing.T) {
 	server, instanceId := getMetricsTestServer(t, "ad_bids")
 
@@ -691,3 +843,133 @@ func TestServer_MetricsViewTimeSeries_complete_source_sanity_test(t *testing.T)
 	require.True(t, tr.Data[0].Fields["measure_0"].GetNumberValue() > 0)
 	require.True(t, tr.Data[0].Fields["measure_1"].GetNumberValue() > 0)
 }
+
+func TestServer_MetricsViewToplist_RowFilter(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	ctx := context.WithValue(context.Background(), attributesKey{}, map[string]any{"domain": "msn.com", "role": "admin"})
+	tr, err := server.MetricsViewToplist(ctx, &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		DimensionName:   "domain",
+		MeasureNames:    []string{"measure_0", "measure_1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tr.Data))
+	require.Equal(t, "msn.com", tr.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 1.0, tr.Data[0].Fields["measure_0"].GetNumberValue())
+
+	tt, err := server.MetricsViewTotals(ctx, &runtimev1.MetricsViewTotalsRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1.0, tt.Data.Fields["measure_0"].GetNumberValue())
+
+	ts, err := server.MetricsViewTimeSeries(ctx, &runtimev1.MetricsViewTimeSeriesRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		TimeGranularity: "DAY",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(ts.Data))
+}
+
+func TestServer_MetricsViewToplist_RowFilterMissingAttribute(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	_, err := server.MetricsViewToplist(context.Background(), &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		DimensionName:   "domain",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_MetricsViewToplist_FieldAccess(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	ctx := context.WithValue(context.Background(), attributesKey{}, map[string]any{"domain": "msn.com"})
+
+	_, err := server.MetricsViewToplist(ctx, &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		DimensionName:   "publisher",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.MetricsViewToplist(ctx, &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		DimensionName:   "domain",
+		MeasureNames:    []string{"measure_1"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.MetricsViewTotals(ctx, &runtimev1.MetricsViewTotalsRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		MeasureNames:    []string{"measure_0"},
+		Filter: &runtimev1.MetricsViewFilter{
+			Include: []*runtimev1.MetricsViewFilter_Cond{
+				{
+					Name: "publisher",
+					In:   []*structpb.Value{structpb.NewStringValue("Yahoo")},
+				},
+			},
+		},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	tr, err := server.MetricsViewToplist(ctx, &runtimev1.MetricsViewToplistRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		DimensionName:   "domain",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tr.Data))
+}
+
+func TestServer_Query_MetricsView(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	res, err := server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId: instanceId,
+		Sql:        `SELECT domain, measure_0 FROM ad_bids_metrics ORDER BY domain`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res.Data))
+	require.Equal(t, "msn.com", res.Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 1.0, res.Data[0].Fields["measure_0"].GetNumberValue())
+
+	// Queries against other tables are passed through
+	res, err = server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId: instanceId,
+		Sql:        `SELECT domain FROM ad_bids ORDER BY domain`,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(res.Data))
+}
+
+func TestServer_QueryStream_MetricsView(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+
+	srv := &fakeQueryStreamServer{ctx: context.Background()}
+	err := server.QueryStream(&runtimev1.QueryStreamRequest{
+		InstanceId: instanceId,
+		Sql:        `SELECT domain, measure_0 FROM ad_bids_metrics ORDER BY domain`,
+		BatchSize:  1,
+	}, srv)
+	require.NoError(t, err)
+	// The last message is empty since the rows are streamed without knowing in advance how many there are
+	require.Equal(t, 3, len(srv.msgs))
+	require.NotNil(t, srv.msgs[0].Meta)
+	require.Equal(t, "msn.com", srv.msgs[0].Data[0].Fields["domain"].GetStringValue())
+	require.Equal(t, 1, len(srv.msgs[1].Data))
+	require.Equal(t, 0, len(srv.msgs[2].Data))
+}
@@ -17,7 +17,7 @@ func (s *Server) GetTableCardinality(ctx context.Context, req *runtimev1.GetTabl
 	}
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 	return &runtimev1.GetTableCardinalityResponse{
 		Cardinality: q.Result,
@@ -37,7 +37,7 @@ func (s *Server) ProfileColumns(ctx context.Context, req *runtimev1.ProfileColum
 
 	err := s.runtime.Query(ctx, req.InstanceId, q, int(req.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 
 	return &runtimev1.ProfileColumnsResponse{
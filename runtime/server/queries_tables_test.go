@@ -1,14 +1,19 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"testing"
+	"time"
 
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
 	"github.com/jmoiron/sqlx"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/testruntime"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestServer_Database(t *testing.T) {
@@ -20,6 +25,138 @@ func TestServer_Database(t *testing.T) {
 	require.Equal(t, 1, getSingleValue(t, result.Rows))
 }
 
+func TestServer_Query_MaxRows(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+
+	res, err := server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId: instanceId,
+		Sql:        "SELECT * FROM range(5)",
+		MaxRows:    3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, len(res.Data))
+	require.True(t, res.Truncated)
+
+	res, err = server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId: instanceId,
+		Sql:        "SELECT * FROM range(5)",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 5, len(res.Data))
+	require.False(t, res.Truncated)
+}
+
+func TestServer_Query_Arrow(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+
+	res, err := server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId:   instanceId,
+		Sql:          "SELECT range AS id, 'x' || range AS name FROM range(5)",
+		MaxRows:      3,
+		ResultFormat: runtimev1.ResultFormat_RESULT_FORMAT_ARROW,
+	})
+	require.NoError(t, err)
+	require.Empty(t, res.Data)
+	require.True(t, res.Truncated)
+	require.Equal(t, 2, len(res.Meta.Fields))
+
+	r, err := ipc.NewReader(bytes.NewReader(res.ArrowData))
+	require.NoError(t, err)
+	defer r.Release()
+	require.True(t, r.Next())
+	rec := r.Record()
+	require.Equal(t, int64(3), rec.NumRows())
+	require.Equal(t, []int64{0, 1, 2}, rec.Column(0).(*array.Int64).Int64Values())
+	require.Equal(t, "x2", rec.Column(1).(*array.String).Value(2))
+	require.False(t, r.Next())
+}
+
+func TestServer_Query_NestedTypes(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+
+	res, err := server.Query(context.Background(), &runtimev1.QueryRequest{
+		InstanceId: instanceId,
+		Sql:        "SELECT map([1, 2], ['a', 'b']) AS m, '1234567890123456.78'::DECIMAL(18,2) AS d, {'x': [1.5::DECIMAL(4,1)]} AS s",
+	})
+	require.NoError(t, err)
+	require.Equal(t, runtimev1.Type_CODE_MAP, res.Meta.Fields[0].Type.Code)
+	require.Equal(t, runtimev1.Type_CODE_DECIMAL, res.Meta.Fields[1].Type.Code)
+	require.Equal(t, 1, len(res.Data))
+
+	// Map keys are formatted as strings, and decimals are encoded as strings to preserve their precision
+	row := res.Data[0].AsMap()
+	require.Equal(t, map[string]any{"1": "a", "2": "b"}, row["m"])
+	require.Equal(t, "1234567890123456.78", row["d"])
+	require.Equal(t, map[string]any{"x": []any{"1.5"}}, row["s"])
+}
+
+func TestServer_QueryStream(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+
+	srv := &fakeQueryStreamServer{ctx: context.Background()}
+	err := server.QueryStream(&runtimev1.QueryStreamRequest{
+		InstanceId: instanceId,
+		Sql:        "SELECT * FROM range(5)",
+		BatchSize:  2,
+	}, srv)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(srv.msgs))
+	require.NotNil(t, srv.msgs[0].Meta)
+	require.Nil(t, srv.msgs[1].Meta)
+	require.Equal(t, 2, len(srv.msgs[0].Data))
+	require.Equal(t, 2, len(srv.msgs[1].Data))
+	require.Equal(t, 1, len(srv.msgs[2].Data))
+	require.Equal(t, 4.0, srv.msgs[2].Data[0].Fields["range"].GetNumberValue())
+	require.False(t, srv.msgs[2].Truncated)
+
+	srv = &fakeQueryStreamServer{ctx: context.Background()}
+	err = server.QueryStream(&runtimev1.QueryStreamRequest{
+		InstanceId: instanceId,
+		Sql:        "SELECT * FROM range(5)",
+		BatchSize:  2,
+		MaxRows:    4,
+	}, srv)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(srv.msgs))
+	require.Equal(t, 0, len(srv.msgs[2].Data))
+	require.True(t, srv.msgs[2].Truncated)
+}
+
+type fakeQueryStreamServer struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs []*runtimev1.QueryStreamResponse
+}
+
+func (s *fakeQueryStreamServer) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeQueryStreamServer) Send(msg *runtimev1.QueryStreamResponse) error {
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func TestQueryLimits(t *testing.T) {
+	inst := &drivers.Instance{}
+	timeout, maxRows := queryLimits(inst, 10, 100)
+	require.Equal(t, 10*time.Second, timeout)
+	require.Equal(t, int64(100), maxRows)
+
+	inst = &drivers.Instance{QueryTimeoutSeconds: 30, QueryMaxRows: 1000}
+	timeout, maxRows = queryLimits(inst, 0, 0)
+	require.Equal(t, 30*time.Second, timeout)
+	require.Equal(t, int64(1000), maxRows)
+
+	timeout, maxRows = queryLimits(inst, 10, 100)
+	require.Equal(t, 10*time.Second, timeout)
+	require.Equal(t, int64(100), maxRows)
+
+	timeout, maxRows = queryLimits(inst, 60, 10000)
+	require.Equal(t, 30*time.Second, timeout)
+	require.Equal(t, int64(1000), maxRows)
+}
+
 func TestServer_TableCardinality(t *testing.T) {
 	server, instanceId := getTableTestServer(t)
 	cr, err := server.GetTableCardinality(context.Background(), &runtimev1.GetTableCardinalityRequest{
@@ -40,6 +177,40 @@ func TestServer_TableCardinality_EmptyModel(t *testing.T) {
 	require.Equal(t, int64(0), cr.Cardinality)
 }
 
+func TestServer_FlushQueryCache(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	_, err := server.GetTableCardinality(context.Background(), &runtimev1.GetTableCardinalityRequest{
+		InstanceId: instanceId,
+		TableName:  "test",
+	})
+	require.NoError(t, err)
+
+	res, err := server.FlushQueryCache(context.Background(), &runtimev1.FlushQueryCacheRequest{
+		InstanceId:     instanceId,
+		DependencyName: "other",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), res.EntriesFlushed)
+
+	res, err = server.FlushQueryCache(context.Background(), &runtimev1.FlushQueryCacheRequest{
+		InstanceId:     instanceId,
+		DependencyName: "test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.EntriesFlushed)
+
+	res, err = server.FlushQueryCache(context.Background(), &runtimev1.FlushQueryCacheRequest{
+		InstanceId: instanceId,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), res.EntriesFlushed)
+
+	_, err = server.FlushQueryCache(context.Background(), &runtimev1.FlushQueryCacheRequest{
+		InstanceId: "nosuch",
+	})
+	require.Error(t, err)
+}
+
 func TestServer_ProfileColumns(t *testing.T) {
 	server, instanceId := getTableTestServer(t)
 	cr, err := server.ProfileColumns(context.Background(), &runtimev1.ProfileColumnsRequest{
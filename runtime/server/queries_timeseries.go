@@ -2,9 +2,12 @@ package server
 
 import (
 	"context"
+	"time"
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/queries"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Metrics/Timeseries APIs
@@ -15,13 +18,18 @@ func (s *Server) EstimateRollupInterval(ctx context.Context, request *runtimev1.
 	}
 	err := s.runtime.Query(ctx, request.InstanceId, q, int(request.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 
 	return q.Result, nil
 }
 
 func (s *Server) GenerateTimeSeries(ctx context.Context, request *runtimev1.GenerateTimeSeriesRequest) (*runtimev1.GenerateTimeSeriesResponse, error) {
+	err := validateCalendar(request.TimeZone, request.FirstDayOfWeek, request.FirstMonthOfYear)
+	if err != nil {
+		return nil, err
+	}
+
 	q := &queries.ColumnTimeseries{
 		TableName:           request.TableName,
 		TimestampColumnName: request.TimestampColumnName,
@@ -30,13 +38,33 @@ func (s *Server) GenerateTimeSeries(ctx context.Context, request *runtimev1.Gene
 		TimeRange:           request.TimeRange,
 		Pixels:              request.Pixels,
 		SampleSize:          request.SampleSize,
+		TimeZone:            request.TimeZone,
+		FirstDayOfWeek:      request.FirstDayOfWeek,
+		FirstMonthOfYear:    request.FirstMonthOfYear,
 	}
-	err := s.runtime.Query(ctx, request.InstanceId, q, int(request.Priority))
+	err = s.runtime.Query(ctx, request.InstanceId, q, int(request.Priority))
 	if err != nil {
-		return nil, err
+		return nil, queryErrorToStatus(err)
 	}
 
 	return &runtimev1.GenerateTimeSeriesResponse{
 		Rollup: q.Result,
 	}, nil
 }
+
+// validateCalendar returns an InvalidArgument error if a time zone or calendar option of a timeseries request is invalid.
+// A zero first day of week (1 is Monday) or first month of year (1 is January) means the default.
+func validateCalendar(timeZone string, firstDayOfWeek, firstMonthOfYear uint32) error {
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid time zone %q", timeZone)
+		}
+	}
+	if firstDayOfWeek > 7 {
+		return status.Errorf(codes.InvalidArgument, "invalid first day of week %d, must be between 1 and 7", firstDayOfWeek)
+	}
+	if firstMonthOfYear > 12 {
+		return status.Errorf(codes.InvalidArgument, "invalid first month of year %d, must be between 1 and 12", firstMonthOfYear)
+	}
+	return nil
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/testruntime"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	structpb "google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -67,6 +69,24 @@ func TestServer_Timeseries(t *testing.T) {
 	require.Equal(t, 1.0, results[0].Records["max"])
 }
 
+func TestServer_Timeseries_InvalidCalendar(t *testing.T) {
+	server, instanceID := getTimeseriesTestServer(t)
+
+	for _, req := range []*runtimev1.GenerateTimeSeriesRequest{
+		{TimeZone: "Mars/Olympus_Mons"},
+		{FirstDayOfWeek: 100},
+		{FirstMonthOfYear: 13},
+	} {
+		req.InstanceId = instanceID
+		req.TableName = "timeseries"
+		req.TimestampColumnName = "time"
+		req.Measures = []*runtimev1.GenerateTimeSeriesRequest_BasicMeasure{{Expression: "max(clicks)", SqlName: "max"}}
+
+		_, err := server.GenerateTimeSeries(context.Background(), req)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
+
 func TestServer_Timeseries_numeric_dim(t *testing.T) {
 	server, instanceID := getTimeseriesTestServer(t)
 
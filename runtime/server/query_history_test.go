@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_ListQueryHistory(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	ctx := context.Background()
+
+	_, err := server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT * FROM test"})
+	require.NoError(t, err)
+	_, err = server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT * FROM missing"})
+	require.Error(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = server.GetTableCardinality(ctx, &runtimev1.GetTableCardinalityRequest{InstanceId: instanceId, TableName: "test"})
+		require.NoError(t, err)
+	}
+
+	res, err := server.ListQueryHistory(ctx, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId, QueryType: "raw"})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(res.Entries), 2)
+	require.Equal(t, "SELECT * FROM missing", res.Entries[0].Sql)
+	require.NotEmpty(t, res.Entries[0].Error)
+	require.Equal(t, "SELECT * FROM test", res.Entries[1].Sql)
+	require.Equal(t, int64(1), res.Entries[1].Rows)
+	require.Empty(t, res.Entries[1].Error)
+
+	res, err = server.ListQueryHistory(ctx, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId, QueryType: "TableCardinality"})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 2)
+	require.True(t, res.Entries[0].CacheHit)
+	require.Empty(t, res.Entries[0].Sql)
+	require.False(t, res.Entries[1].CacheHit)
+	require.Contains(t, res.Entries[1].Sql, "count(*)")
+	require.Equal(t, int64(1), res.Entries[1].Rows)
+
+	res, err = server.ListQueryHistory(ctx, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 1)
+}
+
+func TestServer_ListQueryHistory_Callers(t *testing.T) {
+	server, instanceId := getMetricsTestServer(t, "ad_bids_2rows")
+	server.opts.AuthSecret = "secret"
+	alice := authContext(t, server, jwt.MapClaims{"sub": "alice", "domain": "msn.com"})
+	bob := authContext(t, server, jwt.MapClaims{"sub": "bob"})
+	admin := authContext(t, server, jwt.MapClaims{"sub": "carol", "admin": true})
+
+	_, err := server.MetricsViewTotals(alice, &runtimev1.MetricsViewTotalsRequest{
+		InstanceId:      instanceId,
+		MetricsViewName: "ad_bids_metrics_secure",
+		MeasureNames:    []string{"measure_0"},
+	})
+	require.NoError(t, err)
+
+	res, err := server.ListQueryHistory(alice, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 1)
+
+	res, err = server.ListQueryHistory(bob, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 0)
+
+	_, err = server.ListQueryHistory(context.Background(), &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Admins see all queries, but not the row filters rendered with other callers' attributes
+	res, err = server.ListQueryHistory(admin, &runtimev1.ListQueryHistoryRequest{InstanceId: instanceId, QueryType: "MetricsViewTotals"})
+	require.NoError(t, err)
+	require.Len(t, res.Entries, 1)
+	require.Contains(t, res.Entries[0].Sql, "{{ .user.domain | sqlString }}")
+	require.NotContains(t, res.Entries[0].Sql, "msn.com")
+}
+
+// authContext returns a context for a request authenticated with a token with the given claims
+func authContext(t *testing.T, server *Server, claims jwt.MapClaims) context.Context {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(server.opts.AuthSecret))
+	require.NoError(t, err)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	ctx, err = server.authenticate(ctx)
+	require.NoError(t, err)
+	return ctx
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_CancelQuery(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	ctx := context.Background()
+
+	listRunning := func() []*runtimev1.RunningQuery {
+		res, err := server.ListRunningQueries(ctx, &runtimev1.ListRunningQueriesRequest{InstanceId: instanceId})
+		require.NoError(t, err)
+		return res.Queries
+	}
+
+	// Start a slow query that keeps DuckDB busy
+	var g errgroup.Group
+	g.Go(func() error {
+		_, err := server.Query(ctx, &runtimev1.QueryRequest{
+			InstanceId: instanceId,
+			Sql:        "SELECT sum(a.range * b.range) AS s FROM range(20000) a, range(20000) b",
+			QueryId:    "slow",
+		})
+		return err
+	})
+	require.Eventually(t, func() bool {
+		qs := listRunning()
+		return len(qs) == 1 && qs[0].QueryId == "slow"
+	}, 5*time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	// Start a query that waits in the queue
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT 1", QueryId: "queued", Priority: 1})
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool {
+		qs := listRunning()
+		return len(qs) == 2 && qs[1].QueryId == "queued" && qs[1].Queued
+	}, 5*time.Second, 5*time.Millisecond)
+
+	qs := listRunning()
+	require.Equal(t, "slow", qs[0].QueryId)
+	require.False(t, qs[0].Queued)
+	require.Equal(t, int32(0), qs[0].QueuePosition)
+	require.Equal(t, int32(1), qs[1].QueuePosition)
+	require.Equal(t, int32(1), qs[1].Priority)
+	require.Equal(t, "SELECT 1", qs[1].Sql)
+	require.NotNil(t, qs[1].Elapsed)
+
+	// IDs must be unique among running queries
+	_, err := server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT 1", QueryId: "queued"})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	cr, err := server.CancelQuery(ctx, &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "queued"})
+	require.NoError(t, err)
+	require.False(t, cr.StillExecuting)
+	require.Equal(t, codes.Canceled, status.Code(<-errCh))
+
+	_, err = server.CancelQuery(ctx, &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "queued"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	// DuckDB can't interrupt the executing statement, so it keeps running after the query returns
+	cr, err = server.CancelQuery(ctx, &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "slow"})
+	require.NoError(t, err)
+	require.True(t, cr.StillExecuting)
+	require.Equal(t, codes.Canceled, status.Code(g.Wait()))
+	require.Eventually(t, func() bool {
+		return len(listRunning()) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Generated IDs are returned in the response
+	res, err := server.Query(ctx, &runtimev1.QueryRequest{InstanceId: instanceId, Sql: "SELECT 1"})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.QueryId)
+}
+
+func TestServer_CancelQuery_Callers(t *testing.T) {
+	server, instanceId := getTableTestServer(t)
+	server.opts.AuthSecret = "secret"
+	alice := authContext(t, server, jwt.MapClaims{"sub": "alice"})
+	bob := authContext(t, server, jwt.MapClaims{"sub": "bob"})
+
+	listRunning := func(ctx context.Context) []*runtimev1.RunningQuery {
+		res, err := server.ListRunningQueries(ctx, &runtimev1.ListRunningQueriesRequest{InstanceId: instanceId})
+		require.NoError(t, err)
+		return res.Queries
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := server.Query(alice, &runtimev1.QueryRequest{
+			InstanceId: instanceId,
+			Sql:        "SELECT sum(a.range * b.range) AS s FROM range(20000) a, range(20000) b",
+			QueryId:    "slow",
+		})
+		errCh <- err
+	}()
+	require.Eventually(t, func() bool {
+		return len(listRunning(alice)) == 1
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// Other callers can't see or cancel the query
+	require.Empty(t, listRunning(bob))
+	_, err := server.CancelQuery(bob, &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "slow"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	_, err = server.CancelQuery(context.Background(), &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "slow"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.CancelQuery(alice, &runtimev1.CancelQueryRequest{InstanceId: instanceId, QueryId: "slow"})
+	require.NoError(t, err)
+	require.Equal(t, codes.Canceled, status.Code(<-errCh))
+}
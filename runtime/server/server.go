@@ -13,10 +13,12 @@ import (
 	metrics "github.com/grpc-ecosystem/go-grpc-middleware/providers/openmetrics/v2"
 	"github.com/grpc-ecosystem/go-grpc-middleware/providers/opentracing/v2"
 	grpczaplog "github.com/grpc-ecosystem/go-grpc-middleware/providers/zap/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/tracing"
 	gateway "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime"
 	"github.com/rilldata/rill/runtime/pkg/graceful"
@@ -29,6 +31,10 @@ import (
 type Options struct {
 	HTTPPort int
 	GRPCPort int
+	// AuthSecret is the HMAC secret used to verify JWTs passed as bearer tokens.
+	// The token's claims are the caller's attributes, and its "sub" claim identifies the caller (see Server.caller).
+	// If empty, tokens are ignored and all requests are anonymous (with admin access).
+	AuthSecret string
 }
 
 type Server struct {
@@ -56,12 +62,14 @@ func (s *Server) ServeGRPC(ctx context.Context) error {
 			metrics.StreamServerInterceptor(metrics.NewServerMetrics()),
 			logging.StreamServerInterceptor(grpczaplog.InterceptorLogger(s.logger), logging.WithCodes(ErrorToCode), logging.WithLevels(GRPCCodeToLevel)),
 			recovery.StreamServerInterceptor(),
+			auth.StreamServerInterceptor(s.authenticate),
 		),
 		grpc.ChainUnaryInterceptor(
 			tracing.UnaryServerInterceptor(opentracing.InterceptorTracer()),
 			metrics.UnaryServerInterceptor(metrics.NewServerMetrics()),
 			logging.UnaryServerInterceptor(grpczaplog.InterceptorLogger(s.logger), logging.WithCodes(ErrorToCode), logging.WithLevels(GRPCCodeToLevel)),
 			recovery.UnaryServerInterceptor(),
+			auth.UnaryServerInterceptor(s.authenticate),
 		),
 	)
 	runtimev1.RegisterRuntimeServiceServer(server, s)
@@ -120,13 +128,27 @@ func (s *Server) HTTPHandler(ctx context.Context) (http.Handler, error) {
 	}
 
 	// One-off REST-only path for multipart file upload
-	err = mux.HandlePath("POST", "/v1/instances/{instance_id}/files/upload/-/{path=**}", s.UploadMultipartFile)
+	err = mux.HandlePath("POST", "/v1/instances/{instance_id}/files/upload/-/{path=**}", s.authenticateHTTP(s.UploadMultipartFile))
 	if err != nil {
 		panic(err)
 	}
 
 	// One-off REST-only path for file export
-	err = mux.HandlePath("GET", "/v1/instances/{instance_id}/table/{table_name}/export/{format}", s.ExportTable)
+	err = mux.HandlePath("GET", "/v1/instances/{instance_id}/table/{table_name}/export/{format}", s.authenticateHTTP(s.ExportTable))
+	if err != nil {
+		panic(err)
+	}
+
+	// REST-only path for downloading the file of an export job (see CreateExport)
+	err = mux.HandlePath("GET", "/v1/instances/{instance_id}/exports/{export_id}/download", s.authenticateHTTP(s.DownloadExportHTTP))
+	if err != nil {
+		panic(err)
+	}
+
+	// Expose runtime metrics (such as query cache stats) for Prometheus
+	err = mux.HandlePath("GET", "/metrics", func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		promhttp.Handler().ServeHTTP(w, r)
+	})
 	if err != nil {
 		panic(err)
 	}
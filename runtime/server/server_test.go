@@ -1,10 +1,16 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/rilldata/rill/runtime/drivers"
 	"github.com/rilldata/rill/runtime/testruntime"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func getTestServer(t *testing.T) (*Server, string) {
@@ -15,3 +21,11 @@ func getTestServer(t *testing.T) (*Server, string) {
 
 	return server, instanceID
 }
+
+func TestQueryErrorToStatus(t *testing.T) {
+	err := fmt.Errorf("%w: metrics_sql queries are not available for dialect 'druid'", drivers.ErrUnsupportedQuery)
+	require.Equal(t, codes.Unimplemented, status.Code(queryErrorToStatus(err)))
+	require.Equal(t, codes.PermissionDenied, status.Code(queryErrorToStatus(status.Error(codes.PermissionDenied, "denied"))))
+	require.Equal(t, codes.DeadlineExceeded, status.Code(queryErrorToStatus(fmt.Errorf("query: %w", context.DeadlineExceeded))))
+	require.Equal(t, codes.Unknown, status.Code(queryErrorToStatus(errors.New("Binder Error"))))
+}
@@ -63,6 +63,44 @@ csv.delimiter: '|'
 			`type: s3
 uri: s3://bucket/path/file.csv
 region: us-east-2
+`,
+		},
+		{
+			"DruidSource",
+			&drivers.CatalogEntry{
+				Name: "DruidSource",
+				Path: "sources/DruidSource.yaml",
+				Type: drivers.ObjectTypeSource,
+				Object: &runtimev1.Source{
+					Name:      "DruidSource",
+					Connector: "s3",
+					Properties: toProtoStruct(map[string]any{
+						"path":                      "s3://bucket/path/file.csv",
+						"druid.timestamp_column":    "timestamp",
+						"druid.segment_granularity": "MONTH",
+						"druid.rollup":              true,
+						"druid.dimensions": []any{
+							map[string]any{"name": "publisher"},
+							map[string]any{"name": "bid_price", "type": "double"},
+						},
+						"druid.metrics": []any{
+							map[string]any{"name": "volume", "type": "longSum"},
+						},
+					}),
+				},
+			},
+			`type: s3
+uri: s3://bucket/path/file.csv
+druid.timestamp_column: timestamp
+druid.segment_granularity: MONTH
+druid.rollup: true
+druid.dimensions:
+- name: publisher
+- name: bid_price
+  type: double
+druid.metrics:
+- name: volume
+  type: longSum
 `,
 		},
 		{
@@ -21,6 +21,26 @@ type Source struct {
 	CsvDelimiter string `yaml:"csv.delimiter,omitempty" mapstructure:"csv.delimiter,omitempty"`
 	URI          string `yaml:"uri,omitempty"`
 	Region       string `yaml:"region,omitempty" mapstructure:"aws.region,omitempty"`
+	// Druid ingestion options (ignored by other OLAP drivers)
+	DruidTimestampColumn    string `yaml:"druid.timestamp_column,omitempty" mapstructure:"druid.timestamp_column,omitempty"`
+	DruidTimestampFormat    string `yaml:"druid.timestamp_format,omitempty" mapstructure:"druid.timestamp_format,omitempty"`
+	DruidSegmentGranularity string `yaml:"druid.segment_granularity,omitempty" mapstructure:"druid.segment_granularity,omitempty"`
+	DruidQueryGranularity   string `yaml:"druid.query_granularity,omitempty" mapstructure:"druid.query_granularity,omitempty"`
+	DruidRollup             bool   `yaml:"druid.rollup,omitempty" mapstructure:"druid.rollup,omitempty"`
+	// DruidDimensions and DruidMetrics declare the columns to ingest (otherwise, all columns are ingested as dimensions)
+	DruidDimensions []*DruidDimension `yaml:"druid.dimensions,omitempty" mapstructure:"druid.dimensions,omitempty"`
+	DruidMetrics    []*DruidMetric    `yaml:"druid.metrics,omitempty" mapstructure:"druid.metrics,omitempty"`
+}
+
+type DruidDimension struct {
+	Name string
+	Type string `yaml:"type,omitempty" mapstructure:"type,omitempty"`
+}
+
+type DruidMetric struct {
+	Name  string
+	Type  string
+	Field string `yaml:"field,omitempty" mapstructure:"field,omitempty"`
 }
 
 type MetricsView struct {
@@ -30,8 +50,12 @@ type MetricsView struct {
 	TimeDimension    string `yaml:"timeseries"`
 	TimeGrains       []string
 	DefaultTimeGrain string `yaml:"default_timegrain"`
+	TimeZone         string `yaml:"time_zone,omitempty"`
+	FirstDayOfWeek   uint32 `yaml:"first_day_of_week,omitempty"`
+	FirstMonthOfYear uint32 `yaml:"first_month_of_year,omitempty"`
 	Dimensions       []*Dimension
 	Measures         []*Measure
+	Security         *Security `yaml:"security,omitempty"`
 }
 
 type Measure struct {
@@ -42,6 +66,17 @@ type Measure struct {
 	Ignore      bool   `yaml:"ignore,omitempty"`
 }
 
+type Security struct {
+	RowFilter  string         `yaml:"row_filter,omitempty"`
+	Dimensions []*FieldAccess `yaml:"dimensions,omitempty"`
+	Measures   []*FieldAccess `yaml:"measures,omitempty"`
+}
+
+type FieldAccess struct {
+	Names     []string
+	Condition string
+}
+
 type Dimension struct {
 	Label       string
 	Property    string `copier:"Name"`
@@ -92,6 +127,43 @@ func fromSourceArtifact(source *Source, path string) (*drivers.CatalogEntry, err
 	if source.CsvDelimiter != "" {
 		props["csv.delimiter"] = source.CsvDelimiter
 	}
+	if source.DruidTimestampColumn != "" {
+		props["druid.timestamp_column"] = source.DruidTimestampColumn
+	}
+	if source.DruidTimestampFormat != "" {
+		props["druid.timestamp_format"] = source.DruidTimestampFormat
+	}
+	if source.DruidSegmentGranularity != "" {
+		props["druid.segment_granularity"] = source.DruidSegmentGranularity
+	}
+	if source.DruidQueryGranularity != "" {
+		props["druid.query_granularity"] = source.DruidQueryGranularity
+	}
+	if source.DruidRollup {
+		props["druid.rollup"] = true
+	}
+	if len(source.DruidDimensions) > 0 {
+		dims := make([]any, len(source.DruidDimensions))
+		for i, d := range source.DruidDimensions {
+			dim := map[string]any{"name": d.Name}
+			if d.Type != "" {
+				dim["type"] = d.Type
+			}
+			dims[i] = dim
+		}
+		props["druid.dimensions"] = dims
+	}
+	if len(source.DruidMetrics) > 0 {
+		metrics := make([]any, len(source.DruidMetrics))
+		for i, m := range source.DruidMetrics {
+			metric := map[string]any{"name": m.Name, "type": m.Type}
+			if m.Field != "" {
+				metric["field"] = m.Field
+			}
+			metrics[i] = metric
+		}
+		props["druid.metrics"] = metrics
+	}
 	propsPB, err := structpb.NewStruct(props)
 	if err != nil {
 		return nil, err
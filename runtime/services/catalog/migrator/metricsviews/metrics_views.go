@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
 	"github.com/rilldata/rill/runtime/drivers"
+	"github.com/rilldata/rill/runtime/pkg/securitypolicy"
 	"github.com/rilldata/rill/runtime/services/catalog/migrator"
 )
 
@@ -22,6 +24,9 @@ const (
 	TimestampNotFound    = "metrics view selected timestamp not found"
 	MissingDimension     = "at least one dimension should be present"
 	MissingMeasure       = "at least one measure should be present"
+	InvalidTimeZone      = "metrics view time zone is not a valid IANA time zone"
+	InvalidFirstDay      = "first day of week should be between 1 (Monday) and 7 (Sunday)"
+	InvalidFirstMonth    = "first month of year should be between 1 (January) and 12 (December)"
 )
 
 type metricsViewMigrator struct{}
@@ -54,6 +59,17 @@ func (m *metricsViewMigrator) Validate(ctx context.Context, olap drivers.OLAPSto
 	if mv.TimeDimension == "" {
 		return migrator.CreateValidationError(catalog.Path, TimestampNotSelected)
 	}
+	if mv.TimeZone != "" {
+		if _, err := time.LoadLocation(mv.TimeZone); err != nil {
+			return migrator.CreateValidationError(catalog.Path, InvalidTimeZone)
+		}
+	}
+	if mv.FirstDayOfWeek > 7 {
+		return migrator.CreateValidationError(catalog.Path, InvalidFirstDay)
+	}
+	if mv.FirstMonthOfYear > 12 {
+		return migrator.CreateValidationError(catalog.Path, InvalidFirstMonth)
+	}
 	model, err := olap.InformationSchema().Lookup(ctx, mv.Model)
 	if err != nil {
 		if errors.Is(err, drivers.ErrNotFound) {
@@ -111,6 +127,15 @@ func (m *metricsViewMigrator) Validate(ctx context.Context, olap drivers.OLAPSto
 		})
 	}
 
+	if err := securitypolicy.Validate(mv); err != nil {
+		validationErrors = append(validationErrors, &runtimev1.ReconcileError{
+			Code:         runtimev1.ReconcileError_CODE_VALIDATION,
+			FilePath:     catalog.Path,
+			Message:      err.Error(),
+			PropertyPath: []string{"Security"},
+		})
+	}
+
 	return validationErrors
 }
 
@@ -27,12 +27,85 @@ func (m *modelMigrator) Create(ctx context.Context, olap drivers.OLAPStore, repo
 		),
 		Priority: 100,
 	})
+	if err != nil && olap.Dialect() == drivers.DialectPostgres && sqlState(err) == pgInvalidTableDefinition {
+		// Postgres can only replace a view with one that has the same leading columns, so we drop and recreate it instead
+		return m.recreate(ctx, olap, catalogObj)
+	}
 	if err != nil {
 		return err
 	}
 	return rows.Close()
 }
 
+// pgInvalidTableDefinition is the SQLSTATE Postgres returns when CREATE OR REPLACE VIEW changes the view's columns
+const pgInvalidTableDefinition = "42P16"
+
+// sqlState returns the SQLSTATE code of a driver error, or an empty string if it doesn't have one
+func sqlState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
+
+// recreate drops and recreates a Postgres view.
+// Postgres doesn't drop views that other views depend on, so the dependent views (including views that depend on them)
+// are dropped first and recreated afterwards from their stored definitions, with the views they depend on first.
+// It uses a DO block so all statements run in one transaction, which means the views are never missing for concurrent queries.
+// If a dependent view no longer works with the new view (e.g. because it uses a removed column), the whole block fails.
+func (m *modelMigrator) recreate(ctx context.Context, olap drivers.OLAPStore, catalogObj *drivers.CatalogEntry) error {
+	rows, err := olap.Execute(ctx, &drivers.Statement{
+		Query: fmt.Sprintf(
+			pgRecreateViewTemplate,
+			strings.ReplaceAll(catalogObj.Name, "'", "''"),
+			catalogObj.Name,
+			sanitizeQuery(catalogObj.GetModel().Sql, false),
+		),
+		Priority: 100,
+	})
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// pgRecreateViewTemplate is the DO block used by recreate.
+// Its arguments are the view name escaped for a string literal, the view name and the view's SQL.
+const pgRecreateViewTemplate = `DO $rill$
+DECLARE
+	dependent record;
+	dependents text[] := '{}';
+	definitions text[] := '{}';
+BEGIN
+	FOR dependent IN
+		WITH RECURSIVE deps(oid, depth) AS (
+			SELECT r.ev_class, 1
+			FROM pg_depend d JOIN pg_rewrite r ON r.oid = d.objid
+			WHERE d.classid = 'pg_rewrite'::regclass AND d.refobjid = to_regclass('%[1]s') AND r.ev_class <> d.refobjid
+			UNION ALL
+			SELECT r.ev_class, deps.depth + 1
+			FROM deps JOIN pg_depend d ON d.refobjid = deps.oid JOIN pg_rewrite r ON r.oid = d.objid
+			WHERE d.classid = 'pg_rewrite'::regclass AND r.ev_class <> d.refobjid
+		)
+		SELECT oid::regclass::text AS name, pg_get_viewdef(oid) AS definition
+		FROM deps
+		GROUP BY oid
+		ORDER BY max(depth)
+	LOOP
+		dependents := dependents || dependent.name;
+		definitions := definitions || dependent.definition;
+	END LOOP;
+	FOR i IN REVERSE coalesce(array_length(dependents, 1), 0) .. 1 LOOP
+		EXECUTE 'DROP VIEW ' || dependents[i];
+	END LOOP;
+	DROP VIEW IF EXISTS %[2]s;
+	CREATE VIEW %[2]s AS (%[3]s);
+	FOR i IN 1 .. coalesce(array_length(dependents, 1), 0) LOOP
+		EXECUTE 'CREATE VIEW ' || dependents[i] || ' AS ' || definitions[i];
+	END LOOP;
+END $rill$`
+
 func (m *modelMigrator) Update(ctx context.Context, olap drivers.OLAPStore, repo drivers.RepoStore, catalogObj *drivers.CatalogEntry) error {
 	return m.Create(ctx, olap, repo, catalogObj)
 }
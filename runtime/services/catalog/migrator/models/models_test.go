@@ -1,9 +1,17 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"testing"
 
+	runtimev1 "github.com/rilldata/rill/proto/gen/rill/runtime/v1"
+	"github.com/rilldata/rill/runtime/drivers"
+	_ "github.com/rilldata/rill/runtime/drivers/postgres"
 	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 func Test_sanitizeQuery(t *testing.T) {
@@ -36,3 +44,84 @@ SELECT 1, 2,     3 from         whateveR;
 		})
 	}
 }
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sql error " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func Test_sqlState(t *testing.T) {
+	require.Equal(t, pgInvalidTableDefinition, sqlState(fmt.Errorf("wrapped: %w", sqlStateError("42P16"))))
+	require.Equal(t, "42601", sqlState(sqlStateError("42601")))
+	require.Equal(t, "", sqlState(errors.New("not a driver error")))
+}
+
+// TestRecreatePostgres starts a Postgres container and checks that views which depend on a recreated view are recreated too
+func TestRecreatePostgres(t *testing.T) {
+	if testing.Short() {
+		t.Skip("postgres: skipping test in short mode")
+	}
+
+	ctx := context.Background()
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		Started: true,
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "postgres:14",
+			ExposedPorts: []string{"5432/tcp"},
+			WaitingFor:   wait.ForListeningPort("5432/tcp"),
+			Env: map[string]string{
+				"POSTGRES_USER":     "postgres",
+				"POSTGRES_PASSWORD": "postgres",
+				"POSTGRES_DB":       "postgres",
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer container.Terminate(ctx)
+
+	host, err := container.Host(ctx)
+	require.NoError(t, err)
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	require.NoError(t, err)
+
+	conn, err := drivers.Open("postgres", fmt.Sprintf("postgres://postgres:postgres@%s:%d/postgres", host, port.Int()))
+	require.NoError(t, err)
+	defer conn.Close()
+	olap, _ := conn.OLAPStore()
+
+	m := &modelMigrator{}
+	create := func(name, sql string) error {
+		return m.Create(ctx, olap, nil, &drivers.CatalogEntry{
+			Name:   name,
+			Type:   drivers.ObjectTypeModel,
+			Object: &runtimev1.Model{Name: name, Sql: sql},
+		})
+	}
+	query := func(sql string) []string {
+		rows, err := olap.Execute(ctx, &drivers.Statement{Query: sql})
+		require.NoError(t, err)
+		defer rows.Close()
+		var res []string
+		for rows.Next() {
+			var s string
+			require.NoError(t, rows.Scan(&s))
+			res = append(res, s)
+		}
+		require.NoError(t, rows.Err())
+		return res
+	}
+
+	// Two chained models on top of model_a
+	require.NoError(t, create("model_a", "SELECT 1 AS id, 'a' AS name"))
+	require.NoError(t, create("model_b", "SELECT id, name FROM model_a"))
+	require.NoError(t, create("model_c", "SELECT name FROM model_b WHERE id > 0"))
+
+	// Changing the leading columns of model_a requires recreating it along with model_b and model_c
+	require.NoError(t, create("model_a", "SELECT 'b' AS name, 2 AS id"))
+	require.Equal(t, []string{"b"}, query("SELECT name FROM model_c"))
+	require.Equal(t, []string{"b"}, query("SELECT name FROM model_b"))
+
+	// Dependent views that don't work with the new columns fail the update, which leaves all views unchanged
+	require.Error(t, create("model_a", "SELECT 'c' AS title"))
+	require.Equal(t, []string{"b"}, query("SELECT name FROM model_c"))
+}
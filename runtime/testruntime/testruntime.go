@@ -32,8 +32,9 @@ func New(t TestingT) *runtime.Runtime {
 		MetastoreDriver:     "sqlite",
 		// Setting a test-specific name ensures a unique connection when "cache=shared" is enabled.
 		// "cache=shared" is needed to prevent threading problems.
-		MetastoreDSN:   fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()),
-		QueryCacheSize: 10000,
+		MetastoreDSN:        fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()),
+		QueryCacheSizeBytes: 100 * 1024 * 1024,
+		ExportDir:           t.TempDir(),
 	}
 	rt, err := runtime.New(opts, nil)
 	require.NoError(t, err)